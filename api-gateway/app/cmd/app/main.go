@@ -2,36 +2,65 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/handlers"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/audit_log_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/event_cursors_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/expr_tree_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/expressions_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/operator_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/organization_quotas_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/task_attempts_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_events_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_stats_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/workers_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/servers"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/servers/grpcsrv"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/api_keys"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/eta"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expressions_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/health_probes"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/operators_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/organization_quotas"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/quarantine"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/retention"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/roles"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/sessions"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/signing_keys"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/task_owners"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/task_queue"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/user_events"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_admin"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_api"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_credentials"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_states"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jwt"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/servicetoken"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/tlsconfig"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/util/calc"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/util/configs"
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 func main() {
 	wg := &sync.WaitGroup{}
+	ctx := context.Background()
 
 	envInit()
 
@@ -44,49 +73,209 @@ func main() {
 		log.Fatalf("error while starting postgresql: %s", err.Error())
 	}
 
+	transactor := postgres.NewTransactor(db)
+
 	expressionsRepository := expressions_repository.NewExpressionsRepository(db)
 	binaryTreeRepository := expr_tree_repository.NewExpressionsTreeRepository(db)
 	workersRepository := workers_repository.NewWorkersRepository(db)
 	operatorsRepository := operator_repository.NewOperatorsRepository(db)
+	workerEventsRepository := worker_events_repository.NewWorkerEventsRepository(db)
+	workerStatsRepository := worker_stats_repository.NewWorkerStatsRepository(db)
+	taskAttemptsRepository := task_attempts_repository.NewTaskAttemptsRepository(db)
+	eventCursorsRepository := event_cursors_repository.NewEventCursorsRepository(db)
+	organizationQuotasRepository := organization_quotas_repository.NewOrganizationQuotasRepository(db)
+	auditLogRepository := audit_log_repository.NewAuditLogRepository(db)
+
+	monitoringPeriod := durationEnv("WORKERS_MONITORING_PERIOD_MS", 30*time.Second)
 
-	workersStorage := workers_storage.NewWorkerStorage(workersRepository)
+	workersStorage := workers_storage.NewWorkerStorage(workersRepository, workerStatsRepository, &worker_states.Policy{
+		Period:       monitoringPeriod,
+		SuspectAfter: intEnv("WORKER_SUSPECT_AFTER_MISSES", 1),
+		DeadAfter:    intEnv("WORKER_DEAD_AFTER_MISSES", 3),
+	})
 	expressionStorage := expressions_storage.NewExpressionStorage(expressionsRepository)
-	binaryTreeStorage := binary_tree_storage.NewBinaryTreeStorage(binaryTreeRepository)
+	binaryTreeStorage := binary_tree_storage.NewBinaryTreeStorage(transactor, binaryTreeRepository, taskAttemptsRepository)
 	operatorsStorage := operators_storage.NewOperatorsStorage(operatorsRepository)
 
-	workerAPI := worker_api.NewGRPCWorkerAPI()
+	tlsReloader, err := tlsconfig.Load(tlsconfig.FromEnv(os.Getenv))
+	if err != nil {
+		log.Fatalf("error while loading tls certificates: %s", err.Error())
+	}
+
+	tlsReloader.Watch(ctx, durationEnv("TLS_RELOAD_PERIOD_MS", time.Minute), func(err error) {
+		log.Printf("tls certificates reload error: %s", err.Error())
+	})
+
+	workerAPI := worker_api.NewGRPCWorkerAPI(tlsReloader.ClientCredentials())
+
+	subtreeLimits := calc.SubtreeLimits{
+		MaxNodes: intEnv("SUBTREE_MAX_NODES", 0),
+		MaxCost:  durationEnv("SUBTREE_MAX_COST_MS", 0),
+	}
+
+	monitor := quarantine.NewMonitor(workersStorage, binaryTreeStorage, workerEventsRepository, &quarantine.Policy{
+		CrossCheckRate: floatEnv("CROSS_CHECK_RATE", 0.05),
+		MinTasks:       intEnv("QUARANTINE_MIN_TASKS", 20),
+		MaxErrorRate:   floatEnv("QUARANTINE_ERROR_RATE", 0.3),
+		MaxMismatches:  intEnv("QUARANTINE_MAX_MISMATCHES", 3),
+	})
+
+	auditLog := audit_log.NewAuditLog(auditLogRepository)
+
+	workerAdmin := worker_admin.NewWorkerAdmin(workersStorage, binaryTreeStorage, workerEventsRepository, auditLog)
+
+	taskQueue := task_queue.NewTaskQueue(
+		binaryTreeStorage,
+		operatorsStorage,
+		expressionStorage,
+		monitor,
+		durationEnv("TASK_LEASE_MS", 60*time.Second),
+	)
 
-	tokensGenerator := &jwt.TokenGenerator{
-		Signature: []byte(os.Getenv("JWT_SIGNATURE")),
-		TokenTTL:  12 * time.Hour,
+	// Access tokens are verified with the public keys of the auth service, so the api-gateway cannot issue them
+	jwksURL := os.Getenv("AUTH_JWKS_URL")
+	if jwksURL == "" {
+		log.Fatal("AUTH_JWKS_URL is required to verify access tokens")
 	}
 
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsReloader.ClientConfig()
+
+	signingKeys := signing_keys.NewJWKSKeyProvider(
+		jwksURL,
+		&http.Client{Transport: transport},
+		durationEnv("JWKS_CACHE_TTL_MS", 5*time.Minute),
+	)
+
+	tokensGenerator := &jwt.TokenGenerator{Keys: signingKeys}
+
+	var (
+		workerCredentials worker_credentials.Verifier
+		revocations       sessions.RevocationChecker
+		apiKeys           api_keys.Verifier
+		userEvents        user_events.Consumer
+	)
+
+	if host := os.Getenv("AUTH_GRPC_HOST"); host != "" {
+		// Internal methods of the auth service accept only callers with the shared service token
+		serviceToken := os.Getenv("SERVICE_TOKEN")
+		if serviceToken == "" {
+			log.Fatal("SERVICE_TOKEN is required with AUTH_GRPC_HOST")
+		}
+
+		serviceCredentials := servicetoken.Credentials(serviceToken)
+
+		workerCredentials = worker_credentials.NewCachedVerifier(
+			worker_credentials.NewGRPCVerifier(host, tlsReloader.ClientCredentials(), serviceCredentials),
+			durationEnv("WORKER_CREDENTIALS_CACHE_TTL_MS", 30*time.Second),
+		)
+
+		revocations = sessions.NewCachedRevocationChecker(
+			sessions.NewGRPCRevocationChecker(host, tlsReloader.ClientCredentials(), serviceCredentials),
+			durationEnv("SESSIONS_CACHE_TTL_MS", 10*time.Second),
+		)
+
+		apiKeys = api_keys.NewCachedVerifier(
+			api_keys.NewGRPCVerifier(host, tlsReloader.ClientCredentials(), serviceCredentials),
+			durationEnv("API_KEYS_CACHE_TTL_MS", 30*time.Second),
+		)
+
+		batchSize := intEnv("USER_EVENTS_BATCH", 100)
+		userEvents = user_events.NewConsumer(
+			user_events.NewGRPCFeed(host, tlsReloader.ClientCredentials(), serviceCredentials),
+			eventCursorsRepository,
+			user_events.NewExpressionsEraser(transactor, expressionStorage, binaryTreeStorage, batchSize),
+			batchSize,
+		)
+	}
+
+	workerToken := os.Getenv("WORKER_TOKEN")
+	if workerToken == "" && workerCredentials == nil {
+		log.Print("WORKER_TOKEN and AUTH_GRPC_HOST are empty, workers will not be able to connect")
+	}
+
+	authorizer := roles.NewAuthorizer(tokensGenerator, workerCredentials, revocations, apiKeys, &roles.Policy{
+		AdminLogins:    listEnv("ADMIN_LOGINS"),
+		OperatorLogins: listEnv("OPERATOR_LOGINS"),
+		WorkerToken:    workerToken,
+	})
+
+	taskOwners := task_owners.NewChecker(workersStorage, binaryTreeStorage)
+
+	quotas := organization_quotas.NewQuotas(organizationQuotasRepository, expressionStorage, organization_quotas.Defaults{
+		MaxActiveExpressions: intEnv("ORGANIZATION_MAX_ACTIVE_EXPRESSIONS", 0),
+		MaxDailyExpressions:  intEnv("ORGANIZATION_MAX_DAILY_EXPRESSIONS", 0),
+	})
+
 	handler := handlers.NewHTTPHandler(
+		transactor,
 		expressionStorage,
 		binaryTreeStorage,
 		workersStorage,
 		operatorsStorage,
 		workerAPI,
-		tokensGenerator,
+		subtreeLimits,
+		taskQueue,
+		monitor,
+		workerAdmin,
+		eta.NewEstimator(binaryTreeStorage, operatorsStorage, workersStorage),
+		authorizer,
+		taskOwners,
+		quotas,
+		auditLog,
 	)
-	server := servers.NewHTTPServer(httpPort, handler.InitRoutes())
+	// The HTTP API is called by browsers, so it uses TLS only on demand and never requires client certificates
+	var httpTLSConfig *tls.Config
+	if os.Getenv("TLS_HTTP") == "true" {
+		httpTLSConfig = tlsReloader.ServerConfig(false)
+	}
+
+	server := servers.NewHTTPServer(httpPort, handler.InitRoutes(), httpTLSConfig)
 
-	err = operationsInit(operatorsStorage, 500)
+	err = operationsInit(ctx, operatorsStorage, 500)
 	if err != nil {
 		log.Fatalf("operators init error: %s", err.Error())
 	}
 
-	err = binaryTreeStorage.DeleteAllWorkers()
+	err = binaryTreeStorage.DeleteAllWorkers(ctx)
 	if err != nil {
 		log.Fatalf("all workers from binary tree deleting error: %s", err.Error())
 	}
 
-	_, err = workersStorage.DeleteExpiredWorkers(time.Now())
+	_, err = workersStorage.DetectFailures(ctx, time.Now())
 	if err != nil {
-		log.Fatalf("all workers deleting error: %s", err.Error())
+		log.Fatalf("workers failure detection error: %s", err.Error())
 	}
 
-	monitorWorkers(workersStorage, binaryTreeStorage)
+	monitorWorkers(ctx, monitoringPeriod, workersStorage, binaryTreeStorage, taskQueue)
+
+	prober := health_probes.NewProber(workersStorage, workerAPI, workerEventsRepository, &health_probes.Policy{
+		Timeout:          durationEnv("WORKER_PROBE_TIMEOUT_MS", 2*time.Second),
+		UnreachableAfter: intEnv("WORKER_UNREACHABLE_AFTER_PROBES", 3),
+	})
+	probeWorkers(ctx, durationEnv("WORKER_PROBE_PERIOD_MS", 10*time.Second), prober)
+
+	var archive retention.Archive
+	if dir := os.Getenv("EXPRESSIONS_ARCHIVE_DIR"); dir != "" {
+		archive = retention.NewFileArchive(dir)
+	}
+
+	compactor, err := retention.NewCompactor(transactor, expressionStorage, binaryTreeStorage, archive, &retention.Policy{
+		TreeMaxAge:   durationEnv("EXPRESSION_TREE_RETENTION_MS", 24*time.Hour),
+		MaxAge:       durationEnv("EXPRESSION_RETENTION_MS", 0),
+		MaxPerUser:   intEnv("EXPRESSION_MAX_PER_USER", 0),
+		FailedMaxAge: durationEnv("FAILED_EXPRESSION_RETENTION_MS", 0),
+		BatchSize:    intEnv("EXPRESSION_COMPACTION_BATCH", 100),
+	})
+	if err != nil {
+		log.Fatalf("retention policy error: %s", err.Error())
+	}
+
+	compactExpressions(ctx, durationEnv("EXPRESSION_COMPACTION_PERIOD_MS", 10*time.Minute), compactor)
+
+	if userEvents != nil {
+		consumeUserEvents(ctx, durationEnv("USER_EVENTS_PERIOD_MS", 30*time.Second), userEvents)
+	}
 
 	wg.Add(1)
 	go func() {
@@ -97,14 +286,24 @@ func main() {
 		}
 	}()
 
-	gRPCServer := grpc.NewServer()
+	gRPCServer := grpc.NewServer(
+		grpc.Creds(tlsReloader.ServerCredentials()),
+		grpc.UnaryInterceptor(grpcsrv.NewAuthInterceptor(authorizer)),
+	)
 	grpcsrv.Register(
 		gRPCServer,
+		transactor,
 		binaryTreeStorage,
 		operatorsStorage,
 		workersStorage,
 		expressionStorage,
 		workerAPI,
+		subtreeLimits,
+		taskQueue,
+		monitor,
+		workerAdmin,
+		taskOwners,
+		auditLog,
 	)
 
 	wg.Add(1)
@@ -122,48 +321,166 @@ func main() {
 	wg.Wait()
 }
 
+// probeWorkers probes workers with gRPC health checks every period. Zero period disables probes
+func probeWorkers(ctx context.Context, period time.Duration, prober health_probes.Prober) {
+	if period <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(period)
+
+		defer ticker.Stop()
+
+		for range ticker.C {
+			err := prober.ProbeAll(ctx)
+			if err != nil {
+				log.Printf("workers probing error: %s", err.Error())
+			}
+		}
+	}()
+}
+
+// compactExpressions applies the retention policy every period. Zero period disables compaction
+func compactExpressions(ctx context.Context, period time.Duration, compactor retention.Compactor) {
+	if period <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(period)
+
+		defer ticker.Stop()
+
+		for t := range ticker.C {
+			report, err := compactor.Compact(ctx, t)
+			if err != nil {
+				log.Printf("expressions compaction error: %s", err.Error())
+			}
+
+			if report.Deleted > 0 || report.Compacted > 0 {
+				log.Printf("expressions compaction: %d deleted, %d trees deleted", report.Deleted, report.Compacted)
+			}
+		}
+	}()
+}
+
+// consumeUserEvents deletes expressions of accounts deleted in the auth service every period. Zero period disables it
+func consumeUserEvents(ctx context.Context, period time.Duration, consumer user_events.Consumer) {
+	if period <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(period)
+
+		defer ticker.Stop()
+
+		for range ticker.C {
+			handled, err := consumer.Consume(ctx)
+			if err != nil {
+				log.Printf("user events consuming error: %s", err.Error())
+			}
+
+			if handled > 0 {
+				log.Printf("user events: %d handled", handled)
+			}
+		}
+	}()
+}
+
 func envInit() {
 	if err := godotenv.Load(); err != nil {
 		log.Print(err)
 	}
 }
 
+// monitorWorkers runs the failure detector every period. Tasks are reassigned
+// only when their worker is declared dead, suspect workers keep them
 func monitorWorkers(
+	ctx context.Context,
+	period time.Duration,
 	workerStorage workers_storage.WorkerStorage,
 	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
+	taskQueue task_queue.TaskQueue,
 ) {
-	period, err := strconv.Atoi(os.Getenv("WORKERS_MONITORING_PERIOD_MS"))
-	if err != nil {
-		log.Fatalf("workers monitoring error: %s", err.Error())
-	}
-
 	go func() {
-		dur := time.Duration(period) * time.Millisecond
-		ticker := time.NewTicker(dur)
+		ticker := time.NewTicker(period)
 
 		defer ticker.Stop()
 
 		for {
 			select {
 			case t := <-ticker.C:
-				workerIds, err := workerStorage.DeleteExpiredWorkers(t.Add(-1 * dur))
+				workerIds, err := workerStorage.DetectFailures(ctx, t)
 				if err != nil {
-					log.Fatalf("expired workers deleting error: %s", err.Error())
+					log.Fatalf("workers failure detection error: %s", err.Error())
 				}
 
 				if len(workerIds) > 0 {
-					err = binaryTreeStorage.DeleteWorkers(workerIds)
+					err = binaryTreeStorage.DeleteWorkers(ctx, workerIds)
 					if err != nil {
 						log.Fatalf("binary tree cleaning error: %s", err.Error())
 					}
 				}
+
+				err = taskQueue.ReleaseExpired(ctx)
+				if err != nil {
+					log.Printf("expired task leases releasing error: %s", err.Error())
+				}
 			}
 		}
 	}()
 }
 
-func operationsInit(storage operators_storage.OperatorsStorage, defaultDurationMS int) error {
-	operations, err := storage.FindAll()
+func intEnv(key string, defaultValue int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return defaultValue
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatalf("invalid %s: %s", key, value)
+	}
+
+	return n
+}
+
+func floatEnv(key string, defaultValue float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return defaultValue
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		log.Fatalf("invalid %s: %s", key, value)
+	}
+
+	return f
+}
+
+// listEnv returns comma separated values of key
+func listEnv(key string) []string {
+	var values []string
+
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+func durationEnv(key string, defaultValue time.Duration) time.Duration {
+	return time.Duration(intEnv(key, int(defaultValue.Milliseconds()))) * time.Millisecond
+}
+
+func operationsInit(ctx context.Context, storage operators_storage.OperatorsStorage, defaultDurationMS int) error {
+	operations, err := storage.FindAll(ctx)
 	if err != nil {
 		return err
 	}
@@ -172,7 +489,7 @@ func operationsInit(storage operators_storage.OperatorsStorage, defaultDurationM
 		return nil
 	}
 
-	err = storage.SaveAll([]*dto.OperationDTO{
+	err = storage.SaveAll(ctx, []*dto.OperationDTO{
 		{
 			OperationType: expr_tokens.Plus,
 			DurationMS:    defaultDurationMS,
@@ -0,0 +1,53 @@
+// Command devca issues certificates of a development CA for the docker-compose setup:
+//
+//	go run ./app/cmd/devca -out ../certs -names api-gateway,auth,daemon1,daemon2
+//
+// The CA in the output directory is reused, so running the command again rotates service certificates only
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/devca"
+)
+
+func main() {
+	var (
+		out      = flag.String("out", "certs", "output directory")
+		names    = flag.String("names", "api-gateway,auth,daemon1,daemon2", "comma separated services to issue certificates for")
+		hosts    = flag.String("hosts", "localhost,127.0.0.1", "comma separated hosts added to every certificate")
+		validFor = flag.Duration("valid-for", 365*24*time.Hour, "validity of issued certificates")
+	)
+
+	flag.Parse()
+
+	ca, err := devca.LoadOrCreate(*out, 10*365*24*time.Hour)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, name := range split(*names) {
+		err = ca.Issue(*out, name, split(*hosts), *validFor)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("issued %s/%s.crt", *out, name)
+	}
+}
+
+func split(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
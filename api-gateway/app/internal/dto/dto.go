@@ -1,27 +1,61 @@
 package dto
 
 import (
+	"encoding/json"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/capabilities"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/statuses"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_states"
 	"time"
 )
 
 type CalculationRequestDTO struct {
-	Expression     string `json:"expression"`
-	IdempotencyKey string `json:"idempotencyKey"`
+	Expression     string            `json:"expression"`
+	IdempotencyKey string            `json:"idempotencyKey"`
+	Selector       map[string]string `json:"selector"`
 }
 
 type CalculationResponseDTO struct {
-	Id int `json:"id"`
+	Id                int        `json:"id"`
+	EstimatedFinishAt *time.Time `json:"estimatedFinishAt,omitempty"`
 }
 
 type ExpressionResponseDTO struct {
-	Id         int       `json:"id"`
-	Expression string    `json:"expression"`
-	CreatedAt  time.Time `json:"createdAt"`
-	FinishedAt time.Time `json:"finishedAt"`
-	Status     int       `json:"status"`
-	Result     float64   `json:"result"`
+	Id         int               `json:"id"`
+	UserID     uint64            `json:"userId"`
+	Expression string            `json:"expression"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
+	Status     int               `json:"status"`
+	Result     float64           `json:"result"`
+	Selector   map[string]string `json:"selector,omitempty"`
+	// OrganizationID is zero for expressions of the personal workspace of the user
+	OrganizationID uint64 `json:"organizationId,omitempty"`
+
+	EstimatedFinishAt *time.Time `json:"estimatedFinishAt,omitempty"`
+	// CompactedAt is set when the tree of the expression has been deleted by the retention policy
+	CompactedAt *time.Time `json:"compactedAt,omitempty"`
+}
+
+// ExpressionsQueryDTO selects a page of the expressions list. Time ranges include their start and exclude their end
+type ExpressionsQueryDTO struct {
+	Statuses     []int      `form:"status"`
+	CreatedFrom  *time.Time `form:"createdFrom" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo    *time.Time `form:"createdTo" time_format:"2006-01-02T15:04:05Z07:00"`
+	FinishedFrom *time.Time `form:"finishedFrom" time_format:"2006-01-02T15:04:05Z07:00"`
+	FinishedTo   *time.Time `form:"finishedTo" time_format:"2006-01-02T15:04:05Z07:00"`
+	Search       string     `form:"q"`
+	Sort         string     `form:"sort" binding:"omitempty,oneof=createdAt finishedAt id"`
+	Order        string     `form:"order" binding:"omitempty,oneof=asc desc"`
+	Cursor       string     `form:"cursor"`
+	Limit        int        `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type ExpressionsPageDTO struct {
+	Expressions []*ExpressionResponseDTO
+	Total       int
+	// NextCursor is empty on the last page
+	NextCursor string
 }
 
 type OperationDTO struct {
@@ -30,20 +64,82 @@ type OperationDTO struct {
 }
 
 type WorkerRequestDTO struct {
-	Id        uint64 `json:"id"`
-	Url       string `json:"url"`
-	Executors int    `json:"executors"`
+	Id           uint64                     `json:"id"`
+	Secret       string                     `json:"secret"`
+	Url          string                     `json:"url"`
+	Executors    int                        `json:"executors"`
+	Capabilities *capabilities.Capabilities `json:"capabilities"`
+	Pull         bool                       `json:"pull"`
+}
+
+type WorkerRegistrationDTO struct {
+	Id     int    `json:"id"`
+	Secret string `json:"secret,omitempty"`
+	Exists bool   `json:"-"`
 }
 
 type WorkerResponseDTO struct {
-	Id           int       `json:"id"`
-	Url          string    `json:"url"`
-	Executors    int       `json:"executors"`
-	LastModified time.Time `json:"lastModified"`
+	Id               int                        `json:"id"`
+	Url              string                     `json:"url"`
+	Executors        int                        `json:"executors"`
+	LastModified     time.Time                  `json:"lastModified"`
+	Capabilities     *capabilities.Capabilities `json:"capabilities"`
+	Pull             bool                       `json:"pull"`
+	State            worker_states.State        `json:"state"`
+	StateChangedAt   time.Time                  `json:"stateChangedAt"`
+	LastHeartbeat    time.Time                  `json:"lastHeartbeat"`
+	InFlight         int                        `json:"inFlight"`
+	MissedHeartbeats int                        `json:"missedHeartbeats"`
+	Deaths           int                        `json:"deaths"`
+
+	TasksCompleted    int    `json:"tasksCompleted"`
+	TasksTimedOut     int    `json:"tasksTimedOut"`
+	ResultsMismatched int    `json:"resultsMismatched"`
+	QuarantineReason  string `json:"quarantineReason,omitempty"`
+
+	Cordoned            bool              `json:"cordoned"`
+	AdvertisedExecutors int               `json:"advertisedExecutors"`
+	AdminLabels         map[string]string `json:"adminLabels,omitempty"`
+
+	Unreachable   bool       `json:"unreachable"`
+	LastProbe     *time.Time `json:"lastProbe,omitempty"`
+	ProbeFailures int        `json:"probeFailures"`
+}
+
+type WorkerStatsDTO struct {
+	State             worker_states.State
+	TasksCompleted    int
+	TasksTimedOut     int
+	ResultsMismatched int
+}
+
+type OperationStatsDTO struct {
+	OperationType expr_tokens.OperationType `json:"operationType"`
+	Samples       int                       `json:"samples"`
+	MeanMS        float64                   `json:"meanMS"`
+	MinMS         float64                   `json:"minMS"`
+	MaxMS         float64                   `json:"maxMS"`
+	LastMS        float64                   `json:"lastMS"`
+	UpdatedAt     time.Time                 `json:"updatedAt"`
+}
+
+type WorkerEventDTO struct {
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WorkerExecutorsRequestDTO struct {
+	Executors *int `json:"executors"`
+}
+
+type WorkerLabelsRequestDTO struct {
+	Labels map[string]string `json:"labels"`
 }
 
 type CalculationResultDTO struct {
-	Result float64 `json:"result"`
+	Result        float64 `json:"result"`
+	ComputeTimeUS int64   `json:"computeTimeUS"`
 }
 
 type ExpressionNodeDTO struct {
@@ -56,6 +152,28 @@ type ExpressionNodeDTO struct {
 	Status        statuses.Status `json:"status"`
 	Result        float64         `json:"result"`
 	WorkerId      int             `json:"workerId"`
+	ComputeTimeUS int64           `json:"computeTimeUS"`
+	StartedAt     *time.Time      `json:"startedAt,omitempty"`
+	Critical      bool            `json:"critical,omitempty"`
+}
+
+type ExpressionTreeDTO struct {
+	EstimatedFinishAt *time.Time           `json:"estimatedFinishAt,omitempty"`
+	CriticalPath      []int                `json:"criticalPath"`
+	Nodes             []*ExpressionNodeDTO `json:"nodes"`
+}
+
+type TaskAttemptDTO struct {
+	Id            int                       `json:"id"`
+	NodeId        int                       `json:"nodeId"`
+	WorkerId      int                       `json:"workerId"`
+	Attempt       int                       `json:"attempt"`
+	OperationType expr_tokens.OperationType `json:"operationType"`
+	EnqueuedAt    time.Time                 `json:"enqueuedAt"`
+	StartedAt     *time.Time                `json:"startedAt,omitempty"`
+	FinishedAt    *time.Time                `json:"finishedAt,omitempty"`
+	Outcome       string                    `json:"outcome"`
+	Error         string                    `json:"error,omitempty"`
 }
 
 type TaskDTO struct {
@@ -64,3 +182,80 @@ type TaskDTO struct {
 	RightResult   float64         `json:"rightResult"`
 	Status        statuses.Status `json:"status"`
 }
+
+type ReadyTaskDTO struct {
+	Id             int
+	UserID         uint64
+	OrganizationID uint64
+	ExpressionId   int
+	OperationType  int
+	LeftResult     float64
+	RightResult    float64
+	Selector       map[string]string
+}
+
+type AcquireTasksRequestDTO struct {
+	Max    int `json:"max"`
+	WaitMS int `json:"waitMS"`
+}
+
+type LeasedTaskDTO struct {
+	Id             int                       `json:"id"`
+	UserID         uint64                    `json:"userId"`
+	First          float64                   `json:"first"`
+	Second         float64                   `json:"second"`
+	Operation      expr_tokens.OperationType `json:"operation"`
+	DurationMS     int64                     `json:"durationMS"`
+	LeaseExpiresAt time.Time                 `json:"leaseExpiresAt"`
+}
+
+// OrganizationQuotaDTO describes effective limits of an organization and their usage. Zero limits mean no limit.
+// Daily expressions are expressions created during the last 24 hours
+type OrganizationQuotaDTO struct {
+	OrganizationID       uint64 `json:"organizationId"`
+	MaxActiveExpressions int    `json:"maxActiveExpressions"`
+	MaxDailyExpressions  int    `json:"maxDailyExpressions"`
+	ActiveExpressions    int    `json:"activeExpressions"`
+	DailyExpressions     int    `json:"dailyExpressions"`
+}
+
+// OrganizationQuotaRequestDTO sets limits of an organization. Null limits return to the defaults, zero limits disable them
+type OrganizationQuotaRequestDTO struct {
+	MaxActiveExpressions *int `json:"maxActiveExpressions"`
+	MaxDailyExpressions  *int `json:"maxDailyExpressions"`
+}
+
+// AuditEntryDTO is a record of the audit log. Before and After are states of the target, if the action has them
+type AuditEntryDTO struct {
+	Id         int64           `json:"id"`
+	ActorType  string          `json:"actorType"`
+	ActorId    uint64          `json:"actorId"`
+	ActorName  string          `json:"actorName,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType,omitempty"`
+	TargetId   string          `json:"targetId,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IP         string          `json:"ip,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// AuditQueryDTO filters the audit log. The time range includes its start and excludes its end
+type AuditQueryDTO struct {
+	ActorType  string     `form:"actorType"`
+	ActorId    *uint64    `form:"actorId"`
+	Action     string     `form:"action"`
+	TargetType string     `form:"targetType"`
+	TargetId   string     `form:"targetId"`
+	From       *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Cursor is the id of the last record of the previous page
+	Cursor int64 `form:"cursor" binding:"omitempty,min=0"`
+	Limit  int   `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type AuditPageDTO struct {
+	Entries []*AuditEntryDTO
+	// NextCursor is zero on the last page
+	NextCursor int64
+}
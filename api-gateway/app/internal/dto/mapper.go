@@ -1,16 +1,52 @@
 package dto
 
 import (
+	"encoding/json"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/audit_log_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/expressions_repository"
 )
 
 func MapExpressionResponseFromEntity(entity *expressions_repository.ExpressionEntity) *ExpressionResponseDTO {
-	return &ExpressionResponseDTO{
+	var selector map[string]string
+	if len(entity.Selector) > 0 {
+		_ = json.Unmarshal(entity.Selector, &selector)
+	}
+
+	response := &ExpressionResponseDTO{
 		Id:         entity.Id,
+		UserID:     entity.UserID,
 		Expression: entity.Expression,
 		CreatedAt:  entity.CreatedAt,
 		FinishedAt: entity.FinishedAt,
 		Status:     entity.Status,
 		Result:     entity.Result,
+		Selector:   selector,
+	}
+
+	if entity.OrganizationID.Valid {
+		response.OrganizationID = uint64(entity.OrganizationID.Int64)
+	}
+
+	if entity.CompactedAt.Valid {
+		response.CompactedAt = &entity.CompactedAt.Time
+	}
+
+	return response
+}
+
+func MapAuditEntryFromEntity(entity *audit_log_repository.AuditEntity) *AuditEntryDTO {
+	return &AuditEntryDTO{
+		Id:         entity.Id,
+		ActorType:  entity.ActorType,
+		ActorId:    entity.ActorId,
+		ActorName:  entity.ActorName,
+		Action:     entity.Action,
+		TargetType: entity.TargetType,
+		TargetId:   entity.TargetId,
+		Before:     entity.Before,
+		After:      entity.After,
+		IP:         entity.IP,
+		CreatedAt:  entity.CreatedAt,
 	}
 }
@@ -1,51 +1,100 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/handlers/middlewares"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/eta"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expressions_storage"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/operators_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/organization_quotas"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/quarantine"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/roles"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/statuses"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/task_owners"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/task_queue"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_admin"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_api"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
-	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jwt"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/util/calc"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 const tempUserID = 1
 
+// workerIdHeader and workerSecretHeader carry the identity issued to a worker on registration
+const (
+	workerIdHeader     = "X-Worker-Id"
+	workerSecretHeader = "X-Worker-Secret"
+)
+
+const (
+	totalCountHeader = "X-Total-Count"
+	nextCursorHeader = "X-Next-Cursor"
+)
+
 type HTTPHandler struct {
+	transactor        postgres.Transactor
 	expressionStorage expressions_storage.ExpressionStorage
 	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
 	workersStorage    workers_storage.WorkerStorage
 	operatorsStorage  operators_storage.OperatorsStorage
 	workerAPI         worker_api.WorkerAPI
-
-	tokensGenerator *jwt.TokenGenerator
+	subtreeLimits     calc.SubtreeLimits
+	taskQueue         task_queue.TaskQueue
+	monitor           quarantine.Monitor
+	workerAdmin       worker_admin.WorkerAdmin
+	estimator         eta.Estimator
+	authorizer        roles.Authorizer
+	taskOwners        task_owners.Checker
+	quotas            organization_quotas.Quotas
+	auditLog          audit_log.AuditLog
 }
 
 func NewHTTPHandler(
+	transactor postgres.Transactor,
 	expressionStorage expressions_storage.ExpressionStorage,
 	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
 	workersStorage workers_storage.WorkerStorage,
 	operatorsStorage operators_storage.OperatorsStorage,
 	workerAPI worker_api.WorkerAPI,
-	tokensGenerator *jwt.TokenGenerator,
+	subtreeLimits calc.SubtreeLimits,
+	taskQueue task_queue.TaskQueue,
+	monitor quarantine.Monitor,
+	workerAdmin worker_admin.WorkerAdmin,
+	estimator eta.Estimator,
+	authorizer roles.Authorizer,
+	taskOwners task_owners.Checker,
+	quotas organization_quotas.Quotas,
+	auditLog audit_log.AuditLog,
 ) *HTTPHandler {
 	return &HTTPHandler{
+		transactor:        transactor,
 		expressionStorage: expressionStorage,
 		binaryTreeStorage: binaryTreeStorage,
 		workersStorage:    workersStorage,
 		operatorsStorage:  operatorsStorage,
 		workerAPI:         workerAPI,
-		tokensGenerator:   tokensGenerator,
+		subtreeLimits:     subtreeLimits,
+		taskQueue:         taskQueue,
+		monitor:           monitor,
+		workerAdmin:       workerAdmin,
+		estimator:         estimator,
+		authorizer:        authorizer,
+		taskOwners:        taskOwners,
+		quotas:            quotas,
+		auditLog:          auditLog,
 	}
 }
 
@@ -54,35 +103,65 @@ func (h *HTTPHandler) InitRoutes() *gin.Engine {
 
 	router.Use(middlewares.CORSHeaders())
 
-	jwtAuth := middlewares.NewJWTAuthMiddleware(h.tokensGenerator)
+	auth := middlewares.NewAuthMiddleware(h.authorizer)
 
 	api := router.Group("/api")
 	{
-		api.GET("/operators", h.getAllOperations)
-		api.POST("/operators", h.saveAllOperations)
-
-		api.POST("/expression", jwtAuth(), h.calculateExpression)
-		api.GET("/expressions", jwtAuth(), h.getAllExpressions)
-		api.GET("/expression/:id", h.handleExpressionStatusRequest)
-
-		api.POST("/task/:id/result", h.handleTaskResult)
-		api.POST("/task/:id/status", h.handleTaskStarting)
-
-		api.POST("/worker", h.handleWorkerRegister)
-		api.GET("/worker/:id/tasks", h.handleWorkerTasks)
-		api.GET("/workers", h.getAllWorkers)
+		api.GET("/operators", auth(roles.User, roles.Worker), h.getAllOperations)
+		api.POST("/operators", auth(roles.Operator), h.saveAllOperations)
+
+		api.PUT("/organization/operators", auth(roles.User), h.saveOrganizationOperations)
+		api.GET("/organization/quota", auth(roles.User), h.getOrganizationQuota)
+
+		api.POST("/expression", auth(roles.User), h.calculateExpression)
+		api.GET("/expressions", auth(roles.User), h.getAllExpressions)
+		api.GET("/expression/:id", auth(roles.User), h.handleExpressionStatusRequest)
+		api.GET("/expression/:id/tree", auth(roles.User), h.getExpressionTree)
+		api.GET("/expression/:id/attempts", auth(roles.User), h.getExpressionAttempts)
+
+		api.POST("/task/:id/result", auth(roles.Worker), h.handleTaskResult)
+		api.POST("/task/:id/status", auth(roles.Worker), h.handleTaskStarting)
+
+		api.POST("/worker", auth(roles.Worker), h.handleWorkerRegister)
+		api.POST("/worker/:id/tasks", auth(roles.Worker), h.acquireWorkerTasks)
+
+		api.GET("/worker/:id/tasks", auth(roles.Operator), h.handleWorkerTasks)
+		api.GET("/worker/:id/events", auth(roles.Operator), h.getWorkerEvents)
+		api.GET("/workers", auth(roles.Operator), h.getAllWorkers)
+		api.GET("/workers/:id/stats", auth(roles.Operator), h.getWorkerStats)
+
+		admin := api.Group("/admin", auth(roles.Admin))
+		{
+			admin.POST("/workers/:id/release", h.releaseWorker)
+			admin.POST("/workers/:id/cordon", h.cordonWorker)
+			admin.POST("/workers/:id/uncordon", h.uncordonWorker)
+			admin.POST("/workers/:id/drain", h.drainWorker)
+			admin.POST("/workers/:id/evict", h.evictWorker)
+			admin.PUT("/workers/:id/executors", h.setWorkerExecutors)
+			admin.PUT("/workers/:id/labels", h.setWorkerLabels)
+
+			admin.GET("/organizations/:id/quota", h.getQuota)
+			admin.PUT("/organizations/:id/quota", h.saveQuota)
+
+			admin.GET("/audit", h.getAuditLog)
+			admin.GET("/audit/export", h.exportAuditLog)
+		}
 	}
 
 	return router
 }
 
 func (h *HTTPHandler) calculateExpression(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID, err := userID(c)
 	if err != nil {
 		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
 		return
 	}
 
+	var organizationID = organizationID(c)
+
 	var calculationRequest dto.CalculationRequestDTO
 
 	err = c.BindJSON(&calculationRequest)
@@ -103,7 +182,7 @@ func (h *HTTPHandler) calculateExpression(c *gin.Context) {
 	}
 
 	if calculationRequest.IdempotencyKey != "" {
-		id, err := h.expressionStorage.FindByIdempotencyKey(calculationRequest.IdempotencyKey, expr)
+		id, err := h.expressionStorage.FindByIdempotencyKey(ctx, userID, calculationRequest.IdempotencyKey, expr)
 		if err != nil {
 			dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 			return
@@ -117,12 +196,6 @@ func (h *HTTPHandler) calculateExpression(c *gin.Context) {
 		}
 	}
 
-	expressionId, err := h.expressionStorage.Create(expr, userID, calculationRequest.IdempotencyKey)
-	if err != nil {
-		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
-		return
-	}
-
 	tokens, err := expression.TokenizeExpression(&expr)
 	if err != nil {
 		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
@@ -131,33 +204,64 @@ func (h *HTTPHandler) calculateExpression(c *gin.Context) {
 
 	root := binary_tree.NewBinaryTree(binary_tree.TokensToNodeArray(tokens))
 
-	taskId, err := h.binaryTreeStorage.SaveTree(root, userID, expressionId, -1, true)
+	var expressionId, taskId int
+
+	// the expression is never visible without its tree
+	err = h.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := h.quotas.Check(ctx, organizationID)
+		if err != nil {
+			return err
+		}
+
+		expressionId, err = h.expressionStorage.Create(ctx, expr, userID, organizationID, calculationRequest.IdempotencyKey, calculationRequest.Selector)
+		if err != nil {
+			return err
+		}
+
+		taskId, err = h.binaryTreeStorage.SaveTree(ctx, root, userID, expressionId)
+		return err
+	})
+	if errors.Is(err, organization_quotas.ErrQuotaExceeded) {
+		dto.NewResponseError(http.StatusTooManyRequests, err.Error()).Abort(c)
+		return
+	}
+
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 
 	err = calc.StartCalculating(
-		c,
+		ctx,
 		taskId,
 		h.binaryTreeStorage,
 		h.operatorsStorage,
 		h.workersStorage,
 		h.expressionStorage,
 		h.workerAPI,
+		h.subtreeLimits,
 	)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 
+	tree, err := h.estimator.Estimate(ctx, expressionId, organizationID)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
 	c.IndentedJSON(http.StatusOK, dto.CalculationResponseDTO{
-		Id: expressionId,
+		Id:                expressionId,
+		EstimatedFinishAt: tree.EstimatedFinishAt,
 	})
 
 }
 
 func (h *HTTPHandler) handleExpressionStatusRequest(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	idStr := c.Param("id")
 
 	id, err := strconv.Atoi(idStr)
@@ -171,37 +275,120 @@ func (h *HTTPHandler) handleExpressionStatusRequest(c *gin.Context) {
 		return
 	}
 
-	statusResponse, err := h.expressionStorage.FindById(id)
-	if errors.Is(err, expressions_storage.ErrExpressionNotFound) {
-		dto.NewResponseError(http.StatusNotFound, "expression not found").Abort(c)
+	statusResponse, ok := h.findOwnExpression(c, id)
+	if !ok {
 		return
 	}
 
+	if statusResponse.Status < int(statuses.Finished) {
+		tree, err := h.estimator.Estimate(ctx, id, statusResponse.OrganizationID)
+		if err != nil {
+			dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+			return
+		}
+
+		statusResponse.EstimatedFinishAt = tree.EstimatedFinishAt
+	}
+
+	c.IndentedJSON(http.StatusOK, statusResponse)
+}
+
+func (h *HTTPHandler) getExpressionTree(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	expr, ok := h.findOwnExpression(c, id)
+	if !ok {
+		return
+	}
+
+	if expr.CompactedAt != nil {
+		dto.NewResponseError(http.StatusGone, "expression tree has been deleted by the retention policy").Abort(c)
+		return
+	}
+
+	tree, err := h.estimator.Estimate(ctx, id, expr.OrganizationID)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, statusResponse)
+	c.IndentedJSON(http.StatusOK, tree)
+}
+
+func (h *HTTPHandler) getExpressionAttempts(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	expr, ok := h.findOwnExpression(c, id)
+	if !ok {
+		return
+	}
+
+	if expr.CompactedAt != nil {
+		dto.NewResponseError(http.StatusGone, "expression tree has been deleted by the retention policy").Abort(c)
+		return
+	}
+
+	attempts, err := h.binaryTreeStorage.FindAttempts(ctx, id)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, attempts)
 }
 
 func (h *HTTPHandler) getAllExpressions(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID, err := userID(c)
 	if err != nil {
 		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
 		return
 	}
 
-	expressions, err := h.expressionStorage.FindAllByUserID(userID)
+	var query = &dto.ExpressionsQueryDTO{}
+
+	err = c.ShouldBindQuery(query)
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	page, err := h.expressionStorage.FindPage(ctx, userID, organizationID(c), query)
+	if errors.Is(err, expressions_storage.ErrInvalidCursor) || errors.Is(err, expressions_storage.ErrInvalidSort) {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, expressions)
+	c.Header(totalCountHeader, strconv.Itoa(page.Total))
+
+	if page.NextCursor != "" {
+		c.Header(nextCursorHeader, page.NextCursor)
+	}
+
+	c.IndentedJSON(http.StatusOK, page.Expressions)
 }
 
 func (h *HTTPHandler) handleWorkerRegister(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var worker = &dto.WorkerRequestDTO{}
 
 	err := c.Bind(worker)
@@ -215,32 +402,45 @@ func (h *HTTPHandler) handleWorkerRegister(c *gin.Context) {
 		return
 	}
 
-	exists, err := h.workersStorage.Register(worker)
+	registration, err := h.workersStorage.Register(ctx, worker)
+	if errors.Is(err, workers_storage.ErrWorkerConflict) {
+		dto.NewResponseError(http.StatusConflict, "worker identity conflict").Abort(c)
+		return
+	}
+
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 
-	if exists {
+	if registration.Exists {
+		c.IndentedJSON(http.StatusOK, registration)
 		return
 	}
 
+	audit_log.RecordQuietly(ctx, h.auditLog, audit_log.WorkerRegistration(registration.Id, worker))
+
 	err = calc.CalculateAll(
-		c,
+		ctx,
 		h.binaryTreeStorage,
 		h.operatorsStorage,
 		h.workersStorage,
 		h.expressionStorage,
 		h.workerAPI,
+		h.subtreeLimits,
 	)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
+
+	c.IndentedJSON(http.StatusOK, registration)
 }
 
 func (h *HTTPHandler) getAllWorkers(c *gin.Context) {
-	workers, err := h.workersStorage.FindAll()
+	ctx := c.Request.Context()
+
+	workers, err := h.workersStorage.FindAll(ctx)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
@@ -250,25 +450,31 @@ func (h *HTTPHandler) getAllWorkers(c *gin.Context) {
 }
 
 func (h *HTTPHandler) handleTaskStarting(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
 		return
 	}
 
-	err = h.binaryTreeStorage.MarkAsCalculating(id)
+	if !h.checkTaskOwner(c, id) {
+		return
+	}
+
+	err = h.binaryTreeStorage.MarkAsCalculating(ctx, id)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 
-	node, err := h.binaryTreeStorage.FindById(id)
+	node, err := h.binaryTreeStorage.FindById(ctx, id)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 
-	err = h.expressionStorage.MarkAsCalculating(node.ExpressionId)
+	err = h.expressionStorage.MarkAsCalculating(ctx, node.ExpressionId)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
@@ -276,12 +482,18 @@ func (h *HTTPHandler) handleTaskStarting(c *gin.Context) {
 }
 
 func (h *HTTPHandler) handleTaskResult(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
 		return
 	}
 
+	if !h.checkTaskOwner(c, id) {
+		return
+	}
+
 	var calculationResult = &dto.CalculationResultDTO{}
 
 	err = c.BindJSON(calculationResult)
@@ -290,20 +502,42 @@ func (h *HTTPHandler) handleTaskResult(c *gin.Context) {
 		return
 	}
 
-	err = h.binaryTreeStorage.SaveResult(id, calculationResult.Result)
+	var reported = calculationResult.Result
+
+	calculationResult.Result, err = h.monitor.CheckResult(ctx, id, calculationResult.Result)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	workerId, _ := strconv.Atoi(c.GetHeader(workerIdHeader))
+	audit_log.RecordQuietly(ctx, h.auditLog, audit_log.TaskResult(workerId, id, reported, calculationResult.Result))
+
+	err = h.workersStorage.RecordLatency(ctx, id)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	err = h.binaryTreeStorage.SaveResult(
+		ctx,
+		id,
+		calculationResult.Result,
+		time.Duration(calculationResult.ComputeTimeUS)*time.Microsecond,
+	)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 
-	node, err := h.binaryTreeStorage.FindById(id)
+	node, err := h.binaryTreeStorage.FindById(ctx, id)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 
 	if node.ParentId == -1 {
-		err = h.expressionStorage.SaveResult(node.ExpressionId, calculationResult.Result)
+		err = h.expressionStorage.SaveResult(ctx, node.ExpressionId, calculationResult.Result)
 		if err != nil {
 			dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		}
@@ -318,20 +552,24 @@ func (h *HTTPHandler) handleTaskResult(c *gin.Context) {
 	//}
 
 	err = calc.CalculateAll(
-		c,
+		ctx,
 		h.binaryTreeStorage,
 		h.operatorsStorage,
 		h.workersStorage,
 		h.expressionStorage,
 		h.workerAPI,
+		h.subtreeLimits,
 	)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 	}
 }
 
+// getAllOperations returns durations of operations used in the active workspace of the caller
 func (h *HTTPHandler) getAllOperations(c *gin.Context) {
-	operations, err := h.operatorsStorage.FindAll()
+	ctx := c.Request.Context()
+
+	operations, err := h.operatorsStorage.FindFor(ctx, organizationID(c))
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
@@ -341,6 +579,8 @@ func (h *HTTPHandler) getAllOperations(c *gin.Context) {
 }
 
 func (h *HTTPHandler) saveAllOperations(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var operations []*dto.OperationDTO
 
 	err := c.BindJSON(&operations)
@@ -356,21 +596,266 @@ func (h *HTTPHandler) saveAllOperations(c *gin.Context) {
 		}
 	}
 
-	err = h.operatorsStorage.SaveAll(operations)
+	err = h.transactor.InTx(ctx, func(ctx context.Context) error {
+		before, err := h.operatorsStorage.FindAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = h.operatorsStorage.SaveAll(ctx, operations)
+		if err != nil {
+			return err
+		}
+
+		after, err := h.operatorsStorage.FindAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		return h.auditLog.Record(ctx, &audit_log.Entry{
+			Action:     audit_log.OperatorsSaved,
+			TargetType: audit_log.TargetOperators,
+			Before:     before,
+			After:      after,
+		})
+	})
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
 	}
 }
 
+// saveOrganizationOperations overrides durations of operations for the active organization of the caller.
+// Only owners and admins of the organization may change them
+func (h *HTTPHandler) saveOrganizationOperations(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	organizationID, ok := manageableOrganization(c)
+	if !ok {
+		return
+	}
+
+	var operations []*dto.OperationDTO
+
+	err := c.BindJSON(&operations)
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	for _, operation := range operations {
+		if operation.DurationMS < 0 {
+			dto.NewResponseError(http.StatusBadRequest, "invalid operation duration time").Abort(c)
+			return
+		}
+	}
+
+	err = h.transactor.InTx(ctx, func(ctx context.Context) error {
+		before, err := h.operatorsStorage.FindFor(ctx, organizationID)
+		if err != nil {
+			return err
+		}
+
+		err = h.operatorsStorage.SaveFor(ctx, organizationID, operations)
+		if err != nil {
+			return err
+		}
+
+		after, err := h.operatorsStorage.FindFor(ctx, organizationID)
+		if err != nil {
+			return err
+		}
+
+		return h.auditLog.Record(ctx, &audit_log.Entry{
+			Action:     audit_log.OrganizationOperatorsSaved,
+			TargetType: audit_log.TargetOrganization,
+			TargetId:   audit_log.TargetId(organizationID),
+			Before:     before,
+			After:      after,
+		})
+	})
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+}
+
+// getOrganizationQuota returns limits of the active organization of the caller and their usage
+func (h *HTTPHandler) getOrganizationQuota(c *gin.Context) {
+	organizationID := organizationID(c)
+	if organizationID == 0 {
+		dto.NewResponseError(http.StatusBadRequest, "no active organization").Abort(c)
+		return
+	}
+
+	h.findQuota(c, organizationID)
+}
+
+func (h *HTTPHandler) getQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		dto.NewResponseError(http.StatusBadRequest, "invalid id").Abort(c)
+		return
+	}
+
+	h.findQuota(c, id)
+}
+
+func (h *HTTPHandler) saveQuota(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		dto.NewResponseError(http.StatusBadRequest, "invalid id").Abort(c)
+		return
+	}
+
+	var request = &dto.OrganizationQuotaRequestDTO{}
+
+	err = c.BindJSON(request)
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	err = h.transactor.InTx(ctx, func(ctx context.Context) error {
+		before, err := h.quotas.Find(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = h.quotas.Save(ctx, id, request)
+		if err != nil {
+			return err
+		}
+
+		after, err := h.quotas.Find(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return h.auditLog.Record(ctx, &audit_log.Entry{
+			Action:     audit_log.OrganizationQuotaSaved,
+			TargetType: audit_log.TargetOrganization,
+			TargetId:   audit_log.TargetId(id),
+			Before:     before,
+			After:      after,
+		})
+	})
+	if errors.Is(err, organization_quotas.ErrInvalidQuota) {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	h.findQuota(c, id)
+}
+
+func (h *HTTPHandler) findQuota(c *gin.Context, organizationID uint64) {
+	quota, err := h.quotas.Find(c.Request.Context(), organizationID)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, quota)
+}
+
+// getAuditLog returns a page of the audit log, the newest records first
+func (h *HTTPHandler) getAuditLog(c *gin.Context) {
+	var query = &dto.AuditQueryDTO{}
+
+	err := c.ShouldBindQuery(query)
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	page, err := h.auditLog.Find(c.Request.Context(), query)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	if page.NextCursor != 0 {
+		c.Header(nextCursorHeader, strconv.FormatInt(page.NextCursor, 10))
+	}
+
+	c.IndentedJSON(http.StatusOK, page.Entries)
+}
+
+// exportAuditLog streams all records of the audit log matching the filters as JSON lines, the oldest first.
+// Errors after the first written record can only be logged, because the response status has already been sent
+func (h *HTTPHandler) exportAuditLog(c *gin.Context) {
+	var query = &dto.AuditQueryDTO{}
+
+	err := c.ShouldBindQuery(query)
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	c.Status(http.StatusOK)
+
+	err = h.auditLog.Export(c.Request.Context(), query, c.Writer)
+	if err != nil {
+		log.Printf("audit log export error: %s", err.Error())
+	}
+}
+
 func (h *HTTPHandler) handleWorkerTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
 		return
 	}
 
-	tasks, err := h.binaryTreeStorage.FindByWorkerId(id)
+	tasks, err := h.binaryTreeStorage.FindByWorkerId(ctx, id)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, tasks)
+}
+
+func (h *HTTPHandler) acquireWorkerTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	var request = &dto.AcquireTasksRequestDTO{}
+
+	err = c.BindJSON(request)
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	worker, err := h.workersStorage.Authenticate(ctx, id, c.GetHeader(workerSecretHeader))
+	if errors.Is(err, workers_storage.ErrWorkerUnauthorized) {
+		dto.NewResponseError(http.StatusUnauthorized, "invalid worker credentials").Abort(c)
+		return
+	}
+
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	tasks, err := h.taskQueue.Acquire(ctx, worker, request.Max, time.Duration(request.WaitMS)*time.Millisecond)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
 		return
@@ -379,6 +864,207 @@ func (h *HTTPHandler) handleWorkerTasks(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, tasks)
 }
 
+func (h *HTTPHandler) getWorkerEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	events, err := h.monitor.Events(ctx, id)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, events)
+}
+
+func (h *HTTPHandler) getWorkerStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	stats, err := h.workersStorage.FindStats(ctx, id)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, stats)
+}
+
+func (h *HTTPHandler) releaseWorker(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	err = h.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := h.monitor.Release(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return h.auditLog.Record(ctx, &audit_log.Entry{
+			Action:     audit_log.WorkerReleased,
+			TargetType: audit_log.TargetWorker,
+			TargetId:   audit_log.TargetId(id),
+		})
+	})
+	if errors.Is(err, quarantine.ErrWorkerNotQuarantined) {
+		dto.NewResponseError(http.StatusConflict, "worker is not quarantined").Abort(c)
+		return
+	}
+
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	err = calc.CalculateAll(
+		ctx,
+		h.binaryTreeStorage,
+		h.operatorsStorage,
+		h.workersStorage,
+		h.expressionStorage,
+		h.workerAPI,
+		h.subtreeLimits,
+	)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+	}
+}
+
+func (h *HTTPHandler) cordonWorker(c *gin.Context) {
+	h.administrateWorker(c, false, h.workerAdmin.Cordon)
+}
+
+func (h *HTTPHandler) uncordonWorker(c *gin.Context) {
+	h.administrateWorker(c, true, h.workerAdmin.Uncordon)
+}
+
+func (h *HTTPHandler) drainWorker(c *gin.Context) {
+	h.administrateWorker(c, true, h.workerAdmin.Drain)
+}
+
+func (h *HTTPHandler) evictWorker(c *gin.Context) {
+	h.administrateWorker(c, true, h.workerAdmin.Evict)
+}
+
+func (h *HTTPHandler) setWorkerExecutors(c *gin.Context) {
+	var request = &dto.WorkerExecutorsRequestDTO{}
+
+	err := c.BindJSON(request)
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	h.administrateWorker(c, true, func(ctx context.Context, admin string, workerId int) error {
+		return h.workerAdmin.SetExecutors(ctx, admin, workerId, request.Executors)
+	})
+}
+
+func (h *HTTPHandler) setWorkerLabels(c *gin.Context) {
+	var request = &dto.WorkerLabelsRequestDTO{}
+
+	err := c.BindJSON(request)
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	h.administrateWorker(c, true, func(ctx context.Context, admin string, workerId int) error {
+		return h.workerAdmin.SetLabels(ctx, admin, workerId, request.Labels)
+	})
+}
+
+// administrateWorker applies operation to the worker from the path. The worker changes, the worker event
+// and the audit log entry are saved in one transaction. If reschedule is set,
+// waiting tasks are distributed again, because the operation could have freed executors
+func (h *HTTPHandler) administrateWorker(c *gin.Context, reschedule bool, operation func(ctx context.Context, admin string, workerId int) error) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	err = h.transactor.InTx(ctx, func(ctx context.Context) error {
+		return operation(ctx, c.GetString(middlewares.LoginContextKey), id)
+	})
+	if errors.Is(err, workers_storage.ErrWorkerNotFound) {
+		dto.NewResponseError(http.StatusNotFound, "worker not found").Abort(c)
+		return
+	}
+
+	if errors.Is(err, worker_admin.ErrInvalidExecutors) {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return
+	}
+
+	if errors.Is(err, worker_admin.ErrInvalidState) {
+		dto.NewResponseError(http.StatusConflict, err.Error()).Abort(c)
+		return
+	}
+
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return
+	}
+
+	if !reschedule {
+		return
+	}
+
+	err = calc.CalculateAll(
+		ctx,
+		h.binaryTreeStorage,
+		h.operatorsStorage,
+		h.workersStorage,
+		h.expressionStorage,
+		h.workerAPI,
+		h.subtreeLimits,
+	)
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+	}
+}
+
+// findOwnExpression returns the expression of the active workspace of the caller or aborts the request.
+// Expressions of other workspaces are reported as not found, so their ids are not disclosed
+func (h *HTTPHandler) findOwnExpression(c *gin.Context, id int) (*dto.ExpressionResponseDTO, bool) {
+	userID, err := userID(c)
+	if err != nil {
+		dto.NewResponseError(http.StatusBadRequest, err.Error()).Abort(c)
+		return nil, false
+	}
+
+	expr, err := h.expressionStorage.FindById(c.Request.Context(), id)
+	if errors.Is(err, expressions_storage.ErrExpressionNotFound) || err == nil && !inWorkspace(expr, userID, organizationID(c)) {
+		dto.NewResponseError(http.StatusNotFound, "expression not found").Abort(c)
+		return nil, false
+	}
+
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return nil, false
+	}
+
+	return expr, true
+}
+
 func userID(c *gin.Context) (uint64, error) {
 	userID, err := strconv.ParseUint(fmt.Sprintf("%v", c.Value("user_id")), 10, 64)
 	if err != nil {
@@ -389,3 +1075,63 @@ func userID(c *gin.Context) (uint64, error) {
 
 	//return tempUserID, nil
 }
+
+// organizationID returns the active organization of the caller, zero for the personal workspace
+func organizationID(c *gin.Context) uint64 {
+	return c.GetUint64(middlewares.OrganizationIdContextKey)
+}
+
+// inWorkspace reports whether the expression belongs to the organization or, if organizationID is zero,
+// to the personal workspace of the user
+func inWorkspace(expr *dto.ExpressionResponseDTO, userID uint64, organizationID uint64) bool {
+	if organizationID != 0 {
+		return expr.OrganizationID == organizationID
+	}
+
+	return expr.OrganizationID == 0 && expr.UserID == userID
+}
+
+// manageableOrganization returns the active organization of the caller, if the caller is its owner or admin.
+// Otherwise the request is aborted
+func manageableOrganization(c *gin.Context) (uint64, bool) {
+	principal, ok := roles.FromContext(c.Request.Context())
+	if !ok || principal.OrganizationID == 0 {
+		dto.NewResponseError(http.StatusBadRequest, "no active organization").Abort(c)
+		return 0, false
+	}
+
+	if principal.OrganizationRole != roles.OrganizationOwner && principal.OrganizationRole != roles.OrganizationAdmin {
+		dto.NewResponseError(http.StatusForbidden, "organization owner or admin access required").Abort(c)
+		return 0, false
+	}
+
+	return principal.OrganizationID, true
+}
+
+// checkTaskOwner allows workers to start and finish only the tasks assigned to them
+func (h *HTTPHandler) checkTaskOwner(c *gin.Context, id int) bool {
+	workerId, _ := strconv.Atoi(c.GetHeader(workerIdHeader))
+
+	err := h.taskOwners.Check(c.Request.Context(), workerId, c.GetHeader(workerSecretHeader), id)
+	if errors.Is(err, workers_storage.ErrWorkerUnauthorized) {
+		dto.NewResponseError(http.StatusUnauthorized, "invalid worker credentials").Abort(c)
+		return false
+	}
+
+	if errors.Is(err, task_owners.ErrNotTaskOwner) {
+		dto.NewResponseError(http.StatusForbidden, err.Error()).Abort(c)
+		return false
+	}
+
+	if errors.Is(err, task_owners.ErrTaskNotInProgress) {
+		dto.NewResponseError(http.StatusConflict, err.Error()).Abort(c)
+		return false
+	}
+
+	if err != nil {
+		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(c)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/roles"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/servicetoken"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	UserIdContextKey = "user_id"
+	LoginContextKey  = "login"
+	RoleContextKey   = "role"
+	// OrganizationIdContextKey is zero for callers working in the personal workspace
+	OrganizationIdContextKey = "organization_id"
+)
+
+// Authorization schemes accepted in the Authorization header
+const (
+	BearerScheme = "Bearer"
+	APIKeyScheme = "ApiKey"
+)
+
+// submitExpressionPath is the only route permitted for api keys with roles.ScopeSubmit
+const submitExpressionPath = "/api/expression"
+
+// NewAuthMiddleware authenticates the caller and allows only callers with one of the passed roles.
+// Callers authenticated with an api key are also limited by the scope of the key.
+// The caller and its address are stored in the request context for the audit log
+func NewAuthMiddleware(authorizer roles.Authorizer) func(allowed ...roles.Role) gin.HandlerFunc {
+	return func(allowed ...roles.Role) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			scheme, token, err := GetAuthorization(c.Request)
+			if err != nil {
+				dto.NewResponseError(http.StatusUnauthorized, err.Error()).Abort(c)
+				return
+			}
+
+			// The auth service limits failed verifications by the address of the caller
+			authCtx := servicetoken.WithClientIP(c.Request.Context(), c.ClientIP())
+
+			var principal *roles.Principal
+			if scheme == APIKeyScheme {
+				principal, err = authorizer.AuthenticateAPIKey(authCtx, token)
+			} else {
+				principal, err = authorizer.Authenticate(authCtx, token)
+			}
+
+			if errors.Is(err, roles.ErrUnauthenticated) {
+				dto.NewResponseError(http.StatusUnauthorized, err.Error()).Abort(c)
+				return
+			}
+
+			if err != nil {
+				dto.NewResponseError(http.StatusServiceUnavailable, err.Error()).Abort(c)
+				return
+			}
+
+			err = authorizer.Authorize(principal, allowed...)
+			if err != nil {
+				dto.NewResponseError(http.StatusForbidden, err.Error()).Abort(c)
+				return
+			}
+
+			err = authorizer.AuthorizeScope(principal, requestAction(c))
+			if err != nil {
+				dto.NewResponseError(http.StatusForbidden, err.Error()).Abort(c)
+				return
+			}
+
+			c.Set(UserIdContextKey, principal.UserID)
+			c.Set(LoginContextKey, principal.Login)
+			c.Set(RoleContextKey, principal.Role)
+			c.Set(OrganizationIdContextKey, principal.OrganizationID)
+
+			ctx := roles.WithPrincipal(c.Request.Context(), principal)
+			c.Request = c.Request.WithContext(audit_log.WithIP(ctx, c.ClientIP()))
+
+			c.Next()
+		}
+	}
+}
+
+// GetAuthorization returns the scheme and the credentials of the Authorization header.
+// The scheme is BearerScheme for access tokens and worker tokens or APIKeyScheme for api keys
+func GetAuthorization(r *http.Request) (scheme string, token string, err error) {
+	headerParts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(headerParts) != 2 {
+		return "", "", errors.New("invalid authorization header length")
+	}
+
+	if headerParts[0] != BearerScheme && headerParts[0] != APIKeyScheme {
+		return "", "", errors.New("invalid auth method")
+	}
+
+	return headerParts[0], headerParts[1], nil
+}
+
+// requestAction classifies the request for scopes of api keys
+func requestAction(c *gin.Context) roles.Action {
+	switch {
+	case c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead:
+		return roles.ActionRead
+	case c.Request.Method == http.MethodPost && c.FullPath() == submitExpressionPath:
+		return roles.ActionSubmit
+	default:
+		return roles.ActionWrite
+	}
+}
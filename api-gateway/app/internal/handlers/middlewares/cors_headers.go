@@ -10,6 +10,7 @@ func CORSHeaders() gin.HandlerFunc {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Expose-Headers", "X-Total-Count, X-Next-Cursor")
 
 		if c.Request.Method == http.MethodOptions {
 			c.Status(http.StatusOK)
@@ -0,0 +1,37 @@
+package audit_log_repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+type AuditEntity struct {
+	Id         int64
+	ActorType  string
+	ActorId    uint64
+	ActorName  string
+	Action     string
+	TargetType string
+	TargetId   string
+	// Before and After are JSON states of the target, null if the action has no such state
+	Before    []byte
+	After     []byte
+	IP        string
+	CreatedAt time.Time
+}
+
+// AuditFilter selects records of the audit log. Null times and empty values disable the filters
+type AuditFilter struct {
+	ActorType  string
+	ActorId    sql.NullInt64
+	Action     string
+	TargetType string
+	TargetId   string
+	From       sql.NullTime
+	To         sql.NullTime
+	// Descending lists the newest records first
+	Descending bool
+	// AfterId continues the list after the record with the id in the listing order. Zero starts from the beginning
+	AfterId int64
+	Limit   int
+}
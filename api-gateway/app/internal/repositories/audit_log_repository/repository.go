@@ -0,0 +1,155 @@
+package audit_log_repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=AuditLogRepository
+
+// AuditLogRepository appends records to the audit log and reads them. Records are never changed or deleted
+type AuditLogRepository interface {
+	Create(ctx context.Context, entity *AuditEntity) error
+	Find(ctx context.Context, filter *AuditFilter) ([]*AuditEntity, error)
+}
+
+type auditLogRepository struct {
+	db *sql.DB
+}
+
+func NewAuditLogRepository(db *sql.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (a *auditLogRepository) Create(ctx context.Context, entity *AuditEntity) error {
+	return a.conn(ctx).QueryRowContext(
+		ctx,
+		`INSERT INTO audit_log (actor_type, actor_id, actor_name, action, target_type, target_id, before, after, ip)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				RETURNING id, created_at`,
+		entity.ActorType,
+		entity.ActorId,
+		entity.ActorName,
+		entity.Action,
+		entity.TargetType,
+		entity.TargetId,
+		entity.Before,
+		entity.After,
+		entity.IP,
+	).Scan(&entity.Id, &entity.CreatedAt)
+}
+
+func (a *auditLogRepository) Find(ctx context.Context, filter *AuditFilter) ([]*AuditEntity, error) {
+	where, args := filter.where()
+
+	var direction = "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+
+	args = append(args, filter.Limit)
+
+	rows, err := a.conn(ctx).QueryContext(
+		ctx,
+		fmt.Sprintf(
+			`SELECT id, actor_type, actor_id, actor_name, action, target_type, target_id, before, after, ip, created_at
+				FROM audit_log WHERE %s ORDER BY id %s LIMIT $%d`,
+			where,
+			direction,
+			len(args),
+		),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var entities = []*AuditEntity{}
+
+	for rows.Next() {
+		var entity = &AuditEntity{}
+
+		err := rows.Scan(
+			&entity.Id,
+			&entity.ActorType,
+			&entity.ActorId,
+			&entity.ActorName,
+			&entity.Action,
+			&entity.TargetType,
+			&entity.TargetId,
+			&entity.Before,
+			&entity.After,
+			&entity.IP,
+			&entity.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entities = append(entities, entity)
+	}
+
+	return entities, rows.Err()
+}
+
+func (f *AuditFilter) where() (string, []any) {
+	var (
+		conditions = []string{"TRUE"}
+		args       []any
+	)
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if f.ActorType != "" {
+		conditions = append(conditions, "actor_type = "+arg(f.ActorType))
+	}
+
+	if f.ActorId.Valid {
+		conditions = append(conditions, "actor_id = "+arg(f.ActorId))
+	}
+
+	if f.Action != "" {
+		conditions = append(conditions, "action = "+arg(f.Action))
+	}
+
+	if f.TargetType != "" {
+		conditions = append(conditions, "target_type = "+arg(f.TargetType))
+	}
+
+	if f.TargetId != "" {
+		conditions = append(conditions, "target_id = "+arg(f.TargetId))
+	}
+
+	if f.From.Valid {
+		conditions = append(conditions, "created_at >= "+arg(f.From))
+	}
+
+	if f.To.Valid {
+		conditions = append(conditions, "created_at < "+arg(f.To))
+	}
+
+	if f.AfterId != 0 {
+		var comparison = ">"
+		if f.Descending {
+			comparison = "<"
+		}
+
+		conditions = append(conditions, fmt.Sprintf("id %s %s", comparison, arg(f.AfterId)))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func (a *auditLogRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, a.db)
+}
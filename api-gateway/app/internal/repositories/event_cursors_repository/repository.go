@@ -0,0 +1,58 @@
+package event_cursors_repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=EventCursorsRepository
+
+// EventCursorsRepository keeps the id of the last handled event of every event stream
+type EventCursorsRepository interface {
+	// Find returns zero for streams without handled events
+	Find(ctx context.Context, name string) (uint64, error)
+	Save(ctx context.Context, name string, lastId uint64) error
+}
+
+type eventCursorsRepository struct {
+	db *sql.DB
+}
+
+func NewEventCursorsRepository(db *sql.DB) EventCursorsRepository {
+	return &eventCursorsRepository{db: db}
+}
+
+func (e *eventCursorsRepository) Find(ctx context.Context, name string) (uint64, error) {
+	var lastId uint64
+
+	err := e.conn(ctx).QueryRowContext(
+		ctx,
+		"SELECT last_id FROM event_cursors WHERE name = $1",
+		name,
+	).Scan(&lastId)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return lastId, err
+}
+
+func (e *eventCursorsRepository) Save(ctx context.Context, name string, lastId uint64) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		`INSERT INTO event_cursors (name, last_id) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET last_id = EXCLUDED.last_id, updated_at = NOW()`,
+		name,
+		lastId,
+	)
+
+	return err
+}
+
+func (e *eventCursorsRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, e.db)
+}
@@ -1,15 +1,23 @@
 package expr_tree_repository
 
+import (
+	"database/sql"
+	"time"
+)
+
 type ExpressionTreeNodeEntity struct {
-	Id            int
-	UserID        uint64
-	ParentId      int
-	ExpressionId  int
-	Type          int
-	OperationType int
-	Status        int
-	Result        float64
-	WorkerId      int
+	Id             int
+	UserID         uint64
+	ParentId       int
+	ExpressionId   int
+	Type           int
+	OperationType  int
+	Status         int
+	Result         float64
+	WorkerId       int
+	ComputeTimeUS  int64
+	LeaseExpiresAt time.Time
+	StartedAt      time.Time
 }
 
 type TaskEntity struct {
@@ -18,3 +26,19 @@ type TaskEntity struct {
 	RightResult   float64
 	Status        int
 }
+
+type ReadyTaskEntity struct {
+	Id             int
+	UserID         uint64
+	OrganizationID sql.NullInt64
+	ExpressionId   int
+	OperationType  int
+	LeftResult     float64
+	RightResult    float64
+	Selector       []byte
+}
+
+type ExpiredLeaseEntity struct {
+	NodeId   int
+	WorkerId int
+}
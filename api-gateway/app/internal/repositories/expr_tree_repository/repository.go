@@ -1,20 +1,34 @@
 package expr_tree_repository
 
 import (
+	"context"
 	"database/sql"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/lib/pq"
 )
 
 type ExpressionsTreeRepository interface {
-	Create(entity *ExpressionTreeNodeEntity) (int, error)
-	SetStatus(id int, status int) error
-	SaveResult(id int, result float64, status int) error
-	FindByParentId(parentId int) ([]*ExpressionTreeNodeEntity, error)
-	SaveWorker(id int, workerId int, status int) error
-	FindById(id int) (*ExpressionTreeNodeEntity, error)
-	FindByWorkerId(id int) ([]*TaskEntity, error)
-	DeleteWorker(workerId int) error
-	DeleteAllWorkers() error
-	FindUncalculated() ([]int, error)
+	ReserveIds(ctx context.Context, count int) ([]int, error)
+	CreateAll(ctx context.Context, entities []*ExpressionTreeNodeEntity) error
+	SetStatus(ctx context.Context, id int, status int) error
+	Start(ctx context.Context, id int, status int) error
+	SaveResult(ctx context.Context, id int, result float64, status int, computeTimeUS int64) error
+	FindByParentId(ctx context.Context, parentId int) ([]*ExpressionTreeNodeEntity, error)
+	SaveWorker(ctx context.Context, id int, workerId int, status int) error
+	FindById(ctx context.Context, id int) (*ExpressionTreeNodeEntity, error)
+	FindByExpressionId(ctx context.Context, expressionId int) ([]*ExpressionTreeNodeEntity, error)
+	CountQueued(ctx context.Context) (int, error)
+	FindByWorkerId(ctx context.Context, id int) ([]*TaskEntity, error)
+	DeleteWorker(ctx context.Context, workerId int) error
+	DeleteNodeWorker(ctx context.Context, id int, workerId int) error
+	DeleteAllWorkers(ctx context.Context) error
+	FindUncalculated(ctx context.Context) ([]int, error)
+	FindReady(ctx context.Context, limit int) ([]*ReadyTaskEntity, error)
+	Lease(ctx context.Context, id int, workerId int, status int, until time.Time) (bool, error)
+	ReleaseExpiredLeases(ctx context.Context, now time.Time) ([]*ExpiredLeaseEntity, error)
+	DeleteByExpressionIds(ctx context.Context, expressionIds []int) error
 }
 
 type expressionsTreeRepository struct {
@@ -25,29 +39,81 @@ func NewExpressionsTreeRepository(db *sql.DB) ExpressionsTreeRepository {
 	return &expressionsTreeRepository{db: db}
 }
 
-func (e *expressionsTreeRepository) Create(entity *ExpressionTreeNodeEntity) (int, error) {
-	row := e.db.QueryRow(
-		"INSERT INTO expressions_tree (user_id, parent_id, expression_id, type, operation_type, status, result) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
-		entity.UserID,
-		nullableInt(entity.ParentId),
-		entity.ExpressionId,
-		entity.Type,
-		nullableInt(entity.OperationType),
-		entity.Status,
-		entity.Result,
+// ReserveIds allocates ids for count nodes, so a whole tree can be linked before it is inserted
+func (e *expressionsTreeRepository) ReserveIds(ctx context.Context, count int) ([]int, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
+		"SELECT nextval(pg_get_serial_sequence('expressions_tree', 'id')) FROM generate_series(1, $1)",
+		count,
 	)
-
-	var id int
-	err := row.Scan(&id)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids = make([]int, 0, count)
+
+	for rows.Next() {
+		var id int
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
 	}
 
-	return id, nil
+	return ids, rows.Err()
+}
+
+// CreateAll inserts nodes with reserved ids with a single COPY. Either all nodes are inserted or none of them
+func (e *expressionsTreeRepository) CreateAll(ctx context.Context, entities []*ExpressionTreeNodeEntity) error {
+	return postgres.InTx(ctx, e.db, func(ctx context.Context) error {
+		stmt, err := e.conn(ctx).PrepareContext(ctx, pq.CopyIn(
+			"expressions_tree",
+			"id",
+			"user_id",
+			"parent_id",
+			"expression_id",
+			"type",
+			"operation_type",
+			"status",
+			"result",
+		))
+		if err != nil {
+			return err
+		}
+
+		defer stmt.Close()
+
+		for _, entity := range entities {
+			_, err = stmt.ExecContext(
+				ctx,
+				entity.Id,
+				entity.UserID,
+				nullableInt(entity.ParentId),
+				entity.ExpressionId,
+				entity.Type,
+				nullableInt(entity.OperationType),
+				entity.Status,
+				entity.Result,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = stmt.ExecContext(ctx)
+
+		return err
+	})
 }
 
-func (e *expressionsTreeRepository) SetStatus(id int, status int) error {
-	_, err := e.db.Exec(
+func (e *expressionsTreeRepository) SetStatus(ctx context.Context, id int, status int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
 		"UPDATE expressions_tree SET status=$1 WHERE id=$2 and status < $1",
 		status,
 		id,
@@ -56,11 +122,25 @@ func (e *expressionsTreeRepository) SetStatus(id int, status int) error {
 	return err
 }
 
-func (e *expressionsTreeRepository) SaveResult(id int, result float64, status int) error {
-	_, err := e.db.Exec(
-		"UPDATE expressions_tree SET result=$1, status=$2 WHERE id=$3",
+// Start sets status of a node which has been started by its worker and remembers the start time
+func (e *expressionsTreeRepository) Start(ctx context.Context, id int, status int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE expressions_tree SET status=$1, started_at=NOW() WHERE id=$2 and status < $1",
+		status,
+		id,
+	)
+
+	return err
+}
+
+func (e *expressionsTreeRepository) SaveResult(ctx context.Context, id int, result float64, status int, computeTimeUS int64) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE expressions_tree SET result=$1, status=$2, compute_time_us=$3, lease_expires_at=null WHERE id=$4",
 		result,
 		status,
+		computeTimeUS,
 		id,
 	)
 
@@ -74,8 +154,9 @@ func nullableInt(n int) sql.NullInt32 {
 	return sql.NullInt32{}
 }
 
-func (e *expressionsTreeRepository) FindByParentId(parentId int) ([]*ExpressionTreeNodeEntity, error) {
-	rows, err := e.db.Query(
+func (e *expressionsTreeRepository) FindByParentId(ctx context.Context, parentId int) ([]*ExpressionTreeNodeEntity, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
 		"SELECT * FROM expressions_tree WHERE parent_id = $1 ORDER BY type",
 		parentId,
 	)
@@ -92,14 +173,21 @@ func (e *expressionsTreeRepository) FindByParentId(parentId int) ([]*ExpressionT
 		var nullableParentId sql.NullInt32
 		var nullableWorkerId sql.NullInt32
 		var nullableOperationType sql.NullInt32
+		var nullableComputeTime sql.NullInt64
+		var nullableLeaseExpiresAt sql.NullTime
+		var nullableStartedAt sql.NullTime
 
-		err := rows.Scan(&entity.Id, &entity.UserID, &nullableParentId, &entity.ExpressionId, &entity.Type, &nullableOperationType, &entity.Status, &entity.Result, &nullableWorkerId)
+		err := rows.Scan(&entity.Id, &entity.UserID, &nullableParentId, &entity.ExpressionId, &entity.Type, &nullableOperationType, &entity.Status, &entity.Result, &nullableWorkerId, &nullableComputeTime, &nullableLeaseExpiresAt, &nullableStartedAt)
 		if err != nil {
 			return nil, err
 		}
 
+		entity.LeaseExpiresAt = nullableLeaseExpiresAt.Time
+		entity.StartedAt = nullableStartedAt.Time
+
 		entity.ParentId = int(nullableParentId.Int32)
 		entity.WorkerId = int(nullableWorkerId.Int32)
+		entity.ComputeTimeUS = nullableComputeTime.Int64
 
 		if nullableOperationType.Valid {
 			entity.OperationType = int(nullableOperationType.Int32)
@@ -113,8 +201,9 @@ func (e *expressionsTreeRepository) FindByParentId(parentId int) ([]*ExpressionT
 	return entities, nil
 }
 
-func (e *expressionsTreeRepository) SaveWorker(id int, workerId int, status int) error {
-	_, err := e.db.Exec(
+func (e *expressionsTreeRepository) SaveWorker(ctx context.Context, id int, workerId int, status int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
 		"UPDATE expressions_tree SET status=$1, worker_id=$2 WHERE id=$3",
 		status,
 		workerId,
@@ -124,8 +213,9 @@ func (e *expressionsTreeRepository) SaveWorker(id int, workerId int, status int)
 	return err
 }
 
-func (e *expressionsTreeRepository) FindById(id int) (*ExpressionTreeNodeEntity, error) {
-	row := e.db.QueryRow(
+func (e *expressionsTreeRepository) FindById(ctx context.Context, id int) (*ExpressionTreeNodeEntity, error) {
+	row := e.conn(ctx).QueryRowContext(
+		ctx,
 		"SELECT * FROM expressions_tree WHERE id = $1",
 		id,
 	)
@@ -135,9 +225,15 @@ func (e *expressionsTreeRepository) FindById(id int) (*ExpressionTreeNodeEntity,
 	var nullableParentId sql.NullInt32
 	var nullableWorkerId sql.NullInt32
 	var nullableOperationType sql.NullInt32
+	var nullableComputeTime sql.NullInt64
+	var nullableLeaseExpiresAt sql.NullTime
+	var nullableStartedAt sql.NullTime
 
-	err := row.Scan(&entity.Id, &entity.UserID, &nullableParentId, &entity.ExpressionId, &entity.Type, &nullableOperationType, &entity.Status, &entity.Result, &nullableWorkerId)
+	err := row.Scan(&entity.Id, &entity.UserID, &nullableParentId, &entity.ExpressionId, &entity.Type, &nullableOperationType, &entity.Status, &entity.Result, &nullableWorkerId, &nullableComputeTime, &nullableLeaseExpiresAt, &nullableStartedAt)
 	entity.WorkerId = int(nullableWorkerId.Int32)
+	entity.LeaseExpiresAt = nullableLeaseExpiresAt.Time
+	entity.StartedAt = nullableStartedAt.Time
+	entity.ComputeTimeUS = nullableComputeTime.Int64
 
 	if nullableParentId.Valid {
 		entity.ParentId = int(nullableParentId.Int32)
@@ -154,8 +250,84 @@ func (e *expressionsTreeRepository) FindById(id int) (*ExpressionTreeNodeEntity,
 	return entity, err
 }
 
-func (e *expressionsTreeRepository) FindByWorkerId(workerId int) ([]*TaskEntity, error) {
-	rows, err := e.db.Query(
+// FindByExpressionId returns all nodes of the expression. Root nodes have parent id -1
+func (e *expressionsTreeRepository) FindByExpressionId(ctx context.Context, expressionId int) ([]*ExpressionTreeNodeEntity, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
+		"SELECT * FROM expressions_tree WHERE expression_id = $1 ORDER BY id",
+		expressionId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var entities []*ExpressionTreeNodeEntity
+
+	for rows.Next() {
+		var entity = &ExpressionTreeNodeEntity{}
+
+		var nullableParentId sql.NullInt32
+		var nullableWorkerId sql.NullInt32
+		var nullableOperationType sql.NullInt32
+		var nullableComputeTime sql.NullInt64
+		var nullableLeaseExpiresAt sql.NullTime
+		var nullableStartedAt sql.NullTime
+
+		err := rows.Scan(&entity.Id, &entity.UserID, &nullableParentId, &entity.ExpressionId, &entity.Type, &nullableOperationType, &entity.Status, &entity.Result, &nullableWorkerId, &nullableComputeTime, &nullableLeaseExpiresAt, &nullableStartedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		entity.WorkerId = int(nullableWorkerId.Int32)
+		entity.ComputeTimeUS = nullableComputeTime.Int64
+		entity.LeaseExpiresAt = nullableLeaseExpiresAt.Time
+		entity.StartedAt = nullableStartedAt.Time
+
+		entity.ParentId = -1
+		if nullableParentId.Valid {
+			entity.ParentId = int(nullableParentId.Int32)
+		}
+
+		entity.OperationType = -1
+		if nullableOperationType.Valid {
+			entity.OperationType = int(nullableOperationType.Int32)
+		}
+
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
+
+// CountQueued returns the number of operation nodes which occupy or wait for workers:
+// assigned unfinished nodes and ready nodes which have both operands calculated
+func (e *expressionsTreeRepository) CountQueued(ctx context.Context) (int, error) {
+	row := e.conn(ctx).QueryRowContext(
+		ctx,
+		`select count(*) from expressions_tree op
+				where op.operation_type is not null and op.status <> 3 and op.status <> 4 and (
+					op.worker_id is not null or not exists (
+						select 1 from expressions_tree c where c.parent_id = op.id and c.status <> 3
+					)
+				)`,
+	)
+
+	var count int
+
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (e *expressionsTreeRepository) FindByWorkerId(ctx context.Context, workerId int) ([]*TaskEntity, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
 		`select 
     			(select l.result from expressions_tree l where l.parent_id = op.id and l.type = 0) as left_result, 
     			op.operation_type, 
@@ -185,25 +357,40 @@ func (e *expressionsTreeRepository) FindByWorkerId(workerId int) ([]*TaskEntity,
 	return entities, nil
 }
 
-func (e *expressionsTreeRepository) DeleteWorker(workerId int) error {
-	_, err := e.db.Exec(
-		"UPDATE expressions_tree SET worker_id = null, status=0 WHERE worker_id = $1 AND status <> 3 AND status <> 4",
+func (e *expressionsTreeRepository) DeleteWorker(ctx context.Context, workerId int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE expressions_tree SET worker_id = null, status=0, lease_expires_at = null, started_at = null WHERE worker_id = $1 AND status <> 3 AND status <> 4",
+		workerId,
+	)
+
+	return err
+}
+
+// DeleteNodeWorker returns the node back to the queue, if it is still assigned to the worker and not finished
+func (e *expressionsTreeRepository) DeleteNodeWorker(ctx context.Context, id int, workerId int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE expressions_tree SET worker_id = null, status=0, lease_expires_at = null, started_at = null WHERE id = $1 AND worker_id = $2 AND status <> 3 AND status <> 4",
+		id,
 		workerId,
 	)
 
 	return err
 }
 
-func (e *expressionsTreeRepository) DeleteAllWorkers() error {
-	_, err := e.db.Exec(
-		"UPDATE expressions_tree SET worker_id = null, status=0 WHERE status <> 3 AND status <> 4 AND worker_id IS NOT NULL",
+func (e *expressionsTreeRepository) DeleteAllWorkers(ctx context.Context) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE expressions_tree SET worker_id = null, status=0, lease_expires_at = null, started_at = null WHERE status <> 3 AND status <> 4 AND worker_id IS NOT NULL",
 	)
 
 	return err
 }
 
-func (e *expressionsTreeRepository) FindUncalculated() ([]int, error) {
-	rows, err := e.db.Query(
+func (e *expressionsTreeRepository) FindUncalculated(ctx context.Context) ([]int, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
 		`select id from expressions_tree where parent_id IS NULL AND status = 0`,
 	)
 
@@ -225,3 +412,116 @@ func (e *expressionsTreeRepository) FindUncalculated() ([]int, error) {
 
 	return ids, nil
 }
+
+// FindReady returns operation nodes which are not assigned yet and have both operands calculated
+func (e *expressionsTreeRepository) FindReady(ctx context.Context, limit int) ([]*ReadyTaskEntity, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
+		`select op.id, op.user_id, ex.organization_id, op.expression_id, op.operation_type, l.result, r.result, ex.selector
+				from expressions_tree op
+				join expressions_tree l on l.parent_id = op.id and l.type = 0
+				join expressions_tree r on r.parent_id = op.id and r.type = 1
+				join expressions ex on ex.id = op.expression_id
+				where op.status = 0 and op.worker_id is null and l.status = 3 and r.status = 3
+				order by op.id
+				limit $1`,
+		limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var entities []*ReadyTaskEntity
+
+	for rows.Next() {
+		var entity = &ReadyTaskEntity{}
+
+		err := rows.Scan(&entity.Id, &entity.UserID, &entity.OrganizationID, &entity.ExpressionId, &entity.OperationType, &entity.LeftResult, &entity.RightResult, &entity.Selector)
+		if err != nil {
+			return nil, err
+		}
+
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
+
+// Lease assigns a not yet assigned node to the worker until the lease expires.
+// Returns false, if the node was assigned by someone else in the meantime
+func (e *expressionsTreeRepository) Lease(ctx context.Context, id int, workerId int, status int, until time.Time) (bool, error) {
+	res, err := e.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE expressions_tree SET worker_id=$1, status=$2, lease_expires_at=$3 WHERE id=$4 AND status = 0 AND worker_id IS NULL",
+		workerId,
+		status,
+		until,
+		id,
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// ReleaseExpiredLeases returns nodes with expired leases back to the queue.
+// Returns the released nodes with the workers which held them
+func (e *expressionsTreeRepository) ReleaseExpiredLeases(ctx context.Context, now time.Time) ([]*ExpiredLeaseEntity, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
+		`WITH expired AS (
+			SELECT id, worker_id FROM expressions_tree
+			WHERE lease_expires_at < $1 AND status <> 3 AND status <> 4
+			FOR UPDATE
+		)
+		UPDATE expressions_tree t SET worker_id = null, status=0, lease_expires_at = null, started_at = null
+		FROM expired e WHERE t.id = e.id
+		returning e.id, e.worker_id`,
+		now,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var leases []*ExpiredLeaseEntity
+
+	for rows.Next() {
+		var lease = &ExpiredLeaseEntity{}
+		var workerId sql.NullInt32
+
+		err := rows.Scan(&lease.NodeId, &workerId)
+		if err != nil {
+			return nil, err
+		}
+
+		if workerId.Valid {
+			lease.WorkerId = int(workerId.Int32)
+			leases = append(leases, lease)
+		}
+	}
+
+	return leases, nil
+}
+
+func (e *expressionsTreeRepository) DeleteByExpressionIds(ctx context.Context, expressionIds []int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		"DELETE FROM expressions_tree WHERE expression_id = ANY($1)",
+		pq.Array(expressionIds),
+	)
+
+	return err
+}
+
+func (e *expressionsTreeRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, e.db)
+}
@@ -1,6 +1,9 @@
 package expressions_repository
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
 
 type ExpressionEntity struct {
 	Id             int
@@ -11,4 +14,39 @@ type ExpressionEntity struct {
 	CreatedAt      time.Time
 	FinishedAt     time.Time
 	IdempotencyKey string
+	Selector       []byte
+	CompactedAt    sql.NullTime
+	// OrganizationID is null for expressions of the personal workspace of the user
+	OrganizationID sql.NullInt64
+}
+
+const (
+	SortByCreatedAt  = "created_at"
+	SortByFinishedAt = "finished_at"
+	SortById         = "id"
+)
+
+// ExpressionsFilter selects a page of expressions of a workspace: the expressions of the organization,
+// if OrganizationID is set, or the personal expressions of the user. Null times and empty values disable the filters
+type ExpressionsFilter struct {
+	UserID         uint64
+	OrganizationID uint64
+	Statuses       []int
+	CreatedFrom    sql.NullTime
+	CreatedTo      sql.NullTime
+	FinishedFrom   sql.NullTime
+	FinishedTo     sql.NullTime
+	// Search is a substring of the expression text
+	Search string
+	// Sort is one of SortByCreatedAt, SortByFinishedAt and SortById
+	Sort       string
+	Descending bool
+	// After continues the list after the expression with the given sort value and id
+	After *ExpressionsCursor
+	Limit int
+}
+
+type ExpressionsCursor struct {
+	Time time.Time
+	Id   int
 }
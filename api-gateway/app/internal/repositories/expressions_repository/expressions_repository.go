@@ -1,8 +1,16 @@
 package expressions_repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/lib/pq"
 )
 
 var (
@@ -10,13 +18,22 @@ var (
 )
 
 type ExpressionsRepository interface {
-	Update(entity *ExpressionEntity) error
-	FindAll() ([]*ExpressionEntity, error)
-	FindAllByUserID(userID uint64) ([]*ExpressionEntity, error)
-	FindById(id int) (*ExpressionEntity, error)
-	FindByIdempotencyKey(key string, expression string) (int, error)
-	Create(expressions string, userID uint64, status int, key string) (int, error)
-	SetStatus(id int, status int) error
+	Update(ctx context.Context, entity *ExpressionEntity) error
+	FindAll(ctx context.Context) ([]*ExpressionEntity, error)
+	FindPage(ctx context.Context, filter *ExpressionsFilter) ([]*ExpressionEntity, error)
+	Count(ctx context.Context, filter *ExpressionsFilter) (int, error)
+	FindById(ctx context.Context, id int) (*ExpressionEntity, error)
+	FindByIdempotencyKey(ctx context.Context, userID uint64, key string, expression string) (int, error)
+	Create(ctx context.Context, expressions string, userID uint64, organizationID sql.NullInt64, status int, key string, selector []byte) (int, error)
+	SetStatus(ctx context.Context, id int, status int) error
+	Fail(ctx context.Context, id int, status int) error
+	FindExpired(ctx context.Context, finishedBefore sql.NullTime, failedBefore sql.NullTime, maxPerUser sql.NullInt32, limit int) ([]int, error)
+	FindCompactable(ctx context.Context, finishedBefore time.Time, failedBefore sql.NullTime, limit int) ([]int, error)
+	MarkAsCompacted(ctx context.Context, ids []int) error
+	FindIdsByUser(ctx context.Context, userID uint64, limit int) ([]int, error)
+	CountActiveByOrganization(ctx context.Context, organizationID uint64) (int, error)
+	CountCreatedByOrganization(ctx context.Context, organizationID uint64, since time.Time) (int, error)
+	Delete(ctx context.Context, ids []int) error
 }
 
 type expressionsRepository struct {
@@ -27,9 +44,11 @@ func NewExpressionsRepository(db *sql.DB) ExpressionsRepository {
 	return &expressionsRepository{db: db}
 }
 
-func (e *expressionsRepository) FindByIdempotencyKey(key string, expression string) (int, error) {
-	row := e.db.QueryRow(
-		"SELECT id FROM expressions WHERE idempotency_key=$1 AND expression=$2 limit 1",
+func (e *expressionsRepository) FindByIdempotencyKey(ctx context.Context, userID uint64, key string, expression string) (int, error) {
+	row := e.conn(ctx).QueryRowContext(
+		ctx,
+		"SELECT id FROM expressions WHERE user_id=$1 AND idempotency_key=$2 AND expression=$3 limit 1",
+		userID,
 		key,
 		expression,
 	)
@@ -48,13 +67,16 @@ func (e *expressionsRepository) FindByIdempotencyKey(key string, expression stri
 	return id, nil
 }
 
-func (e *expressionsRepository) Create(expressions string, userID uint64, status int, key string) (int, error) {
-	row := e.db.QueryRow(
-		"INSERT INTO expressions (user_id, expression, status, idempotency_key) VALUES ($1, $2, $3, $4) returning id",
+func (e *expressionsRepository) Create(ctx context.Context, expressions string, userID uint64, organizationID sql.NullInt64, status int, key string, selector []byte) (int, error) {
+	row := e.conn(ctx).QueryRowContext(
+		ctx,
+		"INSERT INTO expressions (user_id, organization_id, expression, status, idempotency_key, selector) VALUES ($1, $2, $3, $4, $5, $6) returning id",
 		userID,
+		organizationID,
 		expressions,
 		status,
 		key,
+		selector,
 	)
 
 	var id int
@@ -67,8 +89,9 @@ func (e *expressionsRepository) Create(expressions string, userID uint64, status
 	return id, nil
 }
 
-func (e *expressionsRepository) FindById(id int) (*ExpressionEntity, error) {
-	row := e.db.QueryRow(
+func (e *expressionsRepository) FindById(ctx context.Context, id int) (*ExpressionEntity, error) {
+	row := e.conn(ctx).QueryRowContext(
+		ctx,
 		"SELECT * FROM expressions WHERE id=$1",
 		id,
 	)
@@ -83,6 +106,9 @@ func (e *expressionsRepository) FindById(id int) (*ExpressionEntity, error) {
 		&entity.CreatedAt,
 		&entity.FinishedAt,
 		&entity.IdempotencyKey,
+		&entity.Selector,
+		&entity.CompactedAt,
+		&entity.OrganizationID,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -96,8 +122,9 @@ func (e *expressionsRepository) FindById(id int) (*ExpressionEntity, error) {
 	return entity, nil
 }
 
-func (e *expressionsRepository) Update(entity *ExpressionEntity) error {
-	_, err := e.db.Exec(
+func (e *expressionsRepository) Update(ctx context.Context, entity *ExpressionEntity) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
 		"UPDATE expressions SET status=$1, result=$2, finished_at=$3 WHERE id=$4",
 		entity.Status,
 		entity.Result,
@@ -108,8 +135,8 @@ func (e *expressionsRepository) Update(entity *ExpressionEntity) error {
 	return err
 }
 
-func (e *expressionsRepository) FindAll() ([]*ExpressionEntity, error) {
-	rows, err := e.db.Query("SELECT * FROM expressions ORDER BY created_at DESC")
+func (e *expressionsRepository) FindAll(ctx context.Context) ([]*ExpressionEntity, error) {
+	rows, err := e.conn(ctx).QueryContext(ctx, "SELECT * FROM expressions ORDER BY created_at DESC")
 	if err != nil {
 		return []*ExpressionEntity{}, err
 	}
@@ -128,6 +155,9 @@ func (e *expressionsRepository) FindAll() ([]*ExpressionEntity, error) {
 			&expr.CreatedAt,
 			&expr.FinishedAt,
 			&expr.IdempotencyKey,
+			&expr.Selector,
+			&expr.CompactedAt,
+			&expr.OrganizationID,
 		)
 
 		if err != nil {
@@ -140,16 +170,34 @@ func (e *expressionsRepository) FindAll() ([]*ExpressionEntity, error) {
 	return expressions, nil
 }
 
-func (e *expressionsRepository) FindAllByUserID(userID uint64) ([]*ExpressionEntity, error) {
-	rows, err := e.db.Query(
-		"SELECT * FROM expressions WHERE user_id = $1 ORDER BY created_at DESC",
-		userID,
+// FindPage returns expressions matching the filter ordered by the sort column and id
+func (e *expressionsRepository) FindPage(ctx context.Context, filter *ExpressionsFilter) ([]*ExpressionEntity, error) {
+	where, args := filter.where(true)
+
+	var direction = "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+
+	var order = fmt.Sprintf("id %s", direction)
+	if filter.Sort != SortById {
+		order = fmt.Sprintf("%s %s, id %s", filter.Sort, direction, direction)
+	}
+
+	args = append(args, filter.Limit)
+
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT * FROM expressions WHERE %s ORDER BY %s LIMIT $%d", where, order, len(args)),
+		args...,
 	)
 	if err != nil {
-		return []*ExpressionEntity{}, err
+		return nil, err
 	}
 
-	var expressions []*ExpressionEntity
+	defer rows.Close()
+
+	var expressions = []*ExpressionEntity{}
 
 	for rows.Next() {
 		var expr = &ExpressionEntity{}
@@ -163,20 +211,108 @@ func (e *expressionsRepository) FindAllByUserID(userID uint64) ([]*ExpressionEnt
 			&expr.CreatedAt,
 			&expr.FinishedAt,
 			&expr.IdempotencyKey,
+			&expr.Selector,
+			&expr.CompactedAt,
+			&expr.OrganizationID,
 		)
 
 		if err != nil {
-			return []*ExpressionEntity{}, err
+			return nil, err
 		}
 
 		expressions = append(expressions, expr)
 	}
 
-	return expressions, nil
+	return expressions, rows.Err()
 }
 
-func (e *expressionsRepository) SetStatus(id int, status int) error {
-	_, err := e.db.Exec(
+// Count returns the number of expressions matching the filter regardless of its cursor and limit
+func (e *expressionsRepository) Count(ctx context.Context, filter *ExpressionsFilter) (int, error) {
+	where, args := filter.where(false)
+
+	row := e.conn(ctx).QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT count(*) FROM expressions WHERE %s", where),
+		args...,
+	)
+
+	var count int
+	err := row.Scan(&count)
+
+	return count, err
+}
+
+func (f *ExpressionsFilter) where(withCursor bool) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if f.OrganizationID != 0 {
+		conditions = append(conditions, "organization_id = "+arg(f.OrganizationID))
+	} else {
+		conditions = append(conditions, "user_id = "+arg(f.UserID), "organization_id IS NULL")
+	}
+
+	if len(f.Statuses) > 0 {
+		conditions = append(conditions, "status = ANY("+arg(pq.Array(f.Statuses))+")")
+	}
+
+	if f.CreatedFrom.Valid {
+		conditions = append(conditions, "created_at >= "+arg(f.CreatedFrom))
+	}
+
+	if f.CreatedTo.Valid {
+		conditions = append(conditions, "created_at < "+arg(f.CreatedTo))
+	}
+
+	if f.FinishedFrom.Valid {
+		conditions = append(conditions, "finished_at >= "+arg(f.FinishedFrom))
+	}
+
+	if f.FinishedTo.Valid {
+		conditions = append(conditions, "finished_at < "+arg(f.FinishedTo))
+	}
+
+	if f.Search != "" {
+		conditions = append(conditions, "expression ILIKE '%' || "+arg(escapeLike(f.Search))+" || '%'")
+	}
+
+	if withCursor && f.After != nil {
+		var comparison = ">"
+		if f.Descending {
+			comparison = "<"
+		}
+
+		if f.Sort == SortById {
+			conditions = append(conditions, fmt.Sprintf("id %s %s", comparison, arg(f.After.Id)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf(
+				"(%s, id) %s (%s, %s)",
+				f.Sort,
+				comparison,
+				arg(f.After.Time),
+				arg(f.After.Id),
+			))
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// escapeLike escapes wildcards of LIKE patterns, backslash is the default escape character in PostgreSQL
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (e *expressionsRepository) SetStatus(ctx context.Context, id int, status int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
 		"UPDATE expressions SET status=$1 WHERE id=$2 and status < $1",
 		status,
 		id,
@@ -184,3 +320,162 @@ func (e *expressionsRepository) SetStatus(id int, status int) error {
 
 	return err
 }
+
+// Fail sets the status and the finish time of the expression unless it has already reached the status
+func (e *expressionsRepository) Fail(ctx context.Context, id int, status int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE expressions SET status=$1, finished_at=NOW() WHERE id=$2 and status < $1",
+		status,
+		id,
+	)
+
+	return err
+}
+
+// FindExpired returns ids of finished and failed expressions which have to be deleted: finished before finishedBefore
+// or beyond maxPerUser newest expressions of their user. If failedBefore is set, failed expressions are deleted
+// only when they have failed before it and are not counted against maxPerUser. Null arguments disable the limits
+func (e *expressionsRepository) FindExpired(
+	ctx context.Context,
+	finishedBefore sql.NullTime,
+	failedBefore sql.NullTime,
+	maxPerUser sql.NullInt32,
+	limit int,
+) ([]int, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
+		`WITH ranked AS (
+			SELECT id, status, finished_at,
+			row_number() OVER (
+				PARTITION BY user_id, (status = 4 AND $2::timestamptz IS NOT NULL)
+				ORDER BY created_at DESC, id DESC
+			) AS rank
+			FROM expressions WHERE status = 3 OR status = 4
+		)
+		SELECT id FROM ranked WHERE
+		CASE WHEN status = 4 AND $2::timestamptz IS NOT NULL THEN finished_at < $2::timestamptz
+		ELSE finished_at < $1::timestamptz OR rank > $3::int END
+		ORDER BY id LIMIT $4`,
+		finishedBefore,
+		failedBefore,
+		maxPerUser,
+		limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIds(rows)
+}
+
+// FindCompactable returns ids of expressions with trees which have to be deleted. If failedBefore is set,
+// trees of failed expressions are kept until they have failed before it
+func (e *expressionsRepository) FindCompactable(ctx context.Context, finishedBefore time.Time, failedBefore sql.NullTime, limit int) ([]int, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
+		`SELECT id FROM expressions WHERE compacted_at IS NULL AND (status = 3 OR status = 4) AND finished_at < $1
+		AND (status <> 4 OR $2::timestamptz IS NULL OR finished_at < $2::timestamptz)
+		ORDER BY id LIMIT $3`,
+		finishedBefore,
+		failedBefore,
+		limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIds(rows)
+}
+
+func (e *expressionsRepository) MarkAsCompacted(ctx context.Context, ids []int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE expressions SET compacted_at=NOW() WHERE id = ANY($1)",
+		pq.Array(ids),
+	)
+
+	return err
+}
+
+// FindIdsByUser returns ids of expressions of the personal workspace of the user in any status.
+// Expressions created in organizations stay with their organizations
+func (e *expressionsRepository) FindIdsByUser(ctx context.Context, userID uint64, limit int) ([]int, error) {
+	rows, err := e.conn(ctx).QueryContext(
+		ctx,
+		"SELECT id FROM expressions WHERE user_id = $1 AND organization_id IS NULL ORDER BY id LIMIT $2",
+		userID,
+		limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIds(rows)
+}
+
+// CountActiveByOrganization returns the number of expressions of the organization which are not finished or failed yet
+func (e *expressionsRepository) CountActiveByOrganization(ctx context.Context, organizationID uint64) (int, error) {
+	row := e.conn(ctx).QueryRowContext(
+		ctx,
+		"SELECT count(*) FROM expressions WHERE organization_id = $1 AND status < 3",
+		organizationID,
+	)
+
+	var count int
+	err := row.Scan(&count)
+
+	return count, err
+}
+
+// CountCreatedByOrganization returns the number of expressions created in the organization since the time
+func (e *expressionsRepository) CountCreatedByOrganization(ctx context.Context, organizationID uint64, since time.Time) (int, error) {
+	row := e.conn(ctx).QueryRowContext(
+		ctx,
+		"SELECT count(*) FROM expressions WHERE organization_id = $1 AND created_at >= $2",
+		organizationID,
+		since,
+	)
+
+	var count int
+	err := row.Scan(&count)
+
+	return count, err
+}
+
+// Delete deletes the expressions together with their trees
+func (e *expressionsRepository) Delete(ctx context.Context, ids []int) error {
+	_, err := e.conn(ctx).ExecContext(
+		ctx,
+		"DELETE FROM expressions WHERE id = ANY($1)",
+		pq.Array(ids),
+	)
+
+	return err
+}
+
+func scanIds(rows *sql.Rows) ([]int, error) {
+	defer rows.Close()
+
+	var ids []int
+
+	for rows.Next() {
+		var id int
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (e *expressionsRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, e.db)
+}
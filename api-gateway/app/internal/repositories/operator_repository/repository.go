@@ -1,10 +1,17 @@
 package operator_repository
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+)
 
 type OperatorsRepository interface {
-	Save(entity *OperatorEntity) error
-	FindAll() ([]*OperatorEntity, error)
+	Save(ctx context.Context, entity *OperatorEntity) error
+	FindAll(ctx context.Context) ([]*OperatorEntity, error)
+	SaveForOrganization(ctx context.Context, organizationID uint64, entity *OperatorEntity) error
+	FindByOrganization(ctx context.Context, organizationID uint64) ([]*OperatorEntity, error)
 }
 
 type operatorsRepository struct {
@@ -17,8 +24,9 @@ func NewOperatorsRepository(db *sql.DB) OperatorsRepository {
 	}
 }
 
-func (o *operatorsRepository) Save(entity *OperatorEntity) error {
-	_, err := o.db.Exec(
+func (o *operatorsRepository) Save(ctx context.Context, entity *OperatorEntity) error {
+	_, err := o.conn(ctx).ExecContext(
+		ctx,
 		"INSERT INTO operators (operator_type, duration_ms) VALUES ($1, $2) ON CONFLICT (operator_type) DO UPDATE SET duration_ms = $2",
 		entity.OperatorType,
 		entity.DurationMS,
@@ -27,8 +35,9 @@ func (o *operatorsRepository) Save(entity *OperatorEntity) error {
 	return err
 }
 
-func (o *operatorsRepository) FindAll() ([]*OperatorEntity, error) {
-	rows, err := o.db.Query(
+func (o *operatorsRepository) FindAll(ctx context.Context) ([]*OperatorEntity, error) {
+	rows, err := o.conn(ctx).QueryContext(
+		ctx,
 		"SELECT * FROM operators",
 	)
 
@@ -36,6 +45,41 @@ func (o *operatorsRepository) FindAll() ([]*OperatorEntity, error) {
 		return nil, err
 	}
 
+	return scanOperators(rows)
+}
+
+// SaveForOrganization overrides the duration of the operator for expressions of the organization
+func (o *operatorsRepository) SaveForOrganization(ctx context.Context, organizationID uint64, entity *OperatorEntity) error {
+	_, err := o.conn(ctx).ExecContext(
+		ctx,
+		`INSERT INTO organization_operators (organization_id, operator_type, duration_ms) VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, operator_type) DO UPDATE SET duration_ms = $3`,
+		organizationID,
+		entity.OperatorType,
+		entity.DurationMS,
+	)
+
+	return err
+}
+
+// FindByOrganization returns only the durations overridden by the organization
+func (o *operatorsRepository) FindByOrganization(ctx context.Context, organizationID uint64) ([]*OperatorEntity, error) {
+	rows, err := o.conn(ctx).QueryContext(
+		ctx,
+		"SELECT operator_type, duration_ms FROM organization_operators WHERE organization_id = $1",
+		organizationID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOperators(rows)
+}
+
+func scanOperators(rows *sql.Rows) ([]*OperatorEntity, error) {
+	defer rows.Close()
+
 	var operators = []*OperatorEntity{}
 
 	for rows.Next() {
@@ -49,5 +93,9 @@ func (o *operatorsRepository) FindAll() ([]*OperatorEntity, error) {
 		operators = append(operators, operator)
 	}
 
-	return operators, nil
+	return operators, rows.Err()
+}
+
+func (o *operatorsRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, o.db)
 }
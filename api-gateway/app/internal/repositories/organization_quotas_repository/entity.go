@@ -0,0 +1,11 @@
+package organization_quotas_repository
+
+import "database/sql"
+
+// OrganizationQuotaEntity keeps limits of an organization. Null limits fall back to the defaults of the service
+type OrganizationQuotaEntity struct {
+	OrganizationID       uint64
+	MaxActiveExpressions sql.NullInt32
+	MaxDailyExpressions  sql.NullInt32
+	UpdatedAt            sql.NullTime
+}
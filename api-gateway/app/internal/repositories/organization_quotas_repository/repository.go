@@ -0,0 +1,88 @@
+package organization_quotas_repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=OrganizationQuotasRepository
+type OrganizationQuotasRepository interface {
+	// Find returns an entity without limits for organizations without their own quota
+	Find(ctx context.Context, organizationID uint64) (*OrganizationQuotaEntity, error)
+	Save(ctx context.Context, entity *OrganizationQuotaEntity) error
+	// Lock serializes quota checks of the organization till the end of the transaction of ctx.
+	// It must be called in a transaction
+	Lock(ctx context.Context, organizationID uint64) error
+}
+
+type organizationQuotasRepository struct {
+	db *sql.DB
+}
+
+func NewOrganizationQuotasRepository(db *sql.DB) OrganizationQuotasRepository {
+	return &organizationQuotasRepository{db: db}
+}
+
+func (o *organizationQuotasRepository) Find(ctx context.Context, organizationID uint64) (*OrganizationQuotaEntity, error) {
+	var entity = &OrganizationQuotaEntity{OrganizationID: organizationID}
+
+	err := o.conn(ctx).QueryRowContext(
+		ctx,
+		"SELECT max_active_expressions, max_daily_expressions, updated_at FROM organization_quotas WHERE organization_id = $1",
+		organizationID,
+	).Scan(&entity.MaxActiveExpressions, &entity.MaxDailyExpressions, &entity.UpdatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (o *organizationQuotasRepository) Save(ctx context.Context, entity *OrganizationQuotaEntity) error {
+	_, err := o.conn(ctx).ExecContext(
+		ctx,
+		`INSERT INTO organization_quotas (organization_id, max_active_expressions, max_daily_expressions) VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id) DO UPDATE SET
+		max_active_expressions = EXCLUDED.max_active_expressions,
+		max_daily_expressions = EXCLUDED.max_daily_expressions,
+		updated_at = NOW()`,
+		entity.OrganizationID,
+		entity.MaxActiveExpressions,
+		entity.MaxDailyExpressions,
+	)
+
+	return err
+}
+
+// Lock locks the quota row of the organization, creating a row without limits if there is none,
+// so concurrent checks wait for the transaction which created an expression to finish
+func (o *organizationQuotasRepository) Lock(ctx context.Context, organizationID uint64) error {
+	_, err := o.conn(ctx).ExecContext(
+		ctx,
+		"INSERT INTO organization_quotas (organization_id) VALUES ($1) ON CONFLICT (organization_id) DO NOTHING",
+		organizationID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.conn(ctx).ExecContext(
+		ctx,
+		"SELECT organization_id FROM organization_quotas WHERE organization_id = $1 FOR UPDATE",
+		organizationID,
+	)
+
+	return err
+}
+
+func (o *organizationQuotasRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, o.db)
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Conn is implemented by both *sql.DB and *sql.Tx
+type Conn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+type txKey struct{}
+
+// ConnFrom returns the transaction started by Transactor.InTx for ctx or db, if ctx is not in a transaction.
+// Repositories use it for every query, so they join transactions of their callers
+func ConnFrom(ctx context.Context, db *sql.DB) Conn {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// Transactor runs a unit of work in a single transaction
+type Transactor interface {
+	// InTx runs fn in a transaction which is committed if fn returns nil and rolled back otherwise.
+	// Repositories called with the context passed to fn run their queries in the transaction.
+	// Nested calls join the outer transaction
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactor struct {
+	db *sql.DB
+}
+
+func NewTransactor(db *sql.DB) Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return InTx(ctx, t.db, fn)
+}
+
+// InTx is Transactor.InTx for repositories which need a transaction for a single method
+func InTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txKey{}, tx))
+	if err != nil {
+		return errors.Join(err, ignoreDone(tx.Rollback()))
+	}
+
+	return tx.Commit()
+}
+
+func ignoreDone(err error) error {
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,31 @@
+package task_attempts_repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Outcomes of task attempts
+const (
+	Enqueued       = "enqueued"
+	Running        = "running"
+	Succeeded      = "succeeded"
+	Failed         = "failed"
+	Reassigned     = "reassigned"
+	TimedOut       = "timed_out"
+	DispatchFailed = "dispatch_failed"
+)
+
+type TaskAttemptEntity struct {
+	Id            int
+	NodeId        int
+	ExpressionId  int
+	WorkerId      int
+	Attempt       int
+	OperationType int
+	EnqueuedAt    time.Time
+	StartedAt     sql.NullTime
+	FinishedAt    sql.NullTime
+	Outcome       string
+	Error         string
+}
@@ -0,0 +1,162 @@
+package task_attempts_repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/lib/pq"
+)
+
+type TaskAttemptsRepository interface {
+	Create(ctx context.Context, nodeId int, workerId int) error
+	Start(ctx context.Context, nodeId int) error
+	Finish(ctx context.Context, nodeId int, outcome string, reason string) error
+	FinishByWorker(ctx context.Context, workerId int, outcome string) error
+	FinishAll(ctx context.Context, outcome string) error
+	FindByExpressionId(ctx context.Context, expressionId int) ([]*TaskAttemptEntity, error)
+	DeleteByExpressionIds(ctx context.Context, expressionIds []int) error
+}
+
+type taskAttemptsRepository struct {
+	db *sql.DB
+}
+
+func NewTaskAttemptsRepository(db *sql.DB) TaskAttemptsRepository {
+	return &taskAttemptsRepository{db: db}
+}
+
+// Create records a new dispatch of the node to the worker. Unfinished previous attempts are closed as reassigned.
+// The node may be started or even finished before its dispatch is recorded, so the attempt takes the current node state
+func (t *taskAttemptsRepository) Create(ctx context.Context, nodeId int, workerId int) error {
+	err := t.Finish(ctx, nodeId, Reassigned, "")
+	if err != nil {
+		return err
+	}
+
+	_, err = t.conn(ctx).ExecContext(
+		ctx,
+		`INSERT INTO task_attempts (node_id, expression_id, worker_id, attempt, started_at, finished_at, outcome)
+		SELECT n.id, n.expression_id, $2,
+		(SELECT COALESCE(MAX(a.attempt), 0) + 1 FROM task_attempts a WHERE a.node_id = n.id),
+		n.started_at,
+		CASE WHEN n.status = 3 THEN NOW() END,
+		CASE WHEN n.status = 3 THEN $3 WHEN n.started_at IS NOT NULL THEN $4 ELSE $5 END
+		FROM expressions_tree n WHERE n.id = $1`,
+		nodeId,
+		workerId,
+		Succeeded,
+		Running,
+		Enqueued,
+	)
+
+	return err
+}
+
+// Start marks the latest unfinished attempt of the node as running
+func (t *taskAttemptsRepository) Start(ctx context.Context, nodeId int) error {
+	_, err := t.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE task_attempts SET started_at = NOW(), outcome = $2 WHERE id = (
+			SELECT id FROM task_attempts WHERE node_id = $1 AND finished_at IS NULL ORDER BY id DESC LIMIT 1
+		)`,
+		nodeId,
+		Running,
+	)
+
+	return err
+}
+
+// Finish closes unfinished attempts of the node
+func (t *taskAttemptsRepository) Finish(ctx context.Context, nodeId int, outcome string, reason string) error {
+	_, err := t.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE task_attempts SET finished_at = NOW(), outcome = $2, error = $3 WHERE node_id = $1 AND finished_at IS NULL",
+		nodeId,
+		outcome,
+		reason,
+	)
+
+	return err
+}
+
+// FinishByWorker closes unfinished attempts of the worker
+func (t *taskAttemptsRepository) FinishByWorker(ctx context.Context, workerId int, outcome string) error {
+	_, err := t.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE task_attempts SET finished_at = NOW(), outcome = $2 WHERE worker_id = $1 AND finished_at IS NULL",
+		workerId,
+		outcome,
+	)
+
+	return err
+}
+
+func (t *taskAttemptsRepository) FinishAll(ctx context.Context, outcome string) error {
+	_, err := t.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE task_attempts SET finished_at = NOW(), outcome = $1 WHERE finished_at IS NULL",
+		outcome,
+	)
+
+	return err
+}
+
+// FindByExpressionId returns attempts of all nodes of the expression in order of their dispatch
+func (t *taskAttemptsRepository) FindByExpressionId(ctx context.Context, expressionId int) ([]*TaskAttemptEntity, error) {
+	rows, err := t.conn(ctx).QueryContext(
+		ctx,
+		`SELECT a.id, a.node_id, a.expression_id, a.worker_id, a.attempt, n.operation_type,
+		a.enqueued_at, a.started_at, a.finished_at, a.outcome, a.error
+		FROM task_attempts a JOIN expressions_tree n ON n.id = a.node_id
+		WHERE a.expression_id = $1 ORDER BY a.enqueued_at, a.id`,
+		expressionId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var attempts = []*TaskAttemptEntity{}
+
+	for rows.Next() {
+		var attempt = &TaskAttemptEntity{}
+
+		err := rows.Scan(
+			&attempt.Id,
+			&attempt.NodeId,
+			&attempt.ExpressionId,
+			&attempt.WorkerId,
+			&attempt.Attempt,
+			&attempt.OperationType,
+			&attempt.EnqueuedAt,
+			&attempt.StartedAt,
+			&attempt.FinishedAt,
+			&attempt.Outcome,
+			&attempt.Error,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
+
+func (t *taskAttemptsRepository) DeleteByExpressionIds(ctx context.Context, expressionIds []int) error {
+	_, err := t.conn(ctx).ExecContext(
+		ctx,
+		"DELETE FROM task_attempts WHERE expression_id = ANY($1)",
+		pq.Array(expressionIds),
+	)
+
+	return err
+}
+
+func (t *taskAttemptsRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, t.db)
+}
@@ -0,0 +1,11 @@
+package worker_events_repository
+
+import "time"
+
+type WorkerEventEntity struct {
+	Id        int
+	WorkerId  int
+	Type      string
+	Reason    string
+	CreatedAt time.Time
+}
@@ -0,0 +1,65 @@
+package worker_events_repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=WorkerEventsRepository
+type WorkerEventsRepository interface {
+	Create(ctx context.Context, entity *WorkerEventEntity) error
+	FindByWorkerId(ctx context.Context, workerId int) ([]*WorkerEventEntity, error)
+}
+
+type workerEventsRepository struct {
+	db *sql.DB
+}
+
+func NewWorkerEventsRepository(db *sql.DB) WorkerEventsRepository {
+	return &workerEventsRepository{db: db}
+}
+
+func (w *workerEventsRepository) Create(ctx context.Context, entity *WorkerEventEntity) error {
+	_, err := w.conn(ctx).ExecContext(
+		ctx,
+		"INSERT INTO worker_events (worker_id, type, reason) VALUES ($1, $2, $3)",
+		entity.WorkerId,
+		entity.Type,
+		entity.Reason,
+	)
+
+	return err
+}
+
+func (w *workerEventsRepository) FindByWorkerId(ctx context.Context, workerId int) ([]*WorkerEventEntity, error) {
+	rows, err := w.conn(ctx).QueryContext(
+		ctx,
+		"SELECT * FROM worker_events WHERE worker_id = $1 ORDER BY id",
+		workerId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var events = []*WorkerEventEntity{}
+
+	for rows.Next() {
+		var event = &WorkerEventEntity{}
+
+		err := rows.Scan(&event.Id, &event.WorkerId, &event.Type, &event.Reason, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (w *workerEventsRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, w.db)
+}
@@ -0,0 +1,14 @@
+package worker_stats_repository
+
+import "time"
+
+type OperationStatsEntity struct {
+	WorkerId      int
+	OperationType int
+	Samples       int
+	MeanMS        float64
+	MinMS         float64
+	MaxMS         float64
+	LastMS        float64
+	UpdatedAt     time.Time
+}
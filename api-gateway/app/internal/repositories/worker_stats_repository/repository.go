@@ -0,0 +1,105 @@
+package worker_stats_repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+)
+
+type WorkerStatsRepository interface {
+	Record(ctx context.Context, nodeId int, alpha float64) error
+	FindByWorkerId(ctx context.Context, workerId int) ([]*OperationStatsEntity, error)
+	FindAll(ctx context.Context) ([]*OperationStatsEntity, error)
+}
+
+type workerStatsRepository struct {
+	db *sql.DB
+}
+
+func NewWorkerStatsRepository(db *sql.DB) WorkerStatsRepository {
+	return &workerStatsRepository{db: db}
+}
+
+// Record adds the latency of node (time since it was started by its worker) to the statistics
+// of the worker for the node operation. The mean is an exponential moving average with weight alpha.
+// Nodes which have not been started by a worker are ignored
+func (w *workerStatsRepository) Record(ctx context.Context, nodeId int, alpha float64) error {
+	_, err := w.conn(ctx).ExecContext(
+		ctx,
+		`INSERT INTO worker_operation_stats AS s (worker_id, operation_type, samples, mean_ms, min_ms, max_ms, last_ms, updated_at)
+		SELECT t.worker_id, t.operation_type, 1, l.ms, l.ms, l.ms, l.ms, NOW()
+		FROM expressions_tree t, LATERAL (SELECT EXTRACT(EPOCH FROM NOW() - t.started_at) * 1000 AS ms) l
+		WHERE t.id = $1 AND t.worker_id IS NOT NULL AND t.operation_type IS NOT NULL AND t.started_at IS NOT NULL
+		ON CONFLICT (worker_id, operation_type) DO UPDATE SET
+		samples = s.samples + 1,
+		mean_ms = s.mean_ms + $2 * (EXCLUDED.last_ms - s.mean_ms),
+		min_ms = LEAST(s.min_ms, EXCLUDED.last_ms),
+		max_ms = GREATEST(s.max_ms, EXCLUDED.last_ms),
+		last_ms = EXCLUDED.last_ms,
+		updated_at = NOW()`,
+		nodeId,
+		alpha,
+	)
+
+	return err
+}
+
+func (w *workerStatsRepository) FindByWorkerId(ctx context.Context, workerId int) ([]*OperationStatsEntity, error) {
+	rows, err := w.conn(ctx).QueryContext(
+		ctx,
+		"SELECT * FROM worker_operation_stats WHERE worker_id = $1 ORDER BY operation_type",
+		workerId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanStats(rows)
+}
+
+func (w *workerStatsRepository) FindAll(ctx context.Context) ([]*OperationStatsEntity, error) {
+	rows, err := w.conn(ctx).QueryContext(
+		ctx,
+		"SELECT * FROM worker_operation_stats ORDER BY worker_id, operation_type",
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanStats(rows)
+}
+
+func scanStats(rows *sql.Rows) ([]*OperationStatsEntity, error) {
+	defer rows.Close()
+
+	var entities = []*OperationStatsEntity{}
+
+	for rows.Next() {
+		var entity = &OperationStatsEntity{}
+
+		err := rows.Scan(
+			&entity.WorkerId,
+			&entity.OperationType,
+			&entity.Samples,
+			&entity.MeanMS,
+			&entity.MinMS,
+			&entity.MaxMS,
+			&entity.LastMS,
+			&entity.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
+
+func (w *workerStatsRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, w.db)
+}
@@ -1,16 +1,52 @@
 package workers_repository
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
 
 type WorkerEntity struct {
 	Id           int
 	Url          string
 	Executors    int
 	LastModified time.Time
+	Capabilities []byte
+	SecretHash   string
+	Pull         bool
+
+	State            string
+	MissedHeartbeats int
+	Deaths           int
+	StateChangedAt   time.Time
+
+	TasksCompleted    int
+	TasksTimedOut     int
+	ResultsMismatched int
+	QuarantineReason  string
+
+	Cordoned          bool
+	ExecutorsOverride sql.NullInt32
+	AdminLabels       []byte
+
+	LastProbeAt   sql.NullTime
+	LastProbeOkAt sql.NullTime
+	ProbeFailures int
+	Unreachable   bool
+
+	InFlight int
+}
+
+type WorkerStatsEntity struct {
+	State             string
+	TasksCompleted    int
+	TasksTimedOut     int
+	ResultsMismatched int
 }
 
 type FreeWorkerEntity struct {
-	Id        int
-	Url       string
-	Executors int
+	Id           int
+	Url          string
+	Executors    int
+	Capabilities []byte
+	AdminLabels  []byte
 }
@@ -1,18 +1,56 @@
 package workers_repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrWorkerNotFound    = errors.New("workers_repository: worker not found")
+	ErrWorkerUrlConflict = errors.New("workers_repository: url is used by another worker")
+)
+
+const (
+	uniqueViolationCode = "23505"
+
+	// urlConstraint is the unique constraint created for workers.url
+	urlConstraint = "workers_url_key"
 )
 
 type WorkersRepository interface {
-	Register(entity *WorkerEntity) (bool, error)
-	FindAll() ([]*WorkerEntity, error)
-	DeleteExpiredWorkers(deadline time.Time) ([]int, error)
-	FindFreeWorker() (*FreeWorkerEntity, error)
+	Create(ctx context.Context, entity *WorkerEntity) (int, error)
+	Register(ctx context.Context, entity *WorkerEntity) (bool, error)
+	FindSecretHash(ctx context.Context, id int) (string, error)
+	FindById(ctx context.Context, id int) (*WorkerEntity, error)
+	FindAll(ctx context.Context) ([]*WorkerEntity, error)
+	ReleaseUrl(ctx context.Context, url string) error
+	SetHealth(ctx context.Context, id int, state string, missedHeartbeats int) error
+	AddStats(ctx context.Context, id int, completed int, timedOut int, mismatched int) (*WorkerStatsEntity, error)
+	Quarantine(ctx context.Context, id int, reason string) (bool, error)
+	Release(ctx context.Context, id int) (bool, error)
+	SetState(ctx context.Context, id int, state string) error
+	SetCordoned(ctx context.Context, id int, cordoned bool) error
+	RevokeSecret(ctx context.Context, id int) error
+	SetExecutorsOverride(ctx context.Context, id int, executors sql.NullInt32) error
+	SetAdminLabels(ctx context.Context, id int, labels []byte) error
+	SaveProbe(ctx context.Context, id int, ok bool) (int, error)
+	SetUnreachable(ctx context.Context, id int, unreachable bool) error
+	FindFreeWorkers(ctx context.Context) ([]*FreeWorkerEntity, error)
 }
 
+// selectWorkers selects the workers columns read by scanWorker with the number of not finished tasks assigned to a worker
+const selectWorkers = `SELECT w.id, w.url, w.executors, w.last_modified, w.capabilities, w.secret_hash, w.pull,
+	w.state, w.missed_heartbeats, w.deaths, w.state_changed_at,
+	w.tasks_completed, w.tasks_timed_out, w.results_mismatched, w.quarantine_reason,
+	w.cordoned, w.executors_override, w.admin_labels,
+	w.last_probe_at, w.last_probe_ok_at, w.probe_failures, w.unreachable,
+	(select count(*) from expressions_tree t where t.worker_id = w.id and t.status <> 3 and t.status <> 4) as in_flight
+	FROM workers w`
+
 type workersRepository struct {
 	db *sql.DB
 }
@@ -21,39 +59,130 @@ func NewWorkersRepository(db *sql.DB) WorkersRepository {
 	return &workersRepository{db: db}
 }
 
-func (w *workersRepository) Register(entity *WorkerEntity) (bool, error) {
-	row := w.db.QueryRow(
-		"INSERT INTO workers (id, url, executors) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET url = $2, executors = $3, last_modified = NOW() returning xmax::text::int > 0 as is_updated",
+func (w *workersRepository) Create(ctx context.Context, entity *WorkerEntity) (int, error) {
+	row := w.conn(ctx).QueryRowContext(
+		ctx,
+		"INSERT INTO workers (url, executors, capabilities, secret_hash, pull) VALUES ($1, $2, $3, $4, $5) returning id",
+		entity.Url,
+		entity.Executors,
+		entity.Capabilities,
+		entity.SecretHash,
+		entity.Pull,
+	)
+
+	var id int
+
+	err := row.Scan(&id)
+	if isUrlConflict(err) {
+		return 0, ErrWorkerUrlConflict
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (w *workersRepository) Register(ctx context.Context, entity *WorkerEntity) (bool, error) {
+	row := w.conn(ctx).QueryRowContext(
+		ctx,
+		`INSERT INTO workers (id, url, executors, capabilities, secret_hash, pull) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET url = $2, executors = $3, capabilities = $4, pull = $6, last_modified = NOW(),
+		secret_hash = CASE WHEN workers.secret_hash = '' THEN $5 ELSE workers.secret_hash END,
+		missed_heartbeats = 0,
+		probe_failures = CASE WHEN workers.url <> $2 THEN 0 ELSE workers.probe_failures END,
+		unreachable = CASE WHEN workers.url <> $2 THEN false ELSE workers.unreachable END,
+		state = CASE WHEN workers.state IN ('suspect', 'dead') THEN 'active' ELSE workers.state END,
+		state_changed_at = CASE WHEN workers.state IN ('suspect', 'dead') THEN NOW() ELSE workers.state_changed_at END
+		returning xmax::text::int > 0 as is_updated`,
 		entity.Id,
 		entity.Url,
 		entity.Executors,
+		entity.Capabilities,
+		entity.SecretHash,
+		entity.Pull,
 	)
 
 	var exists bool
 
 	err := row.Scan(&exists)
+	if isUrlConflict(err) {
+		return false, ErrWorkerUrlConflict
+	}
+
+	if err != nil || exists {
+		return exists, err
+	}
 
-	return exists, err
+	// the id was inserted explicitly, so the sequence has to be moved past it for the following Create calls
+	_, err = w.conn(ctx).ExecContext(
+		ctx,
+		"SELECT setval(pg_get_serial_sequence('workers', 'id'), (SELECT MAX(id) FROM workers))",
+	)
+
+	return false, err
 }
 
-func (w *workersRepository) FindAll() ([]*WorkerEntity, error) {
-	rows, err := w.db.Query("SELECT * FROM workers ORDER BY id")
+func (w *workersRepository) FindSecretHash(ctx context.Context, id int) (string, error) {
+	row := w.conn(ctx).QueryRowContext(
+		ctx,
+		"SELECT secret_hash FROM workers WHERE id = $1",
+		id,
+	)
+
+	var secretHash string
+
+	err := row.Scan(&secretHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrWorkerNotFound
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return secretHash, nil
+}
+
+func (w *workersRepository) FindById(ctx context.Context, id int) (*WorkerEntity, error) {
+	row := w.conn(ctx).QueryRowContext(
+		ctx,
+		selectWorkers+" WHERE w.id = $1",
+		id,
+	)
+
+	worker, err := scanWorker(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkerNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return worker, nil
+}
+
+// isUrlConflict reports whether err is a violation of the unique url constraint.
+// Other unique violations are returned as they are
+func isUrlConflict(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode && pqErr.Constraint == urlConstraint
+}
+
+func (w *workersRepository) FindAll(ctx context.Context) ([]*WorkerEntity, error) {
+	rows, err := w.conn(ctx).QueryContext(ctx, selectWorkers+" ORDER BY w.id")
 	if err != nil {
 		return []*WorkerEntity{}, err
 	}
 
+	defer rows.Close()
+
 	var workers []*WorkerEntity
 
 	for rows.Next() {
-		var worker = &WorkerEntity{}
-
-		err := rows.Scan(
-			&worker.Id,
-			&worker.Url,
-			&worker.Executors,
-			&worker.LastModified,
-		)
-
+		worker, err := scanWorker(rows)
 		if err != nil {
 			return []*WorkerEntity{}, err
 		}
@@ -61,53 +190,253 @@ func (w *workersRepository) FindAll() ([]*WorkerEntity, error) {
 		workers = append(workers, worker)
 	}
 
-	return workers, nil
+	return workers, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
 }
 
-func (w *workersRepository) DeleteExpiredWorkers(deadline time.Time) ([]int, error) {
-	rows, err := w.db.Query(
-		"DELETE FROM workers WHERE last_modified < $1 returning id",
-		deadline,
+func scanWorker(row scanner) (*WorkerEntity, error) {
+	var worker = &WorkerEntity{}
+
+	err := row.Scan(
+		&worker.Id,
+		&worker.Url,
+		&worker.Executors,
+		&worker.LastModified,
+		&worker.Capabilities,
+		&worker.SecretHash,
+		&worker.Pull,
+		&worker.State,
+		&worker.MissedHeartbeats,
+		&worker.Deaths,
+		&worker.StateChangedAt,
+		&worker.TasksCompleted,
+		&worker.TasksTimedOut,
+		&worker.ResultsMismatched,
+		&worker.QuarantineReason,
+		&worker.Cordoned,
+		&worker.ExecutorsOverride,
+		&worker.AdminLabels,
+		&worker.LastProbeAt,
+		&worker.LastProbeOkAt,
+		&worker.ProbeFailures,
+		&worker.Unreachable,
+		&worker.InFlight,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	var ids []int
+	return worker, nil
+}
 
-	for rows.Next() {
-		var id int
+// ReleaseUrl frees url taken by a dead worker, so a new worker may register with it.
+// The dead worker is kept for history with a suffixed url
+func (w *workersRepository) ReleaseUrl(ctx context.Context, url string) error {
+	_, err := w.conn(ctx).ExecContext(
+		ctx,
+		"UPDATE workers SET url = url || '#' || id WHERE url = $1 AND state = 'dead'",
+		url,
+	)
 
-		err := rows.Scan(&id)
-		if err != nil {
-			return nil, err
-		}
+	return err
+}
+
+// SetHealth updates the state found by the failure detector. Deaths are counted on transitions to dead
+func (w *workersRepository) SetHealth(ctx context.Context, id int, state string, missedHeartbeats int) error {
+	_, err := w.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE workers SET missed_heartbeats = $2,
+		deaths = deaths + CASE WHEN $3::varchar = 'dead' AND state <> 'dead' THEN 1 ELSE 0 END,
+		state_changed_at = CASE WHEN state <> $3::varchar THEN NOW() ELSE state_changed_at END,
+		state = $3
+		WHERE id = $1`,
+		id,
+		missedHeartbeats,
+		state,
+	)
+
+	return err
+}
 
-		ids = append(ids, id)
+// AddStats increments task counters of the worker and returns their new values
+func (w *workersRepository) AddStats(ctx context.Context, id int, completed int, timedOut int, mismatched int) (*WorkerStatsEntity, error) {
+	row := w.conn(ctx).QueryRowContext(
+		ctx,
+		`UPDATE workers SET tasks_completed = tasks_completed + $2, tasks_timed_out = tasks_timed_out + $3,
+		results_mismatched = results_mismatched + $4
+		WHERE id = $1
+		returning state, tasks_completed, tasks_timed_out, results_mismatched`,
+		id,
+		completed,
+		timedOut,
+		mismatched,
+	)
+
+	var stats = &WorkerStatsEntity{}
+
+	err := row.Scan(&stats.State, &stats.TasksCompleted, &stats.TasksTimedOut, &stats.ResultsMismatched)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkerNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Quarantine excludes the worker from scheduling. Returns false, if it is already quarantined
+func (w *workersRepository) Quarantine(ctx context.Context, id int, reason string) (bool, error) {
+	res, err := w.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE workers SET state = 'quarantined', quarantine_reason = $2, state_changed_at = NOW()
+		WHERE id = $1 AND state <> 'quarantined'`,
+		id,
+		reason,
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
 	}
 
-	return ids, nil
+	return affected > 0, nil
 }
 
-func (w *workersRepository) FindFreeWorker() (*FreeWorkerEntity, error) {
-	row := w.db.QueryRow(
-		`select
-		w.id, w.url, w.executors - (select count(*) from expressions_tree t where t.worker_id = w.id and t.status <> 3 and t.status <> 4) as free_executors
-		from workers w order by free_executors desc, w.id asc limit 1`,
+// Release returns a quarantined worker to scheduling and resets its task counters.
+// Returns false, if the worker is not quarantined
+func (w *workersRepository) Release(ctx context.Context, id int) (bool, error) {
+	res, err := w.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE workers SET state = 'active', quarantine_reason = '', state_changed_at = NOW(),
+		tasks_completed = 0, tasks_timed_out = 0, results_mismatched = 0
+		WHERE id = $1 AND state = 'quarantined'`,
+		id,
 	)
 
-	var worker = &FreeWorkerEntity{}
+	if err != nil {
+		return false, err
+	}
 
-	err := row.Scan(&worker.Id, &worker.Url, &worker.Executors)
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (w *workersRepository) SetState(ctx context.Context, id int, state string) error {
+	return w.update(ctx, "UPDATE workers SET state = $2, state_changed_at = NOW() WHERE id = $1", id, state)
+}
+
+func (w *workersRepository) SetCordoned(ctx context.Context, id int, cordoned bool) error {
+	return w.update(ctx, "UPDATE workers SET cordoned = $2 WHERE id = $1", id, cordoned)
+}
+
+// RevokeSecret removes the secret hash, so the worker id is not accepted anymore
+func (w *workersRepository) RevokeSecret(ctx context.Context, id int) error {
+	return w.update(ctx, "UPDATE workers SET secret_hash = $2 WHERE id = $1", id, "")
+}
+
+// SetExecutorsOverride replaces the executors count advertised by the worker. Null removes the override
+func (w *workersRepository) SetExecutorsOverride(ctx context.Context, id int, executors sql.NullInt32) error {
+	return w.update(ctx, "UPDATE workers SET executors_override = $2 WHERE id = $1", id, executors)
+}
+
+// SetAdminLabels sets labels which are added to the labels advertised by the worker
+func (w *workersRepository) SetAdminLabels(ctx context.Context, id int, labels []byte) error {
+	return w.update(ctx, "UPDATE workers SET admin_labels = $2 WHERE id = $1", id, labels)
+}
 
+// SaveProbe records the result of a health probe. Returns the number of consecutive failed probes
+func (w *workersRepository) SaveProbe(ctx context.Context, id int, ok bool) (int, error) {
+	row := w.conn(ctx).QueryRowContext(
+		ctx,
+		`UPDATE workers SET last_probe_at = NOW(),
+		last_probe_ok_at = CASE WHEN $2 THEN NOW() ELSE last_probe_ok_at END,
+		probe_failures = CASE WHEN $2 THEN 0 ELSE probe_failures + 1 END
+		WHERE id = $1 RETURNING probe_failures`,
+		id,
+		ok,
+	)
+
+	var failures int
+
+	err := row.Scan(&failures)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+		return 0, ErrWorkerNotFound
 	}
 
+	return failures, err
+}
+
+func (w *workersRepository) SetUnreachable(ctx context.Context, id int, unreachable bool) error {
+	return w.update(ctx, "UPDATE workers SET unreachable = $2 WHERE id = $1", id, unreachable)
+}
+
+// update executes a single worker update. Returns ErrWorkerNotFound, if there is no such worker
+func (w *workersRepository) update(ctx context.Context, query string, id int, value any) error {
+	res, err := w.conn(ctx).ExecContext(ctx, query, id, value)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrWorkerNotFound
+	}
+
+	return nil
+}
+
+// FindFreeWorkers returns active push workers which have free executors.
+// Pull workers are excluded, because they acquire tasks by themselves
+func (w *workersRepository) FindFreeWorkers(ctx context.Context) ([]*FreeWorkerEntity, error) {
+	rows, err := w.conn(ctx).QueryContext(
+		ctx,
+		`select f.id, f.url, f.free_executors, f.capabilities, f.admin_labels from (select
+		w.id, w.url, coalesce(w.executors_override, w.executors) - (select count(*) from expressions_tree t where t.worker_id = w.id and t.status <> 3 and t.status <> 4) as free_executors,
+		w.capabilities, w.admin_labels, w.pull, w.state, w.cordoned, w.unreachable
+		from workers w) f where f.free_executors > 0 and not f.pull and f.state = 'active' and not f.cordoned and not f.unreachable
+		order by f.free_executors desc, f.id asc`,
+	)
+
 	if err != nil {
 		return nil, err
 	}
 
-	return worker, nil
+	defer rows.Close()
+
+	var workers []*FreeWorkerEntity
+
+	for rows.Next() {
+		var worker = &FreeWorkerEntity{}
+
+		err := rows.Scan(&worker.Id, &worker.Url, &worker.Executors, &worker.Capabilities, &worker.AdminLabels)
+		if err != nil {
+			return nil, err
+		}
+
+		workers = append(workers, worker)
+	}
+
+	return workers, rows.Err()
+}
+
+func (w *workersRepository) conn(ctx context.Context) postgres.Conn {
+	return postgres.ConnFrom(ctx, w.db)
 }
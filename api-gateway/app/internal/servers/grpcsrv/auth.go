@@ -0,0 +1,114 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/roles"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/task_owners"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/servicetoken"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationMetadataKey carries "Bearer <token>" with an access token of a user, a worker credential or the worker token,
+// or "ApiKey <key>" with an api key of a user
+const authorizationMetadataKey = "authorization"
+
+// methodRoles lists roles allowed to call every method. Methods which are not listed are denied
+var methodRoles = map[string][]roles.Role{
+	"/orchestrator.v1.Orchestrator/RegisterWorker": {roles.Worker},
+	"/orchestrator.v1.Orchestrator/StartTask":      {roles.Worker},
+	"/orchestrator.v1.Orchestrator/SendTaskResult": {roles.Worker},
+
+	sendSubtreeResultMethod: {roles.Worker},
+	acquireTasksMethod:      {roles.Worker},
+
+	workersAdminMethodName("Cordon"):       {roles.Admin},
+	workersAdminMethodName("Uncordon"):     {roles.Admin},
+	workersAdminMethodName("Drain"):        {roles.Admin},
+	workersAdminMethodName("SetExecutors"): {roles.Admin},
+	workersAdminMethodName("SetLabels"):    {roles.Admin},
+	workersAdminMethodName("Evict"):        {roles.Admin},
+	workersAdminMethodName("Release"):      {roles.Admin},
+}
+
+// NewAuthInterceptor authenticates callers of every unary method and checks their roles with methodRoles.
+// Every method changes data, so api keys must have roles.ScopeAll.
+// The caller is stored in the context with roles.WithPrincipal and its address with audit_log.WithIP
+func NewAuthInterceptor(authorizer roles.Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		allowed, ok := methodRoles[info.FullMethod]
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, roles.ErrForbidden.Error())
+		}
+
+		// The auth service limits failed verifications by the address of the caller
+		ctx = servicetoken.WithClientIP(ctx, peerIP(ctx))
+
+		value, _ := metadataValue(ctx, authorizationMetadataKey)
+
+		var (
+			principal *roles.Principal
+			err       error
+		)
+
+		if key, ok := strings.CutPrefix(value, "ApiKey "); ok && key != "" {
+			principal, err = authorizer.AuthenticateAPIKey(ctx, key)
+		} else if token, ok := strings.CutPrefix(value, "Bearer "); ok && token != "" {
+			principal, err = authorizer.Authenticate(ctx, token)
+		} else {
+			return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata")
+		}
+
+		if errors.Is(err, roles.ErrUnauthenticated) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+
+		err = authorizer.Authorize(principal, allowed...)
+		if err == nil {
+			err = authorizer.AuthorizeScope(principal, roles.ActionWrite)
+		}
+
+		if errors.Is(err, roles.ErrForbidden) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return handler(audit_log.WithIP(roles.WithPrincipal(ctx, principal), peerIP(ctx)), req)
+	}
+}
+
+// checkTaskOwner allows workers to start and finish only the tasks assigned to them.
+// The worker is identified by the id and secret issued on registration
+func (s *Server) checkTaskOwner(ctx context.Context, taskIds ...int) error {
+	err := s.taskOwners.Check(ctx, workerId(ctx), workerSecret(ctx), taskIds...)
+	if errors.Is(err, workers_storage.ErrWorkerUnauthorized) {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if errors.Is(err, task_owners.ErrNotTaskOwner) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if errors.Is(err, task_owners.ErrTaskNotInProgress) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return nil
+}
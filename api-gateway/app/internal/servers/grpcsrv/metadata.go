@@ -0,0 +1,136 @@
+package grpcsrv
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"time"
+
+	orchestrator "github.com/AleksandrVishniakov/dc-protos/gen/go/orchestrator/v1"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/capabilities"
+	"github.com/AleksandrVishniakov/distributed-calculator/protocol/taskmd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// capabilitiesMetadataKey carries JSON encoded capabilities.Capabilities of a registering daemon
+	capabilitiesMetadataKey = "x-worker-capabilities"
+
+	// workerIdMetadataKey and workerSecretMetadataKey carry the identity issued to a daemon.
+	// The daemon sends them back with every following call
+	workerIdMetadataKey     = "x-worker-id"
+	workerSecretMetadataKey = "x-worker-secret"
+
+	// workerModeMetadataKey carries pullWorkerMode for daemons which acquire tasks by themselves
+	workerModeMetadataKey = "x-worker-mode"
+	pullWorkerMode        = "pull"
+)
+
+func computeTime(ctx context.Context) time.Duration {
+	value, ok := metadataValue(ctx, taskmd.ComputeTime)
+	if !ok {
+		return 0
+	}
+
+	us, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || us < 0 {
+		return 0
+	}
+
+	return time.Duration(us) * time.Microsecond
+}
+
+// taskResult returns the float64 result sent in metadata or the float32 result of the request without it
+func taskResult(ctx context.Context, request *orchestrator.TaskResultRequest) float64 {
+	value, ok := metadataValue(ctx, taskmd.Result)
+	if !ok {
+		return float64(request.GetResult())
+	}
+
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return float64(request.GetResult())
+	}
+
+	return result
+}
+
+func workerCapabilities(ctx context.Context) (*capabilities.Capabilities, error) {
+	value, ok := metadataValue(ctx, capabilitiesMetadataKey)
+	if !ok {
+		return nil, nil
+	}
+
+	var workerCapabilities = &capabilities.Capabilities{}
+
+	err := json.Unmarshal([]byte(value), workerCapabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	return workerCapabilities, nil
+}
+
+func workerId(ctx context.Context) int {
+	value, _ := metadataValue(ctx, workerIdMetadataKey)
+
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
+
+func workerSecret(ctx context.Context) string {
+	value, _ := metadataValue(ctx, workerSecretMetadataKey)
+	return value
+}
+
+func workerMode(ctx context.Context) string {
+	value, _ := metadataValue(ctx, workerModeMetadataKey)
+	return value
+}
+
+func sendWorkerIdentity(ctx context.Context, registration *dto.WorkerRegistrationDTO) error {
+	md := metadata.Pairs(workerIdMetadataKey, strconv.Itoa(registration.Id))
+
+	if registration.Secret != "" {
+		md.Append(workerSecretMetadataKey, registration.Secret)
+	}
+
+	return grpc.SetHeader(ctx, md)
+}
+
+func metadataValue(ctx context.Context, key string) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return "", false
+	}
+
+	return values[0], true
+}
+
+// peerIP returns the host of the address of the caller or an empty string, if it is unknown
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
@@ -2,11 +2,18 @@ package grpcsrv
 
 import (
 	"context"
+	"errors"
 	orchestrator "github.com/AleksandrVishniakov/dc-protos/gen/go/orchestrator/v1"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expressions_storage"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/operators_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/quarantine"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/task_owners"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/task_queue"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_admin"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_api"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/util/calc"
@@ -15,52 +22,102 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-const tempUserID = 1
-
 type Server struct {
 	orchestrator.UnimplementedOrchestratorServer
 
+	transactor        postgres.Transactor
 	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
 	operatorsStorage  operators_storage.OperatorsStorage
 	workersStorage    workers_storage.WorkerStorage
 	expressionStorage expressions_storage.ExpressionStorage
 
-	workerAPI worker_api.WorkerAPI
+	workerAPI     worker_api.WorkerAPI
+	subtreeLimits calc.SubtreeLimits
+	monitor       quarantine.Monitor
+	taskOwners    task_owners.Checker
+	auditLog      audit_log.AuditLog
 }
 
 func Register(
 	gRPCServer *grpc.Server,
 
+	transactor postgres.Transactor,
 	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
 	operatorsStorage operators_storage.OperatorsStorage,
 	workersStorage workers_storage.WorkerStorage,
 	expressionStorage expressions_storage.ExpressionStorage,
 
 	workerAPI worker_api.WorkerAPI,
+	subtreeLimits calc.SubtreeLimits,
+	taskQueue task_queue.TaskQueue,
+	monitor quarantine.Monitor,
+	workerAdmin worker_admin.WorkerAdmin,
+	taskOwners task_owners.Checker,
+	auditLog audit_log.AuditLog,
 ) {
-	orchestrator.RegisterOrchestratorServer(gRPCServer, &Server{
+	server := &Server{
+		transactor:        transactor,
 		binaryTreeStorage: binaryTreeStorage,
 		operatorsStorage:  operatorsStorage,
 		workersStorage:    workersStorage,
 		expressionStorage: expressionStorage,
 		workerAPI:         workerAPI,
+		subtreeLimits:     subtreeLimits,
+		monitor:           monitor,
+		taskOwners:        taskOwners,
+		auditLog:          auditLog,
+	}
+
+	orchestrator.RegisterOrchestratorServer(gRPCServer, server)
+	gRPCServer.RegisterService(&subtreesServiceDesc, server)
+
+	gRPCServer.RegisterService(&taskQueueServiceDesc, &taskQueueServer{
+		workersStorage: workersStorage,
+		taskQueue:      taskQueue,
+	})
+
+	gRPCServer.RegisterService(&workersAdminServiceDesc, &workersAdminServer{
+		server:      server,
+		workerAdmin: workerAdmin,
+		monitor:     monitor,
 	})
 }
 
 func (s *Server) RegisterWorker(ctx context.Context, request *orchestrator.WorkerRegisterRequest) (*orchestrator.WorkerRegisterResponse, error) {
-	exists, err := s.workersStorage.Register(&dto.WorkerRequestDTO{
-		Id:        request.Id,
-		Url:       request.Url,
-		Executors: int(request.Executors),
-	})
+	workerCapabilities, err := workerCapabilities(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var worker = &dto.WorkerRequestDTO{
+		Id:           request.Id,
+		Secret:       workerSecret(ctx),
+		Url:          request.Url,
+		Executors:    int(request.Executors),
+		Capabilities: workerCapabilities,
+		Pull:         workerMode(ctx) == pullWorkerMode,
+	}
+
+	registration, err := s.workersStorage.Register(ctx, worker)
+	if errors.Is(err, workers_storage.ErrWorkerConflict) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	err = sendWorkerIdentity(ctx, registration)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	if exists {
+	if registration.Exists {
 		return &orchestrator.WorkerRegisterResponse{Ok: true}, nil
 	}
 
+	audit_log.RecordQuietly(ctx, s.auditLog, audit_log.WorkerRegistration(registration.Id, worker))
+
 	err = calc.CalculateAll(
 		ctx,
 		s.binaryTreeStorage,
@@ -68,6 +125,7 @@ func (s *Server) RegisterWorker(ctx context.Context, request *orchestrator.Worke
 		s.workersStorage,
 		s.expressionStorage,
 		s.workerAPI,
+		s.subtreeLimits,
 	)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
@@ -76,20 +134,25 @@ func (s *Server) RegisterWorker(ctx context.Context, request *orchestrator.Worke
 	return &orchestrator.WorkerRegisterResponse{Ok: true}, nil
 }
 
-func (s *Server) StartTask(_ context.Context, request *orchestrator.TaskStartingRequest) (*orchestrator.TaskStartingResponse, error) {
+func (s *Server) StartTask(ctx context.Context, request *orchestrator.TaskStartingRequest) (*orchestrator.TaskStartingResponse, error) {
 	var id = int(request.GetId())
 
-	err := s.binaryTreeStorage.MarkAsCalculating(id)
+	err := s.checkTaskOwner(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.binaryTreeStorage.MarkAsCalculating(ctx, id)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	node, err := s.binaryTreeStorage.FindById(id)
+	node, err := s.binaryTreeStorage.FindById(ctx, id)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	err = s.expressionStorage.MarkAsCalculating(node.ExpressionId)
+	err = s.expressionStorage.MarkAsCalculating(ctx, node.ExpressionId)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -100,18 +163,37 @@ func (s *Server) StartTask(_ context.Context, request *orchestrator.TaskStarting
 func (s *Server) SendTaskResult(ctx context.Context, request *orchestrator.TaskResultRequest) (*orchestrator.TaskResultResponse, error) {
 	var id = int(request.GetId())
 
-	err := s.binaryTreeStorage.SaveResult(id, float64(request.GetResult()))
+	err := s.checkTaskOwner(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var reported = taskResult(ctx, request)
+
+	result, err := s.monitor.CheckResult(ctx, id, reported)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	audit_log.RecordQuietly(ctx, s.auditLog, audit_log.TaskResult(workerId(ctx), id, reported, result))
+
+	err = s.workersStorage.RecordLatency(ctx, id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	err = s.binaryTreeStorage.SaveResult(ctx, id, result, computeTime(ctx))
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	node, err := s.binaryTreeStorage.FindById(id)
+	node, err := s.binaryTreeStorage.FindById(ctx, id)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	if node.ParentId == -1 {
-		err = s.expressionStorage.SaveResult(node.ExpressionId, float64(request.GetResult()))
+		err = s.expressionStorage.SaveResult(ctx, node.ExpressionId, result)
 		if err != nil {
 			return nil, status.Error(codes.Internal, err.Error())
 		}
@@ -126,6 +208,7 @@ func (s *Server) SendTaskResult(ctx context.Context, request *orchestrator.TaskR
 		s.workersStorage,
 		s.expressionStorage,
 		s.workerAPI,
+		s.subtreeLimits,
 	)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
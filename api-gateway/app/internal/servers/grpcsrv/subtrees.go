@@ -0,0 +1,180 @@
+package grpcsrv
+
+import (
+	"context"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/util/calc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	subtreesServiceName     = "orchestrator.v1.Subtrees"
+	sendSubtreeResultMethod = "/" + subtreesServiceName + "/SendSubtreeResult"
+)
+
+type NodeResult struct {
+	Id            int     `json:"id"`
+	Result        float64 `json:"result"`
+	ComputeTimeUS int64   `json:"computeTimeUS"`
+}
+
+// SubtreeResultRequest carries results of all operations of a sub-tree calculated by a single worker.
+// Results are ordered from leaves to the root. If some operation has failed, FailedId is its id
+// and results contain only the operations calculated before it. Workers report failed single tasks
+// as sub-trees of one operation
+type SubtreeResultRequest struct {
+	RootId   int           `json:"rootId"`
+	Results  []*NodeResult `json:"results"`
+	FailedId int           `json:"failedId,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type SubtreeResultResponse struct {
+	Ok bool `json:"ok"`
+}
+
+type SubtreesServer interface {
+	SendSubtreeResult(ctx context.Context, request *SubtreeResultRequest) (*SubtreeResultResponse, error)
+}
+
+var subtreesServiceDesc = grpc.ServiceDesc{
+	ServiceName: subtreesServiceName,
+	HandlerType: (*SubtreesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SendSubtreeResult",
+			Handler:    sendSubtreeResultHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "subtrees.go",
+}
+
+func sendSubtreeResultHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var request = &SubtreeResultRequest{}
+
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(SubtreesServer).SendSubtreeResult(ctx, request)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: sendSubtreeResultMethod,
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(SubtreesServer).SendSubtreeResult(ctx, req.(*SubtreeResultRequest))
+	}
+
+	return interceptor(ctx, request, info, handler)
+}
+
+// SendSubtreeResult saves intermediate results of a sub-tree, so the expression tree stays complete.
+// Latencies are not recorded, because sub-tree nodes are calculated together without separate start times
+func (s *Server) SendSubtreeResult(ctx context.Context, request *SubtreeResultRequest) (*SubtreeResultResponse, error) {
+	var ids = []int{request.RootId}
+	for _, result := range request.Results {
+		ids = append(ids, result.Id)
+	}
+
+	if request.FailedId != 0 {
+		ids = append(ids, request.FailedId)
+	}
+
+	err := s.checkTaskOwner(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		entries  []*audit_log.Entry
+		finished bool
+	)
+
+	// a sub-tree is saved completely or not at all, so a retried request never finds it half-saved
+	err = s.transactor.InTx(ctx, func(ctx context.Context) error {
+		for _, result := range request.Results {
+			value, err := s.monitor.CheckResult(ctx, result.Id, result.Result)
+			if err != nil {
+				return err
+			}
+
+			entries = append(entries, audit_log.TaskResult(workerId(ctx), result.Id, result.Result, value))
+
+			err = s.binaryTreeStorage.SaveResult(
+				ctx,
+				result.Id,
+				value,
+				time.Duration(result.ComputeTimeUS)*time.Microsecond,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if request.FailedId != 0 {
+			node, err := s.binaryTreeStorage.FindById(ctx, request.FailedId)
+			if err != nil {
+				return err
+			}
+
+			err = s.binaryTreeStorage.MarkAsFailed(ctx, node.Id, request.Error)
+			if err != nil {
+				return err
+			}
+
+			entries = append(entries, audit_log.TaskFailure(workerId(ctx), node.Id, request.Error))
+			finished = true
+
+			return s.expressionStorage.MarkAsFailed(ctx, node.ExpressionId)
+		}
+
+		root, err := s.binaryTreeStorage.FindById(ctx, request.RootId)
+		if err != nil {
+			return err
+		}
+
+		if root.ParentId != -1 {
+			return nil
+		}
+
+		finished = true
+
+		return s.expressionStorage.SaveResult(ctx, root.ExpressionId, root.Result)
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	// entries are recorded after the commit, so a failed entry never rolls the results back
+	for _, entry := range entries {
+		audit_log.RecordQuietly(ctx, s.auditLog, entry)
+	}
+
+	if finished {
+		return &SubtreeResultResponse{Ok: true}, nil
+	}
+
+	err = calc.CalculateAll(
+		ctx,
+		s.binaryTreeStorage,
+		s.operatorsStorage,
+		s.workersStorage,
+		s.expressionStorage,
+		s.workerAPI,
+		s.subtreeLimits,
+	)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &SubtreeResultResponse{Ok: true}, nil
+}
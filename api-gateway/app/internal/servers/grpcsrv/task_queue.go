@@ -0,0 +1,95 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/task_queue"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+	_ "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jsoncodec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	taskQueueServiceName = "orchestrator.v1.TaskQueue"
+	acquireTasksMethod   = "/" + taskQueueServiceName + "/AcquireTasks"
+)
+
+type AcquireTasksRequest struct {
+	WorkerId int `json:"workerId"`
+	Max      int `json:"max"`
+	WaitMS   int `json:"waitMS"`
+}
+
+type AcquireTasksResponse struct {
+	Tasks []*dto.LeasedTaskDTO `json:"tasks"`
+}
+
+type TaskQueueServer interface {
+	AcquireTasks(ctx context.Context, request *AcquireTasksRequest) (*AcquireTasksResponse, error)
+}
+
+var taskQueueServiceDesc = grpc.ServiceDesc{
+	ServiceName: taskQueueServiceName,
+	HandlerType: (*TaskQueueServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AcquireTasks",
+			Handler:    acquireTasksHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "task_queue.go",
+}
+
+func acquireTasksHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var request = &AcquireTasksRequest{}
+
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(TaskQueueServer).AcquireTasks(ctx, request)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: acquireTasksMethod,
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(TaskQueueServer).AcquireTasks(ctx, req.(*AcquireTasksRequest))
+	}
+
+	return interceptor(ctx, request, info, handler)
+}
+
+type taskQueueServer struct {
+	workersStorage workers_storage.WorkerStorage
+	taskQueue      task_queue.TaskQueue
+}
+
+// AcquireTasks leases ready tasks to a pull worker.
+// The worker is authenticated with the secret issued on registration
+func (s *taskQueueServer) AcquireTasks(ctx context.Context, request *AcquireTasksRequest) (*AcquireTasksResponse, error) {
+	worker, err := s.workersStorage.Authenticate(ctx, request.WorkerId, workerSecret(ctx))
+	if errors.Is(err, workers_storage.ErrWorkerUnauthorized) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	tasks, err := s.taskQueue.Acquire(ctx, worker, request.Max, time.Duration(request.WaitMS)*time.Millisecond)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &AcquireTasksResponse{Tasks: tasks}, nil
+}
@@ -0,0 +1,214 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/quarantine"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/roles"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_admin"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+	_ "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jsoncodec"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/util/calc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Callers of the workers admin service are authenticated with the same access token as the HTTP API
+const workersAdminServiceName = "orchestrator.v1.WorkersAdmin"
+
+type WorkerAdminRequest struct {
+	WorkerId  int               `json:"workerId"`
+	Executors *int              `json:"executors,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type WorkerAdminResponse struct {
+	Worker *dto.WorkerResponseDTO `json:"worker"`
+}
+
+type WorkersAdminServer interface {
+	Cordon(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error)
+	Uncordon(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error)
+	Drain(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error)
+	SetExecutors(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error)
+	SetLabels(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error)
+	Evict(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error)
+	Release(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error)
+}
+
+var workersAdminServiceDesc = grpc.ServiceDesc{
+	ServiceName: workersAdminServiceName,
+	HandlerType: (*WorkersAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		workersAdminMethod("Cordon", WorkersAdminServer.Cordon),
+		workersAdminMethod("Uncordon", WorkersAdminServer.Uncordon),
+		workersAdminMethod("Drain", WorkersAdminServer.Drain),
+		workersAdminMethod("SetExecutors", WorkersAdminServer.SetExecutors),
+		workersAdminMethod("SetLabels", WorkersAdminServer.SetLabels),
+		workersAdminMethod("Evict", WorkersAdminServer.Evict),
+		workersAdminMethod("Release", WorkersAdminServer.Release),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "workers_admin.go",
+}
+
+// workersAdminMethod describes a method of the workers admin service. All of them share the request type
+func workersAdminMethod(
+	name string,
+	call func(srv WorkersAdminServer, ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error),
+) grpc.MethodDesc {
+	var fullMethod = workersAdminMethodName(name)
+
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			var request = &WorkerAdminRequest{}
+
+			if err := dec(request); err != nil {
+				return nil, err
+			}
+
+			if interceptor == nil {
+				return call(srv.(WorkersAdminServer), ctx, request)
+			}
+
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: fullMethod,
+			}
+
+			handler := func(ctx context.Context, req any) (any, error) {
+				return call(srv.(WorkersAdminServer), ctx, req.(*WorkerAdminRequest))
+			}
+
+			return interceptor(ctx, request, info, handler)
+		},
+	}
+}
+
+func workersAdminMethodName(name string) string {
+	return "/" + workersAdminServiceName + "/" + name
+}
+
+type workersAdminServer struct {
+	server *Server
+
+	workerAdmin worker_admin.WorkerAdmin
+	monitor     quarantine.Monitor
+}
+
+func (s *workersAdminServer) Cordon(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error) {
+	return s.administrate(ctx, request.WorkerId, false, s.workerAdmin.Cordon)
+}
+
+func (s *workersAdminServer) Uncordon(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error) {
+	return s.administrate(ctx, request.WorkerId, true, s.workerAdmin.Uncordon)
+}
+
+func (s *workersAdminServer) Drain(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error) {
+	return s.administrate(ctx, request.WorkerId, true, s.workerAdmin.Drain)
+}
+
+func (s *workersAdminServer) SetExecutors(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error) {
+	return s.administrate(ctx, request.WorkerId, true, func(ctx context.Context, admin string, workerId int) error {
+		return s.workerAdmin.SetExecutors(ctx, admin, workerId, request.Executors)
+	})
+}
+
+func (s *workersAdminServer) SetLabels(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error) {
+	return s.administrate(ctx, request.WorkerId, true, func(ctx context.Context, admin string, workerId int) error {
+		return s.workerAdmin.SetLabels(ctx, admin, workerId, request.Labels)
+	})
+}
+
+func (s *workersAdminServer) Evict(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error) {
+	return s.administrate(ctx, request.WorkerId, true, s.workerAdmin.Evict)
+}
+
+func (s *workersAdminServer) Release(ctx context.Context, request *WorkerAdminRequest) (*WorkerAdminResponse, error) {
+	return s.administrate(ctx, request.WorkerId, true, func(ctx context.Context, _ string, workerId int) error {
+		err := s.monitor.Release(ctx, workerId)
+		if err != nil {
+			return err
+		}
+
+		return s.server.auditLog.Record(ctx, &audit_log.Entry{
+			Action:     audit_log.WorkerReleased,
+			TargetType: audit_log.TargetWorker,
+			TargetId:   audit_log.TargetId(workerId),
+		})
+	})
+}
+
+// administrate authorizes the caller, applies operation to the worker in a transaction and returns its new state.
+// If reschedule is set, waiting tasks are distributed again
+func (s *workersAdminServer) administrate(
+	ctx context.Context,
+	workerId int,
+	reschedule bool,
+	operation func(ctx context.Context, admin string, workerId int) error,
+) (*WorkerAdminResponse, error) {
+	admin, err := s.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.server.transactor.InTx(ctx, func(ctx context.Context) error {
+		return operation(ctx, admin, workerId)
+	})
+	if errors.Is(err, workers_storage.ErrWorkerNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	if errors.Is(err, worker_admin.ErrInvalidExecutors) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if errors.Is(err, worker_admin.ErrInvalidState) || errors.Is(err, quarantine.ErrWorkerNotQuarantined) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if reschedule {
+		err = calc.CalculateAll(
+			ctx,
+			s.server.binaryTreeStorage,
+			s.server.operatorsStorage,
+			s.server.workersStorage,
+			s.server.expressionStorage,
+			s.server.workerAPI,
+			s.server.subtreeLimits,
+		)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	worker, err := s.server.workersStorage.FindById(ctx, workerId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &WorkerAdminResponse{Worker: worker}, nil
+}
+
+// authorize returns the login of the caller authenticated by the auth interceptor
+func (s *workersAdminServer) authorize(ctx context.Context) (string, error) {
+	principal, ok := roles.FromContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "caller is not authenticated")
+	}
+
+	if !principal.Role.Grants(roles.Admin) {
+		return "", status.Error(codes.PermissionDenied, roles.ErrForbidden.Error())
+	}
+
+	return principal.Login, nil
+}
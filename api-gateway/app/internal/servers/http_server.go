@@ -2,6 +2,7 @@ package servers
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 )
 
@@ -9,16 +10,22 @@ type HTTPServer struct {
 	httpServer *http.Server
 }
 
-func NewHTTPServer(port string, handler http.Handler) *HTTPServer {
+// NewHTTPServer creates a server. Nil tlsConfig means plain HTTP
+func NewHTTPServer(port string, handler http.Handler, tlsConfig *tls.Config) *HTTPServer {
 	return &HTTPServer{
 		httpServer: &http.Server{
-			Addr:    ":" + port,
-			Handler: handler,
+			Addr:      ":" + port,
+			Handler:   handler,
+			TLSConfig: tlsConfig,
 		},
 	}
 }
 
 func (s *HTTPServer) Run() error {
+	if s.httpServer.TLSConfig != nil {
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
+
 	return s.httpServer.ListenAndServe()
 }
 
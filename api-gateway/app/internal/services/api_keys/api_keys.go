@@ -0,0 +1,172 @@
+package api_keys
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jsoncodec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// KeyPrefix marks api keys issued to users by the auth service
+const KeyPrefix = "dck_"
+
+// authenticateMethod is served by the auth service
+const authenticateMethod = "/auth.v1.APIKeys/Authenticate"
+
+var ErrInvalidKey = errors.New("api_keys: invalid or revoked api key")
+
+// Key is an api key issued by the auth service
+type Key struct {
+	Id     uint64
+	UserId uint64
+	Login  string
+	// Scope is "all", "read" or "submit"
+	Scope string
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=Verifier --inpackage --testonly
+type Verifier interface {
+	// Verify returns the key. Returns ErrInvalidKey, if the key is unknown or revoked
+	Verify(ctx context.Context, key string) (*Key, error)
+}
+
+type authenticateRequestDTO struct {
+	Key string `json:"key"`
+}
+
+type authenticateResponseDTO struct {
+	KeyId  uint64 `json:"keyId"`
+	UserId uint64 `json:"userId"`
+	Login  string `json:"login"`
+	Scope  string `json:"scope"`
+}
+
+type gRPCVerifier struct {
+	host                 string
+	transportCredentials credentials.TransportCredentials
+	serviceCredentials   credentials.PerRPCCredentials
+}
+
+// NewGRPCVerifier checks keys with the auth service at host.
+// serviceCredentials authenticate the api-gateway, the method is internal
+func NewGRPCVerifier(
+	host string,
+	transportCredentials credentials.TransportCredentials,
+	serviceCredentials credentials.PerRPCCredentials,
+) Verifier {
+	return &gRPCVerifier{
+		host:                 host,
+		transportCredentials: transportCredentials,
+		serviceCredentials:   serviceCredentials,
+	}
+}
+
+func (g *gRPCVerifier) Verify(ctx context.Context, key string) (*Key, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	cc, err := grpc.DialContext(
+		ctx,
+		g.host,
+		grpc.WithTransportCredentials(g.transportCredentials),
+		grpc.WithPerRPCCredentials(g.serviceCredentials),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cc.Close()
+
+	var resp = &authenticateResponseDTO{}
+
+	err = cc.Invoke(ctx, authenticateMethod, &authenticateRequestDTO{
+		Key: key,
+	}, resp, grpc.CallContentSubtype(jsoncodec.Name))
+	if status.Code(err) == codes.Unauthenticated {
+		return nil, ErrInvalidKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		Id:     resp.KeyId,
+		UserId: resp.UserId,
+		Login:  resp.Login,
+		Scope:  resp.Scope,
+	}, nil
+}
+
+type cacheEntry struct {
+	key       *Key
+	expiresAt time.Time
+}
+
+type cachedVerifier struct {
+	mu       *sync.Mutex
+	verifier Verifier
+	ttl      time.Duration
+	now      func() time.Time
+
+	entries map[string]*cacheEntry
+}
+
+// NewCachedVerifier remembers results of verifier for ttl, so the auth service is not called on every request.
+// Invalid keys are not cached, and a revoked key is rejected at most ttl after its revocation.
+// The auth service records the last usage of a key only when it is verified there
+func NewCachedVerifier(verifier Verifier, ttl time.Duration) Verifier {
+	return &cachedVerifier{
+		mu:       &sync.Mutex{},
+		verifier: verifier,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  map[string]*cacheEntry{},
+	}
+}
+
+func (c *cachedVerifier) Verify(ctx context.Context, key string) (*Key, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.key, nil
+	}
+
+	verified, err := c.verifier.Verify(ctx, key)
+	if err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for cached, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, cached)
+		}
+	}
+
+	c.entries[key] = &cacheEntry{
+		key:       verified,
+		expiresAt: now.Add(c.ttl),
+	}
+
+	return verified, nil
+}
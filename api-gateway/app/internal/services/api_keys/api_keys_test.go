@@ -0,0 +1,74 @@
+package api_keys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const defaultKey = "dck_key"
+
+func TestCachedVerifier(t *testing.T) {
+	var key = &Key{Id: 1, UserId: 1, Login: "user", Scope: "read"}
+
+	tests := []struct {
+		name    string
+		after   time.Duration
+		prepare func(verifier *MockVerifier)
+
+		targetErr error
+	}{
+		{
+			name:    "cached",
+			after:   time.Second,
+			prepare: func(verifier *MockVerifier) {},
+		},
+		{
+			name:  "expired",
+			after: time.Minute,
+			prepare: func(verifier *MockVerifier) {
+				verifier.On("Verify", mock.Anything, defaultKey).Once().Return(key, nil)
+			},
+		},
+		{
+			name:  "err_revoked_after_expiration",
+			after: time.Minute,
+			prepare: func(verifier *MockVerifier) {
+				verifier.On("Verify", mock.Anything, defaultKey).Once().Return(nil, ErrInvalidKey)
+			},
+			targetErr: ErrInvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				now      = time.Now()
+				verifier = NewMockVerifier(t)
+				cached   = NewCachedVerifier(verifier, 30*time.Second).(*cachedVerifier)
+			)
+
+			cached.now = func() time.Time { return now }
+
+			verifier.On("Verify", mock.Anything, defaultKey).Once().Return(key, nil)
+
+			_, err := cached.Verify(context.Background(), defaultKey)
+			require.NoError(t, err)
+
+			now = now.Add(tt.after)
+			tt.prepare(verifier)
+
+			_, err = cached.Verify(context.Background(), defaultKey)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
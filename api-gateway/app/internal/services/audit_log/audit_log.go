@@ -0,0 +1,299 @@
+package audit_log
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/audit_log_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/capabilities"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/roles"
+)
+
+// Types of actors
+const (
+	ActorUser   = "user"
+	ActorWorker = "worker"
+)
+
+// Audited actions
+const (
+	OperatorsSaved             = "operators.saved"
+	OrganizationOperatorsSaved = "organization.operators_saved"
+	OrganizationQuotaSaved     = "organization.quota_saved"
+	WorkerRegistered           = "worker.registered"
+	WorkerReleased             = "worker.released"
+	TaskResultReported         = "task.result_reported"
+	TaskFailureReported        = "task.failure_reported"
+)
+
+// Types of targets
+const (
+	TargetOperators    = "operators"
+	TargetOrganization = "organization"
+	TargetWorker       = "worker"
+	TargetTask         = "task"
+)
+
+const (
+	defaultPageSize = 50
+	exportBatchSize = 500
+)
+
+// Actor is who performed an action. Id is the user id for users and the worker id for workers.
+// Workers authenticated only with their token have zero id
+type Actor struct {
+	Type string
+	Id   uint64
+	Name string
+}
+
+// WorkerActor is a worker identified by the id issued on registration
+func WorkerActor(workerId int) *Actor {
+	return &Actor{Type: ActorWorker, Id: uint64(workerId)}
+}
+
+// Entry is an action to record. Before and After are encoded as JSON, nil means no state
+type Entry struct {
+	Action string
+	// Actor is the caller stored in the context with roles.WithPrincipal, if nil
+	Actor      *Actor
+	TargetType string
+	TargetId   string
+	Before     any
+	After      any
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=AuditLog
+
+// AuditLog is an append-only record of security relevant and administrative actions
+type AuditLog interface {
+	// Record appends the entry with the address of the caller stored in the context with WithIP.
+	// In a transaction the entry is appended only if the transaction is committed
+	Record(ctx context.Context, entry *Entry) error
+	// Find returns a page of records matching the query, the newest first
+	Find(ctx context.Context, query *dto.AuditQueryDTO) (*dto.AuditPageDTO, error)
+	// Export writes all records matching the query to w as JSON lines, the oldest first
+	Export(ctx context.Context, query *dto.AuditQueryDTO, w io.Writer) error
+}
+
+type auditLog struct {
+	repository audit_log_repository.AuditLogRepository
+}
+
+func NewAuditLog(repository audit_log_repository.AuditLogRepository) AuditLog {
+	return &auditLog{repository: repository}
+}
+
+func (a *auditLog) Record(ctx context.Context, entry *Entry) error {
+	before, err := state(entry.Before)
+	if err != nil {
+		return err
+	}
+
+	after, err := state(entry.After)
+	if err != nil {
+		return err
+	}
+
+	var actor = entry.Actor
+	if actor == nil {
+		actor = actorFrom(ctx)
+	}
+
+	return a.repository.Create(ctx, &audit_log_repository.AuditEntity{
+		ActorType:  actor.Type,
+		ActorId:    actor.Id,
+		ActorName:  actor.Name,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetId:   entry.TargetId,
+		Before:     before,
+		After:      after,
+		IP:         IPFrom(ctx),
+	})
+}
+
+func (a *auditLog) Find(ctx context.Context, query *dto.AuditQueryDTO) (*dto.AuditPageDTO, error) {
+	var filter = newFilter(query)
+	filter.Descending = true
+	filter.AfterId = query.Cursor
+
+	filter.Limit = query.Limit
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
+	}
+
+	// one more record tells whether there is a next page
+	filter.Limit++
+
+	entities, err := a.repository.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var page = &dto.AuditPageDTO{Entries: make([]*dto.AuditEntryDTO, 0, len(entities))}
+
+	if len(entities) == filter.Limit {
+		entities = entities[:len(entities)-1]
+		page.NextCursor = entities[len(entities)-1].Id
+	}
+
+	for _, entity := range entities {
+		page.Entries = append(page.Entries, dto.MapAuditEntryFromEntity(entity))
+	}
+
+	return page, nil
+}
+
+func (a *auditLog) Export(ctx context.Context, query *dto.AuditQueryDTO, w io.Writer) error {
+	var (
+		filter  = newFilter(query)
+		encoder = json.NewEncoder(w)
+	)
+
+	filter.Limit = exportBatchSize
+
+	for {
+		entities, err := a.repository.Find(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, entity := range entities {
+			err = encoder.Encode(dto.MapAuditEntryFromEntity(entity))
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(entities) < filter.Limit {
+			return nil
+		}
+
+		filter.AfterId = entities[len(entities)-1].Id
+	}
+}
+
+// RecordQuietly records the entry and only logs errors. It is used for actions of workers,
+// which are already applied and must not be reported as failed because of the audit log
+func RecordQuietly(ctx context.Context, auditLog AuditLog, entry *Entry) {
+	err := auditLog.Record(ctx, entry)
+	if err != nil {
+		log.Printf("audit log error: %s", err.Error())
+	}
+}
+
+// registeredWorker is the audited state of a registered worker. The secret of the worker is never recorded
+type registeredWorker struct {
+	Url          string                     `json:"url"`
+	Executors    int                        `json:"executors"`
+	Capabilities *capabilities.Capabilities `json:"capabilities,omitempty"`
+	Pull         bool                       `json:"pull"`
+}
+
+// WorkerRegistration is the entry of a new worker registered with the request
+func WorkerRegistration(workerId int, request *dto.WorkerRequestDTO) *Entry {
+	return &Entry{
+		Action:     WorkerRegistered,
+		Actor:      WorkerActor(workerId),
+		TargetType: TargetWorker,
+		TargetId:   TargetId(workerId),
+		After: &registeredWorker{
+			Url:          request.Url,
+			Executors:    request.Executors,
+			Capabilities: request.Capabilities,
+			Pull:         request.Pull,
+		},
+	}
+}
+
+// TaskResult is the entry of a result reported by the worker. The accepted result differs from the reported one,
+// if the result has been corrected by a cross-check
+func TaskResult(workerId int, taskId int, reported float64, accepted float64) *Entry {
+	return &Entry{
+		Action:     TaskResultReported,
+		Actor:      WorkerActor(workerId),
+		TargetType: TargetTask,
+		TargetId:   TargetId(taskId),
+		After:      map[string]float64{"reported": reported, "accepted": accepted},
+	}
+}
+
+// TaskFailure is the entry of a calculation error reported by the worker
+func TaskFailure(workerId int, taskId int, message string) *Entry {
+	return &Entry{
+		Action:     TaskFailureReported,
+		Actor:      WorkerActor(workerId),
+		TargetType: TargetTask,
+		TargetId:   TargetId(taskId),
+		After:      map[string]string{"error": message},
+	}
+}
+
+func newFilter(query *dto.AuditQueryDTO) *audit_log_repository.AuditFilter {
+	var filter = &audit_log_repository.AuditFilter{
+		ActorType:  query.ActorType,
+		Action:     query.Action,
+		TargetType: query.TargetType,
+		TargetId:   query.TargetId,
+	}
+
+	if query.ActorId != nil {
+		filter.ActorId = sql.NullInt64{Int64: int64(*query.ActorId), Valid: true}
+	}
+
+	if query.From != nil {
+		filter.From = sql.NullTime{Time: *query.From, Valid: true}
+	}
+
+	if query.To != nil {
+		filter.To = sql.NullTime{Time: *query.To, Valid: true}
+	}
+
+	return filter
+}
+
+// actorFrom returns the caller authenticated by the auth middleware or interceptor
+func actorFrom(ctx context.Context) *Actor {
+	principal, ok := roles.FromContext(ctx)
+	if !ok {
+		return &Actor{}
+	}
+
+	if principal.Role == roles.Worker {
+		return &Actor{Type: ActorWorker}
+	}
+
+	return &Actor{Type: ActorUser, Id: principal.UserID, Name: principal.Login}
+}
+
+func state(value any) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(value)
+}
+
+// TargetId formats numeric ids of targets
+func TargetId[T int | int64 | uint64](id T) string {
+	return strconv.FormatInt(int64(id), 10)
+}
+
+type ipKey struct{}
+
+// WithIP puts the address of the caller into the context
+func WithIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ipKey{}, ip)
+}
+
+// IPFrom returns the address of the caller or an empty string, if it is unknown
+func IPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(ipKey{}).(string)
+	return ip
+}
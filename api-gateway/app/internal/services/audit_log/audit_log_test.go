@@ -0,0 +1,223 @@
+package audit_log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/audit_log_repository"
+	auditMocks "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/audit_log_repository/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/roles"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// entities returns stored entries with the ids
+func entities(ids ...int64) []*audit_log_repository.AuditEntity {
+	var entities = make([]*audit_log_repository.AuditEntity, 0, len(ids))
+	for _, id := range ids {
+		entities = append(entities, &audit_log_repository.AuditEntity{Id: id, Action: TaskResultReported})
+	}
+
+	return entities
+}
+
+// sequence returns the ids from first to last
+func sequence(first, last int64) []int64 {
+	var ids = make([]int64, 0, last-first+1)
+	for id := first; id <= last; id++ {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func TestAuditLog_Record(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *roles.Principal
+		actor     *Actor
+
+		wantActor Actor
+	}{
+		{
+			name:      "user",
+			principal: &roles.Principal{UserID: 7, Login: "admin", Role: roles.Admin},
+			wantActor: Actor{Type: ActorUser, Id: 7, Name: "admin"},
+		},
+		{
+			name:      "worker_token",
+			principal: &roles.Principal{Role: roles.Worker, CredentialID: 3},
+			wantActor: Actor{Type: ActorWorker},
+		},
+		{
+			name:      "explicit_worker",
+			principal: &roles.Principal{Role: roles.Worker},
+			actor:     WorkerActor(5),
+			wantActor: Actor{Type: ActorWorker, Id: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := auditMocks.NewAuditLogRepository(t)
+			repository.
+				On("Create", mock.Anything, &audit_log_repository.AuditEntity{
+					ActorType:  tt.wantActor.Type,
+					ActorId:    tt.wantActor.Id,
+					ActorName:  tt.wantActor.Name,
+					Action:     OperatorsSaved,
+					TargetType: TargetOperators,
+					After:      []byte(`[{"operationType":0,"durationMS":100}]`),
+					IP:         "10.0.0.1",
+				}).
+				Once().
+				Return(nil)
+
+			ctx := WithIP(roles.WithPrincipal(context.Background(), tt.principal), "10.0.0.1")
+
+			err := NewAuditLog(repository).Record(ctx, &Entry{
+				Action:     OperatorsSaved,
+				Actor:      tt.actor,
+				TargetType: TargetOperators,
+				After:      []*dto.OperationDTO{{OperationType: 0, DurationMS: 100}},
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAuditLog_Find(t *testing.T) {
+	var workerId uint64 = 1
+
+	tests := []struct {
+		name  string
+		query *dto.AuditQueryDTO
+		// wantFilter is the filter passed to the repository
+		wantFilter *audit_log_repository.AuditFilter
+		stored     []*audit_log_repository.AuditEntity
+
+		wantErr        bool
+		wantIds        []int64
+		wantNextCursor int64
+	}{
+		{
+			name:       "all",
+			query:      &dto.AuditQueryDTO{},
+			wantFilter: &audit_log_repository.AuditFilter{Descending: true, Limit: defaultPageSize + 1},
+			stored:     entities(5, 4, 3, 2, 1),
+			wantIds:    []int64{5, 4, 3, 2, 1},
+		},
+		{
+			name:           "first_page",
+			query:          &dto.AuditQueryDTO{Limit: 2},
+			wantFilter:     &audit_log_repository.AuditFilter{Descending: true, Limit: 3},
+			stored:         entities(5, 4, 3),
+			wantIds:        []int64{5, 4},
+			wantNextCursor: 4,
+		},
+		{
+			name:       "last_page",
+			query:      &dto.AuditQueryDTO{Limit: 2, Cursor: 2},
+			wantFilter: &audit_log_repository.AuditFilter{Descending: true, Limit: 3, AfterId: 2},
+			stored:     entities(1),
+			wantIds:    []int64{1},
+		},
+		{
+			name:  "actor",
+			query: &dto.AuditQueryDTO{ActorType: ActorWorker, ActorId: &workerId},
+			wantFilter: &audit_log_repository.AuditFilter{
+				ActorType:  ActorWorker,
+				ActorId:    sql.NullInt64{Int64: 1, Valid: true},
+				Descending: true,
+				Limit:      defaultPageSize + 1,
+			},
+			stored:  entities(5, 3, 1),
+			wantIds: []int64{5, 3, 1},
+		},
+		{
+			name:       "empty",
+			query:      &dto.AuditQueryDTO{Action: WorkerRegistered},
+			wantFilter: &audit_log_repository.AuditFilter{Action: WorkerRegistered, Descending: true, Limit: defaultPageSize + 1},
+			stored:     entities(),
+			wantIds:    []int64{},
+		},
+		{
+			name:       "err_repository",
+			query:      &dto.AuditQueryDTO{},
+			wantFilter: &audit_log_repository.AuditFilter{Descending: true, Limit: defaultPageSize + 1},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := auditMocks.NewAuditLogRepository(t)
+
+			var err error
+			if tt.wantErr {
+				err = errors.New("unexpected error")
+			}
+
+			repository.On("Find", mock.Anything, tt.wantFilter).Once().Return(tt.stored, err)
+
+			page, err := NewAuditLog(repository).Find(context.Background(), tt.query)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			var ids = []int64{}
+			for _, entry := range page.Entries {
+				ids = append(ids, entry.Id)
+			}
+
+			assert.Equal(t, tt.wantIds, ids)
+			assert.Equal(t, tt.wantNextCursor, page.NextCursor)
+		})
+	}
+}
+
+func TestAuditLog_Export(t *testing.T) {
+	var (
+		repository = auditMocks.NewAuditLogRepository(t)
+		total      = int64(exportBatchSize + 1)
+	)
+
+	repository.
+		On("Find", mock.Anything, &audit_log_repository.AuditFilter{Action: TaskResultReported, Limit: exportBatchSize}).
+		Once().
+		Return(entities(sequence(1, exportBatchSize)...), nil)
+	repository.
+		On("Find", mock.Anything, &audit_log_repository.AuditFilter{Action: TaskResultReported, Limit: exportBatchSize, AfterId: exportBatchSize}).
+		Once().
+		Return(entities(total), nil)
+
+	var buffer = &bytes.Buffer{}
+
+	err := NewAuditLog(repository).Export(context.Background(), &dto.AuditQueryDTO{Action: TaskResultReported}, buffer)
+	require.NoError(t, err)
+
+	var (
+		scanner = bufio.NewScanner(buffer)
+		ids     []int64
+	)
+
+	for scanner.Scan() {
+		var entry = &dto.AuditEntryDTO{}
+
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), entry))
+		ids = append(ids, entry.Id)
+	}
+
+	assert.Equal(t, sequence(1, total), ids)
+}
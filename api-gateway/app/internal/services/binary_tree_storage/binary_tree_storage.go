@@ -1,40 +1,106 @@
 package binary_tree_storage
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/expr_tree_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/task_attempts_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/statuses"
+	"time"
 )
 
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=BinaryTreeStorage
 type BinaryTreeStorage interface {
-	SaveTree(root *binary_tree.Node, userID uint64, expressionId int, parentId int, isLeft bool) (int, error)
-	MarkAsCalculating(id int) error
-	MarkAsFailed(id int) error
-	SaveResult(id int, result float64) error
-	FindByParentId(parentId int) ([]*dto.ExpressionNodeDTO, error)
-	SaveWorker(id int, workerId int) error
-	FindById(id int) (*dto.ExpressionNodeDTO, error)
-	FindByWorkerId(id int) ([]*dto.TaskDTO, error)
-	DeleteWorkers(workerIds []int) error
-	DeleteAllWorkers() error
-	FindUncalculated() ([]int, error)
+	SaveTree(ctx context.Context, root *binary_tree.Node, userID uint64, expressionId int) (int, error)
+	MarkAsCalculating(ctx context.Context, id int) error
+	MarkAsFailed(ctx context.Context, id int, reason string) error
+	SaveResult(ctx context.Context, id int, result float64, computeTime time.Duration) error
+	FindByParentId(ctx context.Context, parentId int) ([]*dto.ExpressionNodeDTO, error)
+	SaveWorker(ctx context.Context, id int, workerId int) error
+	SaveDispatchFailure(ctx context.Context, id int, workerId int, reason string) error
+	FindById(ctx context.Context, id int) (*dto.ExpressionNodeDTO, error)
+	FindByExpressionId(ctx context.Context, expressionId int) ([]*dto.ExpressionNodeDTO, error)
+	CountQueued(ctx context.Context) (int, error)
+	FindByWorkerId(ctx context.Context, id int) ([]*dto.TaskDTO, error)
+	DeleteWorkers(ctx context.Context, workerIds []int) error
+	DeleteAllWorkers(ctx context.Context) error
+	FindUncalculated(ctx context.Context) ([]int, error)
+	FindReady(ctx context.Context, limit int) ([]*dto.ReadyTaskDTO, error)
+	Lease(ctx context.Context, id int, workerId int, until time.Time) (bool, error)
+	ReleaseExpiredLeases(ctx context.Context, now time.Time) ([]int, error)
+	FindAttempts(ctx context.Context, expressionId int) ([]*dto.TaskAttemptDTO, error)
+	DeleteByExpressionIds(ctx context.Context, expressionIds []int) error
 }
 
+// binaryTreeStorage records every dispatch of a node to a worker as a task attempt,
+// so the history survives reassignments. Nodes and their attempts are changed in the same transaction
 type binaryTreeStorage struct {
-	repository expr_tree_repository.ExpressionsTreeRepository
+	transactor         postgres.Transactor
+	repository         expr_tree_repository.ExpressionsTreeRepository
+	attemptsRepository task_attempts_repository.TaskAttemptsRepository
 }
 
-func NewBinaryTreeStorage(repository expr_tree_repository.ExpressionsTreeRepository) BinaryTreeStorage {
-	return &binaryTreeStorage{repository: repository}
+func NewBinaryTreeStorage(
+	transactor postgres.Transactor,
+	repository expr_tree_repository.ExpressionsTreeRepository,
+	attemptsRepository task_attempts_repository.TaskAttemptsRepository,
+) BinaryTreeStorage {
+	return &binaryTreeStorage{
+		transactor:         transactor,
+		repository:         repository,
+		attemptsRepository: attemptsRepository,
+	}
 }
 
-func (b *binaryTreeStorage) SaveTree(node *binary_tree.Node, userID uint64, expressionId int, parentId int, isLeft bool) (int, error) {
-	if node == nil {
+// SaveTree inserts the whole tree at once and returns the id of its root. Either all nodes are saved or none of them
+func (b *binaryTreeStorage) SaveTree(ctx context.Context, root *binary_tree.Node, userID uint64, expressionId int) (int, error) {
+	var entities []*expr_tree_repository.ExpressionTreeNodeEntity
+	flatten(root, -1, true, userID, expressionId, &entities)
+
+	if len(entities) == 0 {
 		return 0, nil
 	}
 
+	ids, err := b.repository.ReserveIds(ctx, len(entities))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, entity := range entities {
+		entity.Id = ids[i]
+
+		// parents precede their children, ParentId holds the index of the parent until ids are known
+		if entity.ParentId != -1 {
+			entity.ParentId = ids[entity.ParentId]
+		}
+	}
+
+	err = b.repository.CreateAll(ctx, entities)
+	if err != nil {
+		return 0, err
+	}
+
+	return entities[0].Id, nil
+}
+
+// flatten appends nodes of the tree to entities in preorder
+func flatten(
+	node *binary_tree.Node,
+	parent int,
+	isLeft bool,
+	userID uint64,
+	expressionId int,
+	entities *[]*expr_tree_repository.ExpressionTreeNodeEntity,
+) {
+	if node == nil {
+		return
+	}
+
 	var status int
 	switch node.Value.Type() {
 	case expr_tokens.BinaryOperation:
@@ -60,9 +126,11 @@ func (b *binaryTreeStorage) SaveTree(node *binary_tree.Node, userID uint64, expr
 		operationType = int(node.Value.(*expr_tokens.BinaryOperationToken).Operation)
 	}
 
-	id, err := b.repository.Create(&expr_tree_repository.ExpressionTreeNodeEntity{
+	var index = len(*entities)
+
+	*entities = append(*entities, &expr_tree_repository.ExpressionTreeNodeEntity{
 		UserID:        userID,
-		ParentId:      parentId,
+		ParentId:      parent,
 		ExpressionId:  expressionId,
 		Type:          taskType,
 		OperationType: operationType,
@@ -70,37 +138,45 @@ func (b *binaryTreeStorage) SaveTree(node *binary_tree.Node, userID uint64, expr
 		Result:        result,
 	})
 
-	if err != nil {
-		return 0, err
-	}
-
-	_, err = b.SaveTree(node.Left, userID, expressionId, id, true)
-	if err != nil {
-		return 0, err
-	}
+	flatten(node.Left, index, true, userID, expressionId, entities)
+	flatten(node.Right, index, false, userID, expressionId, entities)
+}
 
-	_, err = b.SaveTree(node.Right, userID, expressionId, id, false)
-	if err != nil {
-		return 0, err
-	}
+func (b *binaryTreeStorage) MarkAsCalculating(ctx context.Context, id int) error {
+	return b.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := b.repository.Start(ctx, id, int(statuses.Calculating))
+		if err != nil {
+			return err
+		}
 
-	return id, nil
+		return b.attemptsRepository.Start(ctx, id)
+	})
 }
 
-func (b *binaryTreeStorage) MarkAsCalculating(id int) error {
-	return b.repository.SetStatus(id, int(statuses.Calculating))
-}
+func (b *binaryTreeStorage) MarkAsFailed(ctx context.Context, id int, reason string) error {
+	return b.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := b.repository.SetStatus(ctx, id, int(statuses.Failed))
+		if err != nil {
+			return err
+		}
 
-func (b *binaryTreeStorage) MarkAsFailed(id int) error {
-	return b.repository.SetStatus(id, int(statuses.Failed))
+		return b.attemptsRepository.Finish(ctx, id, task_attempts_repository.Failed, reason)
+	})
 }
 
-func (b *binaryTreeStorage) SaveResult(id int, result float64) error {
-	return b.repository.SaveResult(id, result, int(statuses.Finished))
+func (b *binaryTreeStorage) SaveResult(ctx context.Context, id int, result float64, computeTime time.Duration) error {
+	return b.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := b.repository.SaveResult(ctx, id, result, int(statuses.Finished), computeTime.Microseconds())
+		if err != nil {
+			return err
+		}
+
+		return b.attemptsRepository.Finish(ctx, id, task_attempts_repository.Succeeded, "")
+	})
 }
 
-func (b *binaryTreeStorage) FindByParentId(parentId int) ([]*dto.ExpressionNodeDTO, error) {
-	entities, err := b.repository.FindByParentId(parentId)
+func (b *binaryTreeStorage) FindByParentId(ctx context.Context, parentId int) ([]*dto.ExpressionNodeDTO, error) {
+	entities, err := b.repository.FindByParentId(ctx, parentId)
 	if err != nil {
 		return nil, err
 	}
@@ -118,18 +194,39 @@ func (b *binaryTreeStorage) FindByParentId(parentId int) ([]*dto.ExpressionNodeD
 			Status:        statuses.Status(entity.Status),
 			Result:        entity.Result,
 			WorkerId:      entity.WorkerId,
+			ComputeTimeUS: entity.ComputeTimeUS,
 		})
 	}
 
 	return expressionNodes, nil
 }
 
-func (b *binaryTreeStorage) SaveWorker(id int, workerId int) error {
-	return b.repository.SaveWorker(id, workerId, int(statuses.Enqueued))
+func (b *binaryTreeStorage) SaveWorker(ctx context.Context, id int, workerId int) error {
+	return b.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := b.repository.SaveWorker(ctx, id, workerId, int(statuses.Enqueued))
+		if err != nil {
+			return err
+		}
+
+		return b.attemptsRepository.Create(ctx, id, workerId)
+	})
 }
 
-func (b *binaryTreeStorage) FindById(id int) (*dto.ExpressionNodeDTO, error) {
-	entity, err := b.repository.FindById(id)
+// SaveDispatchFailure records that the node assigned with SaveWorker could not be sent to the worker.
+// The node is returned to the queue
+func (b *binaryTreeStorage) SaveDispatchFailure(ctx context.Context, id int, workerId int, reason string) error {
+	return b.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := b.repository.DeleteNodeWorker(ctx, id, workerId)
+		if err != nil {
+			return err
+		}
+
+		return b.attemptsRepository.Finish(ctx, id, task_attempts_repository.DispatchFailed, reason)
+	})
+}
+
+func (b *binaryTreeStorage) FindById(ctx context.Context, id int) (*dto.ExpressionNodeDTO, error) {
+	entity, err := b.repository.FindById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -144,11 +241,49 @@ func (b *binaryTreeStorage) FindById(id int) (*dto.ExpressionNodeDTO, error) {
 		Status:        statuses.Status(entity.Status),
 		Result:        entity.Result,
 		WorkerId:      entity.WorkerId,
+		ComputeTimeUS: entity.ComputeTimeUS,
 	}, err
 }
 
-func (b *binaryTreeStorage) FindByWorkerId(workerId int) ([]*dto.TaskDTO, error) {
-	entities, err := b.repository.FindByWorkerId(workerId)
+func (b *binaryTreeStorage) FindByExpressionId(ctx context.Context, expressionId int) ([]*dto.ExpressionNodeDTO, error) {
+	entities, err := b.repository.FindByExpressionId(ctx, expressionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes = []*dto.ExpressionNodeDTO{}
+
+	for _, entity := range entities {
+		var startedAt *time.Time
+		if !entity.StartedAt.IsZero() {
+			startedAt = &entity.StartedAt
+		}
+
+		nodes = append(nodes, &dto.ExpressionNodeDTO{
+			Id:            entity.Id,
+			UserID:        entity.UserID,
+			ParentId:      entity.ParentId,
+			ExpressionId:  entity.ExpressionId,
+			Type:          entity.Type,
+			OperationType: entity.OperationType,
+			Status:        statuses.Status(entity.Status),
+			Result:        entity.Result,
+			WorkerId:      entity.WorkerId,
+			ComputeTimeUS: entity.ComputeTimeUS,
+			StartedAt:     startedAt,
+		})
+	}
+
+	return nodes, nil
+}
+
+// CountQueued returns the number of tasks which occupy workers or wait for them
+func (b *binaryTreeStorage) CountQueued(ctx context.Context) (int, error) {
+	return b.repository.CountQueued(ctx)
+}
+
+func (b *binaryTreeStorage) FindByWorkerId(ctx context.Context, workerId int) ([]*dto.TaskDTO, error) {
+	entities, err := b.repository.FindByWorkerId(ctx, workerId)
 	if err != nil {
 		return nil, err
 	}
@@ -167,21 +302,162 @@ func (b *binaryTreeStorage) FindByWorkerId(workerId int) ([]*dto.TaskDTO, error)
 	return tasks, nil
 }
 
-func (b *binaryTreeStorage) DeleteWorkers(workerIds []int) error {
-	for _, workerId := range workerIds {
-		err := b.repository.DeleteWorker(workerId)
+func (b *binaryTreeStorage) DeleteWorkers(ctx context.Context, workerIds []int) error {
+	return b.transactor.InTx(ctx, func(ctx context.Context) error {
+		for _, workerId := range workerIds {
+			err := b.repository.DeleteWorker(ctx, workerId)
+			if err != nil {
+				return err
+			}
+
+			err = b.attemptsRepository.FinishByWorker(ctx, workerId, task_attempts_repository.Reassigned)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *binaryTreeStorage) DeleteAllWorkers(ctx context.Context) error {
+	return b.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := b.repository.DeleteAllWorkers(ctx)
+		if err != nil {
+			return err
+		}
+
+		return b.attemptsRepository.FinishAll(ctx, task_attempts_repository.Reassigned)
+	})
+}
+
+func (b *binaryTreeStorage) FindUncalculated(ctx context.Context) ([]int, error) {
+	return b.repository.FindUncalculated(ctx)
+}
+
+func (b *binaryTreeStorage) FindReady(ctx context.Context, limit int) ([]*dto.ReadyTaskDTO, error) {
+	entities, err := b.repository.FindReady(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []*dto.ReadyTaskDTO
+
+	for _, entity := range entities {
+		var selector map[string]string
+
+		if len(entity.Selector) > 0 {
+			err = json.Unmarshal(entity.Selector, &selector)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		tasks = append(tasks, &dto.ReadyTaskDTO{
+			Id:             entity.Id,
+			UserID:         entity.UserID,
+			OrganizationID: uint64(entity.OrganizationID.Int64),
+			ExpressionId:   entity.ExpressionId,
+			OperationType:  entity.OperationType,
+			LeftResult:     entity.LeftResult,
+			RightResult:    entity.RightResult,
+			Selector:       selector,
+		})
+	}
+
+	return tasks, nil
+}
+
+// Lease enqueues the node for the worker until the lease expires.
+// Returns false, if the node has already been taken by another worker
+func (b *binaryTreeStorage) Lease(ctx context.Context, id int, workerId int, until time.Time) (bool, error) {
+	var leased bool
+
+	err := b.transactor.InTx(ctx, func(ctx context.Context) error {
+		ok, err := b.repository.Lease(ctx, id, workerId, int(statuses.Enqueued), until)
+		if err != nil || !ok {
+			return err
+		}
+
+		leased = true
+
+		return b.attemptsRepository.Create(ctx, id, workerId)
+	})
+
+	return leased, err
+}
+
+// ReleaseExpiredLeases returns expired nodes to the queue and the ids of workers which held them, one per node
+func (b *binaryTreeStorage) ReleaseExpiredLeases(ctx context.Context, now time.Time) ([]int, error) {
+	var workerIds []int
+
+	err := b.transactor.InTx(ctx, func(ctx context.Context) error {
+		leases, err := b.repository.ReleaseExpiredLeases(ctx, now)
 		if err != nil {
 			return err
 		}
+
+		for _, lease := range leases {
+			err = b.attemptsRepository.Finish(ctx, lease.NodeId, task_attempts_repository.TimedOut, "lease expired")
+			if err != nil {
+				return err
+			}
+
+			workerIds = append(workerIds, lease.WorkerId)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return workerIds, nil
 }
 
-func (b *binaryTreeStorage) DeleteAllWorkers() error {
-	return b.repository.DeleteAllWorkers()
+// FindAttempts returns the timeline of dispatches of all nodes of the expression
+func (b *binaryTreeStorage) FindAttempts(ctx context.Context, expressionId int) ([]*dto.TaskAttemptDTO, error) {
+	entities, err := b.attemptsRepository.FindByExpressionId(ctx, expressionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var attempts = []*dto.TaskAttemptDTO{}
+
+	for _, entity := range entities {
+		attempts = append(attempts, &dto.TaskAttemptDTO{
+			Id:            entity.Id,
+			NodeId:        entity.NodeId,
+			WorkerId:      entity.WorkerId,
+			Attempt:       entity.Attempt,
+			OperationType: expr_tokens.OperationType(entity.OperationType),
+			EnqueuedAt:    entity.EnqueuedAt,
+			StartedAt:     nullTime(entity.StartedAt),
+			FinishedAt:    nullTime(entity.FinishedAt),
+			Outcome:       entity.Outcome,
+			Error:         entity.Error,
+		})
+	}
+
+	return attempts, nil
 }
 
-func (b *binaryTreeStorage) FindUncalculated() ([]int, error) {
-	return b.repository.FindUncalculated()
+// DeleteByExpressionIds deletes trees of the expressions together with their attempts
+func (b *binaryTreeStorage) DeleteByExpressionIds(ctx context.Context, expressionIds []int) error {
+	return b.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := b.attemptsRepository.DeleteByExpressionIds(ctx, expressionIds)
+		if err != nil {
+			return err
+		}
+
+		return b.repository.DeleteByExpressionIds(ctx, expressionIds)
+	})
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
 }
@@ -0,0 +1,427 @@
+package binary_tree_storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/expr_tree_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/task_attempts_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression"
+)
+
+func TestFlatten(t *testing.T) {
+	type Test struct {
+		name       string
+		expression string
+		parents    []int
+	}
+
+	var tt = []Test{
+		{name: "number", expression: "2", parents: []int{-1}},
+		{name: "operation", expression: "2+2", parents: []int{-1, 0, 0}},
+		{name: "nested", expression: "2+2*2", parents: []int{-1, 0, 0, 2, 2}},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			expr, err := expression.NewExpression(test.expression)
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			tokens, err := expression.TokenizeExpression(&expr)
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			var entities []*expr_tree_repository.ExpressionTreeNodeEntity
+			flatten(binary_tree.NewBinaryTree(binary_tree.TokensToNodeArray(tokens)), -1, true, 1, 1, &entities)
+
+			if len(entities) != len(test.parents) {
+				t.Fatalf("expected %v, but got %v", len(test.parents), len(entities))
+			}
+
+			for i, entity := range entities {
+				if entity.ParentId != test.parents[i] {
+					t.Fatalf("expected %v, but got %v", test.parents[i], entity.ParentId)
+				}
+			}
+		})
+	}
+}
+
+type transactor struct{}
+
+func (transactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// memoryTree keeps statuses and workers of nodes. Methods which are not used by attempts recording are not implemented
+type memoryTree struct {
+	expr_tree_repository.ExpressionsTreeRepository
+
+	statuses map[int]int
+	workers  map[int]int
+	expired  []*expr_tree_repository.ExpiredLeaseEntity
+}
+
+func (m *memoryTree) SetStatus(_ context.Context, id int, status int) error {
+	m.statuses[id] = status
+	return nil
+}
+
+func (m *memoryTree) Start(_ context.Context, id int, status int) error {
+	m.statuses[id] = status
+	return nil
+}
+
+func (m *memoryTree) SaveResult(_ context.Context, id int, _ float64, status int, _ int64) error {
+	m.statuses[id] = status
+	return nil
+}
+
+func (m *memoryTree) SaveWorker(_ context.Context, id int, workerId int, status int) error {
+	m.statuses[id] = status
+	m.workers[id] = workerId
+	return nil
+}
+
+func (m *memoryTree) DeleteNodeWorker(_ context.Context, id int, workerId int) error {
+	if m.workers[id] == workerId {
+		delete(m.workers, id)
+	}
+
+	return nil
+}
+
+func (m *memoryTree) DeleteWorker(_ context.Context, workerId int) error {
+	for id, worker := range m.workers {
+		if worker == workerId {
+			delete(m.workers, id)
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryTree) DeleteAllWorkers(_ context.Context) error {
+	clear(m.workers)
+	return nil
+}
+
+func (m *memoryTree) Lease(_ context.Context, id int, workerId int, status int, _ time.Time) (bool, error) {
+	if _, ok := m.workers[id]; ok {
+		return false, nil
+	}
+
+	m.statuses[id] = status
+	m.workers[id] = workerId
+	return true, nil
+}
+
+func (m *memoryTree) ReleaseExpiredLeases(_ context.Context, _ time.Time) ([]*expr_tree_repository.ExpiredLeaseEntity, error) {
+	expired := m.expired
+	m.expired = nil
+
+	for _, lease := range expired {
+		delete(m.workers, lease.NodeId)
+	}
+
+	return expired, nil
+}
+
+// memoryAttempts follows the rules of the attempts repository: a new dispatch closes the unfinished attempt
+// of the node as reassigned, and only unfinished attempts are started or finished
+type memoryAttempts struct {
+	task_attempts_repository.TaskAttemptsRepository
+
+	attempts []*task_attempts_repository.TaskAttemptEntity
+	err      error
+}
+
+func (m *memoryAttempts) Create(ctx context.Context, nodeId int, workerId int) error {
+	err := m.Finish(ctx, nodeId, task_attempts_repository.Reassigned, "")
+	if err != nil {
+		return err
+	}
+
+	var attempt = 1
+	for _, entity := range m.attempts {
+		if entity.NodeId == nodeId {
+			attempt++
+		}
+	}
+
+	m.attempts = append(m.attempts, &task_attempts_repository.TaskAttemptEntity{
+		Id:       len(m.attempts) + 1,
+		NodeId:   nodeId,
+		WorkerId: workerId,
+		Attempt:  attempt,
+		Outcome:  task_attempts_repository.Enqueued,
+	})
+
+	return nil
+}
+
+func (m *memoryAttempts) Start(_ context.Context, nodeId int) error {
+	return m.update(func(entity *task_attempts_repository.TaskAttemptEntity) bool {
+		return entity.NodeId == nodeId
+	}, func(entity *task_attempts_repository.TaskAttemptEntity) {
+		entity.StartedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		entity.Outcome = task_attempts_repository.Running
+	})
+}
+
+func (m *memoryAttempts) Finish(_ context.Context, nodeId int, outcome string, reason string) error {
+	return m.update(func(entity *task_attempts_repository.TaskAttemptEntity) bool {
+		return entity.NodeId == nodeId
+	}, finish(outcome, reason))
+}
+
+func (m *memoryAttempts) FinishByWorker(_ context.Context, workerId int, outcome string) error {
+	return m.update(func(entity *task_attempts_repository.TaskAttemptEntity) bool {
+		return entity.WorkerId == workerId
+	}, finish(outcome, ""))
+}
+
+func (m *memoryAttempts) FinishAll(_ context.Context, outcome string) error {
+	return m.update(func(_ *task_attempts_repository.TaskAttemptEntity) bool {
+		return true
+	}, finish(outcome, ""))
+}
+
+func (m *memoryAttempts) FindByExpressionId(_ context.Context, _ int) ([]*task_attempts_repository.TaskAttemptEntity, error) {
+	return m.attempts, nil
+}
+
+func (m *memoryAttempts) update(matches func(entity *task_attempts_repository.TaskAttemptEntity) bool, apply func(entity *task_attempts_repository.TaskAttemptEntity)) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	for _, entity := range m.attempts {
+		if !entity.FinishedAt.Valid && matches(entity) {
+			apply(entity)
+		}
+	}
+
+	return nil
+}
+
+func finish(outcome string, reason string) func(entity *task_attempts_repository.TaskAttemptEntity) {
+	return func(entity *task_attempts_repository.TaskAttemptEntity) {
+		entity.FinishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		entity.Outcome = outcome
+		entity.Error = reason
+	}
+}
+
+type attempt struct {
+	workerId int
+	number   int
+	outcome  string
+	err      string
+	started  bool
+}
+
+func TestBinaryTreeStorage_Attempts(t *testing.T) {
+	type Test struct {
+		name     string
+		steps    func(ctx context.Context, storage BinaryTreeStorage, tree *memoryTree) error
+		attempts []attempt
+	}
+
+	var tt = []Test{
+		{
+			name: "succeeded",
+			steps: func(ctx context.Context, storage BinaryTreeStorage, _ *memoryTree) error {
+				return errors.Join(
+					storage.SaveWorker(ctx, 1, 10),
+					storage.MarkAsCalculating(ctx, 1),
+					storage.SaveResult(ctx, 1, 4, time.Millisecond),
+				)
+			},
+			attempts: []attempt{{workerId: 10, number: 1, outcome: task_attempts_repository.Succeeded, started: true}},
+		},
+		{
+			name: "failed",
+			steps: func(ctx context.Context, storage BinaryTreeStorage, _ *memoryTree) error {
+				return errors.Join(
+					storage.SaveWorker(ctx, 1, 10),
+					storage.MarkAsCalculating(ctx, 1),
+					storage.MarkAsFailed(ctx, 1, "division by zero"),
+				)
+			},
+			attempts: []attempt{{workerId: 10, number: 1, outcome: task_attempts_repository.Failed, err: "division by zero", started: true}},
+		},
+		{
+			name: "dispatch_failed",
+			steps: func(ctx context.Context, storage BinaryTreeStorage, _ *memoryTree) error {
+				return errors.Join(
+					storage.SaveWorker(ctx, 1, 10),
+					storage.SaveDispatchFailure(ctx, 1, 10, "connection refused"),
+					storage.SaveWorker(ctx, 1, 11),
+				)
+			},
+			attempts: []attempt{
+				{workerId: 10, number: 1, outcome: task_attempts_repository.DispatchFailed, err: "connection refused"},
+				{workerId: 11, number: 2, outcome: task_attempts_repository.Enqueued},
+			},
+		},
+		{
+			name: "lease_expired",
+			steps: func(ctx context.Context, storage BinaryTreeStorage, tree *memoryTree) error {
+				_, err := storage.Lease(ctx, 1, 10, time.Now())
+				if err != nil {
+					return err
+				}
+
+				tree.expired = []*expr_tree_repository.ExpiredLeaseEntity{{NodeId: 1, WorkerId: 10}}
+
+				workerIds, err := storage.ReleaseExpiredLeases(ctx, time.Now())
+				if err != nil {
+					return err
+				}
+
+				if !slices.Equal(workerIds, []int{10}) {
+					return errors.New("unexpected workers of expired leases")
+				}
+
+				_, err = storage.Lease(ctx, 1, 11, time.Now())
+				return err
+			},
+			attempts: []attempt{
+				{workerId: 10, number: 1, outcome: task_attempts_repository.TimedOut, err: "lease expired"},
+				{workerId: 11, number: 2, outcome: task_attempts_repository.Enqueued},
+			},
+		},
+		{
+			name: "lease_taken",
+			steps: func(ctx context.Context, storage BinaryTreeStorage, _ *memoryTree) error {
+				_, err := storage.Lease(ctx, 1, 10, time.Now())
+				if err != nil {
+					return err
+				}
+
+				leased, err := storage.Lease(ctx, 1, 11, time.Now())
+				if leased {
+					return errors.New("leased node leased again")
+				}
+
+				return err
+			},
+			attempts: []attempt{{workerId: 10, number: 1, outcome: task_attempts_repository.Enqueued}},
+		},
+		{
+			name: "worker_deleted",
+			steps: func(ctx context.Context, storage BinaryTreeStorage, _ *memoryTree) error {
+				return errors.Join(
+					storage.SaveWorker(ctx, 1, 10),
+					storage.MarkAsCalculating(ctx, 1),
+					storage.SaveWorker(ctx, 2, 11),
+					storage.DeleteWorkers(ctx, []int{10}),
+				)
+			},
+			attempts: []attempt{
+				{workerId: 10, number: 1, outcome: task_attempts_repository.Reassigned, started: true},
+				{workerId: 11, number: 1, outcome: task_attempts_repository.Enqueued},
+			},
+		},
+		{
+			name: "all_workers_deleted",
+			steps: func(ctx context.Context, storage BinaryTreeStorage, _ *memoryTree) error {
+				return errors.Join(
+					storage.SaveWorker(ctx, 1, 10),
+					storage.SaveWorker(ctx, 2, 11),
+					storage.DeleteAllWorkers(ctx),
+				)
+			},
+			attempts: []attempt{
+				{workerId: 10, number: 1, outcome: task_attempts_repository.Reassigned},
+				{workerId: 11, number: 1, outcome: task_attempts_repository.Reassigned},
+			},
+		},
+		{
+			name: "reassigned",
+			steps: func(ctx context.Context, storage BinaryTreeStorage, _ *memoryTree) error {
+				return errors.Join(
+					storage.SaveWorker(ctx, 1, 10),
+					storage.SaveWorker(ctx, 1, 11),
+					storage.MarkAsCalculating(ctx, 1),
+					storage.SaveResult(ctx, 1, 4, time.Millisecond),
+				)
+			},
+			attempts: []attempt{
+				{workerId: 10, number: 1, outcome: task_attempts_repository.Reassigned},
+				{workerId: 11, number: 2, outcome: task_attempts_repository.Succeeded, started: true},
+			},
+		},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx     = context.Background()
+				tree    = &memoryTree{statuses: map[int]int{}, workers: map[int]int{}}
+				storage = NewBinaryTreeStorage(transactor{}, tree, &memoryAttempts{})
+			)
+
+			err := test.steps(ctx, storage, tree)
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			attempts, err := storage.FindAttempts(ctx, 1)
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			if len(attempts) != len(test.attempts) {
+				t.Fatalf("expected %v, but got %v", len(test.attempts), len(attempts))
+			}
+
+			for i, got := range attempts {
+				expected := test.attempts[i]
+
+				if got.WorkerId != expected.workerId || got.Attempt != expected.number || got.Outcome != expected.outcome || got.Error != expected.err {
+					t.Fatalf("expected %+v, but got %+v", expected, *got)
+				}
+
+				if (got.StartedAt != nil) != expected.started {
+					t.Fatalf("expected %v, but got %v", expected.started, got.StartedAt)
+				}
+
+				finished := expected.outcome != task_attempts_repository.Enqueued && expected.outcome != task_attempts_repository.Running
+				if (got.FinishedAt != nil) != finished {
+					t.Fatalf("expected %v, but got %v", finished, got.FinishedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestBinaryTreeStorage_AttemptsError(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		targetErr = errors.New("attempts are unavailable")
+		storage   = NewBinaryTreeStorage(
+			transactor{},
+			&memoryTree{statuses: map[int]int{}, workers: map[int]int{}},
+			&memoryAttempts{err: targetErr},
+		)
+	)
+
+	// the change of the node is rolled back by the transaction together with the failed attempt
+	if err := storage.SaveWorker(ctx, 1, 10); !errors.Is(err, targetErr) {
+		t.Fatalf("expected %v, but got %v", targetErr, err)
+	}
+
+	if err := storage.SaveResult(ctx, 1, 4, time.Millisecond); !errors.Is(err, targetErr) {
+		t.Fatalf("expected %v, but got %v", targetErr, err)
+	}
+}
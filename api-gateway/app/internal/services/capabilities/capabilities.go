@@ -0,0 +1,67 @@
+package capabilities
+
+import (
+	"math"
+	"slices"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+)
+
+type Domain string
+
+const (
+	Real    Domain = "real"
+	Integer Domain = "integer"
+)
+
+// Capabilities describes what a worker is able to calculate.
+// Empty fields mean "no restriction", so workers which advertise nothing accept any task
+type Capabilities struct {
+	Operations     []expr_tokens.OperationType `json:"operations,omitempty"`
+	Domains        []Domain                    `json:"domains,omitempty"`
+	PrecisionModes []string                    `json:"precisionModes,omitempty"`
+	MaxOperand     float64                     `json:"maxOperand,omitempty"`
+	Labels         map[string]string           `json:"labels,omitempty"`
+}
+
+// Requirement describes a single task which has to be routed to a worker
+type Requirement struct {
+	Operation expr_tokens.OperationType
+	Operands  []float64
+	Selector  map[string]string
+}
+
+// Satisfies reports whether a worker with capabilities c may calculate the task r
+func (c *Capabilities) Satisfies(r *Requirement) bool {
+	if c == nil {
+		return len(r.Selector) == 0
+	}
+
+	if len(c.Operations) > 0 && !slices.Contains(c.Operations, r.Operation) {
+		return false
+	}
+
+	if len(c.Domains) > 0 && !slices.Contains(c.Domains, Real) {
+		for _, operand := range r.Operands {
+			if operand != math.Trunc(operand) {
+				return false
+			}
+		}
+	}
+
+	if c.MaxOperand > 0 {
+		for _, operand := range r.Operands {
+			if math.Abs(operand) > c.MaxOperand {
+				return false
+			}
+		}
+	}
+
+	for key, value := range r.Selector {
+		if label, ok := c.Labels[key]; !ok || label != value {
+			return false
+		}
+	}
+
+	return true
+}
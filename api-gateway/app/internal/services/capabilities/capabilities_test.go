@@ -0,0 +1,90 @@
+package capabilities
+
+import (
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+)
+
+func TestCapabilities_Satisfies(t *testing.T) {
+	type Test struct {
+		name         string
+		capabilities *Capabilities
+		requirement  *Requirement
+		expected     bool
+	}
+
+	var tt = []Test{
+		{
+			name:         "no_capabilities",
+			capabilities: nil,
+			requirement:  &Requirement{Operation: expr_tokens.Plus, Operands: []float64{1, 2}},
+			expected:     true,
+		},
+
+		{
+			name:         "no_capabilities_with_selector",
+			capabilities: nil,
+			requirement: &Requirement{
+				Operation: expr_tokens.Plus,
+				Operands:  []float64{1, 2},
+				Selector:  map[string]string{"region": "eu"},
+			},
+			expected: false,
+		},
+
+		{
+			name:         "unsupported_operation",
+			capabilities: &Capabilities{Operations: []expr_tokens.OperationType{expr_tokens.Plus, expr_tokens.Minus}},
+			requirement:  &Requirement{Operation: expr_tokens.Divide, Operands: []float64{1, 2}},
+			expected:     false,
+		},
+
+		{
+			name:         "integer_domain",
+			capabilities: &Capabilities{Domains: []Domain{Integer}},
+			requirement:  &Requirement{Operation: expr_tokens.Plus, Operands: []float64{1.5, 2}},
+			expected:     false,
+		},
+
+		{
+			name:         "too_big_operand",
+			capabilities: &Capabilities{MaxOperand: 100},
+			requirement:  &Requirement{Operation: expr_tokens.Plus, Operands: []float64{-101, 2}},
+			expected:     false,
+		},
+
+		{
+			name: "matching_labels",
+			capabilities: &Capabilities{
+				Operations: []expr_tokens.OperationType{expr_tokens.Multiply},
+				Labels:     map[string]string{"region": "eu", "tier": "heavy"},
+			},
+			requirement: &Requirement{
+				Operation: expr_tokens.Multiply,
+				Operands:  []float64{3, 4},
+				Selector:  map[string]string{"region": "eu"},
+			},
+			expected: true,
+		},
+
+		{
+			name:         "mismatching_labels",
+			capabilities: &Capabilities{Labels: map[string]string{"region": "us"}},
+			requirement: &Requirement{
+				Operation: expr_tokens.Multiply,
+				Operands:  []float64{3, 4},
+				Selector:  map[string]string{"region": "eu"},
+			},
+			expected: false,
+		},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.capabilities.Satisfies(test.requirement); got != test.expected {
+				t.Fatalf("expected %v, but got %v", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package eta
+
+import (
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/statuses"
+)
+
+// Costs describes how long tasks take
+type Costs struct {
+	// Durations holds the duration of every operation type
+	Durations map[int]time.Duration
+	// QueueDelay is how long a task waits for a free worker
+	QueueDelay time.Duration
+}
+
+// CriticalPath returns the time left to calculate the tree of nodes and ids of the nodes
+// on its longest remaining path, root first.
+// Returns false, if the tree has no root or one of its nodes has failed
+func CriticalPath(nodes []*dto.ExpressionNodeDTO, costs *Costs, now time.Time) (time.Duration, []int, bool) {
+	var (
+		root     *dto.ExpressionNodeDTO
+		children = make(map[int][]*dto.ExpressionNodeDTO)
+	)
+
+	for _, node := range nodes {
+		if node.ParentId == -1 {
+			root = node
+			continue
+		}
+
+		children[node.ParentId] = append(children[node.ParentId], node)
+	}
+
+	if root == nil {
+		return 0, nil, false
+	}
+
+	return remaining(root, children, costs, now)
+}
+
+func remaining(
+	node *dto.ExpressionNodeDTO,
+	children map[int][]*dto.ExpressionNodeDTO,
+	costs *Costs,
+	now time.Time,
+) (time.Duration, []int, bool) {
+	switch node.Status {
+	case statuses.Finished:
+		return 0, nil, true
+	case statuses.Failed:
+		return 0, nil, false
+	}
+
+	if node.OperationType == -1 {
+		return 0, nil, true
+	}
+
+	var (
+		longest time.Duration
+		path    []int
+	)
+
+	for _, child := range children[node.Id] {
+		left, childPath, ok := remaining(child, children, costs, now)
+		if !ok {
+			return 0, nil, false
+		}
+
+		if left > longest || path == nil {
+			longest, path = left, childPath
+		}
+	}
+
+	own := costs.Durations[node.OperationType]
+
+	switch node.Status {
+	case statuses.Created:
+		own += costs.QueueDelay
+	case statuses.Calculating:
+		if node.StartedAt != nil {
+			own -= now.Sub(*node.StartedAt)
+		}
+	}
+
+	if own < 0 {
+		own = 0
+	}
+
+	return longest + own, append([]int{node.Id}, path...), true
+}
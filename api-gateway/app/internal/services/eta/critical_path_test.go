@@ -0,0 +1,135 @@
+package eta
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/statuses"
+)
+
+func TestCriticalPath(t *testing.T) {
+	type Test struct {
+		name      string
+		nodes     []*dto.ExpressionNodeDTO
+		remaining time.Duration
+		path      []int
+		ok        bool
+	}
+
+	var (
+		now     = time.Now()
+		started = now.Add(-300 * time.Millisecond)
+	)
+
+	var costs = &Costs{
+		Durations: map[int]time.Duration{
+			int(expr_tokens.Plus):     time.Second,
+			int(expr_tokens.Multiply): 2 * time.Second,
+		},
+		QueueDelay: 100 * time.Millisecond,
+	}
+
+	number := func(id int, parentId int) *dto.ExpressionNodeDTO {
+		return &dto.ExpressionNodeDTO{Id: id, ParentId: parentId, OperationType: -1, Status: statuses.Finished}
+	}
+
+	operation := func(id int, parentId int, operation expr_tokens.OperationType, status statuses.Status) *dto.ExpressionNodeDTO {
+		return &dto.ExpressionNodeDTO{Id: id, ParentId: parentId, OperationType: int(operation), Status: status}
+	}
+
+	calculating := operation(2, 1, expr_tokens.Multiply, statuses.Calculating)
+	calculating.StartedAt = &started
+
+	var tt = []Test{
+		{
+			name: "single_operation",
+			nodes: []*dto.ExpressionNodeDTO{
+				operation(1, -1, expr_tokens.Plus, statuses.Created),
+				number(2, 1),
+				number(3, 1),
+			},
+			remaining: 1100 * time.Millisecond,
+			path:      []int{1},
+			ok:        true,
+		},
+		{
+			// (2*3) + (4+5)
+			name: "longest_branch",
+			nodes: []*dto.ExpressionNodeDTO{
+				operation(1, -1, expr_tokens.Plus, statuses.Created),
+				operation(2, 1, expr_tokens.Multiply, statuses.Enqueued),
+				operation(3, 1, expr_tokens.Plus, statuses.Created),
+				number(4, 2),
+				number(5, 2),
+				number(6, 3),
+				number(7, 3),
+			},
+			remaining: 3100 * time.Millisecond,
+			path:      []int{1, 2},
+			ok:        true,
+		},
+		{
+			name: "started_operation",
+			nodes: []*dto.ExpressionNodeDTO{
+				operation(1, -1, expr_tokens.Plus, statuses.Created),
+				calculating,
+				number(3, 1),
+				number(4, 2),
+				number(5, 2),
+			},
+			remaining: 2800 * time.Millisecond,
+			path:      []int{1, 2},
+			ok:        true,
+		},
+		{
+			name: "finished",
+			nodes: []*dto.ExpressionNodeDTO{
+				operation(1, -1, expr_tokens.Plus, statuses.Finished),
+				number(2, 1),
+				number(3, 1),
+			},
+			remaining: 0,
+			path:      nil,
+			ok:        true,
+		},
+		{
+			name: "failed",
+			nodes: []*dto.ExpressionNodeDTO{
+				operation(1, -1, expr_tokens.Plus, statuses.Created),
+				operation(2, 1, expr_tokens.Multiply, statuses.Failed),
+				number(3, 1),
+			},
+			ok: false,
+		},
+		{
+			name:  "no_root",
+			nodes: []*dto.ExpressionNodeDTO{number(2, 1)},
+			ok:    false,
+		},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			remaining, path, ok := CriticalPath(test.nodes, costs, now)
+
+			if ok != test.ok {
+				t.Fatalf("expected %v, but got %v", test.ok, ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			if remaining != test.remaining {
+				t.Fatalf("expected %v, but got %v", test.remaining, remaining)
+			}
+
+			if !slices.Equal(path, test.path) {
+				t.Fatalf("expected %v, but got %v", test.path, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package eta
+
+import (
+	"context"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/operators_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+)
+
+// Estimator predicts when expressions are calculated. Estimates are built on every call
+// from the current state of the expression tree, so they are refined as nodes complete
+type Estimator interface {
+	// Estimate uses durations of operations of the organization the expression belongs to
+	Estimate(ctx context.Context, expressionId int, organizationID uint64) (*dto.ExpressionTreeDTO, error)
+}
+
+type estimator struct {
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
+	operatorsStorage  operators_storage.OperatorsStorage
+	workersStorage    workers_storage.WorkerStorage
+}
+
+func NewEstimator(
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
+	operatorsStorage operators_storage.OperatorsStorage,
+	workersStorage workers_storage.WorkerStorage,
+) Estimator {
+	return &estimator{
+		binaryTreeStorage: binaryTreeStorage,
+		operatorsStorage:  operatorsStorage,
+		workersStorage:    workersStorage,
+	}
+}
+
+// Estimate returns the expression tree with its critical path marked.
+// The finish time is not estimated for finished and failed expressions
+// and when there are no workers which could calculate them
+func (e *estimator) Estimate(ctx context.Context, expressionId int, organizationID uint64) (*dto.ExpressionTreeDTO, error) {
+	nodes, err := e.binaryTreeStorage.FindByExpressionId(ctx, expressionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree = &dto.ExpressionTreeDTO{
+		CriticalPath: []int{},
+		Nodes:        nodes,
+	}
+
+	costs, available, err := e.costs(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var now = time.Now()
+
+	left, path, ok := CriticalPath(nodes, costs, now)
+	if !ok || len(path) == 0 {
+		return tree, nil
+	}
+
+	tree.CriticalPath = path
+
+	var critical = make(map[int]bool, len(path))
+	for _, id := range path {
+		critical[id] = true
+	}
+
+	for _, node := range nodes {
+		node.Critical = critical[node.Id]
+	}
+
+	if available {
+		finishAt := now.Add(left)
+		tree.EstimatedFinishAt = &finishAt
+	}
+
+	return tree, nil
+}
+
+// costs returns operation durations and the queue delay.
+// Returns false, if there are no workers which accept tasks
+func (e *estimator) costs(ctx context.Context, organizationID uint64) (*Costs, bool, error) {
+	operations, err := e.operatorsStorage.FindFor(ctx, organizationID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var (
+		costs = &Costs{Durations: make(map[int]time.Duration, len(operations))}
+		total time.Duration
+	)
+
+	for _, operation := range operations {
+		duration := time.Duration(operation.DurationMS) * time.Millisecond
+
+		costs.Durations[int(operation.OperationType)] = duration
+		total += duration
+	}
+
+	workers, err := e.workersStorage.FindAll(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var executors int
+
+	for _, worker := range workers {
+		if worker.State.Schedulable() && !worker.Cordoned && !worker.Unreachable {
+			executors += worker.Executors
+		}
+	}
+
+	if executors == 0 {
+		return costs, false, nil
+	}
+
+	queued, err := e.binaryTreeStorage.CountQueued(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(operations) > 0 {
+		// every executor calculates queued tasks one after another
+		costs.QueueDelay = time.Duration(queued/executors) * (total / time.Duration(len(operations)))
+	}
+
+	return costs, true, nil
+}
@@ -0,0 +1,74 @@
+package expressions_storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/expressions_repository"
+)
+
+const (
+	SortByCreatedAt  = "createdAt"
+	SortByFinishedAt = "finishedAt"
+	SortById         = "id"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+const defaultPageSize = 50
+
+var (
+	ErrInvalidSort   = errors.New("expressions_storage: invalid sort")
+	ErrInvalidCursor = errors.New("expressions_storage: invalid cursor")
+)
+
+var sortColumns = map[string]string{
+	SortByCreatedAt:  expressions_repository.SortByCreatedAt,
+	SortByFinishedAt: expressions_repository.SortByFinishedAt,
+	SortById:         expressions_repository.SortById,
+}
+
+// cursor points to the last expression of a page. It remembers the sorting,
+// so a cursor of one list can not be used to continue another one
+type cursor struct {
+	Sort  string    `json:"s"`
+	Order string    `json:"o"`
+	Time  time.Time `json:"t"`
+	Id    int       `json:"i"`
+}
+
+func encodeCursor(entity *expressions_repository.ExpressionEntity, sort string, order string) string {
+	var c = &cursor{
+		Sort:  sort,
+		Order: order,
+		Time:  entity.CreatedAt,
+		Id:    entity.Id,
+	}
+
+	if sort == SortByFinishedAt {
+		c.Time = entity.FinishedAt
+	}
+
+	data, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, sort string, order string) (*expressions_repository.ExpressionsCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c = &cursor{}
+
+	err = json.Unmarshal(data, c)
+	if err != nil || c.Sort != sort || c.Order != order {
+		return nil, ErrInvalidCursor
+	}
+
+	return &expressions_repository.ExpressionsCursor{Time: c.Time, Id: c.Id}, nil
+}
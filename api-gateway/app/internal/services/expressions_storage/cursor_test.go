@@ -0,0 +1,53 @@
+package expressions_storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/expressions_repository"
+)
+
+func TestCursor(t *testing.T) {
+	type Test struct {
+		name     string
+		sort     string
+		order    string
+		expected time.Time
+		err      error
+	}
+
+	var (
+		createdAt  = time.Date(2024, 2, 16, 19, 33, 27, 898659000, time.UTC)
+		finishedAt = createdAt.Add(time.Second)
+		entity     = &expressions_repository.ExpressionEntity{Id: 7, CreatedAt: createdAt, FinishedAt: finishedAt}
+		cursor     = encodeCursor(entity, SortByCreatedAt, OrderDesc)
+	)
+
+	var tt = []Test{
+		{name: "same_sorting", sort: SortByCreatedAt, order: OrderDesc, expected: createdAt},
+		{name: "other_order", sort: SortByCreatedAt, order: OrderAsc, err: ErrInvalidCursor},
+		{name: "other_sort", sort: SortByFinishedAt, order: OrderDesc, err: ErrInvalidCursor},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			after, err := decodeCursor(cursor, test.sort, test.order)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, but got %v", test.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if after.Id != entity.Id || !after.Time.Equal(test.expected) {
+				t.Fatalf("expected %v, but got %v", test.expected, after.Time)
+			}
+		})
+	}
+
+	if _, err := decodeCursor("not a cursor", SortByCreatedAt, OrderDesc); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected %v, but got %v", ErrInvalidCursor, err)
+	}
+}
@@ -1,6 +1,9 @@
 package expressions_storage
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/expressions_repository"
@@ -14,14 +17,21 @@ var (
 )
 
 type ExpressionStorage interface {
-	FindByIdempotencyKey(key string, expression expression.Expression) (int, error)
-	Create(expressions expression.Expression, userID uint64, key string) (int, error)
-	FindById(id int) (*dto.ExpressionResponseDTO, error)
-	FindAll() ([]*dto.ExpressionResponseDTO, error)
-	FindAllByUserID(userID uint64) ([]*dto.ExpressionResponseDTO, error)
-	SaveResult(id int, result float64) error
-	MarkAsCalculating(id int) error
-	MarkAsFailed(id int) error
+	FindByIdempotencyKey(ctx context.Context, userID uint64, key string, expression expression.Expression) (int, error)
+	Create(ctx context.Context, expressions expression.Expression, userID uint64, organizationID uint64, key string, selector map[string]string) (int, error)
+	FindById(ctx context.Context, id int) (*dto.ExpressionResponseDTO, error)
+	FindAll(ctx context.Context) ([]*dto.ExpressionResponseDTO, error)
+	FindPage(ctx context.Context, userID uint64, organizationID uint64, query *dto.ExpressionsQueryDTO) (*dto.ExpressionsPageDTO, error)
+	SaveResult(ctx context.Context, id int, result float64) error
+	MarkAsCalculating(ctx context.Context, id int) error
+	MarkAsFailed(ctx context.Context, id int) error
+	FindExpired(ctx context.Context, finishedBefore *time.Time, failedBefore *time.Time, maxPerUser int, limit int) ([]int, error)
+	FindCompactable(ctx context.Context, finishedBefore time.Time, failedBefore *time.Time, limit int) ([]int, error)
+	MarkAsCompacted(ctx context.Context, ids []int) error
+	FindIdsByUser(ctx context.Context, userID uint64, limit int) ([]int, error)
+	CountActive(ctx context.Context, organizationID uint64) (int, error)
+	CountCreatedSince(ctx context.Context, organizationID uint64, since time.Time) (int, error)
+	Delete(ctx context.Context, ids []int) error
 }
 
 type expressionStorage struct {
@@ -32,16 +42,26 @@ func NewExpressionStorage(repository expressions_repository.ExpressionsRepositor
 	return &expressionStorage{repository: repository}
 }
 
-func (e *expressionStorage) FindByIdempotencyKey(key string, expression expression.Expression) (int, error) {
-	return e.repository.FindByIdempotencyKey(key, string(expression))
+func (e *expressionStorage) FindByIdempotencyKey(ctx context.Context, userID uint64, key string, expression expression.Expression) (int, error) {
+	return e.repository.FindByIdempotencyKey(ctx, userID, key, string(expression))
 }
 
-func (e *expressionStorage) Create(expr expression.Expression, userID uint64, key string) (int, error) {
-	return e.repository.Create(string(expr), userID, int(statuses.Created), key)
+// Create creates the expression in the workspace of the organization. Zero organizationID means the personal workspace of the user
+func (e *expressionStorage) Create(ctx context.Context, expr expression.Expression, userID uint64, organizationID uint64, key string, selector map[string]string) (int, error) {
+	if selector == nil {
+		selector = map[string]string{}
+	}
+
+	selectorJSON, err := json.Marshal(selector)
+	if err != nil {
+		return 0, err
+	}
+
+	return e.repository.Create(ctx, string(expr), userID, nullOrganization(organizationID), int(statuses.Created), key, selectorJSON)
 }
 
-func (e *expressionStorage) FindById(id int) (*dto.ExpressionResponseDTO, error) {
-	entity, err := e.repository.FindById(id)
+func (e *expressionStorage) FindById(ctx context.Context, id int) (*dto.ExpressionResponseDTO, error) {
+	entity, err := e.repository.FindById(ctx, id)
 
 	if errors.Is(err, expressions_repository.ErrExpressionNotFound) {
 		return nil, ErrExpressionNotFound
@@ -54,8 +74,8 @@ func (e *expressionStorage) FindById(id int) (*dto.ExpressionResponseDTO, error)
 	return dto.MapExpressionResponseFromEntity(entity), nil
 }
 
-func (e *expressionStorage) FindAll() ([]*dto.ExpressionResponseDTO, error) {
-	entities, err := e.repository.FindAll()
+func (e *expressionStorage) FindAll(ctx context.Context) ([]*dto.ExpressionResponseDTO, error) {
+	entities, err := e.repository.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -69,23 +89,84 @@ func (e *expressionStorage) FindAll() ([]*dto.ExpressionResponseDTO, error) {
 	return expressions, nil
 }
 
-func (e *expressionStorage) FindAllByUserID(userID uint64) ([]*dto.ExpressionResponseDTO, error) {
-	entities, err := e.repository.FindAllByUserID(userID)
+// FindPage returns a page of expressions of the organization or, if organizationID is zero, of the personal workspace
+// of the user. Expressions are sorted by creation time from the newest ones by default
+func (e *expressionStorage) FindPage(ctx context.Context, userID uint64, organizationID uint64, query *dto.ExpressionsQueryDTO) (*dto.ExpressionsPageDTO, error) {
+	var (
+		sort  = query.Sort
+		order = query.Order
+		limit = query.Limit
+	)
+
+	if sort == "" {
+		sort = SortByCreatedAt
+	}
+
+	if order == "" {
+		order = OrderDesc
+	}
+
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	column, ok := sortColumns[sort]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	filter := &expressions_repository.ExpressionsFilter{
+		UserID:         userID,
+		OrganizationID: organizationID,
+		Statuses:       query.Statuses,
+		CreatedFrom:    nullTime(query.CreatedFrom),
+		CreatedTo:      nullTime(query.CreatedTo),
+		FinishedFrom:   nullTime(query.FinishedFrom),
+		FinishedTo:     nullTime(query.FinishedTo),
+		Search:         query.Search,
+		Sort:           column,
+		Descending:     order == OrderDesc,
+		Limit:          limit + 1,
+	}
+
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor, sort, order)
+		if err != nil {
+			return nil, err
+		}
+
+		filter.After = after
+	}
+
+	entities, err := e.repository.FindPage(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	var expressions []*dto.ExpressionResponseDTO
+	total, err := e.repository.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, e := range entities {
-		expressions = append(expressions, dto.MapExpressionResponseFromEntity(e))
+	var page = &dto.ExpressionsPageDTO{
+		Expressions: []*dto.ExpressionResponseDTO{},
+		Total:       total,
 	}
 
-	return expressions, nil
+	if len(entities) > limit {
+		entities = entities[:limit]
+		page.NextCursor = encodeCursor(entities[limit-1], sort, order)
+	}
+
+	for _, entity := range entities {
+		page.Expressions = append(page.Expressions, dto.MapExpressionResponseFromEntity(entity))
+	}
+
+	return page, nil
 }
 
-func (e *expressionStorage) SaveResult(id int, result float64) error {
-	return e.repository.Update(&expressions_repository.ExpressionEntity{
+func (e *expressionStorage) SaveResult(ctx context.Context, id int, result float64) error {
+	return e.repository.Update(ctx, &expressions_repository.ExpressionEntity{
 		Id:         id,
 		Result:     result,
 		Status:     int(statuses.Finished),
@@ -93,10 +174,64 @@ func (e *expressionStorage) SaveResult(id int, result float64) error {
 	})
 }
 
-func (e *expressionStorage) MarkAsCalculating(id int) error {
-	return e.repository.SetStatus(id, int(statuses.Calculating))
+func (e *expressionStorage) MarkAsCalculating(ctx context.Context, id int) error {
+	return e.repository.SetStatus(ctx, id, int(statuses.Calculating))
+}
+
+func (e *expressionStorage) MarkAsFailed(ctx context.Context, id int) error {
+	return e.repository.Fail(ctx, id, int(statuses.Failed))
+}
+
+// FindExpired returns ids of expressions which have to be deleted by the retention policy.
+// Nil times and zero maxPerUser disable the limits
+func (e *expressionStorage) FindExpired(ctx context.Context, finishedBefore *time.Time, failedBefore *time.Time, maxPerUser int, limit int) ([]int, error) {
+	var perUser sql.NullInt32
+	if maxPerUser > 0 {
+		perUser = sql.NullInt32{Int32: int32(maxPerUser), Valid: true}
+	}
+
+	return e.repository.FindExpired(ctx, nullTime(finishedBefore), nullTime(failedBefore), perUser, limit)
+}
+
+// FindCompactable returns ids of expressions with trees which have to be deleted by the retention policy
+func (e *expressionStorage) FindCompactable(ctx context.Context, finishedBefore time.Time, failedBefore *time.Time, limit int) ([]int, error) {
+	return e.repository.FindCompactable(ctx, finishedBefore, nullTime(failedBefore), limit)
+}
+
+func (e *expressionStorage) MarkAsCompacted(ctx context.Context, ids []int) error {
+	return e.repository.MarkAsCompacted(ctx, ids)
+}
+
+func (e *expressionStorage) FindIdsByUser(ctx context.Context, userID uint64, limit int) ([]int, error) {
+	return e.repository.FindIdsByUser(ctx, userID, limit)
+}
+
+// CountActive returns the number of expressions of the organization which are being calculated
+func (e *expressionStorage) CountActive(ctx context.Context, organizationID uint64) (int, error) {
+	return e.repository.CountActiveByOrganization(ctx, organizationID)
+}
+
+// CountCreatedSince returns the number of expressions created in the organization since the time
+func (e *expressionStorage) CountCreatedSince(ctx context.Context, organizationID uint64, since time.Time) (int, error) {
+	return e.repository.CountCreatedByOrganization(ctx, organizationID, since)
+}
+
+func (e *expressionStorage) Delete(ctx context.Context, ids []int) error {
+	return e.repository.Delete(ctx, ids)
 }
 
-func (e *expressionStorage) MarkAsFailed(id int) error {
-	return e.repository.SetStatus(id, int(statuses.Failed))
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func nullOrganization(organizationID uint64) sql.NullInt64 {
+	if organizationID == 0 {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: int64(organizationID), Valid: true}
 }
@@ -0,0 +1,152 @@
+package health_probes
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_events_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_api"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_states"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+)
+
+const (
+	UnreachableEvent = "unreachable"
+	ReachableEvent   = "reachable"
+)
+
+// Policy describes how workers are probed
+type Policy struct {
+	// Timeout limits a single probe
+	Timeout time.Duration
+	// UnreachableAfter is the number of consecutive failed probes after which a worker
+	// which keeps sending heartbeats is marked as unreachable
+	UnreachableAfter int
+}
+
+// Probed reports whether the orchestrator has to probe worker.
+// Pull workers do not accept connections, dead and quarantined ones are not used anyway
+func (p *Policy) Probed(worker *dto.WorkerResponseDTO) bool {
+	return !worker.Pull && worker.State != worker_states.Dead && worker.State != worker_states.Quarantined
+}
+
+// Unreachable reports whether worker has to be marked as unreachable after failures consecutive failed probes.
+// Only workers with fresh heartbeats are marked: they are able to reach the orchestrator,
+// but the orchestrator can not dial them, e.g. because of a wrong DAEMON_HOST.
+// Workers which have stopped sending heartbeats are handled by the failure detector
+func (p *Policy) Unreachable(worker *dto.WorkerResponseDTO, failures int) bool {
+	return p.UnreachableAfter > 0 && failures >= p.UnreachableAfter && worker.MissedHeartbeats == 0
+}
+
+// Prober actively checks workers with the gRPC health checking protocol.
+// Successful probes keep workers alive together with their heartbeats
+type Prober interface {
+	ProbeAll(ctx context.Context) error
+}
+
+type prober struct {
+	workersStorage   workers_storage.WorkerStorage
+	workerAPI        worker_api.WorkerAPI
+	eventsRepository worker_events_repository.WorkerEventsRepository
+
+	policy *Policy
+}
+
+func NewProber(
+	workersStorage workers_storage.WorkerStorage,
+	workerAPI worker_api.WorkerAPI,
+	eventsRepository worker_events_repository.WorkerEventsRepository,
+	policy *Policy,
+) Prober {
+	return &prober{
+		workersStorage:   workersStorage,
+		workerAPI:        workerAPI,
+		eventsRepository: eventsRepository,
+		policy:           policy,
+	}
+}
+
+// ProbeAll probes all workers concurrently and updates their reachability
+func (p *prober) ProbeAll(ctx context.Context) error {
+	workers, err := p.workersStorage.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	var probed []*dto.WorkerResponseDTO
+
+	for _, worker := range workers {
+		if p.policy.Probed(worker) {
+			probed = append(probed, worker)
+		}
+	}
+
+	var (
+		results = make([]error, len(probed))
+		wg      = &sync.WaitGroup{}
+	)
+
+	for i, worker := range probed {
+		wg.Add(1)
+		go func(i int, worker *dto.WorkerResponseDTO) {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, p.policy.Timeout)
+			defer cancel()
+
+			results[i] = p.workerAPI.Probe(probeCtx, worker.Url)
+		}(i, worker)
+	}
+
+	wg.Wait()
+
+	for i, worker := range probed {
+		err = p.record(ctx, worker, results[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *prober) record(ctx context.Context, worker *dto.WorkerResponseDTO, probeErr error) error {
+	failures, err := p.workersStorage.RecordProbe(ctx, worker.Id, probeErr == nil)
+	if err != nil {
+		return err
+	}
+
+	if probeErr == nil && worker.Unreachable {
+		return p.setUnreachable(ctx, worker.Id, false, "")
+	}
+
+	if probeErr != nil && !worker.Unreachable && p.policy.Unreachable(worker, failures) {
+		return p.setUnreachable(ctx, worker.Id, true, probeErr.Error())
+	}
+
+	return nil
+}
+
+func (p *prober) setUnreachable(ctx context.Context, workerId int, unreachable bool, reason string) error {
+	err := p.workersStorage.SetUnreachable(ctx, workerId, unreachable)
+	if err != nil {
+		return err
+	}
+
+	var event = ReachableEvent
+	if unreachable {
+		event = UnreachableEvent
+		log.Printf("worker %d is unreachable: %s", workerId, reason)
+	} else {
+		log.Printf("worker %d is reachable again", workerId)
+	}
+
+	return p.eventsRepository.Create(ctx, &worker_events_repository.WorkerEventEntity{
+		WorkerId: workerId,
+		Type:     event,
+		Reason:   reason,
+	})
+}
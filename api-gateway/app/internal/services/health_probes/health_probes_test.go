@@ -0,0 +1,62 @@
+package health_probes
+
+import (
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_states"
+)
+
+func TestPolicy_Unreachable(t *testing.T) {
+	type Test struct {
+		name     string
+		missed   int
+		failures int
+		expected bool
+	}
+
+	var policy = &Policy{UnreachableAfter: 3}
+
+	var tt = []Test{
+		{name: "reachable", missed: 0, failures: 0, expected: false},
+		{name: "few_failures", missed: 0, failures: 2, expected: false},
+		{name: "unreachable", missed: 0, failures: 3, expected: true},
+		{name: "silent_worker", missed: 2, failures: 5, expected: false},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			worker := &dto.WorkerResponseDTO{State: worker_states.Active, MissedHeartbeats: test.missed}
+
+			if got := policy.Unreachable(worker, test.failures); got != test.expected {
+				t.Fatalf("expected %v, but got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestPolicy_Probed(t *testing.T) {
+	type Test struct {
+		name     string
+		worker   *dto.WorkerResponseDTO
+		expected bool
+	}
+
+	var policy = &Policy{}
+
+	var tt = []Test{
+		{name: "active", worker: &dto.WorkerResponseDTO{State: worker_states.Active}, expected: true},
+		{name: "suspect", worker: &dto.WorkerResponseDTO{State: worker_states.Suspect}, expected: true},
+		{name: "pull", worker: &dto.WorkerResponseDTO{State: worker_states.Active, Pull: true}, expected: false},
+		{name: "dead", worker: &dto.WorkerResponseDTO{State: worker_states.Dead}, expected: false},
+		{name: "quarantined", worker: &dto.WorkerResponseDTO{State: worker_states.Quarantined}, expected: false},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			if got := policy.Probed(test.worker); got != test.expected {
+				t.Fatalf("expected %v, but got %v", test.expected, got)
+			}
+		})
+	}
+}
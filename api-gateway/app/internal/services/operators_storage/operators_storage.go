@@ -1,14 +1,20 @@
 package operators_storage
 
 import (
+	"context"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/operator_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
 )
 
 type OperatorsStorage interface {
-	SaveAll(operations []*dto.OperationDTO) error
-	FindAll() ([]*dto.OperationDTO, error)
+	SaveAll(ctx context.Context, operations []*dto.OperationDTO) error
+	FindAll(ctx context.Context) ([]*dto.OperationDTO, error)
+	// SaveFor overrides durations of the operations for expressions of the organization
+	SaveFor(ctx context.Context, organizationID uint64, operations []*dto.OperationDTO) error
+	// FindFor returns durations used for expressions of the organization: the global durations
+	// with the overrides of the organization. Zero organizationID returns the global durations
+	FindFor(ctx context.Context, organizationID uint64) ([]*dto.OperationDTO, error)
 }
 
 type operatorsStorage struct {
@@ -19,9 +25,9 @@ func NewOperatorsStorage(repository operator_repository.OperatorsRepository) Ope
 	return &operatorsStorage{repository: repository}
 }
 
-func (o *operatorsStorage) SaveAll(operations []*dto.OperationDTO) error {
+func (o *operatorsStorage) SaveAll(ctx context.Context, operations []*dto.OperationDTO) error {
 	for _, operation := range operations {
-		err := o.repository.Save(&operator_repository.OperatorEntity{
+		err := o.repository.Save(ctx, &operator_repository.OperatorEntity{
 			OperatorType: int(operation.OperationType),
 			DurationMS:   operation.DurationMS,
 		})
@@ -34,8 +40,8 @@ func (o *operatorsStorage) SaveAll(operations []*dto.OperationDTO) error {
 	return nil
 }
 
-func (o *operatorsStorage) FindAll() ([]*dto.OperationDTO, error) {
-	entities, err := o.repository.FindAll()
+func (o *operatorsStorage) FindAll(ctx context.Context) ([]*dto.OperationDTO, error) {
+	entities, err := o.repository.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -50,3 +56,43 @@ func (o *operatorsStorage) FindAll() ([]*dto.OperationDTO, error) {
 
 	return operations, nil
 }
+
+func (o *operatorsStorage) SaveFor(ctx context.Context, organizationID uint64, operations []*dto.OperationDTO) error {
+	for _, operation := range operations {
+		err := o.repository.SaveForOrganization(ctx, organizationID, &operator_repository.OperatorEntity{
+			OperatorType: int(operation.OperationType),
+			DurationMS:   operation.DurationMS,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *operatorsStorage) FindFor(ctx context.Context, organizationID uint64) ([]*dto.OperationDTO, error) {
+	operations, err := o.FindAll(ctx)
+	if err != nil || organizationID == 0 {
+		return operations, err
+	}
+
+	overrides, err := o.repository.FindByOrganization(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var durations = make(map[expr_tokens.OperationType]int, len(overrides))
+	for _, entity := range overrides {
+		durations[expr_tokens.OperationType(entity.OperatorType)] = entity.DurationMS
+	}
+
+	for _, operation := range operations {
+		if duration, ok := durations[operation.OperationType]; ok {
+			operation.DurationMS = duration
+		}
+	}
+
+	return operations, nil
+}
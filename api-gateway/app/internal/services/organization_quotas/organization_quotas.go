@@ -0,0 +1,181 @@
+package organization_quotas
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/organization_quotas_repository"
+)
+
+// dailyWindow is the period limited by the daily quota
+const dailyWindow = 24 * time.Hour
+
+var (
+	ErrQuotaExceeded = errors.New("organization_quotas: quota exceeded")
+	ErrInvalidQuota  = errors.New("organization_quotas: quota must not be negative")
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=ExpressionCounter
+
+// ExpressionCounter counts expressions of organizations
+type ExpressionCounter interface {
+	CountActive(ctx context.Context, organizationID uint64) (int, error)
+	CountCreatedSince(ctx context.Context, organizationID uint64, since time.Time) (int, error)
+}
+
+// Defaults are limits of organizations without their own quota. Zero limits mean no limit
+type Defaults struct {
+	MaxActiveExpressions int
+	MaxDailyExpressions  int
+}
+
+// Quotas limit how many expressions organizations calculate at once and create per day.
+// Expressions of personal workspaces are not limited
+type Quotas interface {
+	// Check returns ErrQuotaExceeded, if the organization may not create one more expression.
+	// It has to be called in the transaction which creates the expression, so concurrent checks
+	// of the organization wait for the transaction and see the created expression
+	Check(ctx context.Context, organizationID uint64) error
+	// Find returns effective limits of the organization and their usage
+	Find(ctx context.Context, organizationID uint64) (*dto.OrganizationQuotaDTO, error)
+	// Save sets limits of the organization. Returns ErrInvalidQuota for negative limits
+	Save(ctx context.Context, organizationID uint64, request *dto.OrganizationQuotaRequestDTO) error
+}
+
+type quotas struct {
+	repository organization_quotas_repository.OrganizationQuotasRepository
+	counter    ExpressionCounter
+	defaults   Defaults
+	now        func() time.Time
+}
+
+func NewQuotas(
+	repository organization_quotas_repository.OrganizationQuotasRepository,
+	counter ExpressionCounter,
+	defaults Defaults,
+) Quotas {
+	return &quotas{
+		repository: repository,
+		counter:    counter,
+		defaults:   defaults,
+		now:        time.Now,
+	}
+}
+
+func (q *quotas) Check(ctx context.Context, organizationID uint64) error {
+	if organizationID == 0 {
+		return nil
+	}
+
+	err := q.repository.Lock(ctx, organizationID)
+	if err != nil {
+		return err
+	}
+
+	maxActive, maxDaily, err := q.limits(ctx, organizationID)
+	if err != nil {
+		return err
+	}
+
+	if maxActive > 0 {
+		active, err := q.counter.CountActive(ctx, organizationID)
+		if err != nil {
+			return err
+		}
+
+		if active >= maxActive {
+			return fmt.Errorf("%w: at most %d expressions may be calculated at once", ErrQuotaExceeded, maxActive)
+		}
+	}
+
+	if maxDaily > 0 {
+		daily, err := q.counter.CountCreatedSince(ctx, organizationID, q.now().Add(-dailyWindow))
+		if err != nil {
+			return err
+		}
+
+		if daily >= maxDaily {
+			return fmt.Errorf("%w: at most %d expressions may be created per day", ErrQuotaExceeded, maxDaily)
+		}
+	}
+
+	return nil
+}
+
+func (q *quotas) Find(ctx context.Context, organizationID uint64) (*dto.OrganizationQuotaDTO, error) {
+	maxActive, maxDaily, err := q.limits(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := q.counter.CountActive(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	daily, err := q.counter.CountCreatedSince(ctx, organizationID, q.now().Add(-dailyWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.OrganizationQuotaDTO{
+		OrganizationID:       organizationID,
+		MaxActiveExpressions: maxActive,
+		MaxDailyExpressions:  maxDaily,
+		ActiveExpressions:    active,
+		DailyExpressions:     daily,
+	}, nil
+}
+
+func (q *quotas) Save(ctx context.Context, organizationID uint64, request *dto.OrganizationQuotaRequestDTO) error {
+	maxActive, err := nullLimit(request.MaxActiveExpressions)
+	if err != nil {
+		return err
+	}
+
+	maxDaily, err := nullLimit(request.MaxDailyExpressions)
+	if err != nil {
+		return err
+	}
+
+	return q.repository.Save(ctx, &organization_quotas_repository.OrganizationQuotaEntity{
+		OrganizationID:       organizationID,
+		MaxActiveExpressions: maxActive,
+		MaxDailyExpressions:  maxDaily,
+	})
+}
+
+func (q *quotas) limits(ctx context.Context, organizationID uint64) (maxActive int, maxDaily int, err error) {
+	entity, err := q.repository.Find(ctx, organizationID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	maxActive, maxDaily = q.defaults.MaxActiveExpressions, q.defaults.MaxDailyExpressions
+
+	if entity.MaxActiveExpressions.Valid {
+		maxActive = int(entity.MaxActiveExpressions.Int32)
+	}
+
+	if entity.MaxDailyExpressions.Valid {
+		maxDaily = int(entity.MaxDailyExpressions.Int32)
+	}
+
+	return maxActive, maxDaily, nil
+}
+
+func nullLimit(limit *int) (sql.NullInt32, error) {
+	if limit == nil {
+		return sql.NullInt32{}, nil
+	}
+
+	if *limit < 0 {
+		return sql.NullInt32{}, ErrInvalidQuota
+	}
+
+	return sql.NullInt32{Int32: int32(*limit), Valid: true}, nil
+}
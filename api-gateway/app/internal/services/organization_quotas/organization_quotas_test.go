@@ -0,0 +1,238 @@
+package organization_quotas
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/organization_quotas_repository"
+	quotasMocks "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/organization_quotas_repository/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/organization_quotas/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const defaultOrganizationID uint64 = 1
+
+var defaults = Defaults{MaxActiveExpressions: 2, MaxDailyExpressions: 10}
+
+type fields struct {
+	repository *quotasMocks.OrganizationQuotasRepository
+	counter    *mocks.ExpressionCounter
+	now        time.Time
+}
+
+func newFields(t *testing.T) *fields {
+	return &fields{
+		repository: quotasMocks.NewOrganizationQuotasRepository(t),
+		counter:    mocks.NewExpressionCounter(t),
+		now:        time.Now(),
+	}
+}
+
+func (f *fields) quotas() *quotas {
+	q := NewQuotas(f.repository, f.counter, defaults).(*quotas)
+	q.now = func() time.Time { return f.now }
+
+	return q
+}
+
+// findQuota expects the own limits of the default organization to be found. Nil limits mean the defaults
+func (f *fields) findQuota(maxActive, maxDaily *int) {
+	f.repository.
+		On("Find", mock.Anything, defaultOrganizationID).
+		Once().
+		Return(&organization_quotas_repository.OrganizationQuotaEntity{
+			OrganizationID:       defaultOrganizationID,
+			MaxActiveExpressions: nullInt(maxActive),
+			MaxDailyExpressions:  nullInt(maxDaily),
+		}, nil)
+}
+
+func (f *fields) countActive(active int) {
+	f.counter.On("CountActive", mock.Anything, defaultOrganizationID).Once().Return(active, nil)
+}
+
+func (f *fields) countDaily(daily int) {
+	f.counter.
+		On("CountCreatedSince", mock.Anything, defaultOrganizationID, f.now.Add(-dailyWindow)).
+		Once().
+		Return(daily, nil)
+}
+
+func limit(value int) *int {
+	return &value
+}
+
+func nullInt(value *int) sql.NullInt32 {
+	if value == nil {
+		return sql.NullInt32{}
+	}
+
+	return sql.NullInt32{Int32: int32(*value), Valid: true}
+}
+
+func TestQuotas_Check(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization uint64
+		prepare      func(f *fields)
+
+		targetErr error
+	}{
+		{
+			name:         "personal_workspace",
+			organization: 0,
+			prepare:      func(f *fields) {},
+		},
+		{
+			name:         "under_defaults",
+			organization: defaultOrganizationID,
+			prepare: func(f *fields) {
+				f.findQuota(nil, nil)
+				f.countActive(1)
+				f.countDaily(9)
+			},
+		},
+		{
+			name:         "err_active_default",
+			organization: defaultOrganizationID,
+			prepare: func(f *fields) {
+				f.findQuota(nil, nil)
+				f.countActive(2)
+			},
+			targetErr: ErrQuotaExceeded,
+		},
+		{
+			name:         "err_daily_default",
+			organization: defaultOrganizationID,
+			prepare: func(f *fields) {
+				f.findQuota(nil, nil)
+				f.countActive(0)
+				f.countDaily(10)
+			},
+			targetErr: ErrQuotaExceeded,
+		},
+		{
+			name:         "own_active",
+			organization: defaultOrganizationID,
+			prepare: func(f *fields) {
+				f.findQuota(limit(5), nil)
+				f.countActive(4)
+				f.countDaily(0)
+			},
+		},
+		{
+			name:         "err_own_active",
+			organization: defaultOrganizationID,
+			prepare: func(f *fields) {
+				f.findQuota(limit(5), nil)
+				f.countActive(5)
+			},
+			targetErr: ErrQuotaExceeded,
+		},
+		{
+			name:         "unlimited",
+			organization: defaultOrganizationID,
+			prepare: func(f *fields) {
+				f.findQuota(limit(0), limit(0))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			if tt.organization != 0 {
+				f.repository.On("Lock", mock.Anything, tt.organization).Once().Return(nil)
+			}
+			tt.prepare(f)
+
+			err := f.quotas().Check(context.Background(), tt.organization)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestQuotas_Save(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *dto.OrganizationQuotaRequestDTO
+		prepare func(f *fields)
+
+		targetErr error
+	}{
+		{
+			name:    "own_active",
+			request: &dto.OrganizationQuotaRequestDTO{MaxActiveExpressions: limit(4)},
+			prepare: func(f *fields) {
+				f.repository.
+					On("Save", mock.Anything, &organization_quotas_repository.OrganizationQuotaEntity{
+						OrganizationID:       defaultOrganizationID,
+						MaxActiveExpressions: sql.NullInt32{Int32: 4, Valid: true},
+					}).
+					Once().
+					Return(nil)
+			},
+		},
+		{
+			name:    "reset_to_defaults",
+			request: &dto.OrganizationQuotaRequestDTO{},
+			prepare: func(f *fields) {
+				f.repository.
+					On("Save", mock.Anything, &organization_quotas_repository.OrganizationQuotaEntity{
+						OrganizationID: defaultOrganizationID,
+					}).
+					Once().
+					Return(nil)
+			},
+		},
+		{
+			name:      "err_negative_limit",
+			request:   &dto.OrganizationQuotaRequestDTO{MaxActiveExpressions: limit(-1)},
+			prepare:   func(f *fields) {},
+			targetErr: ErrInvalidQuota,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			err := f.quotas().Save(context.Background(), defaultOrganizationID, tt.request)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestQuotas_Find(t *testing.T) {
+	f := newFields(t)
+	f.findQuota(limit(4), nil)
+	f.countActive(1)
+	f.countDaily(3)
+
+	quota, err := f.quotas().Find(context.Background(), defaultOrganizationID)
+	require.NoError(t, err)
+	assert.Equal(t, &dto.OrganizationQuotaDTO{
+		OrganizationID:       defaultOrganizationID,
+		MaxActiveExpressions: 4,
+		MaxDailyExpressions:  defaults.MaxDailyExpressions,
+		ActiveExpressions:    1,
+		DailyExpressions:     3,
+	}, quota)
+}
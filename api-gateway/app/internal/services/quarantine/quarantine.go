@@ -0,0 +1,277 @@
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_events_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/statuses"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_states"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+)
+
+var (
+	ErrWorkerNotQuarantined = errors.New("quarantine: worker is not quarantined")
+)
+
+const (
+	QuarantinedEvent = "quarantined"
+	ReleasedEvent    = "released"
+)
+
+// resultTolerance is the relative difference allowed between a worker result and its local recomputation.
+// Results are transferred as float32, so they never match float64 exactly
+const resultTolerance = 1e-6
+
+// Policy describes when a worker is quarantined. Zero fields disable the corresponding check
+type Policy struct {
+	// CrossCheckRate is the share of results which are recomputed by the orchestrator
+	CrossCheckRate float64
+
+	// MinTasks is the number of tasks after which the error rate is taken into account
+	MinTasks int
+
+	// MaxErrorRate is the allowed share of timed out and mismatched tasks
+	MaxErrorRate float64
+
+	// MaxMismatches is the number of mismatched results after which the worker is quarantined at once
+	MaxMismatches int
+}
+
+// Violation returns the reason to quarantine a worker with stats or an empty string
+func (p *Policy) Violation(stats *dto.WorkerStatsDTO) string {
+	if p.MaxMismatches > 0 && stats.ResultsMismatched >= p.MaxMismatches {
+		return fmt.Sprintf("%d mismatched results", stats.ResultsMismatched)
+	}
+
+	total := stats.TasksCompleted + stats.TasksTimedOut
+	if p.MaxErrorRate <= 0 || total == 0 || total < p.MinTasks {
+		return ""
+	}
+
+	rate := float64(stats.TasksTimedOut+stats.ResultsMismatched) / float64(total)
+	if rate > p.MaxErrorRate {
+		return fmt.Sprintf("error rate %.2f exceeds %.2f", rate, p.MaxErrorRate)
+	}
+
+	return ""
+}
+
+// Monitor tracks task outcomes of every worker and quarantines workers which exceed the Policy thresholds.
+// Quarantined workers get no tasks and their assigned tasks are reassigned
+type Monitor interface {
+	CheckResult(ctx context.Context, id int, result float64) (float64, error)
+	RecordTimeouts(ctx context.Context, workerIds []int) error
+	Release(ctx context.Context, workerId int) error
+	Events(ctx context.Context, workerId int) ([]*dto.WorkerEventDTO, error)
+}
+
+type monitor struct {
+	workersStorage    workers_storage.WorkerStorage
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
+	eventsRepository  worker_events_repository.WorkerEventsRepository
+
+	policy *Policy
+}
+
+func NewMonitor(
+	workersStorage workers_storage.WorkerStorage,
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
+	eventsRepository worker_events_repository.WorkerEventsRepository,
+	policy *Policy,
+) Monitor {
+	return &monitor{
+		workersStorage:    workersStorage,
+		binaryTreeStorage: binaryTreeStorage,
+		eventsRepository:  eventsRepository,
+		policy:            policy,
+	}
+}
+
+// CheckResult counts the result of node id for its worker. A sample of results and all non-finite ones
+// are recomputed locally, mismatched results are replaced by the local ones.
+// Returns the result which has to be saved
+func (m *monitor) CheckResult(ctx context.Context, id int, result float64) (float64, error) {
+	node, err := m.binaryTreeStorage.FindById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if node.WorkerId == 0 {
+		return result, nil
+	}
+
+	var mismatched int
+
+	if m.shouldCrossCheck(result) {
+		expected, ok, err := m.recompute(ctx, node)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok && !Matches(expected, result) {
+			log.Printf("worker %d returned %v for task %d, expected %v", node.WorkerId, result, id, expected)
+
+			mismatched = 1
+			result = expected
+		}
+	}
+
+	return result, m.record(ctx, node.WorkerId, 1, 0, mismatched)
+}
+
+// RecordTimeouts counts a timed out task for every id in workerIds
+func (m *monitor) RecordTimeouts(ctx context.Context, workerIds []int) error {
+	var timeouts = make(map[int]int)
+
+	for _, workerId := range workerIds {
+		timeouts[workerId]++
+	}
+
+	for workerId, n := range timeouts {
+		err := m.record(ctx, workerId, 0, n, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Release returns a quarantined worker to scheduling
+//
+// Returns ErrWorkerNotQuarantined, if the worker is not quarantined
+func (m *monitor) Release(ctx context.Context, workerId int) error {
+	ok, err := m.workersStorage.Release(ctx, workerId)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrWorkerNotQuarantined
+	}
+
+	log.Printf("worker %d released from quarantine", workerId)
+
+	return m.eventsRepository.Create(ctx, &worker_events_repository.WorkerEventEntity{
+		WorkerId: workerId,
+		Type:     ReleasedEvent,
+	})
+}
+
+func (m *monitor) Events(ctx context.Context, workerId int) ([]*dto.WorkerEventDTO, error) {
+	entities, err := m.eventsRepository.FindByWorkerId(ctx, workerId)
+	if err != nil {
+		return nil, err
+	}
+
+	var events = []*dto.WorkerEventDTO{}
+
+	for _, entity := range entities {
+		events = append(events, &dto.WorkerEventDTO{
+			Type:      entity.Type,
+			Reason:    entity.Reason,
+			CreatedAt: entity.CreatedAt,
+		})
+	}
+
+	return events, nil
+}
+
+func (m *monitor) record(ctx context.Context, workerId int, completed int, timedOut int, mismatched int) error {
+	stats, err := m.workersStorage.RecordStats(ctx, workerId, completed, timedOut, mismatched)
+	if err != nil {
+		return err
+	}
+
+	if stats.State == worker_states.Quarantined {
+		return nil
+	}
+
+	reason := m.policy.Violation(stats)
+	if reason == "" {
+		return nil
+	}
+
+	return m.quarantine(ctx, workerId, reason)
+}
+
+func (m *monitor) quarantine(ctx context.Context, workerId int, reason string) error {
+	ok, err := m.workersStorage.Quarantine(ctx, workerId, reason)
+	if err != nil || !ok {
+		return err
+	}
+
+	log.Printf("worker %d quarantined: %s", workerId, reason)
+
+	err = m.eventsRepository.Create(ctx, &worker_events_repository.WorkerEventEntity{
+		WorkerId: workerId,
+		Type:     QuarantinedEvent,
+		Reason:   reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.binaryTreeStorage.DeleteWorkers(ctx, []int{workerId})
+}
+
+func (m *monitor) shouldCrossCheck(result float64) bool {
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return true
+	}
+
+	return m.policy.CrossCheckRate > 0 && rand.Float64() < m.policy.CrossCheckRate
+}
+
+// recompute calculates node from its operands. Returns false, if the operands are not available
+func (m *monitor) recompute(ctx context.Context, node *dto.ExpressionNodeDTO) (float64, bool, error) {
+	children, err := m.binaryTreeStorage.FindByParentId(ctx, node.Id)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if len(children) != 2 || children[0].Status != statuses.Finished || children[1].Status != statuses.Finished {
+		return 0, false, nil
+	}
+
+	first, second := children[0].Result, children[1].Result
+
+	switch expr_tokens.OperationType(node.OperationType) {
+	case expr_tokens.Plus:
+		return first + second, true, nil
+	case expr_tokens.Minus:
+		return first - second, true, nil
+	case expr_tokens.Multiply:
+		return first * second, true, nil
+	case expr_tokens.Divide:
+		if second == 0 {
+			return 0, false, nil
+		}
+
+		return first / second, true, nil
+	}
+
+	return 0, false, nil
+}
+
+// Matches reports whether a worker result is equal to the expected one within resultTolerance
+func Matches(expected float64, got float64) bool {
+	if expected == got || (math.IsNaN(expected) && math.IsNaN(got)) {
+		return true
+	}
+
+	// results are transferred as float32, so values beyond its range become infinite
+	if math.IsInf(got, 0) && math.Abs(expected) > math.MaxFloat32 {
+		return math.Signbit(expected) == math.Signbit(got)
+	}
+
+	return math.Abs(expected-got) <= resultTolerance*math.Max(math.Abs(expected), math.Abs(got))
+}
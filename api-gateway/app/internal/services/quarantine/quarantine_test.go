@@ -0,0 +1,82 @@
+package quarantine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+)
+
+func TestPolicy_Violation(t *testing.T) {
+	type Test struct {
+		name     string
+		stats    *dto.WorkerStatsDTO
+		violates bool
+	}
+
+	var policy = &Policy{
+		MinTasks:      10,
+		MaxErrorRate:  0.3,
+		MaxMismatches: 3,
+	}
+
+	var tt = []Test{
+		{
+			name:     "healthy",
+			stats:    &dto.WorkerStatsDTO{TasksCompleted: 20, TasksTimedOut: 1},
+			violates: false,
+		},
+
+		{
+			name:     "too_few_tasks",
+			stats:    &dto.WorkerStatsDTO{TasksCompleted: 1, TasksTimedOut: 5},
+			violates: false,
+		},
+
+		{
+			name:     "timeouts",
+			stats:    &dto.WorkerStatsDTO{TasksCompleted: 6, TasksTimedOut: 4},
+			violates: true,
+		},
+
+		{
+			name:     "mismatches",
+			stats:    &dto.WorkerStatsDTO{TasksCompleted: 3, ResultsMismatched: 3},
+			violates: true,
+		},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			if got := policy.Violation(test.stats) != ""; got != test.violates {
+				t.Fatalf("expected %v, but got %v", test.violates, got)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	type Test struct {
+		name     string
+		expected float64
+		got      float64
+		matches  bool
+	}
+
+	var tt = []Test{
+		{name: "equal", expected: 4, got: 4, matches: true},
+		{name: "float32_rounding", expected: 0.1 + 0.2, got: float64(float32(0.1 + 0.2)), matches: true},
+		{name: "nan", expected: math.NaN(), got: math.NaN(), matches: true},
+		{name: "float32_overflow", expected: 1e300, got: math.Inf(1), matches: true},
+		{name: "garbage", expected: 4, got: 5, matches: false},
+		{name: "unexpected_nan", expected: 4, got: math.NaN(), matches: false},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Matches(test.expected, test.got); got != test.matches {
+				t.Fatalf("expected %v, but got %v", test.matches, got)
+			}
+		})
+	}
+}
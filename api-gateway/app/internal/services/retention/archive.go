@@ -0,0 +1,89 @@
+package retention
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+)
+
+// Record is an archived expression with its tree and task attempts
+type Record struct {
+	Scope      string                     `json:"scope"`
+	Expression *dto.ExpressionResponseDTO `json:"expression"`
+	Nodes      []*dto.ExpressionNodeDTO   `json:"nodes"`
+	Attempts   []*dto.TaskAttemptDTO      `json:"attempts"`
+}
+
+// Archive stores records before they are deleted from the database
+type Archive interface {
+	Write(records []*Record) error
+}
+
+type fileArchive struct {
+	dir string
+}
+
+// NewFileArchive creates an archive which writes every batch of records
+// to a separate gzip compressed JSON lines file in dir
+func NewFileArchive(dir string) Archive {
+	return &fileArchive{dir: dir}
+}
+
+// Write writes records to a temporary file and renames it when all records are written,
+// so the archive never contains partial files
+func (a *fileArchive) Write(records []*Record) (err error) {
+	if len(records) == 0 {
+		return nil
+	}
+
+	err = os.MkdirAll(a.dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(a.dir, fmt.Sprintf(
+		"expressions-%s-%d.jsonl.gz",
+		time.Now().UTC().Format("20060102T150405Z"),
+		records[0].Expression.Id,
+	))
+
+	file, err := os.CreateTemp(a.dir, ".expressions-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	zw := gzip.NewWriter(file)
+	encoder := json.NewEncoder(zw)
+
+	for _, record := range records {
+		err = encoder.Encode(record)
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), name)
+}
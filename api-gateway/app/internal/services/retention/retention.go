@@ -0,0 +1,266 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expressions_storage"
+)
+
+const (
+	// TreeScope marks archived trees of expressions which are kept
+	TreeScope = "tree"
+	// ExpressionScope marks archived expressions which have been deleted entirely
+	ExpressionScope = "expression"
+)
+
+const defaultBatchSize = 100
+
+// ErrInvalidPolicy is returned for policies which delete failed expressions before finished ones
+var ErrInvalidPolicy = errors.New("retention: failed expressions must be kept at least as long as finished ones")
+
+// Policy describes how long finished expressions are kept. Zero values disable the limits
+type Policy struct {
+	// TreeMaxAge is the time after which trees of finished expressions are deleted. The expressions themselves are kept
+	TreeMaxAge time.Duration
+	// MaxAge is the time after which finished expressions are deleted
+	MaxAge time.Duration
+	// MaxPerUser is the number of the newest finished expressions kept for every user
+	MaxPerUser int
+	// FailedMaxAge is the time for which failed expressions are kept together with their trees regardless of the other limits
+	FailedMaxAge time.Duration
+	// BatchSize limits the number of expressions deleted at once
+	BatchSize int
+}
+
+// Validate returns ErrInvalidPolicy, if FailedMaxAge is set and is shorter than MaxAge.
+// Zero MaxAge keeps finished expressions forever, so any FailedMaxAge is shorter
+func (p *Policy) Validate() error {
+	if p.FailedMaxAge <= 0 {
+		return nil
+	}
+
+	if p.MaxAge <= 0 || p.FailedMaxAge < p.MaxAge {
+		return ErrInvalidPolicy
+	}
+
+	return nil
+}
+
+// TreesBefore returns the time before which trees of finished expressions are deleted
+func (p *Policy) TreesBefore(now time.Time) (time.Time, bool) {
+	if p.TreeMaxAge <= 0 {
+		return time.Time{}, false
+	}
+
+	return now.Add(-p.TreeMaxAge), true
+}
+
+// FinishedBefore returns the time before which finished expressions are deleted, nil if they are kept forever
+func (p *Policy) FinishedBefore(now time.Time) *time.Time {
+	return before(now, p.MaxAge)
+}
+
+// FailedBefore returns the time before which failed expressions are deleted.
+// Nil means that failed expressions follow the same limits as finished ones
+func (p *Policy) FailedBefore(now time.Time) *time.Time {
+	return before(now, p.FailedMaxAge)
+}
+
+func (p *Policy) batchSize() int {
+	if p.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+
+	return p.BatchSize
+}
+
+func before(now time.Time, age time.Duration) *time.Time {
+	if age <= 0 {
+		return nil
+	}
+
+	t := now.Add(-age)
+	return &t
+}
+
+// Report describes a single compaction
+type Report struct {
+	// Compacted is the number of expressions which trees have been deleted
+	Compacted int
+	// Deleted is the number of deleted expressions
+	Deleted int
+}
+
+// Compactor deletes expressions and their trees according to the retention policy
+type Compactor interface {
+	Compact(ctx context.Context, now time.Time) (*Report, error)
+}
+
+type compactor struct {
+	transactor        postgres.Transactor
+	expressionStorage expressions_storage.ExpressionStorage
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
+	archive           Archive
+
+	policy *Policy
+}
+
+// NewCompactor creates a compactor. Deleted rows are written to archive before deletion, nil archive disables archiving
+//
+// Returns ErrInvalidPolicy, if the policy is not valid
+func NewCompactor(
+	transactor postgres.Transactor,
+	expressionStorage expressions_storage.ExpressionStorage,
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
+	archive Archive,
+	policy *Policy,
+) (Compactor, error) {
+	err := policy.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &compactor{
+		transactor:        transactor,
+		expressionStorage: expressionStorage,
+		binaryTreeStorage: binaryTreeStorage,
+		archive:           archive,
+		policy:            policy,
+	}, nil
+}
+
+// Compact deletes expired expressions first and then trees of the remaining ones,
+// so that trees are not archived twice
+func (c *compactor) Compact(ctx context.Context, now time.Time) (*Report, error) {
+	var (
+		report = &Report{}
+		batch  = c.policy.batchSize()
+	)
+
+	for {
+		ids, err := c.expressionStorage.FindExpired(
+			ctx,
+			c.policy.FinishedBefore(now),
+			c.policy.FailedBefore(now),
+			c.policy.MaxPerUser,
+			batch,
+		)
+		if err != nil {
+			return report, err
+		}
+
+		if len(ids) == 0 {
+			break
+		}
+
+		err = c.deleteExpressions(ctx, ids)
+		if err != nil {
+			return report, err
+		}
+
+		report.Deleted += len(ids)
+
+		if len(ids) < batch {
+			break
+		}
+	}
+
+	treesBefore, ok := c.policy.TreesBefore(now)
+	if !ok {
+		return report, nil
+	}
+
+	for {
+		ids, err := c.expressionStorage.FindCompactable(ctx, treesBefore, c.policy.FailedBefore(now), batch)
+		if err != nil {
+			return report, err
+		}
+
+		if len(ids) == 0 {
+			break
+		}
+
+		err = c.deleteTrees(ctx, ids)
+		if err != nil {
+			return report, err
+		}
+
+		report.Compacted += len(ids)
+
+		if len(ids) < batch {
+			break
+		}
+	}
+
+	return report, nil
+}
+
+func (c *compactor) deleteExpressions(ctx context.Context, ids []int) error {
+	err := c.archiveExpressions(ctx, ExpressionScope, ids)
+	if err != nil {
+		return err
+	}
+
+	return c.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := c.binaryTreeStorage.DeleteByExpressionIds(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		return c.expressionStorage.Delete(ctx, ids)
+	})
+}
+
+func (c *compactor) deleteTrees(ctx context.Context, ids []int) error {
+	err := c.archiveExpressions(ctx, TreeScope, ids)
+	if err != nil {
+		return err
+	}
+
+	return c.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := c.binaryTreeStorage.DeleteByExpressionIds(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		return c.expressionStorage.MarkAsCompacted(ctx, ids)
+	})
+}
+
+func (c *compactor) archiveExpressions(ctx context.Context, scope string, ids []int) error {
+	if c.archive == nil {
+		return nil
+	}
+
+	var records []*Record
+
+	for _, id := range ids {
+		expression, err := c.expressionStorage.FindById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		nodes, err := c.binaryTreeStorage.FindByExpressionId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		attempts, err := c.binaryTreeStorage.FindAttempts(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		records = append(records, &Record{
+			Scope:      scope,
+			Expression: expression,
+			Nodes:      nodes,
+			Attempts:   attempts,
+		})
+	}
+
+	return c.archive.Write(records)
+}
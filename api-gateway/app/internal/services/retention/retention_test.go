@@ -0,0 +1,150 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+)
+
+func TestPolicy_Cutoffs(t *testing.T) {
+	type Test struct {
+		name           string
+		policy         *Policy
+		trees          bool
+		finishedBefore *time.Time
+		failedBefore   *time.Time
+	}
+
+	var (
+		now     = time.Date(2024, 2, 16, 12, 0, 0, 0, time.UTC)
+		hourAgo = now.Add(-time.Hour)
+		dayAgo  = now.Add(-24 * time.Hour)
+	)
+
+	var tt = []Test{
+		{name: "disabled", policy: &Policy{}},
+		{name: "trees_only", policy: &Policy{TreeMaxAge: time.Hour}, trees: true},
+		{
+			name:           "all_limits",
+			policy:         &Policy{TreeMaxAge: time.Hour, MaxAge: time.Hour, FailedMaxAge: 24 * time.Hour},
+			trees:          true,
+			finishedBefore: &hourAgo,
+			failedBefore:   &dayAgo,
+		},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			treesBefore, trees := test.policy.TreesBefore(now)
+			if trees != test.trees {
+				t.Fatalf("expected %v, but got %v", test.trees, trees)
+			}
+
+			if trees && !treesBefore.Equal(hourAgo) {
+				t.Fatalf("expected %v, but got %v", hourAgo, treesBefore)
+			}
+
+			if got := test.policy.FinishedBefore(now); !equalTimes(got, test.finishedBefore) {
+				t.Fatalf("expected %v, but got %v", test.finishedBefore, got)
+			}
+
+			if got := test.policy.FailedBefore(now); !equalTimes(got, test.failedBefore) {
+				t.Fatalf("expected %v, but got %v", test.failedBefore, got)
+			}
+		})
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	type Test struct {
+		name   string
+		policy *Policy
+		err    error
+	}
+
+	var tt = []Test{
+		{name: "disabled", policy: &Policy{}},
+		{name: "failed_as_finished", policy: &Policy{MaxAge: time.Hour}},
+		{name: "failed_longer", policy: &Policy{MaxAge: time.Hour, FailedMaxAge: 24 * time.Hour}},
+		{name: "failed_equal", policy: &Policy{MaxAge: time.Hour, FailedMaxAge: time.Hour}},
+		{name: "failed_shorter", policy: &Policy{MaxAge: 24 * time.Hour, FailedMaxAge: time.Hour}, err: ErrInvalidPolicy},
+		{name: "finished_forever", policy: &Policy{FailedMaxAge: time.Hour}, err: ErrInvalidPolicy},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.policy.Validate(); !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, but got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestFileArchive_Write(t *testing.T) {
+	dir := t.TempDir()
+	archive := NewFileArchive(dir)
+
+	var records = []*Record{
+		{Scope: ExpressionScope, Expression: &dto.ExpressionResponseDTO{Id: 1, Expression: "2+2"}},
+		{Scope: TreeScope, Expression: &dto.ExpressionResponseDTO{Id: 2, Expression: "2*2"}},
+	}
+
+	err := archive.Write(records)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("expected %v, but got %v", 1, len(files))
+	}
+
+	file, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	var ids []int
+
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var record Record
+
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			t.Fatalf("expected %v, but got %v", nil, err)
+		}
+
+		ids = append(ids, record.Expression.Id)
+	}
+
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("expected %v, but got %v", []int{1, 2}, ids)
+	}
+}
+
+func equalTimes(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
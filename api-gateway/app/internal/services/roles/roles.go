@@ -0,0 +1,295 @@
+package roles
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/api_keys"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/sessions"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/signing_keys"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_credentials"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jwt"
+)
+
+type Role string
+
+const (
+	// User can calculate expressions and read their own ones
+	User Role = "user"
+	// Operator can also watch workers and change durations of operations
+	Operator Role = "operator"
+	// Admin can also administrate workers
+	Admin Role = "admin"
+	// Worker is a daemon which calculates tasks. It has no access to the user API
+	Worker Role = "worker"
+)
+
+// Roles of members of organizations issued by the auth service
+const (
+	OrganizationOwner  = "owner"
+	OrganizationAdmin  = "admin"
+	OrganizationMember = "member"
+)
+
+// Scope limits requests of a caller authenticated with an api key
+type Scope string
+
+const (
+	// ScopeAll permits every request allowed by the role of the caller
+	ScopeAll Scope = "all"
+	// ScopeRead permits only requests which read data
+	ScopeRead Scope = "read"
+	// ScopeSubmit permits only submitting expressions
+	ScopeSubmit Scope = "submit"
+)
+
+// Action is the kind of a request checked against the scope of the caller
+type Action int
+
+const (
+	ActionRead Action = iota
+	ActionSubmit
+	ActionWrite
+)
+
+var (
+	ErrUnauthenticated   = errors.New("roles: invalid credentials")
+	ErrForbidden         = errors.New("roles: access denied")
+	ErrSessionRevoked    = errors.New("roles: session has been revoked")
+	ErrInsufficientScope = errors.New("roles: api key scope does not permit the request")
+)
+
+// Grants reports whether r has access to methods available for role.
+// Every admin is an operator and every operator is a user
+func (r Role) Grants(role Role) bool {
+	switch r {
+	case Admin:
+		return role == Admin || role == Operator || role == User
+	case Operator:
+		return role == Operator || role == User
+	default:
+		return r == role
+	}
+}
+
+// Permits reports whether s allows requests of the action. Unknown scopes permit nothing
+func (s Scope) Permits(action Action) bool {
+	switch s {
+	case ScopeAll:
+		return true
+	case ScopeRead:
+		return action == ActionRead
+	case ScopeSubmit:
+		return action == ActionSubmit
+	default:
+		return false
+	}
+}
+
+// Principal is an authenticated caller
+type Principal struct {
+	// UserID and Login are empty for workers
+	UserID uint64
+	Login  string
+	Role   Role
+	// Scope is ScopeAll for every caller except those authenticated with an api key
+	Scope Scope
+	// CredentialID is the id of the worker credential issued by the auth service.
+	// It is empty for users and for workers authenticated with the shared worker token
+	CredentialID uint64
+	// APIKeyID is the id of the api key the user is authenticated with
+	APIKeyID uint64
+	// OrganizationID is the active organization of the user, zero for the personal workspace.
+	// OrganizationRole is the role of the user in it. Api keys always work in the personal workspace
+	OrganizationID   uint64
+	OrganizationRole string
+}
+
+// Policy assigns roles to callers. Users which are not listed get the User role.
+// Roles are granted by login, which is safe because the auth service never gives out logins of deleted accounts again
+type Policy struct {
+	AdminLogins    []string
+	OperatorLogins []string
+	// WorkerToken is the bearer token shared by daemons. Empty token allows daemons to authenticate only with worker credentials
+	WorkerToken string
+}
+
+type Authorizer interface {
+	// Authenticate returns the caller owning an access token, a worker credential or the worker token
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+	// AuthenticateAPIKey returns the user owning an api key, limited to the scope of the key
+	AuthenticateAPIKey(ctx context.Context, key string) (*Principal, error)
+	// Authorize returns ErrForbidden if the principal has none of the roles
+	Authorize(principal *Principal, roles ...Role) error
+	// AuthorizeScope returns ErrForbidden if the scope of the principal does not permit the action
+	AuthorizeScope(principal *Principal, action Action) error
+}
+
+type authorizer struct {
+	tokensGenerator *jwt.TokenGenerator
+	credentials     worker_credentials.Verifier
+	revocations     sessions.RevocationChecker
+	apiKeys         api_keys.Verifier
+	policy          *Policy
+}
+
+// NewAuthorizer creates an authorizer. Worker credentials are checked with credentials, nil disables them.
+// Access tokens of revoked sessions are rejected with revocations, nil accepts every token until it expires.
+// Api keys are checked with apiKeys, nil disables them
+func NewAuthorizer(
+	tokensGenerator *jwt.TokenGenerator,
+	credentials worker_credentials.Verifier,
+	revocations sessions.RevocationChecker,
+	apiKeys api_keys.Verifier,
+	policy *Policy,
+) Authorizer {
+	return &authorizer{
+		tokensGenerator: tokensGenerator,
+		credentials:     credentials,
+		revocations:     revocations,
+		apiKeys:         apiKeys,
+		policy:          policy,
+	}
+}
+
+func (a *authorizer) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	if a.policy.WorkerToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.policy.WorkerToken)) == 1 {
+		return &Principal{Role: Worker, Scope: ScopeAll}, nil
+	}
+
+	if strings.HasPrefix(token, worker_credentials.TokenPrefix) {
+		return a.authenticateWorker(ctx, token)
+	}
+
+	claims, err := a.tokensGenerator.ParseClaims(token)
+	if errors.Is(err, signing_keys.ErrUnavailable) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, errors.Join(ErrUnauthenticated, err)
+	}
+
+	if a.revocations != nil && claims.SessionID != "" {
+		revoked, err := a.revocations.IsRevoked(ctx, claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if revoked {
+			return nil, errors.Join(ErrUnauthenticated, ErrSessionRevoked)
+		}
+	}
+
+	return &Principal{
+		UserID:           claims.UserID,
+		Login:            claims.Login,
+		Role:             a.role(claims.Login),
+		Scope:            ScopeAll,
+		OrganizationID:   claims.OrganizationID,
+		OrganizationRole: claims.OrganizationRole,
+	}, nil
+}
+
+func (a *authorizer) AuthenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+	if key == "" || a.apiKeys == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	verified, err := a.apiKeys.Verify(ctx, key)
+	if errors.Is(err, api_keys.ErrInvalidKey) {
+		return nil, errors.Join(ErrUnauthenticated, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		UserID:   verified.UserId,
+		Login:    verified.Login,
+		Role:     a.role(verified.Login),
+		Scope:    Scope(verified.Scope),
+		APIKeyID: verified.Id,
+	}, nil
+}
+
+func (a *authorizer) authenticateWorker(ctx context.Context, token string) (*Principal, error) {
+	if a.credentials == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	credential, err := a.credentials.Verify(ctx, token)
+	if errors.Is(err, worker_credentials.ErrInvalidCredential) {
+		return nil, errors.Join(ErrUnauthenticated, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		Role:         Worker,
+		Scope:        ScopeAll,
+		CredentialID: credential.Id,
+	}, nil
+}
+
+func (a *authorizer) Authorize(principal *Principal, roles ...Role) error {
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+
+	for _, role := range roles {
+		if principal.Role.Grants(role) {
+			return nil
+		}
+	}
+
+	return ErrForbidden
+}
+
+func (a *authorizer) AuthorizeScope(principal *Principal, action Action) error {
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+
+	if !principal.Scope.Permits(action) {
+		return errors.Join(ErrForbidden, ErrInsufficientScope)
+	}
+
+	return nil
+}
+
+func (a *authorizer) role(login string) Role {
+	switch {
+	case login == "":
+		return User
+	case slices.Contains(a.policy.AdminLogins, login):
+		return Admin
+	case slices.Contains(a.policy.OperatorLogins, login):
+		return Operator
+	default:
+		return User
+	}
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated caller
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the caller stored by WithPrincipal
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
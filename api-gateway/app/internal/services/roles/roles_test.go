@@ -0,0 +1,137 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/api_keys"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_credentials"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jwt"
+)
+
+type revocationChecker map[string]bool
+
+func (c revocationChecker) IsRevoked(_ context.Context, sessionId string) (bool, error) {
+	return c[sessionId], nil
+}
+
+type credentialsVerifier map[string]uint64
+
+func (v credentialsVerifier) Verify(_ context.Context, token string) (*worker_credentials.Credential, error) {
+	id, ok := v[token]
+	if !ok {
+		return nil, worker_credentials.ErrInvalidCredential
+	}
+
+	return &worker_credentials.Credential{Id: id}, nil
+}
+
+type apiKeysVerifier map[string]*api_keys.Key
+
+func (v apiKeysVerifier) Verify(_ context.Context, key string) (*api_keys.Key, error) {
+	verified, ok := v[key]
+	if !ok {
+		return nil, api_keys.ErrInvalidKey
+	}
+
+	return verified, nil
+}
+
+func TestAuthorize(t *testing.T) {
+	type Test struct {
+		name      string
+		login     string
+		sessionId string
+		token     string
+		apiKey    string
+		allowed   []Role
+		action    Action
+		err       error
+	}
+
+	key, err := jwt.GenerateKey(jwt.AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	var (
+		tokensGenerator = &jwt.TokenGenerator{Keys: jwt.StaticKeys{key}, TokenTTL: time.Hour}
+		authorizer      = NewAuthorizer(tokensGenerator, credentialsVerifier{"wrk_issued": 1}, revocationChecker{"revoked": true}, apiKeysVerifier{
+			"dck_all":    {Id: 1, UserId: 1, Login: "user", Scope: string(ScopeAll)},
+			"dck_read":   {Id: 2, UserId: 1, Login: "user", Scope: string(ScopeRead)},
+			"dck_submit": {Id: 3, UserId: 1, Login: "user", Scope: string(ScopeSubmit)},
+			"dck_admin":  {Id: 4, UserId: 2, Login: "admin", Scope: string(ScopeRead)},
+		}, &Policy{
+			AdminLogins:    []string{"admin"},
+			OperatorLogins: []string{"operator"},
+			WorkerToken:    "worker-token",
+		})
+	)
+
+	var tt = []Test{
+		{name: "user", login: "user", allowed: []Role{User}},
+		{name: "user_as_operator", login: "user", allowed: []Role{Operator}, err: ErrForbidden},
+		{name: "operator_as_user", login: "operator", allowed: []Role{User}},
+		{name: "operator_as_admin", login: "operator", allowed: []Role{Admin}, err: ErrForbidden},
+		{name: "admin_as_operator", login: "admin", allowed: []Role{Operator}},
+		{name: "admin_as_worker", login: "admin", allowed: []Role{Worker}, err: ErrForbidden},
+		{name: "worker", token: "worker-token", allowed: []Role{User, Worker}},
+		{name: "worker_as_user", token: "worker-token", allowed: []Role{User}, err: ErrForbidden},
+		{name: "invalid_token", token: "worker", allowed: []Role{Worker}, err: ErrUnauthenticated},
+		{name: "worker_credential", token: "wrk_issued", allowed: []Role{Worker}},
+		{name: "worker_credential_as_operator", token: "wrk_issued", allowed: []Role{Operator}, err: ErrForbidden},
+		{name: "revoked_worker_credential", token: "wrk_revoked", allowed: []Role{Worker}, err: ErrUnauthenticated},
+		{name: "active_session", login: "user", sessionId: "active", allowed: []Role{User}},
+		{name: "revoked_session", login: "user", sessionId: "revoked", allowed: []Role{User}, err: ErrSessionRevoked},
+		{name: "user_writes", login: "user", allowed: []Role{User}, action: ActionWrite},
+		{name: "api_key_all", apiKey: "dck_all", allowed: []Role{User}, action: ActionWrite},
+		{name: "api_key_read", apiKey: "dck_read", allowed: []Role{User}, action: ActionRead},
+		{name: "api_key_read_submits", apiKey: "dck_read", allowed: []Role{User}, action: ActionSubmit, err: ErrInsufficientScope},
+		{name: "api_key_submit", apiKey: "dck_submit", allowed: []Role{User}, action: ActionSubmit},
+		{name: "api_key_submit_reads", apiKey: "dck_submit", allowed: []Role{User}, action: ActionRead, err: ErrForbidden},
+		{name: "api_key_as_operator", apiKey: "dck_all", allowed: []Role{Operator}, err: ErrForbidden},
+		{name: "admin_api_key_as_operator", apiKey: "dck_admin", allowed: []Role{Operator}, action: ActionRead},
+		{name: "admin_api_key_writes", apiKey: "dck_admin", allowed: []Role{Admin}, action: ActionWrite, err: ErrInsufficientScope},
+		{name: "revoked_api_key", apiKey: "dck_revoked", allowed: []Role{User}, err: ErrUnauthenticated},
+		{name: "worker_token_as_api_key", apiKey: "worker-token", allowed: []Role{Worker}, err: ErrUnauthenticated},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			token := test.token
+			if test.login != "" {
+				var err error
+
+				token, err = tokensGenerator.NewToken(&jwt.Claims{UserID: 1, Login: test.login, SessionID: test.sessionId})
+				if err != nil {
+					t.Fatalf("expected %v, but got %v", nil, err)
+				}
+			}
+
+			var (
+				principal *Principal
+				err       error
+			)
+
+			if test.apiKey != "" {
+				principal, err = authorizer.AuthenticateAPIKey(context.Background(), test.apiKey)
+			} else {
+				principal, err = authorizer.Authenticate(context.Background(), token)
+			}
+
+			if err == nil {
+				err = authorizer.Authorize(principal, test.allowed...)
+			}
+
+			if err == nil {
+				err = authorizer.AuthorizeScope(principal, test.action)
+			}
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, but got %v", test.err, err)
+			}
+		})
+	}
+}
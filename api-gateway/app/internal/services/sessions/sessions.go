@@ -0,0 +1,132 @@
+package sessions
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jsoncodec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// isRevokedMethod is served by the auth service
+const isRevokedMethod = "/auth.v1.Sessions/IsRevoked"
+
+type RevocationChecker interface {
+	// IsRevoked reports whether access tokens of the session have been revoked by logout or refresh token reuse
+	IsRevoked(ctx context.Context, sessionId string) (bool, error)
+}
+
+type isRevokedRequestDTO struct {
+	SessionId string `json:"sessionId"`
+}
+
+type isRevokedResponseDTO struct {
+	Revoked bool `json:"revoked"`
+}
+
+type gRPCRevocationChecker struct {
+	host                 string
+	transportCredentials credentials.TransportCredentials
+	serviceCredentials   credentials.PerRPCCredentials
+}
+
+// NewGRPCRevocationChecker checks sessions with the auth service at host.
+// serviceCredentials authenticate the api-gateway, the method is internal
+func NewGRPCRevocationChecker(
+	host string,
+	transportCredentials credentials.TransportCredentials,
+	serviceCredentials credentials.PerRPCCredentials,
+) RevocationChecker {
+	return &gRPCRevocationChecker{
+		host:                 host,
+		transportCredentials: transportCredentials,
+		serviceCredentials:   serviceCredentials,
+	}
+}
+
+func (g *gRPCRevocationChecker) IsRevoked(ctx context.Context, sessionId string) (bool, error) {
+	cc, err := grpc.DialContext(
+		ctx,
+		g.host,
+		grpc.WithTransportCredentials(g.transportCredentials),
+		grpc.WithPerRPCCredentials(g.serviceCredentials),
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer cc.Close()
+
+	var resp = &isRevokedResponseDTO{}
+
+	err = cc.Invoke(ctx, isRevokedMethod, &isRevokedRequestDTO{
+		SessionId: sessionId,
+	}, resp, grpc.CallContentSubtype(jsoncodec.Name))
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Revoked, nil
+}
+
+type cacheEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+type cachedRevocationChecker struct {
+	mu      *sync.Mutex
+	checker RevocationChecker
+	ttl     time.Duration
+	now     func() time.Time
+
+	entries map[string]*cacheEntry
+}
+
+// NewCachedRevocationChecker remembers results of checker for ttl, so the auth service is not called on every request.
+// An access token is rejected at most ttl after its session has been revoked
+func NewCachedRevocationChecker(checker RevocationChecker, ttl time.Duration) RevocationChecker {
+	return &cachedRevocationChecker{
+		mu:      &sync.Mutex{},
+		checker: checker,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+func (c *cachedRevocationChecker) IsRevoked(ctx context.Context, sessionId string) (bool, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[sessionId]
+	c.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := c.checker.IsRevoked(ctx, sessionId)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+
+	c.entries[sessionId] = &cacheEntry{
+		revoked:   revoked,
+		expiresAt: now.Add(c.ttl),
+	}
+
+	return revoked, nil
+}
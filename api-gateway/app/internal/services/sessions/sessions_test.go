@@ -0,0 +1,67 @@
+package sessions
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type countingChecker struct {
+	calls   int
+	revoked bool
+}
+
+func (c *countingChecker) IsRevoked(_ context.Context, _ string) (bool, error) {
+	c.calls++
+	return c.revoked, nil
+}
+
+func TestCachedRevocationChecker(t *testing.T) {
+	type Test struct {
+		name    string
+		after   time.Duration
+		revoked bool
+		calls   int
+		want    bool
+	}
+
+	var tt = []Test{
+		{name: "cached", after: time.Second, calls: 1},
+		{name: "revoked_before_expiration", after: time.Second, revoked: true, calls: 1},
+		{name: "expired", after: time.Minute, calls: 2},
+		{name: "revoked_after_expiration", after: time.Minute, revoked: true, calls: 2, want: true},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				now     = time.Now()
+				checker = &countingChecker{}
+				cached  = NewCachedRevocationChecker(checker, 10*time.Second).(*cachedRevocationChecker)
+			)
+
+			cached.now = func() time.Time { return now }
+
+			_, err := cached.IsRevoked(context.Background(), "session")
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			now = now.Add(test.after)
+			checker.revoked = test.revoked
+
+			revoked, err := cached.IsRevoked(context.Background(), "session")
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			if revoked != test.want {
+				t.Fatalf("expected %v, but got %v", test.want, revoked)
+			}
+
+			if checker.calls != test.calls {
+				t.Fatalf("expected %v, but got %v", test.calls, checker.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package signing_keys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jwks"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jwt"
+)
+
+// minFetchInterval limits fetches caused by unknown key ids, so forged tokens cannot flood the auth service
+const minFetchInterval = 10 * time.Second
+
+const fetchTimeout = 5 * time.Second
+
+var ErrUnavailable = errors.New("signing_keys: key set is unavailable")
+
+type jwksKeyProvider struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu          *sync.Mutex
+	keys        map[string]*jwt.Key
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetchErr    error
+}
+
+// NewJWKSKeyProvider verifies tokens with the keys published by the auth service at url.
+// The key set is fetched again after ttl or when a token is signed with an unknown key.
+// Known keys are used while the auth service is unavailable
+func NewJWKSKeyProvider(url string, client *http.Client, ttl time.Duration) jwt.KeyProvider {
+	return &jwksKeyProvider{
+		url:    url,
+		client: client,
+		ttl:    ttl,
+		now:    time.Now,
+		mu:     &sync.Mutex{},
+		keys:   map[string]*jwt.Key{},
+	}
+}
+
+// SigningKey always fails, since the api-gateway only verifies tokens
+func (p *jwksKeyProvider) SigningKey() (*jwt.Key, error) {
+	return nil, jwt.ErrKeyNotFound
+}
+
+// Key returns ErrUnavailable, if the key is unknown and the key set cannot be fetched
+func (p *jwksKeyProvider) Key(id string) (*jwt.Key, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+
+	key, ok := p.keys[id]
+	if ok && now.Before(p.fetchedAt.Add(p.ttl)) {
+		return key, nil
+	}
+
+	if now.Sub(p.attemptedAt) >= minFetchInterval {
+		p.attemptedAt = now
+		p.fetchErr = p.fetch()
+
+		if p.fetchErr == nil {
+			p.fetchedAt = now
+		}
+
+		key, ok = p.keys[id]
+	}
+
+	if ok {
+		return key, nil
+	}
+
+	if p.fetchErr != nil {
+		return nil, errors.Join(ErrUnavailable, p.fetchErr)
+	}
+
+	return nil, jwt.ErrKeyNotFound
+}
+
+func (p *jwksKeyProvider) fetch() error {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set = &jwks.Set{}
+
+	err = json.NewDecoder(resp.Body).Decode(set)
+	if err != nil {
+		return err
+	}
+
+	var keys = make(map[string]*jwt.Key, len(set.Keys))
+
+	for _, key := range set.Keys {
+		publicKey, err := key.PublicKey()
+		if errors.Is(err, jwks.ErrUnsupportedKey) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		keys[key.KeyID] = &jwt.Key{
+			ID:        key.KeyID,
+			Algorithm: key.Algorithm,
+			PublicKey: publicKey,
+		}
+	}
+
+	p.keys = keys
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package signing_keys
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jwks"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jwt"
+)
+
+func TestJWKSKeyProvider(t *testing.T) {
+	type Test struct {
+		name string
+		// rotated publishes a new key after the first fetch
+		rotated bool
+		after   time.Duration
+		// unavailable makes the auth service fail after the first fetch
+		unavailable bool
+		unknown     bool
+		fetches     int
+		err         error
+	}
+
+	var tt = []Test{
+		{name: "cached", after: time.Second, fetches: 1},
+		{name: "expired", after: 10 * time.Minute, fetches: 2},
+		{name: "rotated", rotated: true, after: time.Minute, fetches: 2},
+		{name: "rotated_recently", rotated: true, after: time.Second, fetches: 1, err: jwt.ErrKeyNotFound},
+		{name: "unknown", unknown: true, after: time.Minute, fetches: 2, err: jwt.ErrKeyNotFound},
+		{name: "unavailable_known_key", unavailable: true, after: 10 * time.Minute, fetches: 2},
+		{name: "unavailable_rotated", rotated: true, unavailable: true, after: time.Minute, fetches: 2, err: ErrUnavailable},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				keys        = generateKeys(t, 2)
+				published   = keys[:1]
+				unavailable = false
+				fetches     = 0
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				fetches++
+
+				if unavailable {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+
+				var set = &jwks.Set{}
+				for _, key := range published {
+					set.Keys = append(set.Keys, publishedKey(key))
+				}
+
+				_ = json.NewEncoder(w).Encode(set)
+			}))
+			defer server.Close()
+
+			var (
+				now      = time.Now()
+				provider = NewJWKSKeyProvider(server.URL, server.Client(), 5*time.Minute).(*jwksKeyProvider)
+			)
+
+			provider.now = func() time.Time { return now }
+
+			_, err := provider.Key(keys[0].ID)
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			now = now.Add(test.after)
+			unavailable = test.unavailable
+
+			var id = keys[0].ID
+			switch {
+			case test.rotated:
+				published = keys
+				id = keys[1].ID
+			case test.unknown:
+				id = "unknown"
+			}
+
+			_, err = provider.Key(id)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, but got %v", test.err, err)
+			}
+
+			if fetches != test.fetches {
+				t.Fatalf("expected %v, but got %v", test.fetches, fetches)
+			}
+		})
+	}
+}
+
+func generateKeys(t *testing.T, n int) []*jwt.Key {
+	var keys []*jwt.Key
+
+	for i := 0; i < n; i++ {
+		key, err := jwt.GenerateKey(jwt.AlgorithmEdDSA)
+		if err != nil {
+			t.Fatalf("expected %v, but got %v", nil, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// publishedKey encodes an Ed25519 key the way the auth service publishes it
+func publishedKey(key *jwt.Key) *jwks.Key {
+	return &jwks.Key{
+		KeyType:   "OKP",
+		KeyID:     key.ID,
+		Use:       "sig",
+		Algorithm: key.Algorithm,
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(key.PublicKey.(ed25519.PublicKey)),
+	}
+}
@@ -0,0 +1,70 @@
+package task_owners
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/statuses"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+)
+
+var (
+	ErrNotTaskOwner      = errors.New("task_owners: task is assigned to another worker")
+	ErrTaskNotInProgress = errors.New("task_owners: task is not in progress")
+)
+
+// Checker binds tasks to the workers they have been assigned to,
+// so a worker can not start or finish tasks of other workers
+type Checker interface {
+	// Check authenticates the worker with the secret issued on registration and checks that all tasks are assigned to it.
+	//
+	// Returns workers_storage.ErrWorkerUnauthorized, if the worker can not be authenticated,
+	// ErrNotTaskOwner, if some task is assigned to another worker,
+	// and ErrTaskNotInProgress, if some task is already finished, failed or requeued
+	Check(ctx context.Context, workerId int, secret string, taskIds ...int) error
+}
+
+type checker struct {
+	workersStorage    workers_storage.WorkerStorage
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
+}
+
+func NewChecker(
+	workersStorage workers_storage.WorkerStorage,
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
+) Checker {
+	return &checker{
+		workersStorage:    workersStorage,
+		binaryTreeStorage: binaryTreeStorage,
+	}
+}
+
+func (c *checker) Check(ctx context.Context, workerId int, secret string, taskIds ...int) error {
+	worker, err := c.workersStorage.Authenticate(ctx, workerId, secret)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range taskIds {
+		node, err := c.binaryTreeStorage.FindById(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotTaskOwner
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if node.WorkerId != worker.Id {
+			return ErrNotTaskOwner
+		}
+
+		if node.Status != statuses.Enqueued && node.Status != statuses.Calculating {
+			return ErrTaskNotInProgress
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package task_owners
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	binaryTreeMocks "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/statuses"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+	workersMocks "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	defaultWorkerId = 1
+	defaultSecret   = "secret"
+	defaultTaskId   = 10
+)
+
+func TestChecker_Check(t *testing.T) {
+	type fields struct {
+		workersStorage    workers_storage.WorkerStorage
+		binaryTreeStorage binary_tree_storage.BinaryTreeStorage
+	}
+
+	prepareNode := func(node *dto.ExpressionNodeDTO) func(t *testing.T, f *fields) {
+		return func(t *testing.T, f *fields) {
+			workersStorage := workersMocks.NewWorkerStorage(t)
+			workersStorage.
+				On("Authenticate", mock.Anything, defaultWorkerId, defaultSecret).
+				Once().
+				Return(&dto.WorkerResponseDTO{Id: defaultWorkerId}, nil)
+
+			binaryTreeStorage := binaryTreeMocks.NewBinaryTreeStorage(t)
+			binaryTreeStorage.
+				On("FindById", mock.Anything, defaultTaskId).
+				Once().
+				Return(node, nil)
+
+			f.workersStorage = workersStorage
+			f.binaryTreeStorage = binaryTreeStorage
+		}
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, f *fields)
+
+		targetErr error
+	}{
+		{
+			name:    "enqueued_task",
+			prepare: prepareNode(&dto.ExpressionNodeDTO{Id: defaultTaskId, WorkerId: defaultWorkerId, Status: statuses.Enqueued}),
+		},
+		{
+			name:    "calculating_task",
+			prepare: prepareNode(&dto.ExpressionNodeDTO{Id: defaultTaskId, WorkerId: defaultWorkerId, Status: statuses.Calculating}),
+		},
+		{
+			name:      "err_another_worker",
+			prepare:   prepareNode(&dto.ExpressionNodeDTO{Id: defaultTaskId, WorkerId: defaultWorkerId + 1, Status: statuses.Calculating}),
+			targetErr: ErrNotTaskOwner,
+		},
+		{
+			name:      "err_finished_task",
+			prepare:   prepareNode(&dto.ExpressionNodeDTO{Id: defaultTaskId, WorkerId: defaultWorkerId, Status: statuses.Finished}),
+			targetErr: ErrTaskNotInProgress,
+		},
+		{
+			name:      "err_failed_task",
+			prepare:   prepareNode(&dto.ExpressionNodeDTO{Id: defaultTaskId, WorkerId: defaultWorkerId, Status: statuses.Failed}),
+			targetErr: ErrTaskNotInProgress,
+		},
+		{
+			name:      "err_requeued_task",
+			prepare:   prepareNode(&dto.ExpressionNodeDTO{Id: defaultTaskId, WorkerId: defaultWorkerId, Status: statuses.Created}),
+			targetErr: ErrTaskNotInProgress,
+		},
+		{
+			name: "err_task_not_found",
+			prepare: func(t *testing.T, f *fields) {
+				workersStorage := workersMocks.NewWorkerStorage(t)
+				workersStorage.
+					On("Authenticate", mock.Anything, defaultWorkerId, defaultSecret).
+					Once().
+					Return(&dto.WorkerResponseDTO{Id: defaultWorkerId}, nil)
+
+				binaryTreeStorage := binaryTreeMocks.NewBinaryTreeStorage(t)
+				binaryTreeStorage.
+					On("FindById", mock.Anything, defaultTaskId).
+					Once().
+					Return(nil, sql.ErrNoRows)
+
+				f.workersStorage = workersStorage
+				f.binaryTreeStorage = binaryTreeStorage
+			},
+			targetErr: ErrNotTaskOwner,
+		},
+		{
+			name: "err_worker_unauthorized",
+			prepare: func(t *testing.T, f *fields) {
+				workersStorage := workersMocks.NewWorkerStorage(t)
+				workersStorage.
+					On("Authenticate", mock.Anything, defaultWorkerId, defaultSecret).
+					Once().
+					Return(nil, workers_storage.ErrWorkerUnauthorized)
+
+				f.workersStorage = workersStorage
+				f.binaryTreeStorage = binaryTreeMocks.NewBinaryTreeStorage(t)
+			},
+			targetErr: workers_storage.ErrWorkerUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fields{}
+			tt.prepare(t, f)
+
+			c := NewChecker(f.workersStorage, f.binaryTreeStorage)
+
+			err := c.Check(context.Background(), defaultWorkerId, defaultSecret, defaultTaskId)
+			if tt.targetErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.targetErr)
+		})
+	}
+}
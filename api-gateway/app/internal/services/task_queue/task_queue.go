@@ -0,0 +1,237 @@
+package task_queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/capabilities"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expressions_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/operators_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/quarantine"
+)
+
+const (
+	// MaxWait limits how long a single Acquire call may wait for new tasks
+	MaxWait = 30 * time.Second
+
+	pollInterval = 500 * time.Millisecond
+
+	// readyTasksBatch is how many ready tasks are scanned per attempt.
+	// It is bigger than a usual max, because some tasks may not fit the worker capabilities
+	readyTasksBatch = 100
+)
+
+var (
+	ErrOperationNotFound = errors.New("task_queue: operation not found")
+)
+
+// TaskQueue hands out ready tasks to workers, which pull them instead of being called by the orchestrator.
+// Every task is leased for the operation duration plus leaseTTL. Tasks which are not finished
+// until the lease expires are returned to the queue
+type TaskQueue interface {
+	Acquire(ctx context.Context, worker *dto.WorkerResponseDTO, max int, wait time.Duration) ([]*dto.LeasedTaskDTO, error)
+	ReleaseExpired(ctx context.Context) error
+}
+
+type taskQueue struct {
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
+	operatorsStorage  operators_storage.OperatorsStorage
+	expressionStorage expressions_storage.ExpressionStorage
+	monitor           quarantine.Monitor
+
+	leaseTTL time.Duration
+}
+
+func NewTaskQueue(
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
+	operatorsStorage operators_storage.OperatorsStorage,
+	expressionStorage expressions_storage.ExpressionStorage,
+	monitor quarantine.Monitor,
+	leaseTTL time.Duration,
+) TaskQueue {
+	return &taskQueue{
+		binaryTreeStorage: binaryTreeStorage,
+		operatorsStorage:  operatorsStorage,
+		expressionStorage: expressionStorage,
+		monitor:           monitor,
+		leaseTTL:          leaseTTL,
+	}
+}
+
+// Acquire leases up to max tasks to the worker. If there are no suitable tasks,
+// it waits for them until wait has passed and returns an empty list.
+// Workers which are not active or cordoned get no tasks, and workers without free executors
+// only wait, because tasks leased to them before still occupy their executors
+func (q *taskQueue) Acquire(ctx context.Context, worker *dto.WorkerResponseDTO, max int, wait time.Duration) ([]*dto.LeasedTaskDTO, error) {
+	if !worker.State.Schedulable() || worker.Cordoned {
+		return []*dto.LeasedTaskDTO{}, nil
+	}
+
+	max = capacity(worker, max)
+
+	if wait > MaxWait {
+		wait = MaxWait
+	}
+
+	deadline := time.Now().Add(wait)
+
+	for {
+		var tasks = []*dto.LeasedTaskDTO{}
+
+		if max > 0 {
+			var err error
+
+			tasks, err = q.tryAcquire(ctx, worker, max)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		remaining := time.Until(deadline)
+		if len(tasks) > 0 || remaining <= 0 {
+			return tasks, nil
+		}
+
+		timer := time.NewTimer(min(pollInterval, remaining))
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return []*dto.LeasedTaskDTO{}, nil
+		}
+	}
+}
+
+// capacity returns how many tasks may be leased to the worker at once: at least one task is requested,
+// and no more than the executors of the worker which are not occupied by its active leases
+func capacity(worker *dto.WorkerResponseDTO, max int) int {
+	if max <= 0 {
+		max = 1
+	}
+
+	if worker.Executors <= 0 {
+		return max
+	}
+
+	free := worker.Executors - worker.InFlight
+	if free <= 0 {
+		return 0
+	}
+
+	return min(max, free)
+}
+
+// ReleaseExpired returns tasks with expired leases to the queue and counts them as timeouts of their workers
+func (q *taskQueue) ReleaseExpired(ctx context.Context) error {
+	workerIds, err := q.binaryTreeStorage.ReleaseExpiredLeases(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return q.monitor.RecordTimeouts(ctx, workerIds)
+}
+
+func (q *taskQueue) tryAcquire(ctx context.Context, worker *dto.WorkerResponseDTO, max int) ([]*dto.LeasedTaskDTO, error) {
+	err := q.ReleaseExpired(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ready, err := q.binaryTreeStorage.FindReady(ctx, readyTasksBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks = []*dto.LeasedTaskDTO{}
+
+	if len(ready) == 0 {
+		return tasks, nil
+	}
+
+	// durations are loaded once per organization of the ready tasks
+	var operations = map[uint64][]*dto.OperationDTO{}
+
+	for _, task := range ready {
+		if len(tasks) >= max {
+			break
+		}
+
+		var operation = expr_tokens.OperationType(task.OperationType)
+
+		if operation == expr_tokens.Divide && task.RightResult == 0 {
+			err = q.fail(ctx, task)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if !worker.Capabilities.Satisfies(&capabilities.Requirement{
+			Operation: operation,
+			Operands:  []float64{task.LeftResult, task.RightResult},
+			Selector:  task.Selector,
+		}) {
+			continue
+		}
+
+		if _, ok := operations[task.OrganizationID]; !ok {
+			operations[task.OrganizationID], err = q.operatorsStorage.FindFor(ctx, task.OrganizationID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		duration, err := operationDuration(operations[task.OrganizationID], operation)
+		if err != nil {
+			return nil, err
+		}
+
+		leaseExpiresAt := time.Now().Add(duration + q.leaseTTL)
+
+		ok, err := q.binaryTreeStorage.Lease(ctx, task.Id, worker.Id, leaseExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		tasks = append(tasks, &dto.LeasedTaskDTO{
+			Id:             task.Id,
+			UserID:         task.UserID,
+			First:          task.LeftResult,
+			Second:         task.RightResult,
+			Operation:      operation,
+			DurationMS:     duration.Milliseconds(),
+			LeaseExpiresAt: leaseExpiresAt,
+		})
+	}
+
+	return tasks, nil
+}
+
+func (q *taskQueue) fail(ctx context.Context, task *dto.ReadyTaskDTO) error {
+	err := q.expressionStorage.MarkAsFailed(ctx, task.ExpressionId)
+	if err != nil {
+		return err
+	}
+
+	return q.binaryTreeStorage.MarkAsFailed(ctx, task.Id, "division by zero")
+}
+
+func operationDuration(operations []*dto.OperationDTO, operationType expr_tokens.OperationType) (time.Duration, error) {
+	for _, operation := range operations {
+		if operation.OperationType == operationType {
+			return time.Duration(operation.DurationMS) * time.Millisecond, nil
+		}
+	}
+
+	return 0, ErrOperationNotFound
+}
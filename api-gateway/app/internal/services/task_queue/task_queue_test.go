@@ -0,0 +1,37 @@
+package task_queue
+
+import (
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+)
+
+func TestCapacity(t *testing.T) {
+	type Test struct {
+		name      string
+		executors int
+		inFlight  int
+		max       int
+		expected  int
+	}
+
+	var tt = []Test{
+		{name: "free_worker", executors: 4, max: 2, expected: 2},
+		{name: "capped_by_executors", executors: 2, max: 5, expected: 2},
+		{name: "capped_by_leases", executors: 4, inFlight: 3, max: 4, expected: 1},
+		{name: "busy_worker", executors: 2, inFlight: 2, max: 1, expected: 0},
+		{name: "overloaded_worker", executors: 2, inFlight: 3, max: 1, expected: 0},
+		{name: "default_max", executors: 2, max: 0, expected: 1},
+		{name: "unknown_executors", max: 3, inFlight: 5, expected: 3},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			worker := &dto.WorkerResponseDTO{Executors: test.executors, InFlight: test.inFlight}
+
+			if got := capacity(worker, test.max); got != test.expected {
+				t.Fatalf("expected %v, but got %v", test.expected, got)
+			}
+		})
+	}
+}
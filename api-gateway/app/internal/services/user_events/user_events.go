@@ -0,0 +1,241 @@
+package user_events
+
+import (
+	"context"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/event_cursors_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/postgres"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expressions_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jsoncodec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// UserDeleted is published by the auth service when an account is deleted
+const UserDeleted = "user.deleted"
+
+// eventsMethod is served by the auth service
+const eventsMethod = "/auth.v1.Users/Events"
+
+// cursorName identifies the stream of the auth service in the event cursors
+const cursorName = "auth.users"
+
+const defaultBatchSize = 100
+
+// Event is a change of an account in the auth service
+type Event struct {
+	Id     uint64
+	Type   string
+	UserId uint64
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=Feed --inpackage --testonly
+type Feed interface {
+	// Events returns at most limit events with ids greater than afterId in the order of ids
+	Events(ctx context.Context, afterId uint64, limit int) ([]*Event, error)
+}
+
+type eventsRequestDTO struct {
+	AfterId uint64 `json:"afterId"`
+	Limit   int    `json:"limit"`
+}
+
+type eventDTO struct {
+	Id     uint64 `json:"id"`
+	Type   string `json:"type"`
+	UserId uint64 `json:"userId"`
+}
+
+type eventsResponseDTO struct {
+	Events []*eventDTO `json:"events"`
+}
+
+type gRPCFeed struct {
+	host                 string
+	transportCredentials credentials.TransportCredentials
+	serviceCredentials   credentials.PerRPCCredentials
+}
+
+// NewGRPCFeed reads events from the auth service at host.
+// serviceCredentials authenticate the api-gateway, the method is internal
+func NewGRPCFeed(
+	host string,
+	transportCredentials credentials.TransportCredentials,
+	serviceCredentials credentials.PerRPCCredentials,
+) Feed {
+	return &gRPCFeed{
+		host:                 host,
+		transportCredentials: transportCredentials,
+		serviceCredentials:   serviceCredentials,
+	}
+}
+
+func (g *gRPCFeed) Events(ctx context.Context, afterId uint64, limit int) ([]*Event, error) {
+	cc, err := grpc.DialContext(
+		ctx,
+		g.host,
+		grpc.WithTransportCredentials(g.transportCredentials),
+		grpc.WithPerRPCCredentials(g.serviceCredentials),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cc.Close()
+
+	var resp = &eventsResponseDTO{}
+
+	err = cc.Invoke(ctx, eventsMethod, &eventsRequestDTO{
+		AfterId: afterId,
+		Limit:   limit,
+	}, resp, grpc.CallContentSubtype(jsoncodec.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	var events = make([]*Event, 0, len(resp.Events))
+	for _, event := range resp.Events {
+		events = append(events, &Event{
+			Id:     event.Id,
+			Type:   event.Type,
+			UserId: event.UserId,
+		})
+	}
+
+	return events, nil
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=Eraser --inpackage --testonly
+
+// Eraser deletes data of users
+type Eraser interface {
+	// DeleteUser deletes all expressions of the user. Deleting data of a user without data succeeds
+	DeleteUser(ctx context.Context, userId uint64) error
+}
+
+type expressionsEraser struct {
+	transactor        postgres.Transactor
+	expressionStorage expressions_storage.ExpressionStorage
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
+	batchSize         int
+}
+
+// NewExpressionsEraser deletes expressions of users with their trees in batches of batchSize expressions
+func NewExpressionsEraser(
+	transactor postgres.Transactor,
+	expressionStorage expressions_storage.ExpressionStorage,
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
+	batchSize int,
+) Eraser {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return &expressionsEraser{
+		transactor:        transactor,
+		expressionStorage: expressionStorage,
+		binaryTreeStorage: binaryTreeStorage,
+		batchSize:         batchSize,
+	}
+}
+
+func (e *expressionsEraser) DeleteUser(ctx context.Context, userId uint64) error {
+	for {
+		ids, err := e.expressionStorage.FindIdsByUser(ctx, userId, e.batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		err = e.transactor.InTx(ctx, func(ctx context.Context) error {
+			err := e.binaryTreeStorage.DeleteByExpressionIds(ctx, ids)
+			if err != nil {
+				return err
+			}
+
+			return e.expressionStorage.Delete(ctx, ids)
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(ids) < e.batchSize {
+			return nil
+		}
+	}
+}
+
+// Consumer handles events of the auth service
+type Consumer interface {
+	// Consume handles all events published since the last handled one and returns the number of handled events
+	Consume(ctx context.Context) (int, error)
+}
+
+type consumer struct {
+	feed      Feed
+	cursors   event_cursors_repository.EventCursorsRepository
+	eraser    Eraser
+	batchSize int
+}
+
+// NewConsumer creates a consumer which deletes data of deleted users with eraser. The id of the last handled event
+// is saved to cursors after the event is handled, so events are handled at least once
+func NewConsumer(
+	feed Feed,
+	cursors event_cursors_repository.EventCursorsRepository,
+	eraser Eraser,
+	batchSize int,
+) Consumer {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return &consumer{
+		feed:      feed,
+		cursors:   cursors,
+		eraser:    eraser,
+		batchSize: batchSize,
+	}
+}
+
+func (c *consumer) Consume(ctx context.Context) (int, error) {
+	lastId, err := c.cursors.Find(ctx, cursorName)
+	if err != nil {
+		return 0, err
+	}
+
+	var handled = 0
+
+	for {
+		events, err := c.feed.Events(ctx, lastId, c.batchSize)
+		if err != nil {
+			return handled, err
+		}
+
+		for _, event := range events {
+			if event.Type == UserDeleted {
+				err = c.eraser.DeleteUser(ctx, event.UserId)
+				if err != nil {
+					return handled, err
+				}
+			}
+
+			err = c.cursors.Save(ctx, cursorName, event.Id)
+			if err != nil {
+				return handled, err
+			}
+
+			lastId = event.Id
+			handled++
+		}
+
+		if len(events) < c.batchSize {
+			return handled, nil
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package user_events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cursorsMocks "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/event_cursors_repository/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const batchSize = 3
+
+type fields struct {
+	feed    *MockFeed
+	cursors *cursorsMocks.EventCursorsRepository
+	eraser  *MockEraser
+}
+
+func newFields(t *testing.T) *fields {
+	return &fields{
+		feed:    NewMockFeed(t),
+		cursors: cursorsMocks.NewEventCursorsRepository(t),
+		eraser:  NewMockEraser(t),
+	}
+}
+
+// handle expects the event to be handled and the cursor to be moved to it
+func (f *fields) handle(event *Event) {
+	if event.Type == UserDeleted {
+		f.eraser.On("DeleteUser", mock.Anything, event.UserId).Once().Return(nil)
+	}
+
+	f.cursors.On("Save", mock.Anything, cursorName, event.Id).Once().Return(nil)
+}
+
+func TestConsumer_Consume(t *testing.T) {
+	var events = []*Event{
+		{Id: 1, Type: UserDeleted, UserId: 10},
+		{Id: 2, Type: "user.renamed", UserId: 11},
+		{Id: 3, Type: UserDeleted, UserId: 12},
+		{Id: 4, Type: UserDeleted, UserId: 13},
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(f *fields)
+
+		wantErr bool
+		handled int
+	}{
+		{
+			name: "all",
+			prepare: func(f *fields) {
+				f.cursors.On("Find", mock.Anything, cursorName).Once().Return(uint64(0), nil)
+				f.feed.On("Events", mock.Anything, uint64(0), batchSize).Once().Return(events[:3], nil)
+				f.feed.On("Events", mock.Anything, uint64(3), batchSize).Once().Return(events[3:], nil)
+
+				for _, event := range events {
+					f.handle(event)
+				}
+			},
+			handled: 4,
+		},
+		{
+			name: "after_cursor",
+			prepare: func(f *fields) {
+				f.cursors.On("Find", mock.Anything, cursorName).Once().Return(uint64(2), nil)
+				f.feed.On("Events", mock.Anything, uint64(2), batchSize).Once().Return(events[2:], nil)
+
+				f.handle(events[2])
+				f.handle(events[3])
+			},
+			handled: 2,
+		},
+		{
+			name: "nothing_new",
+			prepare: func(f *fields) {
+				f.cursors.On("Find", mock.Anything, cursorName).Once().Return(uint64(4), nil)
+				f.feed.On("Events", mock.Anything, uint64(4), batchSize).Once().Return([]*Event{}, nil)
+			},
+		},
+		{
+			name: "err_delete_user",
+			prepare: func(f *fields) {
+				f.cursors.On("Find", mock.Anything, cursorName).Once().Return(uint64(0), nil)
+				f.feed.On("Events", mock.Anything, uint64(0), batchSize).Once().Return(events[:3], nil)
+
+				f.handle(events[0])
+				f.handle(events[1])
+				f.eraser.
+					On("DeleteUser", mock.Anything, events[2].UserId).
+					Once().
+					Return(errors.New("database is unavailable"))
+			},
+			wantErr: true,
+			handled: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			consumer := NewConsumer(f.feed, f.cursors, f.eraser, batchSize)
+
+			handled, err := consumer.Consume(context.Background())
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.handled, handled)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package worker_admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_events_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_states"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+)
+
+var (
+	ErrInvalidExecutors = errors.New("worker_admin: executors count must be positive")
+	ErrInvalidState     = errors.New("worker_admin: operation is not allowed in the current worker state")
+)
+
+const (
+	CordonedEvent         = "cordoned"
+	UncordonedEvent       = "uncordoned"
+	DrainedEvent          = "drained"
+	ExecutorsChangedEvent = "executors_changed"
+	LabelsChangedEvent    = "labels_changed"
+	EvictedEvent          = "evicted"
+)
+
+// WorkerAdmin applies manual administrator decisions to workers.
+// Every operation is recorded as a worker event with the login of the administrator and in the audit log.
+// Operations return workers_storage.ErrWorkerNotFound, if there is no such worker
+type WorkerAdmin interface {
+	Cordon(ctx context.Context, admin string, workerId int) error
+	Uncordon(ctx context.Context, admin string, workerId int) error
+	Drain(ctx context.Context, admin string, workerId int) error
+	SetExecutors(ctx context.Context, admin string, workerId int, executors *int) error
+	SetLabels(ctx context.Context, admin string, workerId int, labels map[string]string) error
+	Evict(ctx context.Context, admin string, workerId int) error
+}
+
+type workerAdmin struct {
+	workersStorage    workers_storage.WorkerStorage
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
+	eventsRepository  worker_events_repository.WorkerEventsRepository
+	auditLog          audit_log.AuditLog
+}
+
+func NewWorkerAdmin(
+	workersStorage workers_storage.WorkerStorage,
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
+	eventsRepository worker_events_repository.WorkerEventsRepository,
+	auditLog audit_log.AuditLog,
+) WorkerAdmin {
+	return &workerAdmin{
+		workersStorage:    workersStorage,
+		binaryTreeStorage: binaryTreeStorage,
+		eventsRepository:  eventsRepository,
+		auditLog:          auditLog,
+	}
+}
+
+// Cordon stops assigning new tasks to the worker. Tasks which are already assigned are finished as usual
+func (a *workerAdmin) Cordon(ctx context.Context, admin string, workerId int) error {
+	err := a.workersStorage.SetCordoned(ctx, workerId, true)
+	if err != nil {
+		return err
+	}
+
+	return a.record(ctx, admin, workerId, CordonedEvent, "", nil)
+}
+
+// Uncordon allows assigning new tasks to the worker again and returns a draining worker to the active state
+func (a *workerAdmin) Uncordon(ctx context.Context, admin string, workerId int) error {
+	worker, err := a.workersStorage.FindById(ctx, workerId)
+	if err != nil {
+		return err
+	}
+
+	err = a.workersStorage.SetCordoned(ctx, workerId, false)
+	if err != nil {
+		return err
+	}
+
+	if worker.State == worker_states.Draining {
+		err = a.workersStorage.SetState(ctx, workerId, worker_states.Active)
+		if err != nil {
+			return err
+		}
+	}
+
+	return a.record(ctx, admin, workerId, UncordonedEvent, "", nil)
+}
+
+// Drain moves the worker to the draining state, so it gets no new tasks, and reassigns its current tasks
+// to other workers. Dead and quarantined workers can not be drained
+func (a *workerAdmin) Drain(ctx context.Context, admin string, workerId int) error {
+	worker, err := a.workersStorage.FindById(ctx, workerId)
+	if err != nil {
+		return err
+	}
+
+	if worker.State == worker_states.Dead || worker.State == worker_states.Quarantined {
+		return ErrInvalidState
+	}
+
+	err = a.workersStorage.SetState(ctx, workerId, worker_states.Draining)
+	if err != nil {
+		return err
+	}
+
+	err = a.binaryTreeStorage.DeleteWorkers(ctx, []int{workerId})
+	if err != nil {
+		return err
+	}
+
+	return a.record(ctx, admin, workerId, DrainedEvent, "", nil)
+}
+
+// SetExecutors overrides the executors count advertised by the worker. Nil returns the advertised count
+func (a *workerAdmin) SetExecutors(ctx context.Context, admin string, workerId int, executors *int) error {
+	if executors != nil && *executors <= 0 {
+		return ErrInvalidExecutors
+	}
+
+	err := a.workersStorage.SetExecutors(ctx, workerId, executors)
+	if err != nil {
+		return err
+	}
+
+	var reason = "advertised"
+	if executors != nil {
+		reason = fmt.Sprint(*executors)
+	}
+
+	return a.record(ctx, admin, workerId, ExecutorsChangedEvent, reason, map[string]*int{"executors": executors})
+}
+
+// SetLabels replaces the labels attached to the worker by administrators
+func (a *workerAdmin) SetLabels(ctx context.Context, admin string, workerId int, labels map[string]string) error {
+	err := a.workersStorage.SetLabels(ctx, workerId, labels)
+	if err != nil {
+		return err
+	}
+
+	return a.record(ctx, admin, workerId, LabelsChangedEvent, formatLabels(labels), map[string]map[string]string{"labels": labels})
+}
+
+// Evict declares the worker dead, revokes its secret and reassigns all its tasks.
+// The daemon can not register again with the revoked identity, so the next heartbeat does not bring it back.
+// Quarantined workers keep their state
+func (a *workerAdmin) Evict(ctx context.Context, admin string, workerId int) error {
+	worker, err := a.workersStorage.FindById(ctx, workerId)
+	if err != nil {
+		return err
+	}
+
+	if worker.State != worker_states.Quarantined {
+		err = a.workersStorage.SetState(ctx, workerId, worker_states.Dead)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = a.workersStorage.Revoke(ctx, workerId)
+	if err != nil {
+		return err
+	}
+
+	err = a.binaryTreeStorage.DeleteWorkers(ctx, []int{workerId})
+	if err != nil {
+		return err
+	}
+
+	return a.record(ctx, admin, workerId, EvictedEvent, "", nil)
+}
+
+// record saves the worker event and the audit log entry "worker.<event>" with the new settings of the worker in after
+func (a *workerAdmin) record(ctx context.Context, admin string, workerId int, event string, details string, after any) error {
+	var reason = "by " + admin
+	if details != "" {
+		reason = details + " " + reason
+	}
+
+	log.Printf("worker %d %s: %s", workerId, event, reason)
+
+	err := a.eventsRepository.Create(ctx, &worker_events_repository.WorkerEventEntity{
+		WorkerId: workerId,
+		Type:     event,
+		Reason:   reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.auditLog.Record(ctx, &audit_log.Entry{
+		Action:     "worker." + event,
+		TargetType: audit_log.TargetWorker,
+		TargetId:   audit_log.TargetId(workerId),
+		After:      after,
+	})
+}
+
+func formatLabels(labels map[string]string) string {
+	var pairs = make([]string, 0, len(labels))
+
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
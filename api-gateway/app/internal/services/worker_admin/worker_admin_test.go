@@ -0,0 +1,245 @@
+package worker_admin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_events_repository"
+	eventsMocks "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_events_repository/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log"
+	auditMocks "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/audit_log/mocks"
+	binaryTreeMocks "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_states"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+	workersMocks "github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	defaultWorkerId = 1
+	defaultAdmin    = "root"
+)
+
+type fields struct {
+	workersStorage    *workersMocks.WorkerStorage
+	binaryTreeStorage *binaryTreeMocks.BinaryTreeStorage
+	eventsRepository  *eventsMocks.WorkerEventsRepository
+	auditLog          *auditMocks.AuditLog
+}
+
+func newFields(t *testing.T) *fields {
+	return &fields{
+		workersStorage:    workersMocks.NewWorkerStorage(t),
+		binaryTreeStorage: binaryTreeMocks.NewBinaryTreeStorage(t),
+		eventsRepository:  eventsMocks.NewWorkerEventsRepository(t),
+		auditLog:          auditMocks.NewAuditLog(t),
+	}
+}
+
+// findWorker expects the worker to be found in the given state
+func (f *fields) findWorker(state worker_states.State) {
+	f.workersStorage.
+		On("FindById", mock.Anything, defaultWorkerId).
+		Once().
+		Return(&dto.WorkerResponseDTO{Id: defaultWorkerId, State: state}, nil)
+}
+
+// expectRecord expects the worker event and the audit log entry "worker.<event>"
+func (f *fields) expectRecord(event string, reason string) {
+	f.eventsRepository.
+		On("Create", mock.Anything, &worker_events_repository.WorkerEventEntity{
+			WorkerId: defaultWorkerId,
+			Type:     event,
+			Reason:   reason,
+		}).
+		Once().
+		Return(nil)
+
+	f.auditLog.
+		On("Record", mock.Anything, mock.MatchedBy(func(entry *audit_log.Entry) bool {
+			return entry.Action == "worker."+event &&
+				entry.TargetType == audit_log.TargetWorker &&
+				entry.TargetId == audit_log.TargetId(defaultWorkerId)
+		})).
+		Once().
+		Return(nil)
+}
+
+func TestWorkerAdmin_Operations(t *testing.T) {
+	var executors = 2
+
+	tests := []struct {
+		name      string
+		prepare   func(f *fields)
+		operation func(ctx context.Context, admin WorkerAdmin) error
+
+		targetErr error
+	}{
+		{
+			name: "cordon",
+			prepare: func(f *fields) {
+				f.workersStorage.On("SetCordoned", mock.Anything, defaultWorkerId, true).Once().Return(nil)
+				f.expectRecord(CordonedEvent, "by root")
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.Cordon(ctx, defaultAdmin, defaultWorkerId)
+			},
+		},
+		{
+			name: "uncordon_draining",
+			prepare: func(f *fields) {
+				f.findWorker(worker_states.Draining)
+				f.workersStorage.On("SetCordoned", mock.Anything, defaultWorkerId, false).Once().Return(nil)
+				f.workersStorage.On("SetState", mock.Anything, defaultWorkerId, worker_states.Active).Once().Return(nil)
+				f.expectRecord(UncordonedEvent, "by root")
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.Uncordon(ctx, defaultAdmin, defaultWorkerId)
+			},
+		},
+		{
+			name: "uncordon_quarantined",
+			prepare: func(f *fields) {
+				f.findWorker(worker_states.Quarantined)
+				f.workersStorage.On("SetCordoned", mock.Anything, defaultWorkerId, false).Once().Return(nil)
+				f.expectRecord(UncordonedEvent, "by root")
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.Uncordon(ctx, defaultAdmin, defaultWorkerId)
+			},
+		},
+		{
+			name: "drain",
+			prepare: func(f *fields) {
+				f.findWorker(worker_states.Suspect)
+				f.workersStorage.On("SetState", mock.Anything, defaultWorkerId, worker_states.Draining).Once().Return(nil)
+				f.binaryTreeStorage.On("DeleteWorkers", mock.Anything, []int{defaultWorkerId}).Once().Return(nil)
+				f.expectRecord(DrainedEvent, "by root")
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.Drain(ctx, defaultAdmin, defaultWorkerId)
+			},
+		},
+		{
+			name: "err_drain_dead",
+			prepare: func(f *fields) {
+				f.findWorker(worker_states.Dead)
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.Drain(ctx, defaultAdmin, defaultWorkerId)
+			},
+			targetErr: ErrInvalidState,
+		},
+		{
+			name: "err_drain_quarantined",
+			prepare: func(f *fields) {
+				f.findWorker(worker_states.Quarantined)
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.Drain(ctx, defaultAdmin, defaultWorkerId)
+			},
+			targetErr: ErrInvalidState,
+		},
+		{
+			name: "resize",
+			prepare: func(f *fields) {
+				f.workersStorage.On("SetExecutors", mock.Anything, defaultWorkerId, &executors).Once().Return(nil)
+				f.expectRecord(ExecutorsChangedEvent, "2 by root")
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.SetExecutors(ctx, defaultAdmin, defaultWorkerId, &executors)
+			},
+		},
+		{
+			name: "resize_to_advertised",
+			prepare: func(f *fields) {
+				f.workersStorage.On("SetExecutors", mock.Anything, defaultWorkerId, (*int)(nil)).Once().Return(nil)
+				f.expectRecord(ExecutorsChangedEvent, "advertised by root")
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.SetExecutors(ctx, defaultAdmin, defaultWorkerId, nil)
+			},
+		},
+		{
+			name:    "err_resize_to_zero",
+			prepare: func(f *fields) {},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				var zero = 0
+				return admin.SetExecutors(ctx, defaultAdmin, defaultWorkerId, &zero)
+			},
+			targetErr: ErrInvalidExecutors,
+		},
+		{
+			name: "labels",
+			prepare: func(f *fields) {
+				f.workersStorage.
+					On("SetLabels", mock.Anything, defaultWorkerId, map[string]string{"zone": "b", "gpu": "true"}).
+					Once().
+					Return(nil)
+				f.expectRecord(LabelsChangedEvent, "gpu=true,zone=b by root")
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.SetLabels(ctx, defaultAdmin, defaultWorkerId, map[string]string{"zone": "b", "gpu": "true"})
+			},
+		},
+		{
+			name: "evict",
+			prepare: func(f *fields) {
+				f.findWorker(worker_states.Active)
+				f.workersStorage.On("SetState", mock.Anything, defaultWorkerId, worker_states.Dead).Once().Return(nil)
+				f.workersStorage.On("Revoke", mock.Anything, defaultWorkerId).Once().Return(nil)
+				f.binaryTreeStorage.On("DeleteWorkers", mock.Anything, []int{defaultWorkerId}).Once().Return(nil)
+				f.expectRecord(EvictedEvent, "by root")
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.Evict(ctx, defaultAdmin, defaultWorkerId)
+			},
+		},
+		{
+			name: "evict_quarantined",
+			prepare: func(f *fields) {
+				f.findWorker(worker_states.Quarantined)
+				f.workersStorage.On("Revoke", mock.Anything, defaultWorkerId).Once().Return(nil)
+				f.binaryTreeStorage.On("DeleteWorkers", mock.Anything, []int{defaultWorkerId}).Once().Return(nil)
+				f.expectRecord(EvictedEvent, "by root")
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.Evict(ctx, defaultAdmin, defaultWorkerId)
+			},
+		},
+		{
+			name: "err_missing_worker",
+			prepare: func(f *fields) {
+				f.workersStorage.
+					On("FindById", mock.Anything, defaultWorkerId).
+					Once().
+					Return(nil, workers_storage.ErrWorkerNotFound)
+			},
+			operation: func(ctx context.Context, admin WorkerAdmin) error {
+				return admin.Evict(ctx, defaultAdmin, defaultWorkerId)
+			},
+			targetErr: workers_storage.ErrWorkerNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			admin := NewWorkerAdmin(f.workersStorage, f.binaryTreeStorage, f.eventsRepository, f.auditLog)
+
+			err := tt.operation(context.Background(), admin)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"fmt"
 	daemonv1 "github.com/AleksandrVishniakov/dc-protos/gen/go/daemon/v1"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jsoncodec"
+	"github.com/AleksandrVishniakov/distributed-calculator/protocol/taskmd"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"strconv"
 	"time"
 )
 
@@ -18,21 +23,49 @@ type CalculationRequestDTO struct {
 	Duration  time.Duration             `json:"duration"`
 }
 
+// SubtreeNodeDTO is a node of an expression sub-tree which is calculated by a single worker.
+// Nodes without children are already calculated and carry their result in Value
+type SubtreeNodeDTO struct {
+	Id         uint64                    `json:"id"`
+	Operation  expr_tokens.OperationType `json:"operation"`
+	DurationMS int64                     `json:"durationMS"`
+	Value      float64                   `json:"value"`
+	Left       *SubtreeNodeDTO           `json:"left,omitempty"`
+	Right      *SubtreeNodeDTO           `json:"right,omitempty"`
+}
+
+type subtreeRequestDTO struct {
+	UserID uint64          `json:"userId"`
+	Root   *SubtreeNodeDTO `json:"root"`
+}
+
+type subtreeResponseDTO struct {
+	Ok bool `json:"ok"`
+}
+
+// calculateSubtreeMethod is served by daemons
+const calculateSubtreeMethod = "/daemon.v1.Subtrees/CalculateSubtree"
+
 type WorkerAPI interface {
 	Calculate(ctx context.Context, host string, userID uint64, requestBody *CalculationRequestDTO) error
+	CalculateSubtree(ctx context.Context, host string, userID uint64, root *SubtreeNodeDTO) error
+	Probe(ctx context.Context, host string) error
 }
 
-type gRPCWorkerAPI struct{}
+type gRPCWorkerAPI struct {
+	transportCredentials credentials.TransportCredentials
+}
 
-func NewGRPCWorkerAPI() WorkerAPI {
-	return &gRPCWorkerAPI{}
+// NewGRPCWorkerAPI calls workers with transportCredentials, which are either insecure or TLS ones
+func NewGRPCWorkerAPI(transportCredentials credentials.TransportCredentials) WorkerAPI {
+	return &gRPCWorkerAPI{transportCredentials: transportCredentials}
 }
 
 func (g *gRPCWorkerAPI) Calculate(ctx context.Context, host string, userID uint64, requestBody *CalculationRequestDTO) error {
 	cc, err := grpc.DialContext(
 		ctx,
 		host,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(g.transportCredentials),
 	)
 
 	if err != nil {
@@ -41,6 +74,12 @@ func (g *gRPCWorkerAPI) Calculate(ctx context.Context, host string, userID uint6
 
 	client := daemonv1.NewDaemonClient(cc)
 
+	ctx = metadata.AppendToOutgoingContext(
+		ctx,
+		taskmd.First, strconv.FormatFloat(requestBody.First, 'g', -1, 64),
+		taskmd.Second, strconv.FormatFloat(requestBody.Second, 'g', -1, 64),
+	)
+
 	resp, err := client.CalculateTask(ctx, &daemonv1.CalculationRequestDTO{
 		Id:        requestBody.Id,
 		UserID:    userID,
@@ -59,3 +98,58 @@ func (g *gRPCWorkerAPI) Calculate(ctx context.Context, host string, userID uint6
 
 	return nil
 }
+
+func (g *gRPCWorkerAPI) CalculateSubtree(ctx context.Context, host string, userID uint64, root *SubtreeNodeDTO) error {
+	cc, err := grpc.DialContext(
+		ctx,
+		host,
+		grpc.WithTransportCredentials(g.transportCredentials),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	var resp = &subtreeResponseDTO{}
+
+	err = cc.Invoke(ctx, calculateSubtreeMethod, &subtreeRequestDTO{
+		UserID: userID,
+		Root:   root,
+	}, resp, grpc.CallContentSubtype(jsoncodec.Name))
+	if err != nil {
+		return err
+	}
+
+	if !resp.Ok {
+		return fmt.Errorf("response is not ok")
+	}
+
+	return nil
+}
+
+// Probe checks the worker with the standard gRPC health checking protocol.
+// Returns an error, if the worker can not be dialed or is not serving
+func (g *gRPCWorkerAPI) Probe(ctx context.Context, host string) error {
+	cc, err := grpc.DialContext(
+		ctx,
+		host,
+		grpc.WithTransportCredentials(g.transportCredentials),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	defer cc.Close()
+
+	resp, err := grpc_health_v1.NewHealthClient(cc).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("worker is %s", resp.Status)
+	}
+
+	return nil
+}
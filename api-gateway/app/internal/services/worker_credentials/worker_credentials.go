@@ -0,0 +1,164 @@
+package worker_credentials
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/jsoncodec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// TokenPrefix marks tokens issued to workers by the auth service
+const TokenPrefix = "wrk_"
+
+// authenticateMethod is served by the auth service
+const authenticateMethod = "/auth.v1.WorkerCredentials/Authenticate"
+
+var ErrInvalidCredential = errors.New("worker_credentials: invalid or revoked worker token")
+
+// Credential is a worker token issued by the auth service
+type Credential struct {
+	Id   uint64
+	Name string
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=Verifier --inpackage --testonly
+type Verifier interface {
+	// Verify returns the credential of the token. Returns ErrInvalidCredential, if the token is unknown or revoked
+	Verify(ctx context.Context, token string) (*Credential, error)
+}
+
+type authenticateRequestDTO struct {
+	Token string `json:"token"`
+}
+
+type authenticateResponseDTO struct {
+	CredentialId uint64 `json:"credentialId"`
+	Name         string `json:"name"`
+}
+
+type gRPCVerifier struct {
+	host                 string
+	transportCredentials credentials.TransportCredentials
+	serviceCredentials   credentials.PerRPCCredentials
+}
+
+// NewGRPCVerifier checks tokens with the auth service at host.
+// serviceCredentials authenticate the api-gateway, the method is internal
+func NewGRPCVerifier(
+	host string,
+	transportCredentials credentials.TransportCredentials,
+	serviceCredentials credentials.PerRPCCredentials,
+) Verifier {
+	return &gRPCVerifier{
+		host:                 host,
+		transportCredentials: transportCredentials,
+		serviceCredentials:   serviceCredentials,
+	}
+}
+
+func (g *gRPCVerifier) Verify(ctx context.Context, token string) (*Credential, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, ErrInvalidCredential
+	}
+
+	cc, err := grpc.DialContext(
+		ctx,
+		g.host,
+		grpc.WithTransportCredentials(g.transportCredentials),
+		grpc.WithPerRPCCredentials(g.serviceCredentials),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cc.Close()
+
+	var resp = &authenticateResponseDTO{}
+
+	err = cc.Invoke(ctx, authenticateMethod, &authenticateRequestDTO{
+		Token: token,
+	}, resp, grpc.CallContentSubtype(jsoncodec.Name))
+	if status.Code(err) == codes.Unauthenticated {
+		return nil, ErrInvalidCredential
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		Id:   resp.CredentialId,
+		Name: resp.Name,
+	}, nil
+}
+
+type cacheEntry struct {
+	credential *Credential
+	expiresAt  time.Time
+}
+
+type cachedVerifier struct {
+	mu       *sync.Mutex
+	verifier Verifier
+	ttl      time.Duration
+	now      func() time.Time
+
+	entries map[string]*cacheEntry
+}
+
+// NewCachedVerifier remembers results of verifier for ttl, so the auth service is not called on every request.
+// Invalid tokens are not cached, and a revoked token is rejected at most ttl after its revocation
+func NewCachedVerifier(verifier Verifier, ttl time.Duration) Verifier {
+	return &cachedVerifier{
+		mu:       &sync.Mutex{},
+		verifier: verifier,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  map[string]*cacheEntry{},
+	}
+}
+
+func (c *cachedVerifier) Verify(ctx context.Context, token string) (*Credential, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[token]
+	c.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.credential, nil
+	}
+
+	credential, err := c.verifier.Verify(ctx, token)
+	if err != nil {
+		c.mu.Lock()
+		delete(c.entries, token)
+		c.mu.Unlock()
+
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+
+	c.entries[token] = &cacheEntry{
+		credential: credential,
+		expiresAt:  now.Add(c.ttl),
+	}
+
+	return credential, nil
+}
@@ -0,0 +1,74 @@
+package worker_credentials
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const defaultToken = "wrk_token"
+
+func TestCachedVerifier(t *testing.T) {
+	var credential = &Credential{Id: 1, Name: "daemon1"}
+
+	tests := []struct {
+		name    string
+		after   time.Duration
+		prepare func(verifier *MockVerifier)
+
+		targetErr error
+	}{
+		{
+			name:    "cached",
+			after:   time.Second,
+			prepare: func(verifier *MockVerifier) {},
+		},
+		{
+			name:  "expired",
+			after: time.Minute,
+			prepare: func(verifier *MockVerifier) {
+				verifier.On("Verify", mock.Anything, defaultToken).Once().Return(credential, nil)
+			},
+		},
+		{
+			name:  "err_revoked_after_expiration",
+			after: time.Minute,
+			prepare: func(verifier *MockVerifier) {
+				verifier.On("Verify", mock.Anything, defaultToken).Once().Return(nil, ErrInvalidCredential)
+			},
+			targetErr: ErrInvalidCredential,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				now      = time.Now()
+				verifier = NewMockVerifier(t)
+				cached   = NewCachedVerifier(verifier, 30*time.Second).(*cachedVerifier)
+			)
+
+			cached.now = func() time.Time { return now }
+
+			verifier.On("Verify", mock.Anything, defaultToken).Once().Return(credential, nil)
+
+			_, err := cached.Verify(context.Background(), defaultToken)
+			require.NoError(t, err)
+
+			now = now.Add(tt.after)
+			tt.prepare(verifier)
+
+			_, err = cached.Verify(context.Background(), defaultToken)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
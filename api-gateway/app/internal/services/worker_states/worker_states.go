@@ -0,0 +1,68 @@
+package worker_states
+
+import "time"
+
+type State string
+
+const (
+	// Active workers send heartbeats in time and receive new tasks
+	Active State = "active"
+
+	// Suspect workers have missed some heartbeats. They keep their tasks, but receive no new ones
+	Suspect State = "suspect"
+
+	// Draining workers finish their tasks, but receive no new ones
+	Draining State = "draining"
+
+	// Dead workers have missed too many heartbeats. Their tasks are reassigned to other workers
+	Dead State = "dead"
+
+	// Quarantined workers are excluded from scheduling until they are released by an administrator
+	Quarantined State = "quarantined"
+)
+
+// Schedulable reports whether new tasks may be assigned to a worker in state s
+func (s State) Schedulable() bool {
+	return s == Active
+}
+
+// Policy is a consecutive-miss failure detector: a worker is expected to send a heartbeat
+// every Period and changes its state after SuspectAfter and DeadAfter missed heartbeats
+type Policy struct {
+	Period       time.Duration
+	SuspectAfter int
+	DeadAfter    int
+}
+
+// Missed returns how many heartbeats were missed since lastHeartbeat
+func (p *Policy) Missed(lastHeartbeat time.Time, now time.Time) int {
+	if p.Period <= 0 || !now.After(lastHeartbeat) {
+		return 0
+	}
+
+	return int(now.Sub(lastHeartbeat) / p.Period)
+}
+
+// Next returns the state of a worker in state current which has missed missed heartbeats.
+// Dead workers stay dead until the next heartbeat, quarantined ones until they are released
+func (p *Policy) Next(current State, missed int) State {
+	switch current {
+	case Dead, Quarantined:
+		return current
+	}
+
+	if missed >= p.DeadAfter {
+		return Dead
+	}
+
+	switch current {
+	case Active, Suspect:
+		if missed >= p.SuspectAfter {
+			return Suspect
+		}
+
+		return Active
+	}
+
+	return current
+}
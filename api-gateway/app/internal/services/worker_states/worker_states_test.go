@@ -0,0 +1,53 @@
+package worker_states
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicy_Next(t *testing.T) {
+	type Test struct {
+		name     string
+		current  State
+		missed   int
+		expected State
+	}
+
+	var policy = &Policy{
+		Period:       time.Second,
+		SuspectAfter: 1,
+		DeadAfter:    3,
+	}
+
+	var tt = []Test{
+		{name: "active", current: Active, missed: 0, expected: Active},
+		{name: "suspect", current: Active, missed: 1, expected: Suspect},
+		{name: "recovered", current: Suspect, missed: 0, expected: Active},
+		{name: "dead", current: Suspect, missed: 3, expected: Dead},
+		{name: "dead_stays_dead", current: Dead, missed: 0, expected: Dead},
+		{name: "draining_is_not_suspect", current: Draining, missed: 1, expected: Draining},
+		{name: "draining_dead", current: Draining, missed: 5, expected: Dead},
+		{name: "quarantined", current: Quarantined, missed: 5, expected: Quarantined},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			if got := policy.Next(test.current, test.missed); got != test.expected {
+				t.Fatalf("expected %s, but got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestPolicy_Missed(t *testing.T) {
+	var policy = &Policy{Period: 10 * time.Second}
+	var now = time.Now()
+
+	if got := policy.Missed(now.Add(-25*time.Second), now); got != 2 {
+		t.Fatalf("expected 2, but got %d", got)
+	}
+
+	if got := policy.Missed(now.Add(time.Second), now); got != 0 {
+		t.Fatalf("expected 0, but got %d", got)
+	}
+}
@@ -0,0 +1,72 @@
+package workers_storage
+
+import (
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_stats_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/capabilities"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+)
+
+const (
+	// latencyAlpha is the weight of the newest sample in the moving average of operation latencies
+	latencyAlpha = 0.2
+
+	// minLatencySamples is the number of samples after which the learned latency is trusted
+	minLatencySamples = 3
+)
+
+// latencies holds learned operation latencies (in milliseconds) of every worker
+// and their averages over all workers
+type latencies struct {
+	byWorker map[int]map[expr_tokens.OperationType]float64
+	average  map[expr_tokens.OperationType]float64
+}
+
+func newLatencies(stats []*worker_stats_repository.OperationStatsEntity) *latencies {
+	var (
+		l = &latencies{
+			byWorker: make(map[int]map[expr_tokens.OperationType]float64),
+			average:  make(map[expr_tokens.OperationType]float64),
+		}
+		samples = make(map[expr_tokens.OperationType]int)
+	)
+
+	for _, s := range stats {
+		if s.Samples < minLatencySamples {
+			continue
+		}
+
+		operation := expr_tokens.OperationType(s.OperationType)
+
+		if l.byWorker[s.WorkerId] == nil {
+			l.byWorker[s.WorkerId] = make(map[expr_tokens.OperationType]float64)
+		}
+
+		l.byWorker[s.WorkerId][operation] = s.MeanMS
+
+		l.average[operation] += s.MeanMS * float64(s.Samples)
+		samples[operation] += s.Samples
+	}
+
+	for operation, n := range samples {
+		l.average[operation] /= float64(n)
+	}
+
+	return l
+}
+
+// cost estimates how long the worker calculates all requirements.
+// Operations without learned latency of the worker are estimated with the average latency
+func (l *latencies) cost(workerId int, requirements []*capabilities.Requirement) float64 {
+	var total float64
+
+	for _, requirement := range requirements {
+		if latency, ok := l.byWorker[workerId][requirement.Operation]; ok {
+			total += latency
+			continue
+		}
+
+		total += l.average[requirement.Operation]
+	}
+
+	return total
+}
@@ -0,0 +1,48 @@
+package workers_storage
+
+import (
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_stats_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/capabilities"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+)
+
+func TestLatencies_cost(t *testing.T) {
+	type Test struct {
+		name         string
+		workerId     int
+		requirements []*capabilities.Requirement
+		expected     float64
+	}
+
+	var l = newLatencies([]*worker_stats_repository.OperationStatsEntity{
+		{WorkerId: 1, OperationType: int(expr_tokens.Plus), Samples: 10, MeanMS: 100},
+		{WorkerId: 2, OperationType: int(expr_tokens.Plus), Samples: 30, MeanMS: 200},
+		{WorkerId: 2, OperationType: int(expr_tokens.Multiply), Samples: 5, MeanMS: 400},
+		{WorkerId: 3, OperationType: int(expr_tokens.Multiply), Samples: 1, MeanMS: 10},
+	})
+
+	var (
+		plus     = &capabilities.Requirement{Operation: expr_tokens.Plus}
+		multiply = &capabilities.Requirement{Operation: expr_tokens.Multiply}
+		divide   = &capabilities.Requirement{Operation: expr_tokens.Divide}
+	)
+
+	var tt = []Test{
+		{name: "learned", workerId: 1, requirements: []*capabilities.Requirement{plus}, expected: 100},
+		{name: "average", workerId: 1, requirements: []*capabilities.Requirement{multiply}, expected: 400},
+		{name: "weighted_average", workerId: 4, requirements: []*capabilities.Requirement{plus}, expected: 175},
+		{name: "too_few_samples", workerId: 3, requirements: []*capabilities.Requirement{multiply}, expected: 400},
+		{name: "unknown", workerId: 1, requirements: []*capabilities.Requirement{divide}, expected: 0},
+		{name: "sum", workerId: 2, requirements: []*capabilities.Requirement{plus, multiply}, expected: 600},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			if got := l.cost(test.workerId, test.requirements); got != test.expected {
+				t.Fatalf("expected %v, but got %v", test.expected, got)
+			}
+		})
+	}
+}
@@ -1,36 +1,190 @@
 package workers_storage
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/worker_stats_repository"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/repositories/workers_repository"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/capabilities"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_states"
 	"time"
 )
 
+var (
+	ErrWorkerConflict     = errors.New("workers_storage: worker identity conflict")
+	ErrWorkerUnauthorized = errors.New("workers_storage: invalid worker credentials")
+	ErrWorkerNotFound     = errors.New("workers_storage: worker not found")
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=WorkerStorage
 type WorkerStorage interface {
-	Register(worker *dto.WorkerRequestDTO) (bool, error)
-	FindAll() ([]*dto.WorkerResponseDTO, error)
-	DeleteExpiredWorkers(deadline time.Time) ([]int, error)
-	FindFreeWorker() (*dto.WorkerResponseDTO, error)
+	Register(ctx context.Context, worker *dto.WorkerRequestDTO) (*dto.WorkerRegistrationDTO, error)
+	Authenticate(ctx context.Context, id int, secret string) (*dto.WorkerResponseDTO, error)
+	FindById(ctx context.Context, id int) (*dto.WorkerResponseDTO, error)
+	FindAll(ctx context.Context) ([]*dto.WorkerResponseDTO, error)
+	DetectFailures(ctx context.Context, now time.Time) ([]int, error)
+	RecordStats(ctx context.Context, id int, completed int, timedOut int, mismatched int) (*dto.WorkerStatsDTO, error)
+	Quarantine(ctx context.Context, id int, reason string) (bool, error)
+	Release(ctx context.Context, id int) (bool, error)
+	SetState(ctx context.Context, id int, state worker_states.State) error
+	SetCordoned(ctx context.Context, id int, cordoned bool) error
+	Revoke(ctx context.Context, id int) error
+	SetExecutors(ctx context.Context, id int, executors *int) error
+	SetLabels(ctx context.Context, id int, labels map[string]string) error
+	RecordProbe(ctx context.Context, id int, ok bool) (int, error)
+	SetUnreachable(ctx context.Context, id int, unreachable bool) error
+	RecordLatency(ctx context.Context, nodeId int) error
+	FindStats(ctx context.Context, id int) ([]*dto.OperationStatsDTO, error)
+	FindFreeWorker(ctx context.Context, requirements ...*capabilities.Requirement) (*dto.WorkerResponseDTO, error)
 }
 
 type workerStorage struct {
-	repository workers_repository.WorkersRepository
+	repository      workers_repository.WorkersRepository
+	statsRepository worker_stats_repository.WorkerStatsRepository
+	policy          *worker_states.Policy
 }
 
-func NewWorkerStorage(repository workers_repository.WorkersRepository) WorkerStorage {
-	return &workerStorage{repository: repository}
+func NewWorkerStorage(
+	repository workers_repository.WorkersRepository,
+	statsRepository worker_stats_repository.WorkerStatsRepository,
+	policy *worker_states.Policy,
+) WorkerStorage {
+	return &workerStorage{
+		repository:      repository,
+		statsRepository: statsRepository,
+		policy:          policy,
+	}
 }
 
-func (w *workerStorage) Register(worker *dto.WorkerRequestDTO) (bool, error) {
-	return w.repository.Register(&workers_repository.WorkerEntity{
-		Id:        int(worker.Id),
-		Url:       worker.Url,
-		Executors: worker.Executors,
-	})
+// Register registers a new worker or refreshes an existing one.
+// Workers without id get a new orchestrator-issued id and secret, which they have to present
+// on every following registration
+//
+// Returns ErrWorkerConflict, if the id was not issued by the orchestrator, the secret does not match
+// or url is used by another worker
+func (w *workerStorage) Register(ctx context.Context, worker *dto.WorkerRequestDTO) (*dto.WorkerRegistrationDTO, error) {
+	var workerCapabilities = worker.Capabilities
+	if workerCapabilities == nil {
+		workerCapabilities = &capabilities.Capabilities{}
+	}
+
+	capabilitiesJSON, err := json.Marshal(workerCapabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	var entity = &workers_repository.WorkerEntity{
+		Id:           int(worker.Id),
+		Url:          worker.Url,
+		Executors:    worker.Executors,
+		Capabilities: capabilitiesJSON,
+		Pull:         worker.Pull,
+	}
+
+	var registration = &dto.WorkerRegistrationDTO{Id: int(worker.Id)}
+
+	if worker.Id == 0 {
+		registration.Secret, err = newSecret()
+		if err != nil {
+			return nil, err
+		}
+
+		entity.SecretHash = hashSecret(registration.Secret)
+
+		err = w.repository.ReleaseUrl(ctx, worker.Url)
+		if err != nil {
+			return nil, err
+		}
+
+		registration.Id, err = w.repository.Create(ctx, entity)
+		if errors.Is(err, workers_repository.ErrWorkerUrlConflict) {
+			return nil, ErrWorkerConflict
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return registration, nil
+	}
+
+	// only ids issued by the orchestrator are accepted, and only together with the secret issued with them
+	secretHash, err := w.repository.FindSecretHash(ctx, entity.Id)
+	if errors.Is(err, workers_repository.ErrWorkerNotFound) {
+		return nil, ErrWorkerConflict
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if secretHash == "" || !secretMatches(secretHash, worker.Secret) {
+		return nil, ErrWorkerConflict
+	}
+
+	entity.SecretHash = secretHash
+
+	err = w.repository.ReleaseUrl(ctx, worker.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	registration.Exists, err = w.repository.Register(ctx, entity)
+	if errors.Is(err, workers_repository.ErrWorkerUrlConflict) {
+		return nil, ErrWorkerConflict
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return registration, nil
 }
 
-func (w *workerStorage) FindAll() ([]*dto.WorkerResponseDTO, error) {
-	entities, err := w.repository.FindAll()
+// Authenticate returns the worker with the given id, if secret was issued to it
+//
+// Returns ErrWorkerUnauthorized, if there is no such worker or the secret does not match
+func (w *workerStorage) Authenticate(ctx context.Context, id int, secret string) (*dto.WorkerResponseDTO, error) {
+	entity, err := w.repository.FindById(ctx, id)
+	if errors.Is(err, workers_repository.ErrWorkerNotFound) {
+		return nil, ErrWorkerUnauthorized
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if entity.SecretHash == "" || !secretMatches(entity.SecretHash, secret) {
+		return nil, ErrWorkerUnauthorized
+	}
+
+	return workerDTO(entity), nil
+}
+
+// FindById returns the worker with the given id. Returns ErrWorkerNotFound, if there is no such worker
+func (w *workerStorage) FindById(ctx context.Context, id int) (*dto.WorkerResponseDTO, error) {
+	entity, err := w.repository.FindById(ctx, id)
+	if errors.Is(err, workers_repository.ErrWorkerNotFound) {
+		return nil, ErrWorkerNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return workerDTO(entity), nil
+}
+
+func (w *workerStorage) FindAll(ctx context.Context) ([]*dto.WorkerResponseDTO, error) {
+	entities, err := w.repository.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -38,12 +192,7 @@ func (w *workerStorage) FindAll() ([]*dto.WorkerResponseDTO, error) {
 	var workers []*dto.WorkerResponseDTO
 
 	for _, e := range entities {
-		workers = append(workers, &dto.WorkerResponseDTO{
-			Id:           e.Id,
-			Url:          e.Url,
-			Executors:    e.Executors,
-			LastModified: e.LastModified,
-		})
+		workers = append(workers, workerDTO(e))
 	}
 
 	if len(workers) == 0 {
@@ -53,27 +202,348 @@ func (w *workerStorage) FindAll() ([]*dto.WorkerResponseDTO, error) {
 	return workers, nil
 }
 
-func (w *workerStorage) DeleteExpiredWorkers(deadline time.Time) ([]int, error) {
-	return w.repository.DeleteExpiredWorkers(deadline)
+// DetectFailures updates worker states according to missed heartbeats.
+// A successful health probe counts as a heartbeat, so workers which answer probes stay alive.
+// Returns ids of workers which have just been declared dead, so their tasks can be reassigned
+func (w *workerStorage) DetectFailures(ctx context.Context, now time.Time) ([]int, error) {
+	entities, err := w.repository.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var dead []int
+
+	for _, entity := range entities {
+		var current = worker_states.State(entity.State)
+
+		missed := w.policy.Missed(lastSeen(entity), now)
+		next := w.policy.Next(current, missed)
+
+		if next == current && missed == entity.MissedHeartbeats {
+			continue
+		}
+
+		err = w.repository.SetHealth(ctx, entity.Id, string(next), missed)
+		if err != nil {
+			return nil, err
+		}
+
+		if next == worker_states.Dead && current != worker_states.Dead {
+			dead = append(dead, entity.Id)
+		}
+	}
+
+	return dead, nil
 }
 
-func (w *workerStorage) FindFreeWorker() (*dto.WorkerResponseDTO, error) {
-	worker, err := w.repository.FindFreeWorker()
+// RecordStats adds task outcomes to the worker counters and returns the updated counters
+func (w *workerStorage) RecordStats(ctx context.Context, id int, completed int, timedOut int, mismatched int) (*dto.WorkerStatsDTO, error) {
+	stats, err := w.repository.AddStats(ctx, id, completed, timedOut, mismatched)
 	if err != nil {
 		return nil, err
 	}
 
-	if worker == nil {
-		return nil, nil
+	return &dto.WorkerStatsDTO{
+		State:             worker_states.State(stats.State),
+		TasksCompleted:    stats.TasksCompleted,
+		TasksTimedOut:     stats.TasksTimedOut,
+		ResultsMismatched: stats.ResultsMismatched,
+	}, nil
+}
+
+// Quarantine moves the worker to the quarantined state. Returns false, if it is already there
+func (w *workerStorage) Quarantine(ctx context.Context, id int, reason string) (bool, error) {
+	return w.repository.Quarantine(ctx, id, reason)
+}
+
+// Release moves a quarantined worker back to the active state. Returns false, if it is not quarantined
+func (w *workerStorage) Release(ctx context.Context, id int) (bool, error) {
+	return w.repository.Release(ctx, id)
+}
+
+// SetState moves the worker to the given state regardless of its heartbeats
+func (w *workerStorage) SetState(ctx context.Context, id int, state worker_states.State) error {
+	return notFound(w.repository.SetState(ctx, id, string(state)))
+}
+
+// SetCordoned forbids or allows assigning new tasks to the worker. Its current tasks are not affected
+func (w *workerStorage) SetCordoned(ctx context.Context, id int, cordoned bool) error {
+	return notFound(w.repository.SetCordoned(ctx, id, cordoned))
+}
+
+// Revoke invalidates the secret issued to the worker. The worker can neither authenticate nor register
+// with its id anymore and has to register as a new worker
+func (w *workerStorage) Revoke(ctx context.Context, id int) error {
+	return notFound(w.repository.RevokeSecret(ctx, id))
+}
+
+// SetExecutors overrides the executors count advertised by the worker. Nil removes the override
+func (w *workerStorage) SetExecutors(ctx context.Context, id int, executors *int) error {
+	var override sql.NullInt32
+
+	if executors != nil {
+		override = sql.NullInt32{Int32: int32(*executors), Valid: true}
+	}
+
+	return notFound(w.repository.SetExecutorsOverride(ctx, id, override))
+}
+
+// SetLabels replaces labels attached to the worker by administrators.
+// They are merged with the labels advertised by the worker and take precedence over them
+func (w *workerStorage) SetLabels(ctx context.Context, id int, labels map[string]string) error {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return err
 	}
 
-	if worker.Executors <= 0 {
-		return nil, nil
+	return notFound(w.repository.SetAdminLabels(ctx, id, labelsJSON))
+}
+
+// RecordProbe saves the result of a health probe and returns the number of consecutive failed probes
+func (w *workerStorage) RecordProbe(ctx context.Context, id int, ok bool) (int, error) {
+	failures, err := w.repository.SaveProbe(ctx, id, ok)
+	if err != nil {
+		return 0, notFound(err)
 	}
 
+	return failures, nil
+}
+
+// SetUnreachable marks the worker which can not be dialed by the orchestrator. Such workers get no pushed tasks
+func (w *workerStorage) SetUnreachable(ctx context.Context, id int, unreachable bool) error {
+	return notFound(w.repository.SetUnreachable(ctx, id, unreachable))
+}
+
+// RecordLatency adds the time since node was started by its worker to the worker statistics
+func (w *workerStorage) RecordLatency(ctx context.Context, nodeId int) error {
+	return w.statsRepository.Record(ctx, nodeId, latencyAlpha)
+}
+
+// FindStats returns learned operation latencies of the worker
+func (w *workerStorage) FindStats(ctx context.Context, id int) ([]*dto.OperationStatsDTO, error) {
+	entities, err := w.statsRepository.FindByWorkerId(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats = []*dto.OperationStatsDTO{}
+
+	for _, entity := range entities {
+		stats = append(stats, &dto.OperationStatsDTO{
+			OperationType: expr_tokens.OperationType(entity.OperationType),
+			Samples:       entity.Samples,
+			MeanMS:        entity.MeanMS,
+			MinMS:         entity.MinMS,
+			MaxMS:         entity.MaxMS,
+			LastMS:        entity.LastMS,
+			UpdatedAt:     entity.UpdatedAt,
+		})
+	}
+
+	return stats, nil
+}
+
+// FindFreeWorker returns the worker which is able to calculate all requirements
+// or nil, if there is no such worker. Workers with lower learned latencies of the required operations
+// are preferred, among equal ones the least loaded worker is returned
+func (w *workerStorage) FindFreeWorker(ctx context.Context, requirements ...*capabilities.Requirement) (*dto.WorkerResponseDTO, error) {
+	workers, err := w.repository.FindFreeWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*dto.WorkerResponseDTO
+
+	for _, worker := range workers {
+		workerCapabilities := withLabels(unmarshalCapabilities(worker.Capabilities), unmarshalLabels(worker.AdminLabels))
+
+		if !satisfiesAll(workerCapabilities, requirements) {
+			continue
+		}
+
+		candidates = append(candidates, &dto.WorkerResponseDTO{
+			Id:           worker.Id,
+			Url:          worker.Url,
+			Executors:    worker.Executors,
+			Capabilities: workerCapabilities,
+		})
+	}
+
+	if len(candidates) <= 1 || len(requirements) == 0 {
+		return first(candidates), nil
+	}
+
+	stats, err := w.statsRepository.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		learned  = newLatencies(stats)
+		fastest  = candidates[0]
+		bestCost = learned.cost(fastest.Id, requirements)
+	)
+
+	for _, candidate := range candidates[1:] {
+		if cost := learned.cost(candidate.Id, requirements); cost < bestCost {
+			fastest, bestCost = candidate, cost
+		}
+	}
+
+	return fastest, nil
+}
+
+func first(workers []*dto.WorkerResponseDTO) *dto.WorkerResponseDTO {
+	if len(workers) == 0 {
+		return nil
+	}
+
+	return workers[0]
+}
+
+func workerDTO(entity *workers_repository.WorkerEntity) *dto.WorkerResponseDTO {
+	var executors = entity.Executors
+	if entity.ExecutorsOverride.Valid {
+		executors = int(entity.ExecutorsOverride.Int32)
+	}
+
+	adminLabels := unmarshalLabels(entity.AdminLabels)
+
 	return &dto.WorkerResponseDTO{
-		Id:        worker.Id,
-		Url:       worker.Url,
-		Executors: worker.Executors,
-	}, nil
+		Id:               entity.Id,
+		Url:              entity.Url,
+		Executors:        executors,
+		LastModified:     entity.LastModified,
+		Capabilities:     withLabels(unmarshalCapabilities(entity.Capabilities), adminLabels),
+		Pull:             entity.Pull,
+		State:            worker_states.State(entity.State),
+		StateChangedAt:   entity.StateChangedAt,
+		LastHeartbeat:    entity.LastModified,
+		InFlight:         entity.InFlight,
+		MissedHeartbeats: entity.MissedHeartbeats,
+		Deaths:           entity.Deaths,
+
+		TasksCompleted:    entity.TasksCompleted,
+		TasksTimedOut:     entity.TasksTimedOut,
+		ResultsMismatched: entity.ResultsMismatched,
+		QuarantineReason:  entity.QuarantineReason,
+
+		Cordoned:            entity.Cordoned,
+		AdvertisedExecutors: entity.Executors,
+		AdminLabels:         adminLabels,
+
+		Unreachable:   entity.Unreachable,
+		LastProbe:     nullTime(entity.LastProbeAt),
+		ProbeFailures: entity.ProbeFailures,
+	}
+}
+
+// lastSeen returns the last time the worker has sent a heartbeat or answered a health probe
+func lastSeen(entity *workers_repository.WorkerEntity) time.Time {
+	if entity.LastProbeOkAt.Valid && entity.LastProbeOkAt.Time.After(entity.LastModified) {
+		return entity.LastProbeOkAt.Time
+	}
+
+	return entity.LastModified
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+func notFound(err error) error {
+	if errors.Is(err, workers_repository.ErrWorkerNotFound) {
+		return ErrWorkerNotFound
+	}
+
+	return err
+}
+
+func satisfiesAll(workerCapabilities *capabilities.Capabilities, requirements []*capabilities.Requirement) bool {
+	for _, requirement := range requirements {
+		if !workerCapabilities.Satisfies(requirement) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func unmarshalCapabilities(data []byte) *capabilities.Capabilities {
+	var workerCapabilities = &capabilities.Capabilities{}
+
+	if len(data) == 0 {
+		return workerCapabilities
+	}
+
+	err := json.Unmarshal(data, workerCapabilities)
+	if err != nil {
+		return &capabilities.Capabilities{}
+	}
+
+	return workerCapabilities
+}
+
+func unmarshalLabels(data []byte) map[string]string {
+	var labels map[string]string
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	err := json.Unmarshal(data, &labels)
+	if err != nil || len(labels) == 0 {
+		return nil
+	}
+
+	return labels
+}
+
+// withLabels adds labels to the worker capabilities, replacing the advertised ones with the same keys
+func withLabels(workerCapabilities *capabilities.Capabilities, labels map[string]string) *capabilities.Capabilities {
+	if len(labels) == 0 {
+		return workerCapabilities
+	}
+
+	var merged = make(map[string]string, len(workerCapabilities.Labels)+len(labels))
+
+	for key, value := range workerCapabilities.Labels {
+		merged[key] = value
+	}
+
+	for key, value := range labels {
+		merged[key] = value
+	}
+
+	workerCapabilities.Labels = merged
+
+	return workerCapabilities
+}
+
+func newSecret() (string, error) {
+	var secret = make([]byte, 32)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+func secretMatches(secretHash string, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(secretHash), []byte(hashSecret(secret))) == 1
 }
@@ -0,0 +1,34 @@
+// Package jsoncodec registers a gRPC codec which encodes messages as JSON.
+//
+// dc-protos declares only the Orchestrator, Daemon and Auth services. Other gRPC services of the calculator
+// are declared by hand next to them: their method names are constants and their messages are plain Go structs,
+// which are transferred with this codec (grpc.CallContentSubtype(jsoncodec.Name) on the client side).
+//
+// Every module keeps its own copy of the package, because the modules are built separately
+package jsoncodec
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+const Name = "json"
+
+type Codec struct{}
+
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
+
+func (Codec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (Codec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (Codec) Name() string {
+	return Name
+}
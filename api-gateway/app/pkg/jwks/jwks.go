@@ -0,0 +1,75 @@
+// Package jwks decodes public keys published as JSON Web Keys (RFC 7517). Only Ed25519 and RSA keys are supported.
+// The keys are encoded by the package of auth with the same name
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrUnsupportedKey = errors.New("jwks: unsupported key")
+
+// Key is a public key. Parameters of other key types are empty
+type Key struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg"`
+
+	// Curve and X are parameters of OKP keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+
+	// N and E are parameters of RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+// Set is the document published at the JWKS endpoint of auth
+type Set struct {
+	Keys []*Key `json:"keys"`
+}
+
+// PublicKey decodes the key
+func (k *Key) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.KeyType == "OKP" && k.Curve == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key size %d", ErrUnsupportedKey, len(x))
+		}
+
+		return ed25519.PublicKey(x), nil
+	case k.KeyType == "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("%w: invalid RSA exponent", ErrUnsupportedKey)
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(exponent.Int64()),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, k.KeyType)
+	}
+}
@@ -1,36 +1,151 @@
 package jwt
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+const (
+	keyIDBytes = 8
+	rsaBits    = 2048
+)
+
+var (
+	ErrKeyNotFound          = errors.New("jwt: signing key not found")
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported signing algorithm")
+)
+
+// Key is a key of an asymmetric signature algorithm. Keys used only for verification have no private key
+type Key struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// GenerateKey creates a key with a random id for AlgorithmEdDSA or AlgorithmRS256
+func GenerateKey(algorithm string) (*Key, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	var privateKey crypto.Signer
+
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	case AlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaBits)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:         id,
+		Algorithm:  algorithm,
+		PrivateKey: privateKey,
+		PublicKey:  privateKey.Public(),
+	}, nil
+}
+
+type KeyProvider interface {
+	// SigningKey returns the key new tokens are signed with
+	SigningKey() (*Key, error)
+	// Key returns the key with the id. Returns ErrKeyNotFound, if there is no such key
+	Key(id string) (*Key, error)
+}
+
+// StaticKeys is a fixed set of keys. The first key signs tokens
+type StaticKeys []*Key
+
+func (s StaticKeys) SigningKey() (*Key, error) {
+	if len(s) == 0 || s[0].PrivateKey == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	return s[0], nil
+}
+
+func (s StaticKeys) Key(id string) (*Key, error) {
+	for _, key := range s {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+
+	return nil, ErrKeyNotFound
+}
+
 type TokenGenerator struct {
-	Signature []byte
-	TokenTTL  time.Duration
+	Keys     KeyProvider
+	TokenTTL time.Duration
 }
 
 type tokenClaims struct {
 	jwt.StandardClaims
 	UserID uint64 `json:"userID"`
 	Login  string `json:"login"`
+	// SessionID lets the api-gateway reject tokens of revoked sessions
+	SessionID string `json:"sid,omitempty"`
+	// OrganizationID is the active organization of the session, zero for the personal workspace
+	OrganizationID   uint64 `json:"org,omitempty"`
+	OrganizationRole string `json:"orgRole,omitempty"`
+}
+
+// Claims describe the owner of a token
+type Claims struct {
+	UserID    uint64
+	Login     string
+	SessionID string
+	// OrganizationID is zero, if the user works in the personal workspace
+	OrganizationID   uint64
+	OrganizationRole string
 }
 
-func (g *TokenGenerator) NewToken(
-	userID uint64,
-	login string,
-) (token string, err error) {
-	accessTokenStr, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
+func (g *TokenGenerator) NewToken(claims *Claims) (token string, err error) {
+	key, err := g.Keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	method, err := signingMethod(key.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	accessToken := jwt.NewWithClaims(method, &tokenClaims{
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  time.Now().Unix(),
 			ExpiresAt: time.Now().Add(g.TokenTTL).Unix(),
 		},
-		UserID: userID,
-		Login:  login,
-	}).SignedString(g.Signature)
+		UserID:           claims.UserID,
+		Login:            claims.Login,
+		SessionID:        claims.SessionID,
+		OrganizationID:   claims.OrganizationID,
+		OrganizationRole: claims.OrganizationRole,
+	})
 
+	accessToken.Header["kid"] = key.ID
+
+	accessTokenStr, err := accessToken.SignedString(key.PrivateKey)
 	if err != nil {
 		return "", err
 	}
@@ -39,24 +154,78 @@ func (g *TokenGenerator) NewToken(
 }
 
 func (g *TokenGenerator) ParseToken(token string) (userID uint64, login string, err error) {
+	claims, err := g.ParseClaims(token)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return claims.UserID, claims.Login, nil
+}
+
+// ParseClaims verifies the token with the key named by its kid header.
+// Errors of the key provider are returned as is, so callers can tell unavailable keys from invalid tokens
+func (g *TokenGenerator) ParseClaims(token string) (*Claims, error) {
 	accessToken, err := jwt.ParseWithClaims(
 		token, &tokenClaims{},
 		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			id, _ := token.Header["kid"].(string)
+			if id == "" {
+				return nil, ErrKeyNotFound
+			}
+
+			key, err := g.Keys.Key(id)
+			if err != nil {
+				return nil, err
+			}
+
+			if token.Method.Alg() != key.Algorithm {
 				return nil, errors.New("invalid signing method")
 			}
 
-			return g.Signature, nil
+			return key.PublicKey, nil
 		})
 
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Inner != nil {
+		return nil, validationErr.Inner
+	}
+
 	if err != nil {
-		return 0, "", err
+		return nil, err
 	}
 
 	claims, ok := accessToken.Claims.(*tokenClaims)
 	if !ok {
-		return 0, "", errors.New("undefined token claims type")
+		return nil, errors.New("undefined token claims type")
 	}
 
-	return claims.UserID, claims.Login, nil
+	return &Claims{
+		UserID:           claims.UserID,
+		Login:            claims.Login,
+		SessionID:        claims.SessionID,
+		OrganizationID:   claims.OrganizationID,
+		OrganizationRole: claims.OrganizationRole,
+	}, nil
+}
+
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+func randomID() (string, error) {
+	var id = make([]byte, keyIDBytes)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(id), nil
 }
@@ -0,0 +1,39 @@
+// Package servicetoken authenticates the api-gateway in internal methods of the auth service
+package servicetoken
+
+import (
+	"context"
+)
+
+const (
+	// Header carries the token shared by the api-gateway and the auth service
+	Header = "x-service-token"
+	// ClientIPHeader carries the address of the client on whose behalf the api-gateway calls the auth service,
+	// so the auth service limits failed calls of every client separately
+	ClientIPHeader = "x-client-ip"
+)
+
+type clientIPKey struct{}
+
+// WithClientIP puts the address of the client into the context of calls to the auth service
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// Credentials are gRPC per-call credentials which add the token and the address of the client to every call
+type Credentials string
+
+func (c Credentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	var md = map[string]string{Header: string(c)}
+
+	if ip, _ := ctx.Value(clientIPKey{}).(string); ip != "" {
+		md[ClientIPHeader] = ip
+	}
+
+	return md, nil
+}
+
+// RequireTransportSecurity is false, because internal links use TLS only when it is configured
+func (c Credentials) RequireTransportSecurity() bool {
+	return false
+}
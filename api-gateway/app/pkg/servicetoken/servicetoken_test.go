@@ -0,0 +1,51 @@
+package servicetoken
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCredentials_GetRequestMetadata(t *testing.T) {
+	type Test struct {
+		name     string
+		ctx      context.Context
+		expected map[string]string
+	}
+
+	var tt = []Test{
+		{
+			name:     "token",
+			ctx:      context.Background(),
+			expected: map[string]string{Header: "secret"},
+		},
+		{
+			name:     "client_ip",
+			ctx:      WithClientIP(context.Background(), "10.0.0.1"),
+			expected: map[string]string{Header: "secret", ClientIPHeader: "10.0.0.1"},
+		},
+		{
+			name:     "unknown_client_ip",
+			ctx:      WithClientIP(context.Background(), ""),
+			expected: map[string]string{Header: "secret"},
+		},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			md, err := Credentials("secret").GetRequestMetadata(test.ctx)
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			if len(md) != len(test.expected) {
+				t.Fatalf("expected %v, but got %v", test.expected, md)
+			}
+
+			for key, value := range test.expected {
+				if md[key] != value {
+					t.Fatalf("expected %v, but got %v", test.expected, md)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/pkg/devca"
+)
+
+func TestHandshake(t *testing.T) {
+	type Test struct {
+		name       string
+		serverName string
+		clientCert bool
+		ok         bool
+	}
+
+	var tt = []Test{
+		{name: "mutual_tls", serverName: "server", clientCert: true, ok: true},
+		{name: "wrong_server_name", serverName: "other", clientCert: true},
+		{name: "no_client_certificate", serverName: "server"},
+	}
+
+	dir := t.TempDir()
+	issue(t, dir, "server", "client")
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			server := load(t, &Config{
+				CertFile:   filepath.Join(dir, "server.crt"),
+				KeyFile:    filepath.Join(dir, "server.key"),
+				CAFile:     filepath.Join(dir, devca.CACertFile),
+				ClientAuth: true,
+			})
+
+			var clientConfig = &Config{CAFile: filepath.Join(dir, devca.CACertFile)}
+			if test.clientCert {
+				clientConfig.CertFile = filepath.Join(dir, "client.crt")
+				clientConfig.KeyFile = filepath.Join(dir, "client.key")
+			}
+
+			client := load(t, clientConfig)
+
+			err := handshake(server, client, test.serverName)
+			if (err == nil) != test.ok {
+				t.Fatalf("expected %v, but got %v", test.ok, err)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	issue(t, dir, "server")
+
+	server := load(t, &Config{
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	})
+
+	client := load(t, &Config{CAFile: filepath.Join(dir, devca.CACertFile)})
+
+	err := handshake(server, client, "server")
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	// The server certificate is rotated with a new CA, so the client has to reload the CA as well
+	err = os.Remove(filepath.Join(dir, devca.CACertFile))
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	issue(t, dir, "server")
+	touch(t, dir)
+
+	reloaded, err := server.Reload()
+	if err != nil || !reloaded {
+		t.Fatalf("expected %v, but got %v (%v)", true, reloaded, err)
+	}
+
+	err = handshake(server, client, "server")
+	if err == nil {
+		t.Fatalf("expected an error, but got %v", err)
+	}
+
+	reloaded, err = client.Reload()
+	if err != nil || !reloaded {
+		t.Fatalf("expected %v, but got %v (%v)", true, reloaded, err)
+	}
+
+	err = handshake(server, client, "server")
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+}
+
+func issue(t *testing.T, dir string, names ...string) {
+	t.Helper()
+
+	ca, err := devca.LoadOrCreate(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	for _, name := range names {
+		err = ca.Issue(dir, name, nil, time.Hour)
+		if err != nil {
+			t.Fatalf("expected %v, but got %v", nil, err)
+		}
+	}
+}
+
+// touch moves modification times of the files forward, because they may be rewritten within the timer resolution
+func touch(t *testing.T, dir string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	future := time.Now().Add(time.Minute)
+
+	for _, entry := range entries {
+		err = os.Chtimes(filepath.Join(dir, entry.Name()), future, future)
+		if err != nil {
+			t.Fatalf("expected %v, but got %v", nil, err)
+		}
+	}
+}
+
+func load(t *testing.T, config *Config) *Reloader {
+	t.Helper()
+
+	r, err := Load(config)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	return r
+}
+
+func handshake(server *Reloader, client *Reloader, serverName string) error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	defer l.Close()
+
+	var serverErr = make(chan error, 1)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+
+		defer conn.Close()
+
+		serverErr <- tls.Server(conn, server.ServerConfig(server.config.ClientAuth)).Handshake()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	var clientConfig = client.ClientConfig()
+	clientConfig.ServerName = serverName
+
+	tlsClient := tls.Client(conn, clientConfig)
+
+	clientErr := tlsClient.Handshake()
+	if clientErr == nil {
+		// Waits until the server has checked the client certificate and closed the connection
+		_, _ = tlsClient.Read(make([]byte, 1))
+	}
+
+	if err := <-serverErr; err != nil {
+		return err
+	}
+
+	return clientErr
+}
+
+func TestDisabled(t *testing.T) {
+	r, err := Load(&Config{})
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	if r != nil {
+		t.Fatalf("expected %v, but got %v", nil, r)
+	}
+
+	if config := r.ServerConfig(true); config != nil {
+		t.Fatalf("expected %v, but got %v", nil, config)
+	}
+
+	if config := r.ClientConfig(); config != nil {
+		t.Fatalf("expected %v, but got %v", nil, config)
+	}
+
+	if protocol := r.ClientCredentials().Info().SecurityProtocol; protocol != "insecure" {
+		t.Fatalf("expected %v, but got %v", "insecure", protocol)
+	}
+}
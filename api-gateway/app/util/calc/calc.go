@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/capabilities"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expressions_storage"
 	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/operators_storage"
@@ -23,8 +24,12 @@ func StartCalculating(
 	expressionStorage expressions_storage.ExpressionStorage,
 
 	workerAPI worker_api.WorkerAPI,
+	subtreeLimits SubtreeLimits,
 ) error {
-	node, err := binaryTreeStorage.FindById(taskID)
+	// tasks are saved before they are dispatched, so dispatching does not stop halfway when the caller goes away
+	ctx = context.WithoutCancel(ctx)
+
+	node, err := binaryTreeStorage.FindById(ctx, taskID)
 	if err != nil {
 		return err
 	}
@@ -35,7 +40,7 @@ func StartCalculating(
 		return nil
 	}
 
-	nodes, err := binaryTreeStorage.FindByParentId(taskID)
+	nodes, err := binaryTreeStorage.FindByParentId(ctx, taskID)
 	if err != nil {
 		return err
 	}
@@ -48,7 +53,12 @@ func StartCalculating(
 	right := nodes[1]
 
 	if left.Status == statuses.Finished && right.Status == statuses.Finished {
-		operations, err := operatorsStorage.FindAll()
+		expr, err := expressionStorage.FindById(ctx, node.ExpressionId)
+		if err != nil {
+			return err
+		}
+
+		operations, err := operatorsStorage.FindFor(ctx, expr.OrganizationID)
 		if err != nil {
 			return err
 		}
@@ -58,7 +68,13 @@ func StartCalculating(
 			return err
 		}
 
-		worker, err := workersStorage.FindFreeWorker()
+		var operation = expr_tokens.OperationType(node.OperationType)
+
+		worker, err := workersStorage.FindFreeWorker(ctx, &capabilities.Requirement{
+			Operation: operation,
+			Operands:  []float64{left.Result, right.Result},
+			Selector:  expr.Selector,
+		})
 		if err != nil {
 			return err
 		}
@@ -67,15 +83,13 @@ func StartCalculating(
 			return nil
 		}
 
-		var operation = expr_tokens.OperationType(node.OperationType)
-
 		if operation == expr_tokens.Divide && right.Result == 0 {
-			err = expressionStorage.MarkAsFailed(node.ExpressionId)
+			err = expressionStorage.MarkAsFailed(ctx, node.ExpressionId)
 			if err != nil {
 				return err
 			}
 
-			err = binaryTreeStorage.MarkAsFailed(node.Id)
+			err = binaryTreeStorage.MarkAsFailed(ctx, node.Id, "division by zero")
 			if err != nil {
 				return err
 			}
@@ -83,6 +97,12 @@ func StartCalculating(
 			return nil
 		}
 
+		// The task is assigned before it is sent, so the worker is allowed to report it at once
+		err = binaryTreeStorage.SaveWorker(ctx, taskID, worker.Id)
+		if err != nil {
+			return err
+		}
+
 		err = workerAPI.Calculate(ctx, worker.Url, userID, &worker_api.CalculationRequestDTO{
 			Id:        uint64(taskID),
 			First:     left.Result,
@@ -91,23 +111,27 @@ func StartCalculating(
 			Duration:  operationDuration,
 		})
 		if err != nil {
-			return err
+			return errors.Join(err, binaryTreeStorage.SaveDispatchFailure(ctx, taskID, worker.Id, err.Error()))
 		}
-
-		err = binaryTreeStorage.SaveWorker(taskID, worker.Id)
+	} else {
+		dispatched, err := startSubtree(ctx, node, binaryTreeStorage, operatorsStorage, workersStorage, expressionStorage, workerAPI, subtreeLimits)
 		if err != nil {
 			return err
 		}
-	} else {
+
+		if dispatched {
+			return nil
+		}
+
 		if left.Status == statuses.Created {
-			err := StartCalculating(ctx, left.Id, binaryTreeStorage, operatorsStorage, workersStorage, expressionStorage, workerAPI)
+			err := StartCalculating(ctx, left.Id, binaryTreeStorage, operatorsStorage, workersStorage, expressionStorage, workerAPI, subtreeLimits)
 			if err != nil {
 				return err
 			}
 		}
 
 		if right.Status == statuses.Created {
-			err := StartCalculating(ctx, right.Id, binaryTreeStorage, operatorsStorage, workersStorage, expressionStorage, workerAPI)
+			err := StartCalculating(ctx, right.Id, binaryTreeStorage, operatorsStorage, workersStorage, expressionStorage, workerAPI, subtreeLimits)
 			if err != nil {
 				return err
 			}
@@ -125,14 +149,17 @@ func CalculateAll(
 	expressionStorage expressions_storage.ExpressionStorage,
 
 	workerAPI worker_api.WorkerAPI,
+	subtreeLimits SubtreeLimits,
 ) error {
-	ids, err := binaryTreeStorage.FindUncalculated()
+	ctx = context.WithoutCancel(ctx)
+
+	ids, err := binaryTreeStorage.FindUncalculated(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, id := range ids {
-		err = StartCalculating(ctx, id, binaryTreeStorage, operatorsStorage, workersStorage, expressionStorage, workerAPI)
+		err = StartCalculating(ctx, id, binaryTreeStorage, operatorsStorage, workersStorage, expressionStorage, workerAPI, subtreeLimits)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,181 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/binary_tree_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/capabilities"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expression/expr_tokens"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/expressions_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/operators_storage"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/statuses"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/worker_api"
+	"github.com/AleksandrVishniakov/distributed-calculator/api-gateway/app/internal/services/workers_storage"
+)
+
+// SubtreeLimits bounds sub-trees which are sent to a single worker at once.
+// MaxNodes is the number of operations in a sub-tree, MaxCost is the sum of their durations.
+// Sub-trees are not sent while MaxNodes is less than 2, zero MaxCost means no cost limit
+type SubtreeLimits struct {
+	MaxNodes int
+	MaxCost  time.Duration
+}
+
+// startSubtree sends the whole sub-tree under node to a single worker, if it fits the limits
+// and some worker is able to calculate all its operations.
+// Returns false, if the sub-tree has to be calculated node by node
+func startSubtree(
+	ctx context.Context,
+	node *dto.ExpressionNodeDTO,
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage,
+	operatorsStorage operators_storage.OperatorsStorage,
+	workersStorage workers_storage.WorkerStorage,
+	expressionStorage expressions_storage.ExpressionStorage,
+
+	workerAPI worker_api.WorkerAPI,
+	limits SubtreeLimits,
+) (bool, error) {
+	if limits.MaxNodes < 2 {
+		return false, nil
+	}
+
+	expr, err := expressionStorage.FindById(ctx, node.ExpressionId)
+	if err != nil {
+		return false, err
+	}
+
+	operations, err := operatorsStorage.FindFor(ctx, expr.OrganizationID)
+	if err != nil {
+		return false, err
+	}
+
+	builder := &subtreeBuilder{
+		binaryTreeStorage: binaryTreeStorage,
+		operations:        operations,
+		limits:            limits,
+	}
+
+	root, ok, err := builder.build(ctx, node)
+	if err != nil {
+		return false, err
+	}
+
+	if !ok || len(builder.ids) < 2 {
+		return false, nil
+	}
+
+	for _, requirement := range builder.requirements {
+		requirement.Selector = expr.Selector
+	}
+
+	worker, err := workersStorage.FindFreeWorker(ctx, builder.requirements...)
+	if err != nil {
+		return false, err
+	}
+
+	if worker == nil {
+		return false, nil
+	}
+
+	for _, id := range builder.ids {
+		err = binaryTreeStorage.SaveWorker(ctx, id, worker.Id)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = workerAPI.CalculateSubtree(ctx, worker.Url, node.UserID, root)
+	if err != nil {
+		var errs = []error{err}
+
+		for _, id := range builder.ids {
+			errs = append(errs, binaryTreeStorage.SaveDispatchFailure(ctx, id, worker.Id, err.Error()))
+		}
+
+		return false, errors.Join(errs...)
+	}
+
+	return true, nil
+}
+
+type subtreeBuilder struct {
+	binaryTreeStorage binary_tree_storage.BinaryTreeStorage
+	operations        []*dto.OperationDTO
+	limits            SubtreeLimits
+
+	cost         time.Duration
+	ids          []int
+	requirements []*capabilities.Requirement
+}
+
+// build collects not started operations under node. Calculated nodes become leaves of the sub-tree.
+// Returns false, if some operation is already assigned or the sub-tree exceeds the limits
+func (b *subtreeBuilder) build(ctx context.Context, node *dto.ExpressionNodeDTO) (*worker_api.SubtreeNodeDTO, bool, error) {
+	if node.Status == statuses.Finished {
+		return &worker_api.SubtreeNodeDTO{
+			Id:    uint64(node.Id),
+			Value: node.Result,
+		}, true, nil
+	}
+
+	if node.Status != statuses.Created || len(b.ids) >= b.limits.MaxNodes {
+		return nil, false, nil
+	}
+
+	var operation = expr_tokens.OperationType(node.OperationType)
+
+	duration, err := getOperationDuration(b.operations, operation)
+	if err != nil {
+		return nil, false, err
+	}
+
+	b.cost += duration
+	if b.limits.MaxCost > 0 && b.cost > b.limits.MaxCost {
+		return nil, false, nil
+	}
+
+	b.ids = append(b.ids, node.Id)
+
+	children, err := b.binaryTreeStorage.FindByParentId(ctx, node.Id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(children) != 2 {
+		return nil, false, nil
+	}
+
+	left, ok, err := b.build(ctx, children[0])
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	right, ok, err := b.build(ctx, children[1])
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	var requirement = &capabilities.Requirement{Operation: operation}
+
+	if left.Left == nil && right.Left == nil {
+		// division by zero is reported by the node by node calculation
+		if operation == expr_tokens.Divide && right.Value == 0 {
+			return nil, false, nil
+		}
+
+		requirement.Operands = []float64{left.Value, right.Value}
+	}
+
+	b.requirements = append(b.requirements, requirement)
+
+	return &worker_api.SubtreeNodeDTO{
+		Id:         uint64(node.Id),
+		Operation:  operation,
+		DurationMS: duration.Milliseconds(),
+		Left:       left,
+		Right:      right,
+	}, true, nil
+}
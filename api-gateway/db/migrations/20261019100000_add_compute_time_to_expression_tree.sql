@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE expressions_tree ADD COLUMN IF NOT EXISTS compute_time_us BIGINT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE expressions_tree DROP COLUMN IF EXISTS compute_time_us;
+-- +goose StatementEnd
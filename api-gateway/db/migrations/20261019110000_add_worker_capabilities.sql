@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS capabilities JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE expressions ADD COLUMN IF NOT EXISTS selector JSONB NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE expressions DROP COLUMN IF EXISTS selector;
+ALTER TABLE workers DROP COLUMN IF EXISTS capabilities;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS secret_hash VARCHAR(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workers DROP COLUMN IF EXISTS secret_hash;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS pull BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE expressions_tree ADD COLUMN IF NOT EXISTS lease_expires_at timestamptz;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE expressions_tree DROP COLUMN IF EXISTS lease_expires_at;
+ALTER TABLE workers DROP COLUMN IF EXISTS pull;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS state VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS missed_heartbeats INT NOT NULL DEFAULT 0;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS deaths INT NOT NULL DEFAULT 0;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS state_changed_at timestamptz NOT NULL DEFAULT NOW();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workers DROP COLUMN IF EXISTS state_changed_at;
+ALTER TABLE workers DROP COLUMN IF EXISTS deaths;
+ALTER TABLE workers DROP COLUMN IF EXISTS missed_heartbeats;
+ALTER TABLE workers DROP COLUMN IF EXISTS state;
+-- +goose StatementEnd
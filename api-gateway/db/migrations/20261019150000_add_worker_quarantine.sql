@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS tasks_completed INT NOT NULL DEFAULT 0;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS tasks_timed_out INT NOT NULL DEFAULT 0;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS results_mismatched INT NOT NULL DEFAULT 0;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS quarantine_reason TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS worker_events (
+    id SERIAL PRIMARY KEY,
+    worker_id INT NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS worker_events_worker_id_idx ON worker_events (worker_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS worker_events;
+ALTER TABLE workers DROP COLUMN IF EXISTS quarantine_reason;
+ALTER TABLE workers DROP COLUMN IF EXISTS results_mismatched;
+ALTER TABLE workers DROP COLUMN IF EXISTS tasks_timed_out;
+ALTER TABLE workers DROP COLUMN IF EXISTS tasks_completed;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS cordoned BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS executors_override INT;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS admin_labels JSONB NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workers DROP COLUMN IF EXISTS admin_labels;
+ALTER TABLE workers DROP COLUMN IF EXISTS executors_override;
+ALTER TABLE workers DROP COLUMN IF EXISTS cordoned;
+-- +goose StatementEnd
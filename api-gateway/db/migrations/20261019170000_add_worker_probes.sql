@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS last_probe_at TIMESTAMP;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS last_probe_ok_at TIMESTAMP;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS probe_failures INT NOT NULL DEFAULT 0;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS unreachable BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workers DROP COLUMN IF EXISTS unreachable;
+ALTER TABLE workers DROP COLUMN IF EXISTS probe_failures;
+ALTER TABLE workers DROP COLUMN IF EXISTS last_probe_ok_at;
+ALTER TABLE workers DROP COLUMN IF EXISTS last_probe_at;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE expressions_tree ADD COLUMN IF NOT EXISTS started_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS worker_operation_stats (
+    worker_id INT NOT NULL,
+    operation_type INT NOT NULL,
+    samples INT NOT NULL DEFAULT 0,
+    mean_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    min_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    last_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (worker_id, operation_type)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS worker_operation_stats;
+ALTER TABLE expressions_tree DROP COLUMN IF EXISTS started_at;
+-- +goose StatementEnd
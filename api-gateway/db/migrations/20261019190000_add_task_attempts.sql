@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS task_attempts (
+    id SERIAL PRIMARY KEY,
+    node_id INT NOT NULL,
+    expression_id INT NOT NULL,
+    worker_id INT NOT NULL,
+    attempt INT NOT NULL,
+    enqueued_at timestamptz NOT NULL DEFAULT NOW(),
+    started_at timestamptz,
+    finished_at timestamptz,
+    outcome VARCHAR(32) NOT NULL,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS task_attempts_node_id_idx ON task_attempts (node_id);
+CREATE INDEX IF NOT EXISTS task_attempts_expression_id_idx ON task_attempts (expression_id);
+CREATE INDEX IF NOT EXISTS task_attempts_open_idx ON task_attempts (worker_id) WHERE finished_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS task_attempts;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE expressions ADD COLUMN IF NOT EXISTS compacted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS expressions_user_id_created_at_idx ON expressions (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS expressions_tree_expression_id_idx ON expressions_tree (expression_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS expressions_tree_expression_id_idx;
+DROP INDEX IF EXISTS expressions_user_id_created_at_idx;
+ALTER TABLE expressions DROP COLUMN IF EXISTS compacted_at;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS expressions_status_idx ON expressions (status);
+CREATE INDEX IF NOT EXISTS expressions_user_id_finished_at_idx ON expressions (user_id, finished_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS expressions_user_id_finished_at_idx;
+DROP INDEX IF EXISTS expressions_status_idx;
+-- +goose StatementEnd
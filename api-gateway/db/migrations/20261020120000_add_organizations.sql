@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE expressions ADD COLUMN IF NOT EXISTS organization_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS expressions_organization_id_created_at_idx ON expressions (organization_id, created_at DESC)
+    WHERE organization_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS organization_operators (
+    organization_id BIGINT NOT NULL,
+    operator_type INT NOT NULL,
+    duration_ms INT NOT NULL,
+    PRIMARY KEY (organization_id, operator_type)
+);
+
+CREATE TABLE IF NOT EXISTS organization_quotas (
+    organization_id BIGINT PRIMARY KEY,
+    max_active_expressions INT,
+    max_daily_expressions INT,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS organization_quotas;
+DROP TABLE IF EXISTS organization_operators;
+DROP INDEX IF EXISTS expressions_organization_id_created_at_idx;
+ALTER TABLE expressions DROP COLUMN IF EXISTS organization_id;
+-- +goose StatementEnd
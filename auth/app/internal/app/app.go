@@ -2,19 +2,38 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"log/slog"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/app/dbapp"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/app/grpcapp"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/app/httpapp"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/apikeysrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/auditrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/credentialsrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/failedloginsrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/keysrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/organizationsrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/sessionsrepo"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/usersrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers/grpcsrv"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers/httpsrv/handlers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/accounts"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/apikeys"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/auth"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/organizations"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/ratelimit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/signingkeys"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/userscache"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/workercreds"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/tlsconfig"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/utils/configs"
 
 	"google.golang.org/grpc"
@@ -27,6 +46,9 @@ type App struct {
 	gRPCServer *grpcapp.App
 	httpApp    *httpapp.App
 	dbApp      *dbapp.App
+
+	signingKeys    *signingkeys.SigningKeys
+	keysSyncPeriod time.Duration
 }
 
 func New(
@@ -54,25 +76,118 @@ func New(
 
 	usersCache := userscache.New(log, usersRepository, cfg.Cache.MaxSize, cfg.Cache.TTL)
 
+	signingKeys := signingkeys.New(log, keysrepo.New(log, dbApp.DB), &signingkeys.Policy{
+		Algorithm:      cfg.JWT.Algorithm,
+		RotationPeriod: cfg.JWT.RotationPeriod,
+		TokenTTL:       cfg.TokenTTL,
+	})
+
 	tokenGenerator := &jwt.TokenGenerator{
-		Signature: []byte(getenv("JWT_SIGNATURE")),
-		TokenTTL:  cfg.TokenTTL,
+		Keys:     signingKeys,
+		TokenTTL: cfg.TokenTTL,
 	}
 
-	authService := auth.New(log, usersRepository, usersCache, tokenGenerator)
+	sessionsRepository := sessionsrepo.New(log, dbApp.DB)
+
+	// Locked keys must not be forgotten before their lockout ends
+	countersTTL := max(cfg.RateLimit.Window, cfg.RateLimit.MaxLockout)
+
+	loginLimiter := ratelimit.New(ratelimit.NewMemoryCounters(countersTTL), &ratelimit.Policy{
+		MaxFailures: cfg.RateLimit.LoginMaxFailures,
+		Lockout:     cfg.RateLimit.Lockout,
+		MaxLockout:  cfg.RateLimit.MaxLockout,
+	})
+
+	ipLimiter := ratelimit.New(ratelimit.NewMemoryCounters(countersTTL), &ratelimit.Policy{
+		MaxFailures: cfg.RateLimit.IPMaxFailures,
+		Lockout:     cfg.RateLimit.Lockout,
+		MaxLockout:  cfg.RateLimit.MaxLockout,
+	})
+
+	auditLog := audit.New(log, auditrepo.New(log, dbApp.DB))
+
+	authService := auth.New(
+		log,
+		usersRepository,
+		usersCache,
+		tokenGenerator,
+		sessionsRepository,
+		cfg.RefreshTokenTTL,
+		loginLimiter,
+		failedloginsrepo.New(log, dbApp.DB),
+		auditLog,
+	)
 
-	gRPCServer := grpc.NewServer()
+	credentialsRepository := credentialsrepo.New(log, dbApp.DB)
+
+	workerCredentials := workercreds.New(log, credentialsRepository, auditLog)
+
+	apiKeys := apikeys.New(log, apikeysrepo.New(log, dbApp.DB), auditLog)
+
+	userAccounts := accounts.New(log, usersRepository, sessionsRepository, usersCache, auditLog)
+
+	userOrganizations := organizations.New(log, organizationsrepo.New(log, dbApp.DB), sessionsRepository, tokenGenerator, auditLog)
+
+	tlsReloader, err := tlsconfig.Load(&tlsconfig.Config{
+		CertFile:   cfg.TLS.CertFile,
+		KeyFile:    cfg.TLS.KeyFile,
+		CAFile:     cfg.TLS.CAFile,
+		ClientAuth: cfg.TLS.ClientAuth,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tlsReloader.Watch(ctx, cfg.TLS.ReloadPeriod, func(err error) {
+		log.Error("failed to reload tls certificates", sl.Err(err))
+	})
+
+	serviceToken := getenv("SERVICE_TOKEN")
+	if serviceToken == "" {
+		log.Warn("SERVICE_TOKEN is empty, internal gRPC methods are disabled")
+	}
+
+	gRPCServer := grpc.NewServer(
+		grpc.Creds(tlsReloader.ServerCredentials()),
+		grpc.ChainUnaryInterceptor(
+			grpcsrv.ClientIPInterceptor,
+			grpcsrv.NewServiceTokenInterceptor(log, serviceToken, grpcsrv.InternalMethod),
+			grpcsrv.NewRateLimitInterceptor(log, ipLimiter, grpcsrv.RateLimitedMethod),
+		),
+	)
 
 	grpcApp := grpcapp.New(
 		log,
 		gRPCServer,
 		cfg.GRPC.Port,
 		authService,
+		authService,
+		workerCredentials,
+		apiKeys,
+		userAccounts,
 	)
 
-	httpHandler := handlers.NewHTTPHandler(log, authService)
+	httpHandler := handlers.NewHTTPHandler(
+		log,
+		authService,
+		workerCredentials,
+		apiKeys,
+		userAccounts,
+		userOrganizations,
+		auditLog,
+		tokenGenerator,
+		signingKeys,
+		listEnv(getenv("ADMIN_LOGINS")),
+		ipLimiter,
+	)
 
-	httpApp := httpapp.New(ctx, log, cfg.HTTP.Port, httpHandler.Handler())
+	// The HTTP API is called by browsers, so it uses TLS only on demand and never requires client certificates
+	var httpTLSConfig *tls.Config
+	if cfg.TLS.HTTP {
+		httpTLSConfig = tlsReloader.ServerConfig(false)
+	}
+
+	httpApp := httpapp.New(ctx, log, cfg.HTTP.Port, httpHandler.Handler(), httpTLSConfig)
 
 	return &App{
 		ctx:        ctx,
@@ -80,6 +195,9 @@ func New(
 		gRPCServer: grpcApp,
 		httpApp:    httpApp,
 		dbApp:      dbApp,
+
+		signingKeys:    signingKeys,
+		keysSyncPeriod: cfg.JWT.SyncPeriod,
 	}, nil
 }
 
@@ -96,6 +214,16 @@ func (a *App) Run(wg *sync.WaitGroup) error {
 		return nil
 	}
 
+	// Tokens cannot be issued or verified without keys, so the first sync must succeed
+	err = a.signingKeys.Sync(a.ctx)
+	if err != nil {
+		return err
+	}
+
+	a.signingKeys.Watch(a.ctx, a.keysSyncPeriod, func(err error) {
+		log.Error("failed to sync signing keys", sl.Err(err))
+	})
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -137,3 +265,17 @@ func (a *App) Stop() error {
 
 	return nil
 }
+
+// listEnv splits a comma separated list and drops empty items
+func listEnv(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -22,8 +22,16 @@ func New(
 	server *grpc.Server,
 	port int,
 	authService grpcsrv.Auth,
+	sessions grpcsrv.Sessions,
+	workerAuthenticator grpcsrv.WorkerAuthenticator,
+	apiKeyAuthenticator grpcsrv.APIKeyAuthenticator,
+	userEvents grpcsrv.UserEvents,
 ) *App {
 	grpcsrv.Register(server, authService)
+	grpcsrv.RegisterSessions(server, sessions)
+	grpcsrv.RegisterWorkerCredentials(server, workerAuthenticator)
+	grpcsrv.RegisterAPIKeys(server, apiKeyAuthenticator)
+	grpcsrv.RegisterUsers(server, userEvents)
 
 	return &App{
 		log:    log,
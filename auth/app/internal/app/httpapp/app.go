@@ -2,6 +2,7 @@ package httpapp
 
 import (
 	"context"
+	"crypto/tls"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
 	"log/slog"
 	"net"
@@ -23,6 +24,7 @@ func New(
 
 	port int,
 	handler http.Handler,
+	tlsConfig *tls.Config,
 ) *App {
 	log = log.With(
 		slog.String("addr", net.JoinHostPort("localhost", strconv.Itoa(port))),
@@ -30,7 +32,7 @@ func New(
 
 	return &App{
 		log:    log,
-		server: httpsrv.New(ctx, port, handler),
+		server: httpsrv.New(ctx, port, handler, tlsConfig),
 	}
 }
 
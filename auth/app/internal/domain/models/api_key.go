@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// APIKey lets scripts of a user call the api-gateway without a password. Only the hash of the key is stored
+type APIKey struct {
+	ID     uint64
+	UserID uint64
+	// Login is the login of the owner. It is set only when the key is authenticated
+	Login      string
+	Name       string
+	Scope      string
+	TokenHash  string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditActorUser is the type of actors of audit records. Records of anonymous actions have no actor type
+const AuditActorUser = "user"
+
+// Audited actions
+const (
+	AuditUserRegistered            = "user.registered"
+	AuditUserLoggedIn              = "user.logged_in"
+	AuditUserLoggedOut             = "user.logged_out"
+	AuditPasswordChanged           = "user.password_changed"
+	AuditUserDeleted               = "user.deleted"
+	AuditAPIKeyCreated             = "api_key.created"
+	AuditAPIKeyRevoked             = "api_key.revoked"
+	AuditWorkerCredentialCreated   = "worker_credential.created"
+	AuditWorkerCredentialRevoked   = "worker_credential.revoked"
+	AuditOrganizationCreated       = "organization.created"
+	AuditOrganizationMemberAdded   = "organization.member_added"
+	AuditOrganizationMemberRemoved = "organization.member_removed"
+)
+
+// Types of targets of audit records
+const (
+	AuditTargetUser             = "user"
+	AuditTargetSession          = "session"
+	AuditTargetAPIKey           = "api_key"
+	AuditTargetWorkerCredential = "worker_credential"
+	AuditTargetOrganization     = "organization"
+)
+
+// AuditRecord is an append-only record of a security relevant or administrative action.
+// Before and After are states of the target encoded as JSON, nil means no state. Secrets are never recorded
+type AuditRecord struct {
+	ID         uint64
+	ActorType  string
+	ActorID    uint64
+	ActorName  string
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     json.RawMessage
+	After      json.RawMessage
+	// IP is the address of the client, empty if it is unknown
+	IP        string
+	CreatedAt time.Time
+}
+
+// AuditFilter selects audit records. Zero fields match all records.
+// Records are ordered by ID, Descending records go before AfterID, others go after it
+type AuditFilter struct {
+	ActorID    uint64
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+
+	Descending bool
+	AfterID    uint64
+	Limit      int
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Reasons of failed logins
+const (
+	FailedLoginUserNotFound    = "user_not_found"
+	FailedLoginInvalidPassword = "invalid_password"
+	FailedLoginLocked          = "locked"
+)
+
+// FailedLogin is an audit record of a rejected login attempt
+type FailedLogin struct {
+	ID    uint64
+	Login string
+	// IP is the address of the client, empty if it is unknown
+	IP        string
+	Reason    string
+	CreatedAt time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Roles of organization members. The owner creates the organization and cannot be removed from it,
+// owners and admins manage members
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+// Organization shares a workspace of expressions between its members
+type Organization struct {
+	ID        uint64
+	Name      string
+	CreatedAt time.Time
+
+	// Role is the role of the user the organization is listed for
+	Role string
+}
+
+type OrganizationMember struct {
+	OrganizationID uint64
+	UserID         uint64
+	Login          string
+	Role           string
+	CreatedAt      time.Time
+}
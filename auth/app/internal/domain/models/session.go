@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Session is started on login and lasts while its refresh tokens are rotated.
+// Access tokens carry the session id, so revoking the session revokes all of them
+type Session struct {
+	ID        string
+	UserID    uint64
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+
+	// OrganizationID is the active organization of the session, zero for the personal workspace.
+	// OrganizationRole is the role of the user in it
+	OrganizationID   uint64
+	OrganizationRole string
+}
+
+// RefreshToken is a single use token exchanged for a new pair of tokens. Only the hash of the token is stored
+type RefreshToken struct {
+	ID        uint64
+	SessionID string
+	TokenHash string
+	UsedAt    *time.Time
+
+	// Session and Login describe the session the token belongs to
+	Session *Session
+	Login   string
+}
+
+// Tokens are issued on login and on every refresh
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
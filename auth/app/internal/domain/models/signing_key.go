@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// SigningKey signs access tokens. A retired key only verifies tokens issued before its retirement
+// and is deleted when they expire
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	// PrivateKey is encoded as PKCS #8 PEM
+	PrivateKey string
+	CreatedAt  time.Time
+	RetiredAt  *time.Time
+	ExpiresAt  *time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserEventDeleted is published when an account is deleted, so other services delete data of the user
+const UserEventDeleted = "user.deleted"
+
+// UserEvent is a change of an account published to other services. Events are ordered by ID
+type UserEvent struct {
+	ID        uint64
+	Type      string
+	UserID    uint64
+	CreatedAt time.Time
+}
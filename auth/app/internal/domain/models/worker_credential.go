@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// WorkerCredential authenticates a daemon in the orchestrator. Only the hash of its token is stored
+type WorkerCredential struct {
+	ID        uint64
+	Name      string
+	TokenHash string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
@@ -0,0 +1,214 @@
+package apikeysrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+var ErrKeyNotFound = errors.New("apikeysrepo: api key not found")
+
+type APIKeyRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func New(
+	log *slog.Logger,
+	db *sql.DB,
+) *APIKeyRepository {
+	return &APIKeyRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Save creates the key and sets its id and creation time
+func (k *APIKeyRepository) Save(ctx context.Context, key *models.APIKey) error {
+	const src = "APIKeyRepository.Save"
+
+	log := k.log.With(
+		slog.String("src", src),
+		slog.Uint64("userID", key.UserID),
+	)
+
+	row := k.db.QueryRowContext(
+		ctx,
+		`INSERT INTO api_keys (user_id, name, scope, token_hash)
+				VALUES ($1, $2, $3, $4)
+				RETURNING id, created_at`,
+		key.UserID,
+		key.Name,
+		key.Scope,
+		key.TokenHash,
+	)
+
+	err := row.Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		log.Error("failed to create api key", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	log.Debug("api key saved")
+
+	return nil
+}
+
+// Keys returns keys of the user, including revoked ones
+func (k *APIKeyRepository) Keys(ctx context.Context, userID uint64) (keys []*models.APIKey, err error) {
+	const src = "APIKeyRepository.Keys"
+
+	log := k.log.With(
+		slog.String("src", src),
+		slog.Uint64("userID", userID),
+	)
+
+	rows, err := k.db.QueryContext(
+		ctx,
+		`SELECT k.id, k.user_id, '', k.name, k.scope, k.token_hash, k.created_at, k.last_used_at, k.revoked_at
+				FROM api_keys k
+				WHERE k.user_id=$1
+				ORDER BY k.id`,
+		userID,
+	)
+	if err != nil {
+		log.Error("failed to get api keys", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	defer rows.Close()
+
+	keys = []*models.APIKey{}
+
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			log.Error("failed to scan api key", sl.Err(err))
+			return nil, e.WrapErr(err, src)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, e.WrapErrIfNotNil(rows.Err(), src)
+}
+
+// KeyByTokenHash returns the key together with the login of its owner
+func (k *APIKeyRepository) KeyByTokenHash(ctx context.Context, tokenHash string) (key *models.APIKey, err error) {
+	const src = "APIKeyRepository.KeyByTokenHash"
+
+	log := k.log.With(
+		slog.String("src", src),
+	)
+
+	row := k.db.QueryRowContext(
+		ctx,
+		`SELECT k.id, k.user_id, u.login, k.name, k.scope, k.token_hash, k.created_at, k.last_used_at, k.revoked_at
+				FROM api_keys k
+				JOIN users u ON u.id = k.user_id
+				WHERE k.token_hash=$1`,
+		tokenHash,
+	)
+
+	key, err = scanKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("api key not found")
+			return nil, e.WrapErr(ErrKeyNotFound, src)
+		}
+		log.Error("failed to get api key", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	return key, nil
+}
+
+// Touch sets the last usage time of the key to now
+func (k *APIKeyRepository) Touch(ctx context.Context, id uint64) error {
+	const src = "APIKeyRepository.Touch"
+
+	_, err := k.db.ExecContext(
+		ctx,
+		`UPDATE api_keys SET last_used_at = NOW()
+				WHERE id=$1`,
+		id,
+	)
+	if err != nil {
+		k.log.Error("failed to update api key usage", slog.String("src", src), slog.Uint64("id", id), sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	return nil
+}
+
+// Revoke marks the key of the user as revoked. Revoking a revoked key keeps its revocation time
+func (k *APIKeyRepository) Revoke(ctx context.Context, userID uint64, id uint64) error {
+	const src = "APIKeyRepository.Revoke"
+
+	log := k.log.With(
+		slog.String("src", src),
+		slog.Uint64("userID", userID),
+		slog.Uint64("id", id),
+	)
+
+	result, err := k.db.ExecContext(
+		ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+				WHERE id=$1 AND user_id=$2`,
+		id,
+		userID,
+	)
+	if err != nil {
+		log.Error("failed to revoke api key", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	if affected == 0 {
+		log.Warn("api key not found")
+		return e.WrapErr(ErrKeyNotFound, src)
+	}
+
+	log.Debug("api key revoked")
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner) (*models.APIKey, error) {
+	var (
+		key        = &models.APIKey{}
+		lastUsedAt sql.NullTime
+		revokedAt  sql.NullTime
+	)
+
+	err := row.Scan(
+		&key.ID, &key.UserID, &key.Login, &key.Name, &key.Scope, &key.TokenHash,
+		&key.CreatedAt, &lastUsedAt, &revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return key, nil
+}
@@ -0,0 +1,182 @@
+package auditrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+// maxNameLength is the length of the actor_name column. Longer names are truncated
+const maxNameLength = 64
+
+// AuditRepository appends records to the audit log and reads them. Records are never changed or deleted
+type AuditRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func New(
+	log *slog.Logger,
+	db *sql.DB,
+) *AuditRepository {
+	return &AuditRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Save appends the record and sets its id and creation time
+func (a *AuditRepository) Save(ctx context.Context, record *models.AuditRecord) error {
+	const src = "AuditRepository.Save"
+
+	name := record.ActorName
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength])
+	}
+
+	row := a.db.QueryRowContext(
+		ctx,
+		`INSERT INTO audit_log (actor_type, actor_id, actor_name, action, target_type, target_id, before, after, ip)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				RETURNING id, created_at`,
+		record.ActorType,
+		record.ActorID,
+		name,
+		record.Action,
+		record.TargetType,
+		record.TargetID,
+		nullJSON(record.Before),
+		nullJSON(record.After),
+		record.IP,
+	)
+
+	err := row.Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		a.log.Error("failed to save audit record", slog.String("src", src), sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	return nil
+}
+
+// Records returns records matching the filter in the order of ids
+func (a *AuditRepository) Records(ctx context.Context, filter *models.AuditFilter) (records []*models.AuditRecord, err error) {
+	const src = "AuditRepository.Records"
+
+	where, args := conditions(filter)
+
+	direction := "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+
+	args = append(args, filter.Limit)
+
+	rows, err := a.db.QueryContext(
+		ctx,
+		fmt.Sprintf(
+			`SELECT id, actor_type, actor_id, actor_name, action, target_type, target_id, before, after, ip, created_at
+				FROM audit_log WHERE %s ORDER BY id %s LIMIT $%d`,
+			where,
+			direction,
+			len(args),
+		),
+		args...,
+	)
+	if err != nil {
+		a.log.Error("failed to get audit records", slog.String("src", src), sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	defer rows.Close()
+
+	records = []*models.AuditRecord{}
+
+	for rows.Next() {
+		var record = &models.AuditRecord{}
+
+		err = rows.Scan(
+			&record.ID,
+			&record.ActorType,
+			&record.ActorID,
+			&record.ActorName,
+			&record.Action,
+			&record.TargetType,
+			&record.TargetID,
+			(*[]byte)(&record.Before),
+			(*[]byte)(&record.After),
+			&record.IP,
+			&record.CreatedAt,
+		)
+		if err != nil {
+			return nil, e.WrapErr(err, src)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, e.WrapErrIfNotNil(rows.Err(), src)
+}
+
+func conditions(filter *models.AuditFilter) (string, []any) {
+	var (
+		where = []string{"TRUE"}
+		args  []any
+	)
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.ActorID != 0 {
+		where = append(where, "actor_id = "+arg(filter.ActorID))
+	}
+
+	if filter.Action != "" {
+		where = append(where, "action = "+arg(filter.Action))
+	}
+
+	if filter.TargetType != "" {
+		where = append(where, "target_type = "+arg(filter.TargetType))
+	}
+
+	if filter.TargetID != "" {
+		where = append(where, "target_id = "+arg(filter.TargetID))
+	}
+
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= "+arg(filter.From))
+	}
+
+	if !filter.To.IsZero() {
+		where = append(where, "created_at < "+arg(filter.To))
+	}
+
+	if filter.AfterID != 0 {
+		comparison := ">"
+		if filter.Descending {
+			comparison = "<"
+		}
+
+		where = append(where, fmt.Sprintf("id %s %s", comparison, arg(filter.AfterID)))
+	}
+
+	return strings.Join(where, " AND "), args
+}
+
+// nullJSON stores missing states as NULL
+func nullJSON(state []byte) any {
+	if state == nil {
+		return nil
+	}
+
+	return string(state)
+}
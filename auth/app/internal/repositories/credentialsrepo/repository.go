@@ -0,0 +1,176 @@
+package credentialsrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+var ErrCredentialNotFound = errors.New("credentialsrepo: worker credential not found")
+
+type CredentialsRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func New(
+	log *slog.Logger,
+	db *sql.DB,
+) *CredentialsRepository {
+	return &CredentialsRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Save creates the credential and sets its id and creation time
+func (c *CredentialsRepository) Save(ctx context.Context, credential *models.WorkerCredential) error {
+	const src = "CredentialsRepository.Save"
+
+	log := c.log.With(
+		slog.String("src", src),
+		slog.String("name", credential.Name),
+	)
+
+	row := c.db.QueryRowContext(
+		ctx,
+		`INSERT INTO worker_credentials (name, token_hash)
+				VALUES ($1, $2)
+				RETURNING id, created_at`,
+		credential.Name,
+		credential.TokenHash,
+	)
+
+	err := row.Scan(&credential.ID, &credential.CreatedAt)
+	if err != nil {
+		log.Error("failed to create worker credential", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	log.Debug("worker credential saved")
+
+	return nil
+}
+
+func (c *CredentialsRepository) Credentials(ctx context.Context) (credentials []*models.WorkerCredential, err error) {
+	const src = "CredentialsRepository.Credentials"
+
+	log := c.log.With(
+		slog.String("src", src),
+	)
+
+	rows, err := c.db.QueryContext(
+		ctx,
+		`SELECT c.id, c.name, c.token_hash, c.created_at, c.revoked_at FROM worker_credentials c
+				ORDER BY c.id`,
+	)
+	if err != nil {
+		log.Error("failed to get worker credentials", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	defer rows.Close()
+
+	credentials = []*models.WorkerCredential{}
+
+	for rows.Next() {
+		credential, err := scanCredential(rows)
+		if err != nil {
+			log.Error("failed to scan worker credential", sl.Err(err))
+			return nil, e.WrapErr(err, src)
+		}
+
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, e.WrapErrIfNotNil(rows.Err(), src)
+}
+
+func (c *CredentialsRepository) CredentialByTokenHash(ctx context.Context, tokenHash string) (credential *models.WorkerCredential, err error) {
+	const src = "CredentialsRepository.CredentialByTokenHash"
+
+	log := c.log.With(
+		slog.String("src", src),
+	)
+
+	row := c.db.QueryRowContext(
+		ctx,
+		`SELECT c.id, c.name, c.token_hash, c.created_at, c.revoked_at FROM worker_credentials c
+				WHERE token_hash=$1`,
+		tokenHash,
+	)
+
+	credential, err = scanCredential(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("worker credential not found")
+			return nil, e.WrapErr(ErrCredentialNotFound, src)
+		}
+		log.Error("failed to get worker credential", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	return credential, nil
+}
+
+// Revoke marks the credential as revoked. Revoking a revoked credential keeps its revocation time
+func (c *CredentialsRepository) Revoke(ctx context.Context, id uint64) error {
+	const src = "CredentialsRepository.Revoke"
+
+	log := c.log.With(
+		slog.String("src", src),
+		slog.Uint64("id", id),
+	)
+
+	result, err := c.db.ExecContext(
+		ctx,
+		`UPDATE worker_credentials SET revoked_at = COALESCE(revoked_at, NOW())
+				WHERE id=$1`,
+		id,
+	)
+	if err != nil {
+		log.Error("failed to revoke worker credential", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	if affected == 0 {
+		log.Warn("worker credential not found")
+		return e.WrapErr(ErrCredentialNotFound, src)
+	}
+
+	log.Debug("worker credential revoked")
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCredential(row scanner) (*models.WorkerCredential, error) {
+	var (
+		credential = &models.WorkerCredential{}
+		revokedAt  sql.NullTime
+	)
+
+	err := row.Scan(&credential.ID, &credential.Name, &credential.TokenHash, &credential.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		credential.RevokedAt = &revokedAt.Time
+	}
+
+	return credential, nil
+}
@@ -0,0 +1,57 @@
+package failedloginsrepo
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+// maxLoginLength is the length of the login column. Longer logins of failed attempts are truncated
+const maxLoginLength = 64
+
+type FailedLoginRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func New(
+	log *slog.Logger,
+	db *sql.DB,
+) *FailedLoginRepository {
+	return &FailedLoginRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Save appends the record and sets its id and creation time
+func (f *FailedLoginRepository) Save(ctx context.Context, failedLogin *models.FailedLogin) error {
+	const src = "FailedLoginRepository.Save"
+
+	login := failedLogin.Login
+	if runes := []rune(login); len(runes) > maxLoginLength {
+		login = string(runes[:maxLoginLength])
+	}
+
+	row := f.db.QueryRowContext(
+		ctx,
+		`INSERT INTO failed_logins (login, ip, reason)
+				VALUES ($1, $2, $3)
+				RETURNING id, created_at`,
+		login,
+		failedLogin.IP,
+		failedLogin.Reason,
+	)
+
+	err := row.Scan(&failedLogin.ID, &failedLogin.CreatedAt)
+	if err != nil {
+		f.log.Error("failed to save failed login", slog.String("src", src), sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package keysrepo
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+type KeyRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func New(
+	log *slog.Logger,
+	db *sql.DB,
+) *KeyRepository {
+	return &KeyRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Keys returns all stored keys from the oldest to the newest
+func (k *KeyRepository) Keys(ctx context.Context) (keys []*models.SigningKey, err error) {
+	const src = "KeyRepository.Keys"
+
+	log := k.log.With(
+		slog.String("src", src),
+	)
+
+	rows, err := k.db.QueryContext(
+		ctx,
+		`SELECT k.id, k.algorithm, k.private_key, k.created_at, k.retired_at, k.expires_at FROM signing_keys k
+				ORDER BY k.created_at, k.id`,
+	)
+	if err != nil {
+		log.Error("failed to get signing keys", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	defer rows.Close()
+
+	keys = []*models.SigningKey{}
+
+	for rows.Next() {
+		var (
+			key       = &models.SigningKey{}
+			retiredAt sql.NullTime
+			expiresAt sql.NullTime
+		)
+
+		err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.CreatedAt, &retiredAt, &expiresAt)
+		if err != nil {
+			log.Error("failed to scan signing key", sl.Err(err))
+			return nil, e.WrapErr(err, src)
+		}
+
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		}
+
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, e.WrapErrIfNotNil(rows.Err(), src)
+}
+
+// Rotate retires the current keys, which then expire at retiredExpiresAt, and saves the new key.
+// The creation time of the new key is set
+func (k *KeyRepository) Rotate(ctx context.Context, key *models.SigningKey, retiredExpiresAt time.Time) error {
+	const src = "KeyRepository.Rotate"
+
+	log := k.log.With(
+		slog.String("src", src),
+		slog.String("id", key.ID),
+	)
+
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE signing_keys SET retired_at = NOW(), expires_at = $1
+				WHERE retired_at IS NULL`,
+		retiredExpiresAt,
+	)
+	if err != nil {
+		log.Error("failed to retire signing keys", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	row := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO signing_keys (id, algorithm, private_key)
+				VALUES ($1, $2, $3)
+				RETURNING created_at`,
+		key.ID,
+		key.Algorithm,
+		key.PrivateKey,
+	)
+
+	err = row.Scan(&key.CreatedAt)
+	if err != nil {
+		log.Error("failed to create signing key", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error("failed to commit", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	log.Debug("signing key rotated")
+
+	return nil
+}
+
+// DeleteExpired deletes retired keys which tokens have expired
+func (k *KeyRepository) DeleteExpired(ctx context.Context) error {
+	const src = "KeyRepository.DeleteExpired"
+
+	_, err := k.db.ExecContext(
+		ctx,
+		`DELETE FROM signing_keys
+				WHERE expires_at <= NOW()`,
+	)
+	if err != nil {
+		k.log.Error("failed to delete expired signing keys", slog.String("src", src), sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	return nil
+}
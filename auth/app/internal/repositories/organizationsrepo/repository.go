@@ -0,0 +1,349 @@
+package organizationsrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+var (
+	ErrUserNotFound        = errors.New("organizationsrepo: user not found")
+	ErrMemberNotFound      = errors.New("organizationsrepo: member not found")
+	ErrMemberAlreadyExists = errors.New("organizationsrepo: member already exists")
+)
+
+type OrganizationRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func New(
+	log *slog.Logger,
+	db *sql.DB,
+) *OrganizationRepository {
+	return &OrganizationRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create creates the organization with ownerID as its owner and sets the id and creation time of the organization
+func (o *OrganizationRepository) Create(ctx context.Context, organization *models.Organization, ownerID uint64) error {
+	const src = "OrganizationRepository.Create"
+
+	log := o.log.With(
+		slog.String("src", src),
+		slog.Uint64("ownerID", ownerID),
+	)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO organizations (name)
+				VALUES ($1)
+				RETURNING id, created_at`,
+		organization.Name,
+	)
+
+	err = row.Scan(&organization.ID, &organization.CreatedAt)
+	if err != nil {
+		log.Error("failed to create organization", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO organization_members (organization_id, user_id, role)
+				VALUES ($1, $2, $3)`,
+		organization.ID,
+		ownerID,
+		models.OrganizationRoleOwner,
+	)
+	if err != nil {
+		log.Error("failed to add owner", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error("failed to commit", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	organization.Role = models.OrganizationRoleOwner
+
+	log.Debug("organization created", slog.Uint64("id", organization.ID))
+
+	return nil
+}
+
+// Organizations returns organizations the user is a member of together with the role of the user
+func (o *OrganizationRepository) Organizations(ctx context.Context, userID uint64) (organizations []*models.Organization, err error) {
+	const src = "OrganizationRepository.Organizations"
+
+	log := o.log.With(
+		slog.String("src", src),
+		slog.Uint64("userID", userID),
+	)
+
+	rows, err := o.db.QueryContext(
+		ctx,
+		`SELECT o.id, o.name, o.created_at, m.role
+				FROM organizations o
+				JOIN organization_members m ON m.organization_id = o.id
+				WHERE m.user_id=$1
+				ORDER BY o.id`,
+		userID,
+	)
+	if err != nil {
+		log.Error("failed to get organizations", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	defer rows.Close()
+
+	organizations = []*models.Organization{}
+
+	for rows.Next() {
+		var organization models.Organization
+
+		err = rows.Scan(&organization.ID, &organization.Name, &organization.CreatedAt, &organization.Role)
+		if err != nil {
+			log.Error("failed to scan organization", sl.Err(err))
+			return nil, e.WrapErr(err, src)
+		}
+
+		organizations = append(organizations, &organization)
+	}
+
+	return organizations, e.WrapErrIfNotNil(rows.Err(), src)
+}
+
+// Member returns the membership of the user in the organization
+//
+// Returns ErrMemberNotFound, if the user is not a member of the organization
+func (o *OrganizationRepository) Member(ctx context.Context, organizationID uint64, userID uint64) (member *models.OrganizationMember, err error) {
+	const src = "OrganizationRepository.Member"
+
+	log := o.log.With(
+		slog.String("src", src),
+		slog.Uint64("organizationID", organizationID),
+		slog.Uint64("userID", userID),
+	)
+
+	row := o.db.QueryRowContext(
+		ctx,
+		`SELECT m.organization_id, m.user_id, u.login, m.role, m.created_at
+				FROM organization_members m
+				JOIN users u ON u.id = m.user_id
+				WHERE m.organization_id=$1 AND m.user_id=$2`,
+		organizationID,
+		userID,
+	)
+
+	member, err = scanMember(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Debug("member not found")
+			return nil, e.WrapErr(ErrMemberNotFound, src)
+		}
+		log.Error("failed to get member", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	return member, nil
+}
+
+// Members returns members of the organization ordered by the time they joined it
+func (o *OrganizationRepository) Members(ctx context.Context, organizationID uint64) (members []*models.OrganizationMember, err error) {
+	const src = "OrganizationRepository.Members"
+
+	log := o.log.With(
+		slog.String("src", src),
+		slog.Uint64("organizationID", organizationID),
+	)
+
+	rows, err := o.db.QueryContext(
+		ctx,
+		`SELECT m.organization_id, m.user_id, u.login, m.role, m.created_at
+				FROM organization_members m
+				JOIN users u ON u.id = m.user_id
+				WHERE m.organization_id=$1
+				ORDER BY m.created_at, m.user_id`,
+		organizationID,
+	)
+	if err != nil {
+		log.Error("failed to get members", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	defer rows.Close()
+
+	members = []*models.OrganizationMember{}
+
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			log.Error("failed to scan member", sl.Err(err))
+			return nil, e.WrapErr(err, src)
+		}
+
+		members = append(members, member)
+	}
+
+	return members, e.WrapErrIfNotNil(rows.Err(), src)
+}
+
+// AddMember adds the user with the login to the organization
+//
+// Returns ErrUserNotFound, if there is no user with the login, and ErrMemberAlreadyExists,
+// if the user is already a member of the organization
+func (o *OrganizationRepository) AddMember(
+	ctx context.Context,
+	organizationID uint64,
+	login string,
+	role string,
+) (member *models.OrganizationMember, err error) {
+	const src = "OrganizationRepository.AddMember"
+
+	log := o.log.With(
+		slog.String("src", src),
+		slog.Uint64("organizationID", organizationID),
+		slog.String("login", login),
+	)
+
+	member = &models.OrganizationMember{
+		OrganizationID: organizationID,
+		Login:          login,
+		Role:           role,
+	}
+
+	err = o.db.QueryRowContext(
+		ctx,
+		`SELECT id FROM users WHERE login=$1`,
+		login,
+	).Scan(&member.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found")
+			return nil, e.WrapErr(ErrUserNotFound, src)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	err = o.db.QueryRowContext(
+		ctx,
+		`INSERT INTO organization_members (organization_id, user_id, role)
+				VALUES ($1, $2, $3)
+				ON CONFLICT DO NOTHING
+				RETURNING created_at`,
+		organizationID,
+		member.UserID,
+		role,
+	).Scan(&member.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("member already exists")
+			return nil, e.WrapErr(ErrMemberAlreadyExists, src)
+		}
+		log.Error("failed to add member", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	log.Debug("member added")
+
+	return member, nil
+}
+
+// RemoveMember removes the user from the organization and returns its sessions to the personal workspace
+//
+// Returns ErrMemberNotFound, if the user is not a member of the organization
+func (o *OrganizationRepository) RemoveMember(ctx context.Context, organizationID uint64, userID uint64) error {
+	const src = "OrganizationRepository.RemoveMember"
+
+	log := o.log.With(
+		slog.String("src", src),
+		slog.Uint64("organizationID", organizationID),
+		slog.Uint64("userID", userID),
+	)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM organization_members
+				WHERE organization_id=$1 AND user_id=$2`,
+		organizationID,
+		userID,
+	)
+	if err != nil {
+		log.Error("failed to remove member", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	if affected == 0 {
+		log.Warn("member not found")
+		return e.WrapErr(ErrMemberNotFound, src)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE sessions SET organization_id = NULL
+				WHERE organization_id=$1 AND user_id=$2`,
+		organizationID,
+		userID,
+	)
+	if err != nil {
+		log.Error("failed to reset sessions", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error("failed to commit", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	log.Debug("member removed")
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMember(row scanner) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+
+	err := row.Scan(&member.OrganizationID, &member.UserID, &member.Login, &member.Role, &member.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
@@ -0,0 +1,357 @@
+package sessionsrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+var (
+	ErrSessionNotFound      = errors.New("sessionsrepo: session not found")
+	ErrRefreshTokenNotFound = errors.New("sessionsrepo: refresh token not found")
+	ErrRefreshTokenUsed     = errors.New("sessionsrepo: refresh token has already been used")
+)
+
+type SessionRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func New(
+	log *slog.Logger,
+	db *sql.DB,
+) *SessionRepository {
+	return &SessionRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Save creates the session together with its first refresh token and sets the creation time of the session
+func (s *SessionRepository) Save(ctx context.Context, session *models.Session, tokenHash string) error {
+	const src = "SessionRepository.Save"
+
+	log := s.log.With(
+		slog.String("src", src),
+		slog.Uint64("userID", session.UserID),
+	)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO sessions (id, user_id, expires_at)
+				VALUES ($1, $2, $3)
+				RETURNING created_at`,
+		session.ID,
+		session.UserID,
+		session.ExpiresAt,
+	)
+
+	err = row.Scan(&session.CreatedAt)
+	if err != nil {
+		log.Error("failed to create session", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO refresh_tokens (session_id, token_hash)
+				VALUES ($1, $2)`,
+		session.ID,
+		tokenHash,
+	)
+	if err != nil {
+		log.Error("failed to create refresh token", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error("failed to commit", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	log.Debug("session saved")
+
+	return nil
+}
+
+// RefreshToken returns the token with its session and the login of the session owner.
+// The organization of the session is dropped, if the owner is no longer its member
+func (s *SessionRepository) RefreshToken(ctx context.Context, tokenHash string) (token *models.RefreshToken, err error) {
+	const src = "SessionRepository.RefreshToken"
+
+	log := s.log.With(
+		slog.String("src", src),
+	)
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`SELECT t.id, t.session_id, t.token_hash, t.used_at,
+				s.user_id, s.created_at, s.expires_at, s.revoked_at, u.login,
+				m.organization_id, m.role
+				FROM refresh_tokens t
+				JOIN sessions s ON s.id = t.session_id
+				JOIN users u ON u.id = s.user_id
+				LEFT JOIN organization_members m ON m.organization_id = s.organization_id AND m.user_id = s.user_id
+				WHERE t.token_hash=$1`,
+		tokenHash,
+	)
+
+	var (
+		session          = &models.Session{}
+		usedAt           sql.NullTime
+		revokedAt        sql.NullTime
+		organizationID   sql.NullInt64
+		organizationRole sql.NullString
+	)
+
+	token = &models.RefreshToken{Session: session}
+
+	err = row.Scan(
+		&token.ID, &token.SessionID, &token.TokenHash, &usedAt,
+		&session.UserID, &session.CreatedAt, &session.ExpiresAt, &revokedAt, &token.Login,
+		&organizationID, &organizationRole,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("refresh token not found")
+			return nil, e.WrapErr(ErrRefreshTokenNotFound, src)
+		}
+		log.Error("failed to get refresh token", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	session.ID = token.SessionID
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+
+	if organizationID.Valid {
+		session.OrganizationID = uint64(organizationID.Int64)
+		session.OrganizationRole = organizationRole.String
+	}
+
+	return token, nil
+}
+
+// Rotate marks the token as used, issues the next token of its session and prolongs the session till expiresAt.
+//
+// Returns ErrRefreshTokenUsed, if the token has already been used, e.g. by a concurrent request
+func (s *SessionRepository) Rotate(
+	ctx context.Context,
+	token *models.RefreshToken,
+	nextTokenHash string,
+	expiresAt time.Time,
+) error {
+	const src = "SessionRepository.Rotate"
+
+	log := s.log.With(
+		slog.String("src", src),
+		slog.Uint64("id", token.ID),
+	)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE refresh_tokens SET used_at = NOW()
+				WHERE id=$1 AND used_at IS NULL`,
+		token.ID,
+	)
+	if err != nil {
+		log.Error("failed to use refresh token", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	if affected == 0 {
+		log.Warn("refresh token has already been used")
+		return e.WrapErr(ErrRefreshTokenUsed, src)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO refresh_tokens (session_id, token_hash)
+				VALUES ($1, $2)`,
+		token.SessionID,
+		nextTokenHash,
+	)
+	if err != nil {
+		log.Error("failed to create refresh token", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE sessions SET expires_at = $2
+				WHERE id=$1`,
+		token.SessionID,
+		expiresAt,
+	)
+	if err != nil {
+		log.Error("failed to prolong session", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error("failed to commit", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	log.Debug("refresh token rotated")
+
+	return nil
+}
+
+func (s *SessionRepository) Session(ctx context.Context, id string) (session *models.Session, err error) {
+	const src = "SessionRepository.Session"
+
+	log := s.log.With(
+		slog.String("src", src),
+	)
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`SELECT s.id, s.user_id, s.created_at, s.expires_at, s.revoked_at FROM sessions s
+				WHERE id=$1`,
+		id,
+	)
+
+	var revokedAt sql.NullTime
+
+	session = &models.Session{}
+
+	err = row.Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("session not found")
+			return nil, e.WrapErr(ErrSessionNotFound, src)
+		}
+		log.Error("failed to get session", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+
+	return session, nil
+}
+
+// SetOrganization makes the organization active in the session. Zero organizationID returns the session
+// to the personal workspace
+func (s *SessionRepository) SetOrganization(ctx context.Context, id string, organizationID uint64) error {
+	const src = "SessionRepository.SetOrganization"
+
+	log := s.log.With(
+		slog.String("src", src),
+		slog.Uint64("organizationID", organizationID),
+	)
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE sessions SET organization_id = NULLIF($2, 0)
+				WHERE id=$1 AND revoked_at IS NULL`,
+		id,
+		int64(organizationID),
+	)
+	if err != nil {
+		log.Error("failed to set organization of session", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	if affected == 0 {
+		log.Warn("session not found")
+		return e.WrapErr(ErrSessionNotFound, src)
+	}
+
+	return nil
+}
+
+// Revoke marks the session as revoked. Revoking a revoked session keeps its revocation time
+func (s *SessionRepository) Revoke(ctx context.Context, id string) error {
+	const src = "SessionRepository.Revoke"
+
+	log := s.log.With(
+		slog.String("src", src),
+	)
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE sessions SET revoked_at = COALESCE(revoked_at, NOW())
+				WHERE id=$1`,
+		id,
+	)
+	if err != nil {
+		log.Error("failed to revoke session", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	if affected == 0 {
+		log.Warn("session not found")
+		return e.WrapErr(ErrSessionNotFound, src)
+	}
+
+	log.Debug("session revoked")
+
+	return nil
+}
+
+// RevokeUser revokes all sessions of the user except the session exceptID
+func (s *SessionRepository) RevokeUser(ctx context.Context, userID uint64, exceptID string) error {
+	const src = "SessionRepository.RevokeUser"
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE sessions SET revoked_at = NOW()
+				WHERE user_id=$1 AND id<>$2 AND revoked_at IS NULL`,
+		userID,
+		exceptID,
+	)
+	if err != nil {
+		s.log.Error("failed to revoke sessions", slog.String("src", src), slog.Uint64("userID", userID), sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	return nil
+}
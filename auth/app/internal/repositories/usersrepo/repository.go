@@ -14,6 +14,7 @@ import (
 var (
 	ErrUserNotFound      = errors.New("usersrepo: user not found")
 	ErrUserAlreadyExists = errors.New("usersrepo: users already exists")
+	ErrOrganizationOwner = errors.New("usersrepo: user owns an organization")
 )
 
 type UserRepository struct {
@@ -73,6 +74,34 @@ func (u *UserRepository) Save(_ context.Context, login string, passwordHash stri
 		return 0, ErrUserAlreadyExists
 	}
 
+	// logins of deleted accounts are never given out again, because roles of other services are granted by login
+	row = tx.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM deleted_logins WHERE login=$1)`,
+		login,
+	)
+
+	var deleted bool
+	err = row.Scan(&deleted)
+
+	if err != nil {
+		log.Error("failed to check deleted logins", sl.Err(err))
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			log.Error("failed to rollback", sl.Err(rollbackErr))
+		}
+		return 0, err
+	}
+
+	if deleted {
+		log.Warn("login of a deleted user")
+		err := tx.Rollback()
+		if err != nil {
+			log.Error("failed to rollback", sl.Err(err))
+			return 0, err
+		}
+		return 0, ErrUserAlreadyExists
+	}
+
 	row = tx.QueryRow(
 		`INSERT INTO users (login, password_hash)
 				VALUES ($1, $2)
@@ -134,3 +163,209 @@ func (u *UserRepository) User(_ context.Context, login string) (user *models.Use
 
 	return user, nil
 }
+
+func (u *UserRepository) UserByID(ctx context.Context, id uint64) (user *models.User, err error) {
+	const src = "UserRepository.UserByID"
+
+	log := u.log.With(
+		slog.String("src", src),
+		slog.Uint64("id", id),
+	)
+
+	row := u.db.QueryRowContext(
+		ctx,
+		`SELECT u.id, u.login, u.password_hash FROM users u
+				WHERE id=$1`,
+		id,
+	)
+
+	user = &models.User{}
+	err = row.Scan(&user.ID, &user.Login, &user.PasswordHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found")
+			return nil, e.WrapErr(ErrUserNotFound, src)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	return user, nil
+}
+
+func (u *UserRepository) UpdatePassword(ctx context.Context, id uint64, passwordHash string) error {
+	const src = "UserRepository.UpdatePassword"
+
+	log := u.log.With(
+		slog.String("src", src),
+		slog.Uint64("id", id),
+	)
+
+	result, err := u.db.ExecContext(
+		ctx,
+		`UPDATE users SET password_hash=$2
+				WHERE id=$1`,
+		id,
+		passwordHash,
+	)
+	if err != nil {
+		log.Error("failed to update password", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	if affected == 0 {
+		log.Warn("user not found")
+		return e.WrapErr(ErrUserNotFound, src)
+	}
+
+	return nil
+}
+
+// Delete deletes the user together with its sessions and api keys
+// and publishes models.UserEventDeleted in the same transaction. The login of the user is reserved forever
+// and Save rejects it with ErrUserAlreadyExists.
+//
+// Returns ErrOrganizationOwner, if the user owns an organization, because an organization cannot be managed without its owner
+func (u *UserRepository) Delete(ctx context.Context, id uint64) (err error) {
+	const src = "UserRepository.Delete"
+
+	log := u.log.With(
+		slog.String("src", src),
+		slog.Uint64("id", id),
+	)
+
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// the lock makes organizations created concurrently by the user wait for the deletion
+	row := tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+					SELECT 1 FROM organization_members
+					WHERE user_id=u.id AND role=$2
+				)
+				FROM users u
+				WHERE id=$1
+				FOR UPDATE`,
+		id,
+		models.OrganizationRoleOwner,
+	)
+
+	var owner bool
+	err = row.Scan(&owner)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found")
+			return e.WrapErr(ErrUserNotFound, src)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	if owner {
+		log.Warn("user owns an organization")
+		return e.WrapErr(ErrOrganizationOwner, src)
+	}
+
+	row = tx.QueryRowContext(
+		ctx,
+		`DELETE FROM users WHERE id=$1
+				RETURNING login`,
+		id,
+	)
+
+	var login string
+	err = row.Scan(&login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found")
+			return e.WrapErr(ErrUserNotFound, src)
+		}
+		log.Error("failed to delete user", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO deleted_logins (login, user_id)
+				VALUES ($1, $2)`,
+		login,
+		id,
+	)
+	if err != nil {
+		log.Error("failed to reserve login", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO user_events (type, user_id)
+				VALUES ($1, $2)`,
+		models.UserEventDeleted,
+		id,
+	)
+	if err != nil {
+		log.Error("failed to publish user event", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error("failed to commit", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	log.Debug("user deleted")
+
+	return nil
+}
+
+// Events returns at most limit events with ids greater than afterID in the order of ids
+func (u *UserRepository) Events(ctx context.Context, afterID uint64, limit int) (events []*models.UserEvent, err error) {
+	const src = "UserRepository.Events"
+
+	rows, err := u.db.QueryContext(
+		ctx,
+		`SELECT id, type, user_id, created_at FROM user_events
+				WHERE id > $1
+				ORDER BY id
+				LIMIT $2`,
+		afterID,
+		limit,
+	)
+	if err != nil {
+		u.log.Error("failed to get user events", slog.String("src", src), sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	defer rows.Close()
+
+	events = []*models.UserEvent{}
+
+	for rows.Next() {
+		var event = &models.UserEvent{}
+
+		err = rows.Scan(&event.ID, &event.Type, &event.UserID, &event.CreatedAt)
+		if err != nil {
+			return nil, e.WrapErr(err, src)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, e.WrapErrIfNotNil(rows.Err(), src)
+}
@@ -9,10 +9,44 @@ const (
 	CodePasswordIsRequired DeveloperCode = 400_002
 	CodeTooLongPassword    DeveloperCode = 400_003
 	CodeInvalidCredentials DeveloperCode = 400_004
+	CodeNameIsRequired     DeveloperCode = 400_005
+	CodeTooLongName        DeveloperCode = 400_006
+	CodeInvalidID          DeveloperCode = 400_007
 
-	CodeUserNotFound DeveloperCode = 404_001
+	CodeRefreshTokenIsRequired DeveloperCode = 400_008
+	CodeSessionIDIsRequired    DeveloperCode = 400_009
+	CodeInvalidScope           DeveloperCode = 400_010
 
-	CodeUserAlreadyExists DeveloperCode = 409_001
+	CodeTooShortLogin            DeveloperCode = 400_011
+	CodeTooLongLogin             DeveloperCode = 400_012
+	CodeInvalidLoginCharset      DeveloperCode = 400_013
+	CodeTooShortPassword         DeveloperCode = 400_014
+	CodeWeakPassword             DeveloperCode = 400_015
+	CodeCurrentPasswordIsMissing DeveloperCode = 400_016
+	CodeInvalidRole              DeveloperCode = 400_017
+	CodeInvalidAuditFilter       DeveloperCode = 400_018
+
+	CodeInvalidAuthorization DeveloperCode = 401_001
+	CodeInvalidWorkerToken   DeveloperCode = 401_002
+	CodeInvalidRefreshToken  DeveloperCode = 401_003
+	CodeInvalidAPIKey        DeveloperCode = 401_004
+
+	CodeAdminRequired   DeveloperCode = 403_001
+	CodeInvalidPassword DeveloperCode = 403_002
+
+	CodeOrganizationAccessDenied DeveloperCode = 403_003
+	CodeOwnerCannotBeRemoved     DeveloperCode = 403_004
+
+	CodeUserNotFound             DeveloperCode = 404_001
+	CodeWorkerCredentialNotFound DeveloperCode = 404_002
+	CodeAPIKeyNotFound           DeveloperCode = 404_003
+	CodeMemberNotFound           DeveloperCode = 404_004
+
+	CodeUserAlreadyExists   DeveloperCode = 409_001
+	CodeMemberAlreadyExists DeveloperCode = 409_002
+	CodeOrganizationOwner   DeveloperCode = 409_003
+
+	CodeTooManyAttempts DeveloperCode = 429_001
 )
 
 func DevCodeFromErr(err error) (DeveloperCode, bool) {
@@ -33,12 +67,71 @@ func DevCodeFromMsg(msg string) (DeveloperCode, bool) {
 		return CodeTooLongPassword, true
 	case strings.Contains(msg, MsgInvalidCredentials):
 		return CodeInvalidCredentials, true
+	case strings.Contains(msg, MsgNameIsRequired):
+		return CodeNameIsRequired, true
+	case strings.Contains(msg, MsgTooLongName):
+		return CodeTooLongName, true
+	case strings.Contains(msg, MsgInvalidID):
+		return CodeInvalidID, true
+	case strings.Contains(msg, MsgRefreshTokenIsRequired):
+		return CodeRefreshTokenIsRequired, true
+	case strings.Contains(msg, MsgSessionIDIsRequired):
+		return CodeSessionIDIsRequired, true
+	case strings.Contains(msg, MsgInvalidScope):
+		return CodeInvalidScope, true
+	case strings.Contains(msg, MsgTooShortLogin):
+		return CodeTooShortLogin, true
+	case strings.Contains(msg, MsgTooLongLogin):
+		return CodeTooLongLogin, true
+	case strings.Contains(msg, MsgInvalidLoginCharset):
+		return CodeInvalidLoginCharset, true
+	case strings.Contains(msg, MsgTooShortPassword):
+		return CodeTooShortPassword, true
+	case strings.Contains(msg, MsgWeakPassword):
+		return CodeWeakPassword, true
+	case strings.Contains(msg, MsgCurrentPasswordIsMissing):
+		return CodeCurrentPasswordIsMissing, true
+	case strings.Contains(msg, MsgInvalidRole):
+		return CodeInvalidRole, true
+	case strings.Contains(msg, MsgInvalidAuditFilter):
+		return CodeInvalidAuditFilter, true
+
+	case strings.Contains(msg, MsgInvalidAuthorization):
+		return CodeInvalidAuthorization, true
+	case strings.Contains(msg, MsgInvalidWorkerToken):
+		return CodeInvalidWorkerToken, true
+	case strings.Contains(msg, MsgInvalidRefreshToken):
+		return CodeInvalidRefreshToken, true
+	case strings.Contains(msg, MsgInvalidAPIKey):
+		return CodeInvalidAPIKey, true
+
+	case strings.Contains(msg, MsgAdminRequired):
+		return CodeAdminRequired, true
+	case strings.Contains(msg, MsgInvalidPassword):
+		return CodeInvalidPassword, true
+	case strings.Contains(msg, MsgOrganizationAccessDenied):
+		return CodeOrganizationAccessDenied, true
+	case strings.Contains(msg, MsgOwnerCannotBeRemoved):
+		return CodeOwnerCannotBeRemoved, true
 
 	case strings.Contains(msg, MsgUserNotFound):
 		return CodeUserNotFound, true
+	case strings.Contains(msg, MsgWorkerCredentialNotFound):
+		return CodeWorkerCredentialNotFound, true
+	case strings.Contains(msg, MsgAPIKeyNotFound):
+		return CodeAPIKeyNotFound, true
+	case strings.Contains(msg, MsgMemberNotFound):
+		return CodeMemberNotFound, true
 
 	case strings.Contains(msg, MsgUserAlreadyExists):
 		return CodeUserAlreadyExists, true
+	case strings.Contains(msg, MsgMemberAlreadyExists):
+		return CodeMemberAlreadyExists, true
+	case strings.Contains(msg, MsgOrganizationOwner):
+		return CodeOrganizationOwner, true
+
+	case strings.Contains(msg, MsgTooManyAttempts):
+		return CodeTooManyAttempts, true
 
 	default:
 		return DeveloperCode(0), false
@@ -5,10 +5,46 @@ const (
 	MsgPasswordIsRequired = "password is required"
 	MsgTooLongPassword    = "password is too long (max length is 64)"
 	MsgInvalidCredentials = "invalid credentials"
+	MsgNameIsRequired     = "name is required"
+	MsgTooLongName        = "name is too long (max length is 128)"
+	MsgInvalidID          = "invalid id"
 
-	MsgUserNotFound = "user not found"
+	MsgTooShortLogin            = "login is too short (min length is 3)"
+	MsgTooLongLogin             = "login is too long (max length is 32)"
+	MsgInvalidLoginCharset      = "login may contain only latin letters, digits, '.', '_' and '-'"
+	MsgTooShortPassword         = "password is too short (min length is 8)"
+	MsgWeakPassword             = "password must contain both letters and digits"
+	MsgCurrentPasswordIsMissing = "current password must be provided"
 
-	MsgUserAlreadyExists = "user already exists"
+	MsgRefreshTokenIsRequired = "refresh token is required"
+	MsgSessionIDIsRequired    = "session id is required"
+	MsgInvalidScope           = "invalid scope (allowed scopes are all, read and submit)"
+	MsgInvalidRole            = "invalid role (allowed roles are admin and member)"
+	MsgInvalidAuditFilter     = "invalid audit filter (ids must be positive integers and times must be in RFC 3339)"
+
+	MsgInvalidAuthorization = "invalid authorization header"
+	MsgInvalidWorkerToken   = "invalid worker token"
+	MsgInvalidRefreshToken  = "invalid refresh token"
+	MsgInvalidAPIKey        = "invalid api key"
+	MsgInvalidServiceToken  = "invalid service token"
+
+	MsgAdminRequired   = "admin access required"
+	MsgInternalMethod  = "method is available only to internal services"
+	MsgInvalidPassword = "invalid password"
+
+	MsgOrganizationAccessDenied = "organization access denied"
+	MsgOwnerCannotBeRemoved     = "organization owner cannot be removed"
+
+	MsgUserNotFound             = "user not found"
+	MsgWorkerCredentialNotFound = "worker credential not found"
+	MsgAPIKeyNotFound           = "api key not found"
+	MsgMemberNotFound           = "member not found"
+
+	MsgUserAlreadyExists   = "user already exists"
+	MsgMemberAlreadyExists = "member already exists"
+	MsgOrganizationOwner   = "owner of an organization cannot delete the account"
+
+	MsgTooManyAttempts = "too many failed attempts, try again later"
 
 	MsgInternalError = "internal error"
 )
@@ -0,0 +1,106 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/apikeys"
+	_ "github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jsoncodec"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiKeysServiceName       = "auth.v1.APIKeys"
+	authenticateAPIKeyMethod = "/" + apiKeysServiceName + "/Authenticate"
+)
+
+type AuthenticateAPIKeyRequest struct {
+	Key string `json:"key"`
+}
+
+type AuthenticateAPIKeyResponse struct {
+	KeyID  uint64 `json:"keyId"`
+	UserID uint64 `json:"userId"`
+	Login  string `json:"login"`
+	Scope  string `json:"scope"`
+}
+
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*models.APIKey, error)
+}
+
+type APIKeysServer interface {
+	Authenticate(ctx context.Context, request *AuthenticateAPIKeyRequest) (*AuthenticateAPIKeyResponse, error)
+}
+
+var apiKeysServiceDesc = grpc.ServiceDesc{
+	ServiceName: apiKeysServiceName,
+	HandlerType: (*APIKeysServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Authenticate",
+			Handler:    authenticateAPIKeyHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "apikeys.go",
+}
+
+func authenticateAPIKeyHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var request = &AuthenticateAPIKeyRequest{}
+
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(APIKeysServer).Authenticate(ctx, request)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: authenticateAPIKeyMethod,
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(APIKeysServer).Authenticate(ctx, req.(*AuthenticateAPIKeyRequest))
+	}
+
+	return interceptor(ctx, request, info, handler)
+}
+
+type apiKeysServer struct {
+	authenticator APIKeyAuthenticator
+}
+
+// RegisterAPIKeys registers the service which checks api keys of users for the api-gateway
+func RegisterAPIKeys(gRPCServer *grpc.Server, authenticator APIKeyAuthenticator) {
+	gRPCServer.RegisterService(&apiKeysServiceDesc, &apiKeysServer{authenticator: authenticator})
+}
+
+func (s *apiKeysServer) Authenticate(ctx context.Context, r *AuthenticateAPIKeyRequest) (*AuthenticateAPIKeyResponse, error) {
+	if r.Key == "" {
+		return nil, status.Error(codes.Unauthenticated, servers.MsgInvalidAPIKey)
+	}
+
+	key, err := s.authenticator.Authenticate(ctx, r.Key)
+	if err != nil {
+		if errors.Is(err, apikeys.ErrInvalidKey) {
+			return nil, status.Error(codes.Unauthenticated, servers.MsgInvalidAPIKey)
+		}
+
+		return nil, status.Error(codes.Internal, servers.MsgInternalError)
+	}
+
+	return &AuthenticateAPIKeyResponse{
+		KeyID:  key.ID,
+		UserID: key.UserID,
+		Login:  key.Login,
+		Scope:  key.Scope,
+	}, nil
+}
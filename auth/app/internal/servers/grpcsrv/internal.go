@@ -0,0 +1,85 @@
+package grpcsrv
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/clientip"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ServiceTokenHeader carries the token shared by the auth service and the api-gateway
+	ServiceTokenHeader = "x-service-token"
+	// ClientIPHeader carries the address of the client on whose behalf the api-gateway calls an internal method
+	ClientIPHeader = "x-client-ip"
+)
+
+// internalServices are called only by other services of the calculator, not by users
+var internalServices = []string{
+	sessionsServiceName,
+	apiKeysServiceName,
+	workerCredentialsServiceName,
+	usersServiceName,
+}
+
+// InternalMethod reports whether the method belongs to a service called only by the api-gateway
+func InternalMethod(fullMethod string) bool {
+	for _, service := range internalServices {
+		if strings.HasPrefix(fullMethod, "/"+service+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewServiceTokenInterceptor allows calls of the internal methods only with the service token
+// in ServiceTokenHeader. The internal methods are disabled, if the token is empty.
+// The address forwarded in ClientIPHeader replaces the address of the api-gateway set by ClientIPInterceptor,
+// so the rate limit counts failures of every client of the api-gateway separately
+func NewServiceTokenInterceptor(
+	log *slog.Logger,
+	token string,
+	internal func(fullMethod string) bool,
+) grpc.UnaryServerInterceptor {
+	const src = "grpc.ServiceToken"
+	log = log.With(
+		slog.String("src", src),
+	)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !internal(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		if token == "" {
+			return nil, status.Error(codes.PermissionDenied, servers.MsgInternalMethod)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		values := md.Get(ServiceTokenHeader)
+		if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(token)) != 1 {
+			log.Warn("internal call rejected",
+				slog.String("method", info.FullMethod),
+				slog.String("ip", clientip.From(ctx)),
+			)
+
+			return nil, status.Error(codes.Unauthenticated, servers.MsgInvalidServiceToken)
+		}
+
+		if forwarded := md.Get(ClientIPHeader); len(forwarded) == 1 && forwarded[0] != "" {
+			ctx = clientip.With(ctx, forwarded[0])
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,126 @@
+package grpcsrv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/clientip"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServiceTokenInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		method   string
+		md       metadata.MD
+		wantCode codes.Code
+		wantIP   string
+	}{
+		{
+			name:   "public_method",
+			token:  "secret",
+			method: "/auth.Auth/Login",
+			md:     metadata.Pairs(ClientIPHeader, "10.0.0.2"),
+			wantIP: "10.0.0.1",
+		},
+		{
+			name:   "valid_token",
+			token:  "secret",
+			method: isRevokedMethod,
+			md:     metadata.Pairs(ServiceTokenHeader, "secret"),
+			wantIP: "10.0.0.1",
+		},
+		{
+			name:   "forwarded_client_ip",
+			token:  "secret",
+			method: authenticateWorkerMethod,
+			md:     metadata.Pairs(ServiceTokenHeader, "secret", ClientIPHeader, "10.0.0.2"),
+			wantIP: "10.0.0.2",
+		},
+		{
+			name:     "missing_token",
+			token:    "secret",
+			method:   isRevokedMethod,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "invalid_token",
+			token:    "secret",
+			method:   refreshMethod,
+			md:       metadata.Pairs(ServiceTokenHeader, "guess"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "worker_token_without_token",
+			token:    "secret",
+			method:   authenticateWorkerMethod,
+			md:       metadata.Pairs(ClientIPHeader, "10.0.0.2"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "api_key_without_token",
+			token:    "secret",
+			method:   authenticateAPIKeyMethod,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "user_events_without_token",
+			token:    "secret",
+			method:   userEventsMethod,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "disabled",
+			method:   logoutMethod,
+			md:       metadata.Pairs(ServiceTokenHeader, ""),
+			wantCode: codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				interceptor = NewServiceTokenInterceptor(sl.NewDiscardLogger(), tt.token, InternalMethod)
+				ctx         = clientip.With(metadata.NewIncomingContext(context.Background(), tt.md), "10.0.0.1")
+				called      bool
+				ip          string
+			)
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, _ any) (any, error) {
+				called = true
+				ip = clientip.From(ctx)
+				return nil, nil
+			})
+
+			require.Equal(t, tt.wantCode, status.Code(err))
+			require.Equal(t, tt.wantCode == codes.OK, called)
+			require.Equal(t, tt.wantIP, ip)
+		})
+	}
+}
+
+func TestRateLimitedMethod(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		want   bool
+	}{
+		{name: "login", method: "/auth.Auth/Login", want: true},
+		{name: "worker_token", method: authenticateWorkerMethod, want: true},
+		{name: "register", method: "/auth.Auth/Register"},
+		{name: "api_key", method: authenticateAPIKeyMethod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, RateLimitedMethod(tt.method))
+		})
+	}
+}
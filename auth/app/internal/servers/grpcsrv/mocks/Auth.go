@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 
+	models "github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -14,18 +15,20 @@ type Auth struct {
 }
 
 // Login provides a mock function with given fields: ctx, login, password
-func (_m *Auth) Login(ctx context.Context, login string, password string) (string, error) {
+func (_m *Auth) Login(ctx context.Context, login string, password string) (*models.Tokens, error) {
 	ret := _m.Called(ctx, login, password)
 
-	var r0 string
+	var r0 *models.Tokens
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.Tokens, error)); ok {
 		return rf(ctx, login, password)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.Tokens); ok {
 		r0 = rf(ctx, login, password)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Tokens)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
@@ -0,0 +1,106 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/ratelimit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/clientip"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RetryAfterHeader carries the number of seconds after which a rejected call may be retried
+const RetryAfterHeader = "retry-after"
+
+// RateLimiter locks keys after too many failed calls
+type RateLimiter interface {
+	// Check returns ratelimit.ErrLocked, if any of the keys is locked
+	Check(ctx context.Context, keys ...string) error
+	Fail(ctx context.Context, keys ...string) error
+}
+
+// ClientIPInterceptor puts the address of the client into the context
+func ClientIPInterceptor(
+	ctx context.Context,
+	req any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	return handler(clientip.With(ctx, clientip.FromPeer(ctx)), req)
+}
+
+// LoginMethod reports whether the method is Login of the Auth service of dc-protos
+func LoginMethod(fullMethod string) bool {
+	return strings.HasSuffix(fullMethod, "Auth/Login")
+}
+
+// RateLimitedMethod reports whether failed calls of the method are limited: logins and verifications of worker tokens,
+// which could otherwise be guessed online
+func RateLimitedMethod(fullMethod string) bool {
+	return LoginMethod(fullMethod) || fullMethod == authenticateWorkerMethod
+}
+
+// NewRateLimitInterceptor rejects calls of the limited methods from locked addresses with ResourceExhausted.
+// Calls which fail with client errors count as failures. The address is set by ClientIPInterceptor
+func NewRateLimitInterceptor(
+	log *slog.Logger,
+	limiter RateLimiter,
+	limited func(fullMethod string) bool,
+) grpc.UnaryServerInterceptor {
+	const src = "grpc.RateLimit"
+	log = log.With(
+		slog.String("src", src),
+	)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !limited(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		var key = "ip:" + clientip.From(ctx)
+
+		err := limiter.Check(ctx, key)
+		if errors.Is(err, ratelimit.ErrLocked) {
+			log.Warn("call rejected", slog.String("key", key), slog.String("method", info.FullMethod))
+			return nil, tooManyAttempts(ctx, err)
+		}
+
+		if err != nil {
+			log.Error("failed to check rate limit", sl.Err(err))
+		}
+
+		resp, err := handler(ctx, req)
+
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.NotFound, codes.Unauthenticated, codes.PermissionDenied:
+			if err := limiter.Fail(ctx, key); err != nil {
+				log.Error("failed to count failed call", sl.Err(err))
+			}
+		}
+
+		return resp, err
+	}
+}
+
+// tooManyAttempts returns ResourceExhausted and sets RetryAfterHeader, if err is *ratelimit.LockedError
+func tooManyAttempts(ctx context.Context, err error) error {
+	var lockedErr *ratelimit.LockedError
+	if errors.As(err, &lockedErr) {
+		seconds := int(math.Ceil(lockedErr.RetryAfter.Seconds()))
+
+		// SetHeader fails only outside of a gRPC call, e.g. in tests
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, strconv.Itoa(seconds)))
+	}
+
+	return status.Error(codes.ResourceExhausted, servers.MsgTooManyAttempts)
+}
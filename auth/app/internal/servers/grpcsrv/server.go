@@ -5,14 +5,21 @@ import (
 	"errors"
 
 	authgrpc "github.com/AleksandrVishniakov/dc-protos/gen/go/auth/v1"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/auth"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/ratelimit"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
+// RefreshTokenHeader carries the refresh token issued on login,
+// because LoginResponse of dc-protos has a field only for the access token
+const RefreshTokenHeader = "x-refresh-token"
+
 //go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=Auth
 type Auth interface {
 	RegisterNewUser(
@@ -25,7 +32,7 @@ type Auth interface {
 		ctx context.Context,
 		login string,
 		password string,
-	) (token string, err error)
+	) (tokens *models.Tokens, err error)
 }
 
 type serverAPI struct {
@@ -38,12 +45,12 @@ func Register(gRPCServer *grpc.Server, auth Auth) {
 }
 
 func (s *serverAPI) Register(ctx context.Context, r *authgrpc.RegisterRequest) (*authgrpc.RegisterResponse, error) {
-	if r.GetLogin() == "" {
-		return nil, status.Error(codes.InvalidArgument, servers.MsgLoginIsRequired)
+	if err := servers.ValidLogin(r.GetLogin()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	if r.GetPassword() == "" {
-		return nil, status.Error(codes.InvalidArgument, servers.MsgPasswordIsRequired)
+	if err := servers.ValidPassword(r.GetPassword()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
 	id, err := s.auth.RegisterNewUser(ctx, r.GetLogin(), r.GetPassword())
@@ -67,7 +74,7 @@ func (s *serverAPI) Login(ctx context.Context, r *authgrpc.LoginRequest) (*authg
 		return nil, status.Error(codes.InvalidArgument, servers.MsgPasswordIsRequired)
 	}
 
-	token, err := s.auth.Login(ctx, r.GetLogin(), r.GetPassword())
+	tokens, err := s.auth.Login(ctx, r.GetLogin(), r.GetPassword())
 	if err != nil {
 		if errors.Is(err, auth.ErrUserNotFound) {
 			return nil, status.Error(codes.NotFound, servers.MsgUserNotFound)
@@ -75,9 +82,15 @@ func (s *serverAPI) Login(ctx context.Context, r *authgrpc.LoginRequest) (*authg
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, servers.MsgInvalidCredentials)
 		}
+		if errors.Is(err, ratelimit.ErrLocked) {
+			return nil, tooManyAttempts(ctx, err)
+		}
 
 		return nil, status.Error(codes.Internal, servers.MsgInternalError)
 	}
 
-	return &authgrpc.LoginResponse{Token: token}, nil
+	// SetHeader fails only outside of a gRPC call, e.g. in tests
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RefreshTokenHeader, tokens.RefreshToken))
+
+	return &authgrpc.LoginResponse{Token: tokens.AccessToken}, nil
 }
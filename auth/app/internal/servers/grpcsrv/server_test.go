@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	authv1 "github.com/AleksandrVishniakov/dc-protos/gen/go/auth/v1"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers/grpcsrv/mocks"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/auth"
@@ -34,7 +35,7 @@ func TestServerAPI_Register(t *testing.T) {
 			fields: &fields{
 				ctx: context.Background(),
 				req: &authv1.RegisterRequest{
-					Login:    gofakeit.Name(),
+					Login:    fake.RandLogin(),
 					Password: fake.RandPassword(),
 				},
 			},
@@ -64,18 +65,40 @@ func TestServerAPI_Register(t *testing.T) {
 			fields: &fields{
 				ctx: context.Background(),
 				req: &authv1.RegisterRequest{
-					Login:    gofakeit.Name(),
+					Login:    fake.RandLogin(),
 					Password: "",
 				},
 			},
 			wantErr: errors.New(servers.MsgPasswordIsRequired),
 		},
+		{
+			name: "weak_password",
+			fields: &fields{
+				ctx: context.Background(),
+				req: &authv1.RegisterRequest{
+					Login:    fake.RandLogin(),
+					Password: "password",
+				},
+			},
+			wantErr: errors.New(servers.MsgWeakPassword),
+		},
+		{
+			name: "invalid_login",
+			fields: &fields{
+				ctx: context.Background(),
+				req: &authv1.RegisterRequest{
+					Login:    "john doe",
+					Password: fake.RandPassword(),
+				},
+			},
+			wantErr: errors.New(servers.MsgInvalidLoginCharset),
+		},
 		{
 			name: "user_already_exists",
 			fields: &fields{
 				ctx: context.Background(),
 				req: &authv1.RegisterRequest{
-					Login:    gofakeit.Name(),
+					Login:    fake.RandLogin(),
 					Password: fake.RandPassword(),
 				},
 			},
@@ -96,7 +119,7 @@ func TestServerAPI_Register(t *testing.T) {
 			fields: &fields{
 				ctx: context.Background(),
 				req: &authv1.RegisterRequest{
-					Login:    gofakeit.Name(),
+					Login:    fake.RandLogin(),
 					Password: fake.RandPassword(),
 				},
 			},
@@ -152,7 +175,7 @@ func TestServerAPI_Login(t *testing.T) {
 			fields: &fields{
 				ctx: context.Background(),
 				req: &authv1.LoginRequest{
-					Login:    gofakeit.Name(),
+					Login:    fake.RandLogin(),
 					Password: fake.RandPassword(),
 				},
 			},
@@ -161,7 +184,10 @@ func TestServerAPI_Login(t *testing.T) {
 				authService.
 					On("Login", mock.Anything, f.req.Login, f.req.Password).
 					Once().
-					Return(gofakeit.UUID(), nil)
+					Return(&models.Tokens{
+						AccessToken:  gofakeit.UUID(),
+						RefreshToken: gofakeit.UUID(),
+					}, nil)
 
 				f.authService = authService
 			},
@@ -182,7 +208,7 @@ func TestServerAPI_Login(t *testing.T) {
 			fields: &fields{
 				ctx: context.Background(),
 				req: &authv1.LoginRequest{
-					Login:    gofakeit.Name(),
+					Login:    fake.RandLogin(),
 					Password: "",
 				},
 			},
@@ -193,7 +219,7 @@ func TestServerAPI_Login(t *testing.T) {
 			fields: &fields{
 				ctx: context.Background(),
 				req: &authv1.LoginRequest{
-					Login:    gofakeit.Name(),
+					Login:    fake.RandLogin(),
 					Password: fake.RandPassword(),
 				},
 			},
@@ -203,7 +229,7 @@ func TestServerAPI_Login(t *testing.T) {
 				authService.
 					On("Login", mock.Anything, f.req.Login, f.req.Password).
 					Once().
-					Return("", auth.ErrUserNotFound)
+					Return(nil, auth.ErrUserNotFound)
 
 				f.authService = authService
 			},
@@ -214,7 +240,7 @@ func TestServerAPI_Login(t *testing.T) {
 			fields: &fields{
 				ctx: context.Background(),
 				req: &authv1.LoginRequest{
-					Login:    gofakeit.Name(),
+					Login:    fake.RandLogin(),
 					Password: fake.RandPassword(),
 				},
 			},
@@ -224,7 +250,7 @@ func TestServerAPI_Login(t *testing.T) {
 				authService.
 					On("Login", mock.Anything, f.req.Login, f.req.Password).
 					Once().
-					Return("", auth.ErrInvalidCredentials)
+					Return(nil, auth.ErrInvalidCredentials)
 
 				f.authService = authService
 			},
@@ -235,7 +261,7 @@ func TestServerAPI_Login(t *testing.T) {
 			fields: &fields{
 				ctx: context.Background(),
 				req: &authv1.LoginRequest{
-					Login:    gofakeit.Name(),
+					Login:    fake.RandLogin(),
 					Password: fake.RandPassword(),
 				},
 			},
@@ -245,7 +271,7 @@ func TestServerAPI_Login(t *testing.T) {
 				authService.
 					On("Login", mock.Anything, f.req.Login, f.req.Password).
 					Once().
-					Return("", errors.New("unexpected error"))
+					Return(nil, errors.New("unexpected error"))
 
 				f.authService = authService
 			},
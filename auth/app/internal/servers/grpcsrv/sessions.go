@@ -0,0 +1,203 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/auth"
+	_ "github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jsoncodec"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	sessionsServiceName = "auth.v1.Sessions"
+	refreshMethod       = "/" + sessionsServiceName + "/Refresh"
+	logoutMethod        = "/" + sessionsServiceName + "/Logout"
+	isRevokedMethod     = "/" + sessionsServiceName + "/IsRevoked"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type TokensResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type LogoutResponse struct{}
+
+type IsRevokedRequest struct {
+	SessionID string `json:"sessionId"`
+}
+
+type IsRevokedResponse struct {
+	Revoked bool `json:"revoked"`
+}
+
+type Sessions interface {
+	Refresh(ctx context.Context, refreshToken string) (*models.Tokens, error)
+	Logout(ctx context.Context, refreshToken string) error
+	IsRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+type SessionsServer interface {
+	Refresh(ctx context.Context, request *RefreshTokenRequest) (*TokensResponse, error)
+	Logout(ctx context.Context, request *RefreshTokenRequest) (*LogoutResponse, error)
+	IsRevoked(ctx context.Context, request *IsRevokedRequest) (*IsRevokedResponse, error)
+}
+
+var sessionsServiceDesc = grpc.ServiceDesc{
+	ServiceName: sessionsServiceName,
+	HandlerType: (*SessionsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Refresh",
+			Handler:    refreshHandler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    logoutHandler,
+		},
+		{
+			MethodName: "IsRevoked",
+			Handler:    isRevokedHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sessions.go",
+}
+
+func refreshHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var request = &RefreshTokenRequest{}
+
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(SessionsServer).Refresh(ctx, request)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: refreshMethod,
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(SessionsServer).Refresh(ctx, req.(*RefreshTokenRequest))
+	}
+
+	return interceptor(ctx, request, info, handler)
+}
+
+func logoutHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var request = &RefreshTokenRequest{}
+
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(SessionsServer).Logout(ctx, request)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: logoutMethod,
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(SessionsServer).Logout(ctx, req.(*RefreshTokenRequest))
+	}
+
+	return interceptor(ctx, request, info, handler)
+}
+
+func isRevokedHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var request = &IsRevokedRequest{}
+
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(SessionsServer).IsRevoked(ctx, request)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: isRevokedMethod,
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(SessionsServer).IsRevoked(ctx, req.(*IsRevokedRequest))
+	}
+
+	return interceptor(ctx, request, info, handler)
+}
+
+type sessionsServer struct {
+	sessions Sessions
+}
+
+// RegisterSessions registers the service which refreshes tokens, logs users out
+// and reports revoked sessions to the orchestrator
+func RegisterSessions(gRPCServer *grpc.Server, sessions Sessions) {
+	gRPCServer.RegisterService(&sessionsServiceDesc, &sessionsServer{sessions: sessions})
+}
+
+func (s *sessionsServer) Refresh(ctx context.Context, r *RefreshTokenRequest) (*TokensResponse, error) {
+	if r.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, servers.MsgRefreshTokenIsRequired)
+	}
+
+	tokens, err := s.sessions.Refresh(ctx, r.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return nil, status.Error(codes.Unauthenticated, servers.MsgInvalidRefreshToken)
+		}
+
+		return nil, status.Error(codes.Internal, servers.MsgInternalError)
+	}
+
+	return &TokensResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *sessionsServer) Logout(ctx context.Context, r *RefreshTokenRequest) (*LogoutResponse, error) {
+	if r.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, servers.MsgRefreshTokenIsRequired)
+	}
+
+	err := s.sessions.Logout(ctx, r.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return nil, status.Error(codes.Unauthenticated, servers.MsgInvalidRefreshToken)
+		}
+
+		return nil, status.Error(codes.Internal, servers.MsgInternalError)
+	}
+
+	return &LogoutResponse{}, nil
+}
+
+func (s *sessionsServer) IsRevoked(ctx context.Context, r *IsRevokedRequest) (*IsRevokedResponse, error) {
+	if r.SessionID == "" {
+		return nil, status.Error(codes.InvalidArgument, servers.MsgSessionIDIsRequired)
+	}
+
+	revoked, err := s.sessions.IsRevoked(ctx, r.SessionID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, servers.MsgInternalError)
+	}
+
+	return &IsRevokedResponse{Revoked: revoked}, nil
+}
@@ -0,0 +1,108 @@
+package grpcsrv
+
+import (
+	"context"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	_ "github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jsoncodec"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	usersServiceName = "auth.v1.Users"
+	userEventsMethod = "/" + usersServiceName + "/Events"
+)
+
+type UserEventsRequest struct {
+	AfterID uint64 `json:"afterId"`
+	Limit   int    `json:"limit"`
+}
+
+type UserEventDTO struct {
+	ID        uint64 `json:"id"`
+	Type      string `json:"type"`
+	UserID    uint64 `json:"userId"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+type UserEventsResponse struct {
+	Events []*UserEventDTO `json:"events"`
+}
+
+type UserEvents interface {
+	Events(ctx context.Context, afterID uint64, limit int) ([]*models.UserEvent, error)
+}
+
+type UsersServer interface {
+	Events(ctx context.Context, request *UserEventsRequest) (*UserEventsResponse, error)
+}
+
+var usersServiceDesc = grpc.ServiceDesc{
+	ServiceName: usersServiceName,
+	HandlerType: (*UsersServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Events",
+			Handler:    userEventsHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "users.go",
+}
+
+func userEventsHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var request = &UserEventsRequest{}
+
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(UsersServer).Events(ctx, request)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: userEventsMethod,
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(UsersServer).Events(ctx, req.(*UserEventsRequest))
+	}
+
+	return interceptor(ctx, request, info, handler)
+}
+
+type usersServer struct {
+	events UserEvents
+}
+
+// RegisterUsers registers the service which publishes account events, e.g. deletions, for the api-gateway
+func RegisterUsers(gRPCServer *grpc.Server, events UserEvents) {
+	gRPCServer.RegisterService(&usersServiceDesc, &usersServer{events: events})
+}
+
+// Events returns events published after the event AfterID. CreatedAt is a unix time in milliseconds.
+// Limit is capped by the accounts service, so a page never exceeds its maximum
+func (s *usersServer) Events(ctx context.Context, r *UserEventsRequest) (*UserEventsResponse, error) {
+	events, err := s.events.Events(ctx, r.AfterID, r.Limit)
+	if err != nil {
+		return nil, status.Error(codes.Internal, servers.MsgInternalError)
+	}
+
+	var response = &UserEventsResponse{Events: make([]*UserEventDTO, 0, len(events))}
+	for _, event := range events {
+		response.Events = append(response.Events, &UserEventDTO{
+			ID:        event.ID,
+			Type:      event.Type,
+			UserID:    event.UserID,
+			CreatedAt: event.CreatedAt.UnixMilli(),
+		})
+	}
+
+	return response, nil
+}
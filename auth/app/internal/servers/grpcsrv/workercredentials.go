@@ -0,0 +1,102 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/workercreds"
+	_ "github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jsoncodec"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	workerCredentialsServiceName = "auth.v1.WorkerCredentials"
+	authenticateWorkerMethod     = "/" + workerCredentialsServiceName + "/Authenticate"
+)
+
+type AuthenticateWorkerRequest struct {
+	Token string `json:"token"`
+}
+
+type AuthenticateWorkerResponse struct {
+	CredentialID uint64 `json:"credentialId"`
+	Name         string `json:"name"`
+}
+
+type WorkerAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*models.WorkerCredential, error)
+}
+
+type WorkerCredentialsServer interface {
+	Authenticate(ctx context.Context, request *AuthenticateWorkerRequest) (*AuthenticateWorkerResponse, error)
+}
+
+var workerCredentialsServiceDesc = grpc.ServiceDesc{
+	ServiceName: workerCredentialsServiceName,
+	HandlerType: (*WorkerCredentialsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Authenticate",
+			Handler:    authenticateWorkerHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "workercredentials.go",
+}
+
+func authenticateWorkerHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var request = &AuthenticateWorkerRequest{}
+
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(WorkerCredentialsServer).Authenticate(ctx, request)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: authenticateWorkerMethod,
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(WorkerCredentialsServer).Authenticate(ctx, req.(*AuthenticateWorkerRequest))
+	}
+
+	return interceptor(ctx, request, info, handler)
+}
+
+type workerCredentialsServer struct {
+	authenticator WorkerAuthenticator
+}
+
+// RegisterWorkerCredentials registers the service which checks tokens of daemons for the orchestrator
+func RegisterWorkerCredentials(gRPCServer *grpc.Server, authenticator WorkerAuthenticator) {
+	gRPCServer.RegisterService(&workerCredentialsServiceDesc, &workerCredentialsServer{authenticator: authenticator})
+}
+
+func (s *workerCredentialsServer) Authenticate(ctx context.Context, r *AuthenticateWorkerRequest) (*AuthenticateWorkerResponse, error) {
+	if r.Token == "" {
+		return nil, status.Error(codes.Unauthenticated, servers.MsgInvalidWorkerToken)
+	}
+
+	credential, err := s.authenticator.Authenticate(ctx, r.Token)
+	if err != nil {
+		if errors.Is(err, workercreds.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, servers.MsgInvalidWorkerToken)
+		}
+
+		return nil, status.Error(codes.Internal, servers.MsgInternalError)
+	}
+
+	return &AuthenticateWorkerResponse{
+		CredentialID: credential.ID,
+		Name:         credential.Name,
+	}, nil
+}
@@ -1,7 +1,9 @@
 package httpsrv
 
 import (
+	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
 )
@@ -32,5 +34,186 @@ type RegisterResponseDTO struct {
 }
 
 type LoginResponseDTO struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshTokenRequestDTO struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (r *RefreshTokenRequestDTO) Valid() error {
+	if r.RefreshToken == "" {
+		return errors.New(servers.MsgRefreshTokenIsRequired)
+	}
+
+	return nil
+}
+
+type WorkerCredentialRequestDTO struct {
+	Name string `json:"name"`
+}
+
+func (w *WorkerCredentialRequestDTO) Valid() error {
+	if w.Name == "" {
+		return errors.New(servers.MsgNameIsRequired)
+	}
+
+	if len(w.Name) > 128 {
+		return errors.New(servers.MsgTooLongName)
+	}
+
+	return nil
+}
+
+// WorkerCredentialResponseDTO describes a worker credential. Token is returned only when the credential is created
+type WorkerCredentialResponseDTO struct {
+	ID        uint64     `json:"id"`
+	Name      string     `json:"name"`
+	Token     string     `json:"token,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+type APIKeyRequestDTO struct {
+	Name string `json:"name"`
+	// Scope is "all", "read" or "submit". Empty scope means "all"
+	Scope string `json:"scope"`
+}
+
+func (a *APIKeyRequestDTO) Valid() error {
+	if a.Name == "" {
+		return errors.New(servers.MsgNameIsRequired)
+	}
+
+	if len(a.Name) > 128 {
+		return errors.New(servers.MsgTooLongName)
+	}
+
+	return nil
+}
+
+// APIKeyResponseDTO describes an api key. Key is returned only when the key is created
+type APIKeyResponseDTO struct {
+	ID         uint64     `json:"id"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	Key        string     `json:"key,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// RegisterRequestDTO is UserRequestDTO checked against the login and password policy
+type RegisterRequestDTO struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+func (r *RegisterRequestDTO) Valid() error {
+	err := servers.ValidLogin(r.Login)
+	if err != nil {
+		return err
+	}
+
+	return servers.ValidPassword(r.Password)
+}
+
+type UserResponseDTO struct {
+	ID    uint64 `json:"id"`
+	Login string `json:"login"`
+}
+
+type ChangePasswordRequestDTO struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+func (c *ChangePasswordRequestDTO) Valid() error {
+	if c.CurrentPassword == "" {
+		return errors.New(servers.MsgCurrentPasswordIsMissing)
+	}
+
+	return servers.ValidPassword(c.NewPassword)
+}
+
+type DeleteAccountRequestDTO struct {
+	Password string `json:"password"`
+}
+
+func (d *DeleteAccountRequestDTO) Valid() error {
+	if d.Password == "" {
+		return errors.New(servers.MsgPasswordIsRequired)
+	}
+
+	return nil
+}
+
+type OrganizationRequestDTO struct {
+	Name string `json:"name"`
+}
+
+func (o *OrganizationRequestDTO) Valid() error {
+	if o.Name == "" {
+		return errors.New(servers.MsgNameIsRequired)
+	}
+
+	if len(o.Name) > 128 {
+		return errors.New(servers.MsgTooLongName)
+	}
+
+	return nil
+}
+
+// OrganizationResponseDTO describes an organization and the role of the requesting user in it
+type OrganizationResponseDTO struct {
+	ID        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type MemberRequestDTO struct {
+	Login string `json:"login"`
+	// Role is "admin" or "member". Empty role means "member"
+	Role string `json:"role"`
+}
+
+func (m *MemberRequestDTO) Valid() error {
+	if m.Login == "" {
+		return errors.New(servers.MsgLoginIsRequired)
+	}
+
+	return nil
+}
+
+type MemberResponseDTO struct {
+	UserID    uint64    `json:"userId"`
+	Login     string    `json:"login"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SwitchOrganizationRequestDTO selects the active organization. Zero id selects the personal workspace
+type SwitchOrganizationRequestDTO struct {
+	OrganizationID uint64 `json:"organizationId"`
+}
+
+type TokenResponseDTO struct {
 	Token string `json:"token"`
 }
+
+// AuditRecordDTO is a record of the audit log. The api-gateway exports its audit log in the same form
+type AuditRecordDTO struct {
+	ID         uint64          `json:"id"`
+	ActorType  string          `json:"actorType"`
+	ActorID    uint64          `json:"actorId"`
+	ActorName  string          `json:"actorName,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType,omitempty"`
+	TargetID   string          `json:"targetId,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IP         string          `json:"ip,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
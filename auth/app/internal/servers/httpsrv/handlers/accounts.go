@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers/httpsrv"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/accounts"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/parser"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+type Accounts interface {
+	User(ctx context.Context, userID uint64) (*models.User, error)
+
+	ChangePassword(
+		ctx context.Context,
+		userID uint64,
+		sessionID string,
+		currentPassword string,
+		newPassword string,
+	) error
+
+	Delete(ctx context.Context, userID uint64, password string) error
+}
+
+// Me returns the user of the access token
+func (h *HTTPHandler) Me(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.Me"
+	log := h.log.With(
+		"src", src,
+	)
+
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	user, err := h.accounts.User(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, accounts.ErrUserNotFound) {
+			return http.StatusNotFound, errors.New(servers.MsgUserNotFound)
+		}
+
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	err = parser.EncodeResponse(w, &httpsrv.UserResponseDTO{
+		ID:    user.ID,
+		Login: user.Login,
+	}, http.StatusOK)
+
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusOK, nil
+}
+
+// ChangePassword replaces the password of the user. Other sessions of the user are revoked
+func (h *HTTPHandler) ChangePassword(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	claims, ok := Claims(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	request, err := parser.DecodeValid[*httpsrv.ChangePasswordRequestDTO](r.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	err = h.accounts.ChangePassword(r.Context(), claims.UserID, claims.SessionID, request.CurrentPassword, request.NewPassword)
+	if err != nil {
+		return accountErrorStatus(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return http.StatusNoContent, nil
+}
+
+// DeleteAccount deletes the user together with its sessions and api keys.
+// Expressions of the user are deleted by the api-gateway when it receives the event of the deletion
+func (h *HTTPHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	request, err := parser.DecodeValid[*httpsrv.DeleteAccountRequestDTO](r.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	err = h.accounts.Delete(r.Context(), userID, request.Password)
+	if err != nil {
+		return accountErrorStatus(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return http.StatusNoContent, nil
+}
+
+func accountErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, accounts.ErrInvalidPassword):
+		return http.StatusForbidden, errors.New(servers.MsgInvalidPassword)
+	case errors.Is(err, accounts.ErrUserNotFound):
+		return http.StatusNotFound, errors.New(servers.MsgUserNotFound)
+	case errors.Is(err, accounts.ErrOrganizationOwner):
+		return http.StatusConflict, errors.New(servers.MsgOrganizationOwner)
+	default:
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+}
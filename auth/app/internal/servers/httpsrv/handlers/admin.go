@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
+)
+
+type TokenParser interface {
+	ParseClaims(token string) (*jwt.Claims, error)
+}
+
+// NewAdminMiddleware allows only requests with an access token of a user from adminLogins
+// and puts its claims into the request context like the user middleware. Logins of deleted accounts are reserved forever,
+// so a new account can never take over the role of a deleted admin
+func NewAdminMiddleware(log *slog.Logger, tokenParser TokenParser, adminLogins []string) func(next http.Handler) http.Handler {
+	const src = "http.Admin"
+	log = log.With(
+		slog.String("src", src),
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				servers.WriteError(w, http.StatusUnauthorized, servers.MsgInvalidAuthorization)
+				return
+			}
+
+			claims, err := tokenParser.ParseClaims(token)
+			if err != nil {
+				servers.WriteError(w, http.StatusUnauthorized, servers.MsgInvalidAuthorization)
+				return
+			}
+
+			login := claims.Login
+
+			if login == "" || !slices.Contains(adminLogins, login) {
+				log.Warn("admin access denied", slog.String("login", login))
+				servers.WriteError(w, http.StatusForbidden, servers.MsgAdminRequired)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers/httpsrv"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/apikeys"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/parser"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+type APIKeys interface {
+	Create(
+		ctx context.Context,
+		userID uint64,
+		name string,
+		scope string,
+	) (key *models.APIKey, token string, err error)
+
+	Keys(ctx context.Context, userID uint64) ([]*models.APIKey, error)
+
+	Revoke(ctx context.Context, userID uint64, id uint64) error
+}
+
+func (h *HTTPHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.CreateAPIKey"
+	log := h.log.With(
+		"src", src,
+	)
+
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	request, err := parser.DecodeValid[*httpsrv.APIKeyRequestDTO](r.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	key, token, err := h.apiKeys.Create(r.Context(), userID, request.Name, request.Scope)
+	if err != nil {
+		if errors.Is(err, apikeys.ErrInvalidScope) {
+			return http.StatusBadRequest, errors.New(servers.MsgInvalidScope)
+		}
+
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	response := apiKeyResponse(key)
+	response.Key = token
+
+	err = parser.EncodeResponse(w, response, http.StatusCreated)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusCreated, nil
+}
+
+func (h *HTTPHandler) APIKeys(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.APIKeys"
+	log := h.log.With(
+		"src", src,
+	)
+
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	keys, err := h.apiKeys.Keys(r.Context(), userID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	var response = make([]*httpsrv.APIKeyResponseDTO, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, apiKeyResponse(key))
+	}
+
+	err = parser.EncodeResponse(w, response, http.StatusOK)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (h *HTTPHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, errors.New(servers.MsgInvalidID)
+	}
+
+	err = h.apiKeys.Revoke(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, apikeys.ErrKeyNotFound) {
+			return http.StatusNotFound, errors.New(servers.MsgAPIKeyNotFound)
+		}
+
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return http.StatusNoContent, nil
+}
+
+func apiKeyResponse(key *models.APIKey) *httpsrv.APIKeyResponseDTO {
+	return &httpsrv.APIKeyResponseDTO{
+		ID:         key.ID,
+		Name:       key.Name,
+		Scope:      key.Scope,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers/httpsrv"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/parser"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+type AuditLog interface {
+	Records(ctx context.Context, filter *models.AuditFilter) (records []*models.AuditRecord, nextID uint64, err error)
+
+	Export(ctx context.Context, filter *models.AuditFilter, write func(record *models.AuditRecord) error) error
+}
+
+// AuditLog returns a page of the audit log, the newest records first.
+// The X-Next-Cursor header is the cursor of the next page, it is absent on the last page
+func (h *HTTPHandler) AuditLog(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.AuditLog"
+	log := h.log.With(
+		"src", src,
+	)
+
+	query := r.URL.Query()
+
+	filter, err := auditFilter(query)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	filter.AfterID, err = queryID(query, "cursor")
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 {
+			return http.StatusBadRequest, errors.New(servers.MsgInvalidAuditFilter)
+		}
+	}
+
+	records, nextID, err := h.auditLog.Records(r.Context(), filter)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	var response = make([]*httpsrv.AuditRecordDTO, 0, len(records))
+	for _, record := range records {
+		response = append(response, auditRecordResponse(record))
+	}
+
+	if nextID != 0 {
+		w.Header().Set("X-Next-Cursor", strconv.FormatUint(nextID, 10))
+	}
+
+	err = parser.EncodeResponse(w, response, http.StatusOK)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusOK, nil
+}
+
+// ExportAuditLog streams all records of the audit log matching the filters as JSON lines, the oldest first
+func (h *HTTPHandler) ExportAuditLog(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.ExportAuditLog"
+	log := h.log.With(
+		"src", src,
+	)
+
+	filter, err := auditFilter(r.URL.Query())
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+
+	// The status has already been sent, so a failed export only ends the stream
+	err = h.auditLog.Export(r.Context(), filter, func(record *models.AuditRecord) error {
+		return encoder.Encode(auditRecordResponse(record))
+	})
+	if err != nil {
+		log.Error("failed to export audit log", sl.Err(err))
+	}
+
+	return http.StatusOK, nil
+}
+
+// auditFilter reads the filters of the audit log. The time range includes from and excludes to
+func auditFilter(query url.Values) (*models.AuditFilter, error) {
+	var (
+		filter = &models.AuditFilter{
+			Action:     query.Get("action"),
+			TargetType: query.Get("targetType"),
+			TargetID:   query.Get("targetId"),
+		}
+		err error
+	)
+
+	filter.ActorID, err = queryID(query, "actorId")
+	if err != nil {
+		return nil, err
+	}
+
+	filter.From, err = queryTime(query, "from")
+	if err != nil {
+		return nil, err
+	}
+
+	filter.To, err = queryTime(query, "to")
+	if err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+func queryID(query url.Values, key string) (uint64, error) {
+	value := query.Get(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, errors.New(servers.MsgInvalidAuditFilter)
+	}
+
+	return id, nil
+}
+
+func queryTime(query url.Values, key string) (time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New(servers.MsgInvalidAuditFilter)
+	}
+
+	return t, nil
+}
+
+func auditRecordResponse(record *models.AuditRecord) *httpsrv.AuditRecordDTO {
+	return &httpsrv.AuditRecordDTO{
+		ID:         record.ID,
+		ActorType:  record.ActorType,
+		ActorID:    record.ActorID,
+		ActorName:  record.ActorName,
+		Action:     record.Action,
+		TargetType: record.TargetType,
+		TargetID:   record.TargetID,
+		Before:     record.Before,
+		After:      record.After,
+		IP:         record.IP,
+		CreatedAt:  record.CreatedAt,
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers/httpsrv"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/auth"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/ratelimit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwks"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/parser"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
 )
@@ -24,21 +27,64 @@ type Auth interface {
 		ctx context.Context,
 		login string,
 		password string,
-	) (token string, err error)
+	) (tokens *models.Tokens, err error)
+
+	Refresh(
+		ctx context.Context,
+		refreshToken string,
+	) (tokens *models.Tokens, err error)
+
+	Logout(
+		ctx context.Context,
+		refreshToken string,
+	) error
+}
+
+// KeySet publishes the public keys which verify access tokens
+type KeySet interface {
+	JWKS() (*jwks.Set, error)
 }
 
 type HTTPHandler struct {
-	log  *slog.Logger
-	auth Auth
+	log               *slog.Logger
+	auth              Auth
+	workerCredentials WorkerCredentials
+	apiKeys           APIKeys
+	accounts          Accounts
+	organizations     Organizations
+	auditLog          AuditLog
+
+	tokenParser TokenParser
+	keySet      KeySet
+	adminLogins []string
+	ipLimiter   RateLimiter
 }
 
 func NewHTTPHandler(
 	log *slog.Logger,
 	auth Auth,
+	workerCredentials WorkerCredentials,
+	apiKeys APIKeys,
+	accounts Accounts,
+	organizations Organizations,
+	auditLog AuditLog,
+	tokenParser TokenParser,
+	keySet KeySet,
+	adminLogins []string,
+	ipLimiter RateLimiter,
 ) *HTTPHandler {
 	return &HTTPHandler{
-		log:  log,
-		auth: auth,
+		log:               log,
+		auth:              auth,
+		workerCredentials: workerCredentials,
+		apiKeys:           apiKeys,
+		accounts:          accounts,
+		organizations:     organizations,
+		auditLog:          auditLog,
+		tokenParser:       tokenParser,
+		keySet:            keySet,
+		adminLogins:       adminLogins,
+		ipLimiter:         ipLimiter,
 	}
 }
 
@@ -46,11 +92,39 @@ func (h *HTTPHandler) Handler() http.Handler {
 	mux := http.NewServeMux()
 	logger := NewLoggerMiddleware(h.log)
 	recovery := NewRecoveryMiddleware(h.log)
+	admin := NewAdminMiddleware(h.log, h.tokenParser, h.adminLogins)
+	user := NewUserMiddleware(h.tokenParser)
+	ipLimit := NewRateLimitMiddleware(h.log, h.ipLimiter, IPKey)
 
 	mux.Handle("POST /api/v1/register", Errors(h.Register))
-	mux.Handle("POST /api/v1/login", Errors(h.Login))
+	mux.Handle("POST /api/v1/login", ipLimit(Errors(h.Login)))
+	mux.Handle("POST /api/v1/refresh", Errors(h.Refresh))
+	mux.Handle("POST /api/v1/logout", Errors(h.Logout))
+	mux.Handle("GET /.well-known/jwks.json", Errors(h.JWKS))
+
+	mux.Handle("GET /api/v1/me", user(Errors(h.Me)))
+	mux.Handle("PUT /api/v1/me/password", user(Errors(h.ChangePassword)))
+	mux.Handle("DELETE /api/v1/me", user(Errors(h.DeleteAccount)))
+	mux.Handle("PUT /api/v1/me/organization", user(Errors(h.SwitchOrganization)))
+
+	mux.Handle("POST /api/v1/organizations", user(Errors(h.CreateOrganization)))
+	mux.Handle("GET /api/v1/organizations", user(Errors(h.Organizations)))
+	mux.Handle("GET /api/v1/organizations/{id}/members", user(Errors(h.Members)))
+	mux.Handle("POST /api/v1/organizations/{id}/members", user(Errors(h.AddMember)))
+	mux.Handle("DELETE /api/v1/organizations/{id}/members/{userId}", user(Errors(h.RemoveMember)))
 
-	return recovery(logger(CORS(mux)))
+	mux.Handle("POST /api/v1/keys", user(Errors(h.CreateAPIKey)))
+	mux.Handle("GET /api/v1/keys", user(Errors(h.APIKeys)))
+	mux.Handle("DELETE /api/v1/keys/{id}", user(Errors(h.RevokeAPIKey)))
+
+	mux.Handle("POST /api/v1/workers/credentials", admin(Errors(h.CreateWorkerCredential)))
+	mux.Handle("GET /api/v1/workers/credentials", admin(Errors(h.WorkerCredentials)))
+	mux.Handle("DELETE /api/v1/workers/credentials/{id}", admin(Errors(h.RevokeWorkerCredential)))
+
+	mux.Handle("GET /api/v1/audit", admin(Errors(h.AuditLog)))
+	mux.Handle("GET /api/v1/audit/export", admin(Errors(h.ExportAuditLog)))
+
+	return recovery(logger(ClientIP(CORS(mux))))
 }
 
 func (h *HTTPHandler) Register(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
@@ -59,7 +133,7 @@ func (h *HTTPHandler) Register(w http.ResponseWriter, r *http.Request) (statusCo
 		"src", src,
 	)
 
-	user, err := parser.DecodeValid[*httpsrv.UserRequestDTO](r.Body)
+	user, err := parser.DecodeValid[*httpsrv.RegisterRequestDTO](r.Body)
 	if err != nil {
 		return http.StatusBadRequest, err
 	}
@@ -86,7 +160,7 @@ func (h *HTTPHandler) Register(w http.ResponseWriter, r *http.Request) (statusCo
 }
 
 func (h *HTTPHandler) Login(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
-	const src = "HTTPHandler.Register"
+	const src = "HTTPHandler.Login"
 	log := h.log.With(
 		"src", src,
 	)
@@ -96,7 +170,7 @@ func (h *HTTPHandler) Login(w http.ResponseWriter, r *http.Request) (statusCode
 		return http.StatusBadRequest, err
 	}
 
-	token, err := h.auth.Login(r.Context(), user.Login, user.Password)
+	tokens, err := h.auth.Login(r.Context(), user.Login, user.Password)
 	if err != nil {
 		if errors.Is(err, auth.ErrUserNotFound) {
 			return http.StatusNotFound, errors.New(servers.MsgUserNotFound)
@@ -104,12 +178,17 @@ func (h *HTTPHandler) Login(w http.ResponseWriter, r *http.Request) (statusCode
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return http.StatusBadRequest, errors.New(servers.MsgInvalidCredentials)
 		}
+		if errors.Is(err, ratelimit.ErrLocked) {
+			setRetryAfter(w, err)
+			return http.StatusTooManyRequests, errors.New(servers.MsgTooManyAttempts)
+		}
 
 		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
 	}
 
 	err = parser.EncodeResponse(w, &httpsrv.LoginResponseDTO{
-		Token: token,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, http.StatusOK)
 
 	if err != nil {
@@ -119,3 +198,85 @@ func (h *HTTPHandler) Login(w http.ResponseWriter, r *http.Request) (statusCode
 
 	return http.StatusOK, nil
 }
+
+// Refresh exchanges a refresh token for a new pair of tokens. The provided refresh token cannot be used again
+func (h *HTTPHandler) Refresh(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.Refresh"
+	log := h.log.With(
+		"src", src,
+	)
+
+	request, err := parser.DecodeValid[*httpsrv.RefreshTokenRequestDTO](r.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	tokens, err := h.auth.Refresh(r.Context(), request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return http.StatusUnauthorized, errors.New(servers.MsgInvalidRefreshToken)
+		}
+
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	err = parser.EncodeResponse(w, &httpsrv.LoginResponseDTO{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, http.StatusOK)
+
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusOK, nil
+}
+
+// Logout revokes the session of a refresh token together with all its access tokens
+func (h *HTTPHandler) Logout(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	request, err := parser.DecodeValid[*httpsrv.RefreshTokenRequestDTO](r.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	err = h.auth.Logout(r.Context(), request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return http.StatusUnauthorized, errors.New(servers.MsgInvalidRefreshToken)
+		}
+
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return http.StatusNoContent, nil
+}
+
+// jwksMaxAge lets clients cache the key set. New keys are fetched by clients when they meet an unknown kid
+const jwksMaxAge = "max-age=300"
+
+// JWKS publishes the keys which verify access tokens, including retired keys which tokens have not expired yet
+func (h *HTTPHandler) JWKS(w http.ResponseWriter, _ *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.JWKS"
+	log := h.log.With(
+		"src", src,
+	)
+
+	set, err := h.keySet.JWKS()
+	if err != nil {
+		log.Error("failed to get key set", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	w.Header().Set("Cache-Control", jwksMaxAge)
+
+	err = parser.EncodeResponse(w, set, http.StatusOK)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers/httpsrv"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/organizations"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/parser"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+type Organizations interface {
+	Create(ctx context.Context, userID uint64, name string) (*models.Organization, error)
+
+	Organizations(ctx context.Context, userID uint64) ([]*models.Organization, error)
+
+	Members(ctx context.Context, userID uint64, organizationID uint64) ([]*models.OrganizationMember, error)
+
+	AddMember(
+		ctx context.Context,
+		userID uint64,
+		organizationID uint64,
+		login string,
+		role string,
+	) (*models.OrganizationMember, error)
+
+	RemoveMember(ctx context.Context, userID uint64, organizationID uint64, memberID uint64) error
+
+	Switch(ctx context.Context, claims *jwt.Claims, organizationID uint64) (token string, err error)
+}
+
+func (h *HTTPHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.CreateOrganization"
+	log := h.log.With(
+		"src", src,
+	)
+
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	request, err := parser.DecodeValid[*httpsrv.OrganizationRequestDTO](r.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	organization, err := h.organizations.Create(r.Context(), userID, request.Name)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	err = parser.EncodeResponse(w, organizationResponse(organization), http.StatusCreated)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusCreated, nil
+}
+
+func (h *HTTPHandler) Organizations(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.Organizations"
+	log := h.log.With(
+		"src", src,
+	)
+
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	list, err := h.organizations.Organizations(r.Context(), userID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	var response = make([]*httpsrv.OrganizationResponseDTO, 0, len(list))
+	for _, organization := range list {
+		response = append(response, organizationResponse(organization))
+	}
+
+	err = parser.EncodeResponse(w, response, http.StatusOK)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (h *HTTPHandler) Members(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.Members"
+	log := h.log.With(
+		"src", src,
+	)
+
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	organizationID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, errors.New(servers.MsgInvalidID)
+	}
+
+	members, err := h.organizations.Members(r.Context(), userID, organizationID)
+	if err != nil {
+		return organizationErrorStatus(err)
+	}
+
+	var response = make([]*httpsrv.MemberResponseDTO, 0, len(members))
+	for _, member := range members {
+		response = append(response, memberResponse(member))
+	}
+
+	err = parser.EncodeResponse(w, response, http.StatusOK)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (h *HTTPHandler) AddMember(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.AddMember"
+	log := h.log.With(
+		"src", src,
+	)
+
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	organizationID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, errors.New(servers.MsgInvalidID)
+	}
+
+	request, err := parser.DecodeValid[*httpsrv.MemberRequestDTO](r.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	member, err := h.organizations.AddMember(r.Context(), userID, organizationID, request.Login, request.Role)
+	if err != nil {
+		return organizationErrorStatus(err)
+	}
+
+	err = parser.EncodeResponse(w, memberResponse(member), http.StatusCreated)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusCreated, nil
+}
+
+// RemoveMember removes a member from the organization. Members may remove themselves to leave the organization
+func (h *HTTPHandler) RemoveMember(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	userID, ok := UserID(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	organizationID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, errors.New(servers.MsgInvalidID)
+	}
+
+	memberID, err := strconv.ParseUint(r.PathValue("userId"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, errors.New(servers.MsgInvalidID)
+	}
+
+	err = h.organizations.RemoveMember(r.Context(), userID, organizationID, memberID)
+	if err != nil {
+		return organizationErrorStatus(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return http.StatusNoContent, nil
+}
+
+// SwitchOrganization makes an organization active in the session and returns an access token with it.
+// Expressions created with the token belong to the workspace of the organization
+func (h *HTTPHandler) SwitchOrganization(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.SwitchOrganization"
+	log := h.log.With(
+		"src", src,
+	)
+
+	claims, ok := Claims(r.Context())
+	if !ok {
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	}
+
+	request, err := parser.Decode[httpsrv.SwitchOrganizationRequestDTO](r.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	token, err := h.organizations.Switch(r.Context(), claims, request.OrganizationID)
+	if err != nil {
+		return organizationErrorStatus(err)
+	}
+
+	err = parser.EncodeResponse(w, &httpsrv.TokenResponseDTO{Token: token}, http.StatusOK)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusOK, nil
+}
+
+func organizationErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, organizations.ErrInvalidRole):
+		return http.StatusBadRequest, errors.New(servers.MsgInvalidRole)
+	case errors.Is(err, organizations.ErrSessionRevoked):
+		return http.StatusUnauthorized, errors.New(servers.MsgInvalidAuthorization)
+	case errors.Is(err, organizations.ErrAccessDenied):
+		return http.StatusForbidden, errors.New(servers.MsgOrganizationAccessDenied)
+	case errors.Is(err, organizations.ErrOwnerRemoval):
+		return http.StatusForbidden, errors.New(servers.MsgOwnerCannotBeRemoved)
+	case errors.Is(err, organizations.ErrUserNotFound):
+		return http.StatusNotFound, errors.New(servers.MsgUserNotFound)
+	case errors.Is(err, organizations.ErrMemberNotFound):
+		return http.StatusNotFound, errors.New(servers.MsgMemberNotFound)
+	case errors.Is(err, organizations.ErrMemberAlreadyExists):
+		return http.StatusConflict, errors.New(servers.MsgMemberAlreadyExists)
+	default:
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+}
+
+func organizationResponse(organization *models.Organization) *httpsrv.OrganizationResponseDTO {
+	return &httpsrv.OrganizationResponseDTO{
+		ID:        organization.ID,
+		Name:      organization.Name,
+		Role:      organization.Role,
+		CreatedAt: organization.CreatedAt,
+	}
+}
+
+func memberResponse(member *models.OrganizationMember) *httpsrv.MemberResponseDTO {
+	return &httpsrv.MemberResponseDTO{
+		UserID:    member.UserID,
+		Login:     member.Login,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt,
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/ratelimit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/clientip"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+// RateLimiter locks keys after too many failed requests
+type RateLimiter interface {
+	// Check returns ratelimit.ErrLocked, if any of the keys is locked
+	Check(ctx context.Context, keys ...string) error
+	Fail(ctx context.Context, keys ...string) error
+}
+
+// ClientIP puts the address of the client into the request context
+func ClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(clientip.With(r.Context(), clientip.FromRequest(r))))
+	})
+}
+
+// IPKey limits requests by the address of the client
+func IPKey(r *http.Request) string {
+	return "ip:" + clientip.From(r.Context())
+}
+
+// NewRateLimitMiddleware rejects requests of locked keys with 429. Responses with client errors count as failures.
+// Errors of the limiter are only logged, so an unavailable limiter does not reject requests
+func NewRateLimitMiddleware(
+	log *slog.Logger,
+	limiter RateLimiter,
+	key func(r *http.Request) string,
+) func(next http.Handler) http.Handler {
+	const src = "http.RateLimit"
+	log = log.With(
+		slog.String("src", src),
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var k = key(r)
+
+			err := limiter.Check(r.Context(), k)
+			if errors.Is(err, ratelimit.ErrLocked) {
+				log.Warn("request rejected", slog.String("key", k))
+				setRetryAfter(w, err)
+				servers.WriteError(w, http.StatusTooManyRequests, servers.MsgTooManyAttempts)
+				return
+			}
+
+			if err != nil {
+				log.Error("failed to check rate limit", sl.Err(err))
+			}
+
+			recorder := &writerRecorder{
+				w:      w,
+				status: http.StatusOK,
+			}
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status < 400 || recorder.status >= 500 || recorder.status == http.StatusTooManyRequests {
+				return
+			}
+
+			err = limiter.Fail(r.Context(), k)
+			if err != nil {
+				log.Error("failed to count failed request", sl.Err(err))
+			}
+		})
+	}
+}
+
+// setRetryAfter sets the Retry-After header to the rest of the lockout, if err is *ratelimit.LockedError
+func setRetryAfter(w http.ResponseWriter, err error) {
+	var lockedErr *ratelimit.LockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(lockedErr.RetryAfter)))
+	}
+}
+
+func retryAfterSeconds(retryAfter time.Duration) int {
+	return int(math.Ceil(retryAfter.Seconds()))
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
+)
+
+type claimsKey struct{}
+
+// NewUserMiddleware allows only requests with an access token and puts its claims into the request context.
+// The user is also the actor of audited actions of the request
+func NewUserMiddleware(tokenParser TokenParser) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				servers.WriteError(w, http.StatusUnauthorized, servers.MsgInvalidAuthorization)
+				return
+			}
+
+			claims, err := tokenParser.ParseClaims(token)
+			if err != nil || claims.UserID == 0 {
+				servers.WriteError(w, http.StatusUnauthorized, servers.MsgInvalidAuthorization)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+func withClaims(ctx context.Context, claims *jwt.Claims) context.Context {
+	ctx = context.WithValue(ctx, claimsKey{}, claims)
+	return audit.WithActor(ctx, claims.UserID, claims.Login)
+}
+
+// Claims returns the claims of the access token set by the user middleware
+func Claims(ctx context.Context) (*jwt.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*jwt.Claims)
+	return claims, ok
+}
+
+// UserID returns the id of the user set by the user middleware
+func UserID(ctx context.Context) (uint64, bool) {
+	claims, ok := Claims(ctx)
+	if !ok {
+		return 0, false
+	}
+
+	return claims.UserID, true
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/servers/httpsrv"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/workercreds"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/parser"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+type WorkerCredentials interface {
+	Create(
+		ctx context.Context,
+		name string,
+	) (credential *models.WorkerCredential, token string, err error)
+
+	Credentials(ctx context.Context) ([]*models.WorkerCredential, error)
+
+	Revoke(ctx context.Context, id uint64) error
+}
+
+func (h *HTTPHandler) CreateWorkerCredential(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.CreateWorkerCredential"
+	log := h.log.With(
+		"src", src,
+	)
+
+	request, err := parser.DecodeValid[*httpsrv.WorkerCredentialRequestDTO](r.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	credential, token, err := h.workerCredentials.Create(r.Context(), request.Name)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	response := workerCredentialResponse(credential)
+	response.Token = token
+
+	err = parser.EncodeResponse(w, response, http.StatusCreated)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusCreated, nil
+}
+
+func (h *HTTPHandler) WorkerCredentials(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	const src = "HTTPHandler.WorkerCredentials"
+	log := h.log.With(
+		"src", src,
+	)
+
+	credentials, err := h.workerCredentials.Credentials(r.Context())
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	var response = make([]*httpsrv.WorkerCredentialResponseDTO, 0, len(credentials))
+	for _, credential := range credentials {
+		response = append(response, workerCredentialResponse(credential))
+	}
+
+	err = parser.EncodeResponse(w, response, http.StatusOK)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (h *HTTPHandler) RevokeWorkerCredential(w http.ResponseWriter, r *http.Request) (statusCode int, err error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, errors.New(servers.MsgInvalidID)
+	}
+
+	err = h.workerCredentials.Revoke(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, workercreds.ErrCredentialNotFound) {
+			return http.StatusNotFound, errors.New(servers.MsgWorkerCredentialNotFound)
+		}
+
+		return http.StatusInternalServerError, errors.New(servers.MsgInternalError)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return http.StatusNoContent, nil
+}
+
+func workerCredentialResponse(credential *models.WorkerCredential) *httpsrv.WorkerCredentialResponseDTO {
+	return &httpsrv.WorkerCredentialResponseDTO{
+		ID:        credential.ID,
+		Name:      credential.Name,
+		CreatedAt: credential.CreatedAt,
+		RevokedAt: credential.RevokedAt,
+	}
+}
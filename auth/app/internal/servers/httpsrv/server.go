@@ -2,6 +2,7 @@ package httpsrv
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"net/http"
 	"strconv"
@@ -11,11 +12,13 @@ type HTTPServer struct {
 	httpServer *http.Server
 }
 
-func New(ctx context.Context, port int, handler http.Handler) *HTTPServer {
+// New creates a server. Nil tlsConfig means plain HTTP
+func New(ctx context.Context, port int, handler http.Handler, tlsConfig *tls.Config) *HTTPServer {
 	return &HTTPServer{
 		httpServer: &http.Server{
-			Addr:    ":" + strconv.Itoa(port),
-			Handler: handler,
+			Addr:      ":" + strconv.Itoa(port),
+			Handler:   handler,
+			TLSConfig: tlsConfig,
 			BaseContext: func(net.Listener) context.Context {
 				return ctx
 			},
@@ -24,6 +27,10 @@ func New(ctx context.Context, port int, handler http.Handler) *HTTPServer {
 }
 
 func (s *HTTPServer) Run() error {
+	if s.httpServer.TLSConfig != nil {
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
+
 	return s.httpServer.ListenAndServe()
 }
 
@@ -0,0 +1,75 @@
+package servers
+
+import (
+	"errors"
+	"unicode"
+)
+
+// Policy of logins and passwords of new accounts. Existing accounts can log in even if they do not satisfy it
+const (
+	MinLoginLength    = 3
+	MaxLoginLength    = 32
+	MinPasswordLength = 8
+	MaxPasswordLength = 64
+)
+
+// ValidLogin checks the length of login and that it contains only latin letters, digits, '.', '_' and '-'
+func ValidLogin(login string) error {
+	if login == "" {
+		return errors.New(MsgLoginIsRequired)
+	}
+
+	if len(login) < MinLoginLength {
+		return errors.New(MsgTooShortLogin)
+	}
+
+	if len(login) > MaxLoginLength {
+		return errors.New(MsgTooLongLogin)
+	}
+
+	for _, r := range login {
+		if !isLoginRune(r) {
+			return errors.New(MsgInvalidLoginCharset)
+		}
+	}
+
+	return nil
+}
+
+// ValidPassword checks the length of password and that it contains both letters and digits
+func ValidPassword(password string) error {
+	if password == "" {
+		return errors.New(MsgPasswordIsRequired)
+	}
+
+	if len(password) < MinPasswordLength {
+		return errors.New(MsgTooShortPassword)
+	}
+
+	if len(password) > MaxPasswordLength {
+		return errors.New(MsgTooLongPassword)
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		hasLetter = hasLetter || unicode.IsLetter(r)
+		hasDigit = hasDigit || unicode.IsDigit(r)
+	}
+
+	if !hasLetter || !hasDigit {
+		return errors.New(MsgWeakPassword)
+	}
+
+	return nil
+}
+
+func isLoginRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r == '.', r == '_', r == '-':
+		return true
+	default:
+		return false
+	}
+}
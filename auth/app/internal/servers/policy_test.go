@@ -0,0 +1,65 @@
+package servers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		login   string
+		wantMsg string
+	}{
+		{name: "valid", login: "john.doe-42_"},
+		{name: "empty", login: "", wantMsg: MsgLoginIsRequired},
+		{name: "too_short", login: "jd", wantMsg: MsgTooShortLogin},
+		{name: "too_long", login: "abcdefghijklmnopqrstuvwxyz0123456", wantMsg: MsgTooLongLogin},
+		{name: "space", login: "john doe", wantMsg: MsgInvalidLoginCharset},
+		{name: "cyrillic", login: "иван", wantMsg: MsgInvalidLoginCharset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidLogin(tt.login)
+			if tt.wantMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, tt.wantMsg)
+		})
+	}
+}
+
+func TestValidPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantMsg  string
+	}{
+		{name: "valid", password: "correct horse 1"},
+		{name: "empty", password: "", wantMsg: MsgPasswordIsRequired},
+		{name: "too_short", password: "abc123", wantMsg: MsgTooShortPassword},
+		{name: "too_long", password: "a1234567890123456789012345678901234567890123456789012345678901234", wantMsg: MsgTooLongPassword},
+		{name: "letters_only", password: "password", wantMsg: MsgWeakPassword},
+		{name: "digits_only", password: "12345678", wantMsg: MsgWeakPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidPassword(tt.password)
+			if tt.wantMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, tt.wantMsg)
+
+			code, ok := DevCodeFromMsg(err.Error())
+			require.True(t, ok)
+			require.NotZero(t, code)
+		})
+	}
+}
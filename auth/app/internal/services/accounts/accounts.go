@@ -0,0 +1,230 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/usersrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxEventsLimit bounds the number of events returned at once
+const maxEventsLimit = 1000
+
+var (
+	ErrUserNotFound    = errors.New("accounts: user not found")
+	ErrInvalidPassword = errors.New("accounts: invalid password")
+	// ErrOrganizationOwner is returned when the owner of an organization deletes the account
+	ErrOrganizationOwner = errors.New("accounts: user owns an organization")
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=UserStorage
+type UserStorage interface {
+	UserByID(ctx context.Context, id uint64) (user *models.User, err error)
+
+	UpdatePassword(ctx context.Context, id uint64, passwordHash string) error
+
+	Delete(ctx context.Context, id uint64) error
+
+	Events(ctx context.Context, afterID uint64, limit int) (events []*models.UserEvent, err error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=SessionRevoker
+type SessionRevoker interface {
+	RevokeUser(ctx context.Context, userID uint64, exceptID string) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=UsersCache
+
+// UsersCache keeps users for logins. Other replicas of the service forget a changed user only when its entry expires
+type UsersCache interface {
+	Invalidate(login string)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=AuditLog
+
+// AuditLog records changed passwords and deleted accounts
+type AuditLog interface {
+	Record(ctx context.Context, entry *audit.Entry)
+}
+
+type Accounts struct {
+	log      *slog.Logger
+	storage  UserStorage
+	sessions SessionRevoker
+	cache    UsersCache
+	auditLog AuditLog
+}
+
+func New(
+	log *slog.Logger,
+	storage UserStorage,
+	sessions SessionRevoker,
+	cache UsersCache,
+	auditLog AuditLog,
+) *Accounts {
+	return &Accounts{
+		log:      log,
+		storage:  storage,
+		sessions: sessions,
+		cache:    cache,
+		auditLog: auditLog,
+	}
+}
+
+// User returns the user with provided id
+//
+// Returns ErrUserNotFound, if the user has been deleted
+func (a *Accounts) User(ctx context.Context, userID uint64) (*models.User, error) {
+	const src = "Accounts.User"
+
+	user, err := a.storage.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, usersrepo.ErrUserNotFound) {
+			return nil, e.WrapErr(ErrUserNotFound, src)
+		}
+
+		return nil, e.WrapErr(err, src)
+	}
+
+	return user, nil
+}
+
+// ChangePassword replaces the password of the user after checking the current one.
+// Other sessions of the user are revoked, the session sessionID keeps working
+//
+// Returns ErrInvalidPassword, if currentPassword is wrong
+func (a *Accounts) ChangePassword(
+	ctx context.Context,
+	userID uint64,
+	sessionID string,
+	currentPassword string,
+	newPassword string,
+) error {
+	const src = "Accounts.ChangePassword"
+
+	log := a.log.With(
+		slog.String("src", src),
+		slog.Uint64("userID", userID),
+	)
+
+	user, err := a.checkPassword(ctx, userID, currentPassword)
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+		return e.WrapErr(err, src)
+	}
+
+	err = a.storage.UpdatePassword(ctx, userID, string(passwordHash))
+	if err != nil {
+		if errors.Is(err, usersrepo.ErrUserNotFound) {
+			return e.WrapErr(ErrUserNotFound, src)
+		}
+
+		return e.WrapErr(err, src)
+	}
+
+	a.cache.Invalidate(user.Login)
+
+	err = a.sessions.RevokeUser(ctx, userID, sessionID)
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	a.auditLog.Record(ctx, &audit.Entry{
+		Action:     models.AuditPasswordChanged,
+		ActorID:    userID,
+		ActorName:  user.Login,
+		TargetType: models.AuditTargetUser,
+		TargetID:   audit.TargetID(userID),
+	})
+
+	log.Info("password changed")
+
+	return nil
+}
+
+// Delete deletes the account after checking its password. Sessions and api keys of the user are deleted with it,
+// and models.UserEventDeleted is published for other services
+//
+// Returns ErrInvalidPassword, if password is wrong, and ErrOrganizationOwner, if the user owns an organization
+func (a *Accounts) Delete(ctx context.Context, userID uint64, password string) error {
+	const src = "Accounts.Delete"
+
+	user, err := a.checkPassword(ctx, userID, password)
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	err = a.storage.Delete(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usersrepo.ErrUserNotFound):
+			return e.WrapErr(ErrUserNotFound, src)
+		case errors.Is(err, usersrepo.ErrOrganizationOwner):
+			return e.WrapErr(ErrOrganizationOwner, src)
+		default:
+			return e.WrapErr(err, src)
+		}
+	}
+
+	a.cache.Invalidate(user.Login)
+
+	a.auditLog.Record(ctx, &audit.Entry{
+		Action:     models.AuditUserDeleted,
+		ActorID:    userID,
+		ActorName:  user.Login,
+		TargetType: models.AuditTargetUser,
+		TargetID:   audit.TargetID(userID),
+		Before:     map[string]string{"login": user.Login},
+	})
+
+	a.log.Info("account deleted", slog.String("src", src), slog.Uint64("userID", userID))
+
+	return nil
+}
+
+// Events returns at most limit account events published after the event afterID
+func (a *Accounts) Events(ctx context.Context, afterID uint64, limit int) ([]*models.UserEvent, error) {
+	const src = "Accounts.Events"
+
+	if limit <= 0 || limit > maxEventsLimit {
+		limit = maxEventsLimit
+	}
+
+	events, err := a.storage.Events(ctx, afterID, limit)
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	return events, nil
+}
+
+func (a *Accounts) checkPassword(ctx context.Context, userID uint64, password string) (*models.User, error) {
+	user, err := a.storage.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, usersrepo.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err != nil {
+		a.log.Warn("invalid password", slog.Uint64("userID", userID))
+		return nil, ErrInvalidPassword
+	}
+
+	return user, nil
+}
@@ -0,0 +1,214 @@
+package accounts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/usersrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/accounts/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultUserID    uint64 = 1
+	defaultLogin            = "user"
+	defaultPassword         = "password1"
+	defaultSessionID        = "current"
+)
+
+type fields struct {
+	storage  *mocks.UserStorage
+	sessions *mocks.SessionRevoker
+	cache    *mocks.UsersCache
+	auditLog *mocks.AuditLog
+}
+
+func newFields(t *testing.T) *fields {
+	return &fields{
+		storage:  mocks.NewUserStorage(t),
+		sessions: mocks.NewSessionRevoker(t),
+		cache:    mocks.NewUsersCache(t),
+		auditLog: mocks.NewAuditLog(t),
+	}
+}
+
+func (f *fields) accounts() *Accounts {
+	return New(sl.NewDiscardLogger(), f.storage, f.sessions, f.cache, f.auditLog)
+}
+
+// findUser expects the default user with the default password to be found
+func (f *fields) findUser(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(defaultPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	f.storage.
+		On("UserByID", mock.Anything, defaultUserID).
+		Once().
+		Return(&models.User{ID: defaultUserID, Login: defaultLogin, PasswordHash: string(hash)}, nil)
+}
+
+// expectRecord expects the audit entry of the action on the default user
+func (f *fields) expectRecord(action string) {
+	f.auditLog.
+		On("Record", mock.Anything, mock.MatchedBy(func(entry *audit.Entry) bool {
+			return entry.Action == action &&
+				entry.ActorID == defaultUserID &&
+				entry.TargetType == models.AuditTargetUser &&
+				entry.TargetID == audit.TargetID(defaultUserID)
+		})).
+		Once().
+		Return()
+}
+
+func TestAccounts_ChangePassword(t *testing.T) {
+	tests := []struct {
+		name            string
+		currentPassword string
+		prepare         func(t *testing.T, f *fields)
+
+		targetErr error
+	}{
+		{
+			name:            "changed",
+			currentPassword: defaultPassword,
+			prepare: func(t *testing.T, f *fields) {
+				f.findUser(t)
+				f.storage.
+					On("UpdatePassword", mock.Anything, defaultUserID, mock.MatchedBy(func(hash string) bool {
+						return bcrypt.CompareHashAndPassword([]byte(hash), []byte("password3")) == nil
+					})).
+					Once().
+					Return(nil)
+				f.cache.On("Invalidate", defaultLogin).Once().Return()
+				f.sessions.On("RevokeUser", mock.Anything, defaultUserID, defaultSessionID).Once().Return(nil)
+				f.expectRecord(models.AuditPasswordChanged)
+			},
+		},
+		{
+			name:            "err_wrong_password",
+			currentPassword: "password2",
+			prepare: func(t *testing.T, f *fields) {
+				f.findUser(t)
+			},
+			targetErr: ErrInvalidPassword,
+		},
+		{
+			name:            "err_deleted_user",
+			currentPassword: defaultPassword,
+			prepare: func(t *testing.T, f *fields) {
+				f.storage.On("UserByID", mock.Anything, defaultUserID).Once().Return(nil, usersrepo.ErrUserNotFound)
+			},
+			targetErr: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(t, f)
+
+			err := f.accounts().ChangePassword(context.Background(), defaultUserID, defaultSessionID, tt.currentPassword, "password3")
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAccounts_Delete(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		prepare  func(t *testing.T, f *fields)
+
+		targetErr error
+	}{
+		{
+			name:     "deleted",
+			password: defaultPassword,
+			prepare: func(t *testing.T, f *fields) {
+				f.findUser(t)
+				f.storage.On("Delete", mock.Anything, defaultUserID).Once().Return(nil)
+				f.cache.On("Invalidate", defaultLogin).Once().Return()
+				f.expectRecord(models.AuditUserDeleted)
+			},
+		},
+		{
+			name:     "err_wrong_password",
+			password: "password2",
+			prepare: func(t *testing.T, f *fields) {
+				f.findUser(t)
+			},
+			targetErr: ErrInvalidPassword,
+		},
+		{
+			name:     "err_organization_owner",
+			password: defaultPassword,
+			prepare: func(t *testing.T, f *fields) {
+				f.findUser(t)
+				f.storage.On("Delete", mock.Anything, defaultUserID).Once().Return(usersrepo.ErrOrganizationOwner)
+			},
+			targetErr: ErrOrganizationOwner,
+		},
+		{
+			name:     "err_deleted_user",
+			password: defaultPassword,
+			prepare: func(t *testing.T, f *fields) {
+				f.findUser(t)
+				f.storage.On("Delete", mock.Anything, defaultUserID).Once().Return(usersrepo.ErrUserNotFound)
+			},
+			targetErr: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(t, f)
+
+			err := f.accounts().Delete(context.Background(), defaultUserID, tt.password)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAccounts_Events(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{name: "default", limit: 0, want: maxEventsLimit},
+		{name: "negative", limit: -1, want: maxEventsLimit},
+		{name: "small", limit: 10, want: 10},
+		{name: "too_large", limit: maxEventsLimit * 10, want: maxEventsLimit},
+	}
+
+	var events = []*models.UserEvent{{ID: 2, Type: models.UserEventDeleted, UserID: defaultUserID}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			f.storage.On("Events", mock.Anything, uint64(1), tt.want).Once().Return(events, nil)
+
+			got, err := f.accounts().Events(context.Background(), 1, tt.limit)
+			require.NoError(t, err)
+			assert.Equal(t, events, got)
+		})
+	}
+}
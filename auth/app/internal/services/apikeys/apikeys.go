@@ -0,0 +1,231 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/apikeysrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+// TokenPrefix distinguishes api keys from access tokens and worker tokens
+const TokenPrefix = "dck_"
+
+const tokenBytes = 32
+
+// Scopes limit what requests authenticated with a key may do
+const (
+	// ScopeAll allows everything the owner of the key may do
+	ScopeAll = "all"
+	// ScopeRead allows only reading expressions and their results
+	ScopeRead = "read"
+	// ScopeSubmit allows only submitting new expressions
+	ScopeSubmit = "submit"
+)
+
+var (
+	ErrInvalidKey   = errors.New("apikeys: invalid api key")
+	ErrKeyNotFound  = errors.New("apikeys: api key not found")
+	ErrInvalidScope = errors.New("apikeys: invalid scope")
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=KeyStorage
+type KeyStorage interface {
+	Save(ctx context.Context, key *models.APIKey) error
+
+	Keys(ctx context.Context, userID uint64) (keys []*models.APIKey, err error)
+
+	KeyByTokenHash(ctx context.Context, tokenHash string) (key *models.APIKey, err error)
+
+	Touch(ctx context.Context, id uint64) error
+
+	Revoke(ctx context.Context, userID uint64, id uint64) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=AuditLog
+
+// AuditLog records issued and revoked keys
+type AuditLog interface {
+	Record(ctx context.Context, entry *audit.Entry)
+}
+
+type APIKeys struct {
+	log      *slog.Logger
+	storage  KeyStorage
+	auditLog AuditLog
+}
+
+func New(
+	log *slog.Logger,
+	storage KeyStorage,
+	auditLog AuditLog,
+) *APIKeys {
+	return &APIKeys{
+		log:      log,
+		storage:  storage,
+		auditLog: auditLog,
+	}
+}
+
+// ValidScope reports whether scope is one of the known scopes
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeAll, ScopeRead, ScopeSubmit:
+		return true
+	default:
+		return false
+	}
+}
+
+// Create issues a new key of the user. The key is returned only once, the storage keeps its hash.
+// Empty scope means ScopeAll
+//
+// Returns ErrInvalidScope, if scope is unknown
+func (a *APIKeys) Create(
+	ctx context.Context,
+	userID uint64,
+	name string,
+	scope string,
+) (key *models.APIKey, token string, err error) {
+	const src = "APIKeys.Create"
+
+	if scope == "" {
+		scope = ScopeAll
+	}
+
+	log := a.log.With(
+		slog.String("src", src),
+		slog.Uint64("userID", userID),
+		slog.String("scope", scope),
+	)
+
+	if !ValidScope(scope) {
+		return nil, "", e.WrapErr(ErrInvalidScope, src)
+	}
+
+	token, err = newToken()
+	if err != nil {
+		log.Error("failed to generate api key", sl.Err(err))
+		return nil, "", e.WrapErr(err, src)
+	}
+
+	key = &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Scope:     scope,
+		TokenHash: hashToken(token),
+	}
+
+	err = a.storage.Save(ctx, key)
+	if err != nil {
+		return nil, "", e.WrapErr(err, src)
+	}
+
+	a.auditLog.Record(ctx, &audit.Entry{
+		Action:     models.AuditAPIKeyCreated,
+		ActorID:    userID,
+		TargetType: models.AuditTargetAPIKey,
+		TargetID:   audit.TargetID(key.ID),
+		After:      map[string]string{"name": name, "scope": scope},
+	})
+
+	log.Info("api key created", slog.Uint64("id", key.ID))
+
+	return key, token, nil
+}
+
+// Keys returns keys of the user
+func (a *APIKeys) Keys(ctx context.Context, userID uint64) ([]*models.APIKey, error) {
+	const src = "APIKeys.Keys"
+
+	keys, err := a.storage.Keys(ctx, userID)
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	return keys, nil
+}
+
+// Revoke revokes the key of the user, so it is not accepted anymore
+//
+// Returns ErrKeyNotFound, if the user has no key with provided id
+func (a *APIKeys) Revoke(ctx context.Context, userID uint64, id uint64) error {
+	const src = "APIKeys.Revoke"
+
+	err := a.storage.Revoke(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, apikeysrepo.ErrKeyNotFound) {
+			return e.WrapErr(ErrKeyNotFound, src)
+		}
+
+		return e.WrapErr(err, src)
+	}
+
+	a.auditLog.Record(ctx, &audit.Entry{
+		Action:     models.AuditAPIKeyRevoked,
+		ActorID:    userID,
+		TargetType: models.AuditTargetAPIKey,
+		TargetID:   audit.TargetID(id),
+	})
+
+	a.log.Info("api key revoked", slog.String("src", src), slog.Uint64("userID", userID), slog.Uint64("id", id))
+
+	return nil
+}
+
+// Authenticate returns the key of the token and updates its last usage time
+//
+// Returns ErrInvalidKey, if the key was not issued or has been revoked
+func (a *APIKeys) Authenticate(ctx context.Context, token string) (*models.APIKey, error) {
+	const src = "APIKeys.Authenticate"
+
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, e.WrapErr(ErrInvalidKey, src)
+	}
+
+	key, err := a.storage.KeyByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, apikeysrepo.ErrKeyNotFound) {
+			return nil, e.WrapErr(ErrInvalidKey, src)
+		}
+
+		return nil, e.WrapErr(err, src)
+	}
+
+	if key.RevokedAt != nil {
+		return nil, e.WrapErr(ErrInvalidKey, src)
+	}
+
+	// The key is valid even if its usage is not recorded
+	err = a.storage.Touch(ctx, key.ID)
+	if err != nil {
+		a.log.Warn("failed to record api key usage", slog.String("src", src), slog.Uint64("id", key.ID), sl.Err(err))
+	}
+
+	return key, nil
+}
+
+func newToken() (string, error) {
+	var token = make([]byte, tokenBytes)
+
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,260 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/apikeysrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/apikeys/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	defaultUserID uint64 = 1
+	defaultKeyID  uint64 = 2
+)
+
+type fields struct {
+	storage  *mocks.KeyStorage
+	auditLog *mocks.AuditLog
+}
+
+func newFields(t *testing.T) *fields {
+	return &fields{
+		storage:  mocks.NewKeyStorage(t),
+		auditLog: mocks.NewAuditLog(t),
+	}
+}
+
+func (f *fields) service() *APIKeys {
+	return New(sl.NewDiscardLogger(), f.storage, f.auditLog)
+}
+
+// expectRecord expects the audit entry of the action on the default key
+func (f *fields) expectRecord(action string) {
+	f.auditLog.
+		On("Record", mock.Anything, mock.MatchedBy(func(entry *audit.Entry) bool {
+			return entry.Action == action &&
+				entry.ActorID == defaultUserID &&
+				entry.TargetType == models.AuditTargetAPIKey &&
+				entry.TargetID == audit.TargetID(defaultKeyID)
+		})).
+		Once().
+		Return()
+}
+
+func TestAPIKeys_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   string
+		prepare func(f *fields)
+
+		wantErr   bool
+		targetErr error
+		wantScope string
+	}{
+		{
+			name:  "submit_scope",
+			scope: ScopeSubmit,
+			prepare: func(f *fields) {
+				f.storage.
+					On("Save", mock.Anything, mock.MatchedBy(func(key *models.APIKey) bool {
+						return key.UserID == defaultUserID && key.Scope == ScopeSubmit && key.TokenHash != ""
+					})).
+					Once().
+					Run(func(args mock.Arguments) {
+						args.Get(1).(*models.APIKey).ID = defaultKeyID
+					}).
+					Return(nil)
+				f.expectRecord(models.AuditAPIKeyCreated)
+			},
+			wantScope: ScopeSubmit,
+		},
+		{
+			name: "default_scope",
+			prepare: func(f *fields) {
+				f.storage.
+					On("Save", mock.Anything, mock.MatchedBy(func(key *models.APIKey) bool {
+						return key.Scope == ScopeAll
+					})).
+					Once().
+					Run(func(args mock.Arguments) {
+						args.Get(1).(*models.APIKey).ID = defaultKeyID
+					}).
+					Return(nil)
+				f.expectRecord(models.AuditAPIKeyCreated)
+			},
+			wantScope: ScopeAll,
+		},
+		{
+			name:      "err_invalid_scope",
+			scope:     "admin",
+			prepare:   func(f *fields) {},
+			wantErr:   true,
+			targetErr: ErrInvalidScope,
+		},
+		{
+			name:  "err_storage",
+			scope: ScopeRead,
+			prepare: func(f *fields) {
+				f.storage.
+					On("Save", mock.Anything, mock.AnythingOfType("*models.APIKey")).
+					Once().
+					Return(errors.New("unexpected error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			key, token, err := f.service().Create(context.Background(), defaultUserID, "ci", tt.scope)
+			if tt.wantErr {
+				require.Error(t, err)
+				if tt.targetErr != nil {
+					assert.ErrorIs(t, err, tt.targetErr)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantScope, key.Scope)
+			assert.Contains(t, token, TokenPrefix)
+			assert.Equal(t, hashToken(token), key.TokenHash)
+		})
+	}
+}
+
+func TestAPIKeys_Authenticate(t *testing.T) {
+	const token = TokenPrefix + "token"
+
+	var revokedAt = time.Now()
+
+	tests := []struct {
+		name    string
+		token   string
+		prepare func(f *fields)
+
+		targetErr error
+	}{
+		{
+			name:  "issued_key",
+			token: token,
+			prepare: func(f *fields) {
+				f.storage.
+					On("KeyByTokenHash", mock.Anything, hashToken(token)).
+					Once().
+					Return(&models.APIKey{ID: defaultKeyID, UserID: defaultUserID, Scope: ScopeSubmit}, nil)
+				f.storage.On("Touch", mock.Anything, defaultKeyID).Once().Return(nil)
+			},
+		},
+		{
+			name:  "usage_not_recorded",
+			token: token,
+			prepare: func(f *fields) {
+				f.storage.
+					On("KeyByTokenHash", mock.Anything, hashToken(token)).
+					Once().
+					Return(&models.APIKey{ID: defaultKeyID, UserID: defaultUserID, Scope: ScopeSubmit}, nil)
+				f.storage.On("Touch", mock.Anything, defaultKeyID).Once().Return(errors.New("unexpected error"))
+			},
+		},
+		{
+			name:  "err_revoked_key",
+			token: token,
+			prepare: func(f *fields) {
+				f.storage.
+					On("KeyByTokenHash", mock.Anything, hashToken(token)).
+					Once().
+					Return(&models.APIKey{ID: defaultKeyID, UserID: defaultUserID, RevokedAt: &revokedAt}, nil)
+			},
+			targetErr: ErrInvalidKey,
+		},
+		{
+			name:  "err_unknown_key",
+			token: token,
+			prepare: func(f *fields) {
+				f.storage.
+					On("KeyByTokenHash", mock.Anything, hashToken(token)).
+					Once().
+					Return(nil, apikeysrepo.ErrKeyNotFound)
+			},
+			targetErr: ErrInvalidKey,
+		},
+		{
+			name:      "err_worker_token",
+			token:     "wrk_token",
+			prepare:   func(f *fields) {},
+			targetErr: ErrInvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			key, err := f.service().Authenticate(context.Background(), tt.token)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, defaultKeyID, key.ID)
+		})
+	}
+}
+
+func TestAPIKeys_Revoke(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(f *fields)
+
+		targetErr error
+	}{
+		{
+			name: "own_key",
+			prepare: func(f *fields) {
+				f.storage.On("Revoke", mock.Anything, defaultUserID, defaultKeyID).Once().Return(nil)
+				f.expectRecord(models.AuditAPIKeyRevoked)
+			},
+		},
+		{
+			name: "err_key_of_other_user",
+			prepare: func(f *fields) {
+				f.storage.
+					On("Revoke", mock.Anything, defaultUserID, defaultKeyID).
+					Once().
+					Return(apikeysrepo.ErrKeyNotFound)
+			},
+			targetErr: ErrKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			err := f.service().Revoke(context.Background(), defaultUserID, defaultKeyID)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
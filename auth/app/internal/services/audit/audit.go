@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/clientip"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	exportBatchSize = 500
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=Storage
+type Storage interface {
+	Save(ctx context.Context, record *models.AuditRecord) error
+
+	Records(ctx context.Context, filter *models.AuditFilter) (records []*models.AuditRecord, err error)
+}
+
+// Entry is an action to record. Before and After are states of the target encoded as JSON, nil means no state
+type Entry struct {
+	Action string
+	// ActorID is the user who performed the action, the user stored in the context with WithActor, if zero
+	ActorID    uint64
+	ActorName  string
+	TargetType string
+	TargetID   string
+	Before     any
+	After      any
+}
+
+// Audit is an append-only record of security relevant and administrative actions
+type Audit struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+func New(
+	log *slog.Logger,
+	storage Storage,
+) *Audit {
+	return &Audit{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Record appends the entry with the address of the client stored in the context.
+// Errors are only logged, so the audit never fails the recorded action
+func (a *Audit) Record(ctx context.Context, entry *Entry) {
+	const src = "Audit.Record"
+
+	log := a.log.With(
+		slog.String("src", src),
+		slog.String("action", entry.Action),
+	)
+
+	before, err := state(entry.Before)
+	if err != nil {
+		log.Error("failed to encode state", sl.Err(err))
+		return
+	}
+
+	after, err := state(entry.After)
+	if err != nil {
+		log.Error("failed to encode state", sl.Err(err))
+		return
+	}
+
+	record := &models.AuditRecord{
+		ActorID:    entry.ActorID,
+		ActorName:  entry.ActorName,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Before:     before,
+		After:      after,
+		IP:         clientip.From(ctx),
+	}
+
+	if actor, ok := ActorFrom(ctx); ok && (record.ActorID == 0 || record.ActorID == actor.ID) {
+		record.ActorID = actor.ID
+		if record.ActorName == "" {
+			record.ActorName = actor.Login
+		}
+	}
+
+	if record.ActorID != 0 {
+		record.ActorType = models.AuditActorUser
+	}
+
+	err = a.storage.Save(ctx, record)
+	if err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+	}
+}
+
+// Records returns a page of records matching the filter, the newest first.
+// The page goes before the record filter.AfterID, if it is set. Returns the id to request the next page with,
+// zero if there are no more records
+func (a *Audit) Records(ctx context.Context, filter *models.AuditFilter) (records []*models.AuditRecord, nextID uint64, err error) {
+	const src = "Audit.Records"
+
+	page := *filter
+	page.Descending = true
+
+	if page.Limit <= 0 || page.Limit > maxPageSize {
+		page.Limit = defaultPageSize
+	}
+
+	// one more record tells whether there is a next page
+	page.Limit++
+
+	records, err = a.storage.Records(ctx, &page)
+	if err != nil {
+		return nil, 0, e.WrapErr(err, src)
+	}
+
+	if len(records) == page.Limit {
+		records = records[:len(records)-1]
+		nextID = records[len(records)-1].ID
+	}
+
+	return records, nextID, nil
+}
+
+// Export passes all records matching the filter to write, the oldest first. Records are read in batches,
+// so the whole log is never kept in memory
+func (a *Audit) Export(ctx context.Context, filter *models.AuditFilter, write func(record *models.AuditRecord) error) error {
+	const src = "Audit.Export"
+
+	batch := *filter
+	batch.Descending = false
+	batch.Limit = exportBatchSize
+
+	for {
+		records, err := a.storage.Records(ctx, &batch)
+		if err != nil {
+			return e.WrapErr(err, src)
+		}
+
+		for _, record := range records {
+			err = write(record)
+			if err != nil {
+				return e.WrapErr(err, src)
+			}
+		}
+
+		if len(records) < batch.Limit {
+			return nil
+		}
+
+		batch.AfterID = records[len(records)-1].ID
+	}
+}
+
+// TargetID formats numeric ids of targets
+func TargetID(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
+
+func state(value any) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(value)
+}
+
+// Actor is the authenticated user performing an action
+type Actor struct {
+	ID    uint64
+	Login string
+}
+
+type actorKey struct{}
+
+// WithActor puts the authenticated user into the context
+func WithActor(ctx context.Context, id uint64, login string) context.Context {
+	return context.WithValue(ctx, actorKey{}, &Actor{ID: id, Login: login})
+}
+
+// ActorFrom returns the user stored in the context with WithActor
+func ActorFrom(ctx context.Context) (*Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(*Actor)
+	return actor, ok
+}
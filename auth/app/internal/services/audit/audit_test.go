@@ -0,0 +1,228 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/clientip"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// records returns stored records with the ids
+func records(ids ...uint64) []*models.AuditRecord {
+	var records = make([]*models.AuditRecord, 0, len(ids))
+	for _, id := range ids {
+		records = append(records, &models.AuditRecord{ID: id, Action: models.AuditUserLoggedIn})
+	}
+
+	return records
+}
+
+// sequence returns the ids from first to last
+func sequence(first, last uint64) []uint64 {
+	var ids = make([]uint64, 0, last-first+1)
+	for id := first; id <= last; id++ {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func TestAudit_Record(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		entry     *Entry
+		wantActor uint64
+		wantName  string
+		wantType  string
+	}{
+		{
+			name:      "actor_from_context",
+			ctx:       WithActor(context.Background(), 7, "admin"),
+			entry:     &Entry{Action: models.AuditWorkerCredentialCreated},
+			wantActor: 7,
+			wantName:  "admin",
+			wantType:  models.AuditActorUser,
+		},
+		{
+			name:      "name_of_explicit_actor_from_context",
+			ctx:       WithActor(context.Background(), 7, "admin"),
+			entry:     &Entry{Action: models.AuditAPIKeyCreated, ActorID: 7},
+			wantActor: 7,
+			wantName:  "admin",
+			wantType:  models.AuditActorUser,
+		},
+		{
+			name:      "explicit_actor",
+			ctx:       context.Background(),
+			entry:     &Entry{Action: models.AuditUserRegistered, ActorID: 3, ActorName: "user"},
+			wantActor: 3,
+			wantName:  "user",
+			wantType:  models.AuditActorUser,
+		},
+		{
+			name:  "anonymous",
+			ctx:   context.Background(),
+			entry: &Entry{Action: models.AuditUserLoggedOut},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := mocks.NewStorage(t)
+
+			var saved *models.AuditRecord
+			storage.
+				On("Save", mock.Anything, mock.AnythingOfType("*models.AuditRecord")).
+				Once().
+				Run(func(args mock.Arguments) {
+					saved = args.Get(1).(*models.AuditRecord)
+				}).
+				Return(nil)
+
+			tt.entry.After = map[string]string{"name": "key"}
+
+			New(sl.NewDiscardLogger(), storage).Record(clientip.With(tt.ctx, "10.0.0.1"), tt.entry)
+
+			require.NotNil(t, saved)
+			assert.Equal(t, tt.entry.Action, saved.Action)
+			assert.Equal(t, tt.wantActor, saved.ActorID)
+			assert.Equal(t, tt.wantName, saved.ActorName)
+			assert.Equal(t, tt.wantType, saved.ActorType)
+			assert.Equal(t, "10.0.0.1", saved.IP)
+			assert.Nil(t, saved.Before)
+			assert.JSONEq(t, `{"name":"key"}`, string(saved.After))
+		})
+	}
+}
+
+func TestAudit_Record_StorageError(t *testing.T) {
+	storage := mocks.NewStorage(t)
+	storage.
+		On("Save", mock.Anything, mock.AnythingOfType("*models.AuditRecord")).
+		Once().
+		Return(errors.New("unexpected error"))
+
+	// the error is only logged, the recorded action never fails because of the audit
+	New(sl.NewDiscardLogger(), storage).Record(context.Background(), &Entry{Action: models.AuditUserLoggedIn})
+}
+
+func TestAudit_Records(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *models.AuditFilter
+		// wantPage is the filter passed to the storage
+		wantPage *models.AuditFilter
+		stored   []*models.AuditRecord
+
+		wantErr    bool
+		wantIDs    []uint64
+		wantNextID uint64
+	}{
+		{
+			name:     "all",
+			filter:   &models.AuditFilter{},
+			wantPage: &models.AuditFilter{Descending: true, Limit: defaultPageSize + 1},
+			stored:   records(5, 4, 3, 2, 1),
+			wantIDs:  []uint64{5, 4, 3, 2, 1},
+		},
+		{
+			name:       "first_page",
+			filter:     &models.AuditFilter{Limit: 2},
+			wantPage:   &models.AuditFilter{Descending: true, Limit: 3},
+			stored:     records(5, 4, 3),
+			wantIDs:    []uint64{5, 4},
+			wantNextID: 4,
+		},
+		{
+			name:     "last_page",
+			filter:   &models.AuditFilter{Limit: 2, AfterID: 2},
+			wantPage: &models.AuditFilter{Descending: true, Limit: 3, AfterID: 2},
+			stored:   records(1),
+			wantIDs:  []uint64{1},
+		},
+		{
+			name:     "too_large_page",
+			filter:   &models.AuditFilter{Limit: maxPageSize + 1, ActorID: 2},
+			wantPage: &models.AuditFilter{Descending: true, Limit: defaultPageSize + 1, ActorID: 2},
+			stored:   records(5, 3, 1),
+			wantIDs:  []uint64{5, 3, 1},
+		},
+		{
+			name:     "empty",
+			filter:   &models.AuditFilter{Action: models.AuditUserDeleted},
+			wantPage: &models.AuditFilter{Descending: true, Limit: defaultPageSize + 1, Action: models.AuditUserDeleted},
+			stored:   records(),
+			wantIDs:  []uint64{},
+		},
+		{
+			name:     "err_storage",
+			filter:   &models.AuditFilter{},
+			wantPage: &models.AuditFilter{Descending: true, Limit: defaultPageSize + 1},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := mocks.NewStorage(t)
+
+			var err error
+			if tt.wantErr {
+				err = errors.New("unexpected error")
+			}
+
+			storage.On("Records", mock.Anything, tt.wantPage).Once().Return(tt.stored, err)
+
+			found, nextID, err := New(sl.NewDiscardLogger(), storage).Records(context.Background(), tt.filter)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			ids := []uint64{}
+			for _, record := range found {
+				ids = append(ids, record.ID)
+			}
+
+			assert.Equal(t, tt.wantIDs, ids)
+			assert.Equal(t, tt.wantNextID, nextID)
+		})
+	}
+}
+
+func TestAudit_Export(t *testing.T) {
+	var (
+		storage = mocks.NewStorage(t)
+		total   = uint64(exportBatchSize + 1)
+	)
+
+	storage.
+		On("Records", mock.Anything, &models.AuditFilter{ActorID: 1, Limit: exportBatchSize}).
+		Once().
+		Return(records(sequence(1, exportBatchSize)...), nil)
+	storage.
+		On("Records", mock.Anything, &models.AuditFilter{ActorID: 1, Limit: exportBatchSize, AfterID: exportBatchSize}).
+		Once().
+		Return(records(total), nil)
+
+	var ids []uint64
+
+	err := New(sl.NewDiscardLogger(), storage).Export(context.Background(), &models.AuditFilter{ActorID: 1, Descending: true}, func(record *models.AuditRecord) error {
+		ids = append(ids, record.ID)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, sequence(1, total), ids)
+}
@@ -2,12 +2,22 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/sessionsrepo"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/usersrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/ratelimit"
 	"log/slog"
+	"time"
 
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/clientip"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
 
 	"golang.org/x/crypto/bcrypt"
@@ -17,6 +27,13 @@ var (
 	ErrInvalidCredentials = errors.New("auth: invalid credentials")
 	ErrUserAlreadyExists  = errors.New("auth: user already exists")
 	ErrUserNotFound       = errors.New("auth: user not found")
+
+	ErrInvalidRefreshToken = errors.New("auth: invalid refresh token")
+)
+
+const (
+	sessionIDBytes    = 16
+	refreshTokenBytes = 32
 )
 
 type Auth struct {
@@ -24,6 +41,14 @@ type Auth struct {
 	userSaver      UserSaver
 	userProvider   UserProvider
 	tokenGenerator TokenGenerator
+
+	sessionStorage  SessionStorage
+	refreshTokenTTL time.Duration
+
+	// loginLimiter, failedLogins and auditLog are optional
+	loginLimiter LoginLimiter
+	failedLogins FailedLoginStorage
+	auditLog     AuditLog
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=UserSaver
@@ -45,10 +70,56 @@ type UserProvider interface {
 
 //go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=TokenGenerator
 type TokenGenerator interface {
-	NewToken(
-		userId uint64,
-		login string,
-	) (token string, err error)
+	NewToken(claims *jwt.Claims) (token string, err error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=SessionStorage
+type SessionStorage interface {
+	Save(
+		ctx context.Context,
+		session *models.Session,
+		tokenHash string,
+	) error
+
+	RefreshToken(
+		ctx context.Context,
+		tokenHash string,
+	) (token *models.RefreshToken, err error)
+
+	Rotate(
+		ctx context.Context,
+		token *models.RefreshToken,
+		nextTokenHash string,
+		expiresAt time.Time,
+	) error
+
+	Session(
+		ctx context.Context,
+		id string,
+	) (session *models.Session, err error)
+
+	Revoke(
+		ctx context.Context,
+		id string,
+	) error
+}
+
+// LoginLimiter locks logins after too many failed attempts
+type LoginLimiter interface {
+	// Check returns ratelimit.ErrLocked, if any of the keys is locked
+	Check(ctx context.Context, keys ...string) error
+	Fail(ctx context.Context, keys ...string) error
+	Reset(ctx context.Context, keys ...string) error
+}
+
+// FailedLoginStorage keeps the audit of failed logins
+type FailedLoginStorage interface {
+	Save(ctx context.Context, failedLogin *models.FailedLogin) error
+}
+
+// AuditLog records registrations, logins and logouts. Errors are handled by the audit log itself
+type AuditLog interface {
+	Record(ctx context.Context, entry *audit.Entry)
 }
 
 func New(
@@ -56,12 +127,22 @@ func New(
 	userSaver UserSaver,
 	userProvider UserProvider,
 	tokenGenerator TokenGenerator,
+	sessionStorage SessionStorage,
+	refreshTokenTTL time.Duration,
+	loginLimiter LoginLimiter,
+	failedLogins FailedLoginStorage,
+	auditLog AuditLog,
 ) *Auth {
 	return &Auth{
-		log:            log,
-		userSaver:      userSaver,
-		userProvider:   userProvider,
-		tokenGenerator: tokenGenerator,
+		log:             log,
+		userSaver:       userSaver,
+		userProvider:    userProvider,
+		tokenGenerator:  tokenGenerator,
+		sessionStorage:  sessionStorage,
+		refreshTokenTTL: refreshTokenTTL,
+		loginLimiter:    loginLimiter,
+		failedLogins:    failedLogins,
+		auditLog:        auditLog,
 	}
 }
 
@@ -94,17 +175,28 @@ func (a *Auth) RegisterNewUser(ctx context.Context, login string, password strin
 		return 0, e.WrapErr(err, src)
 	}
 
+	a.record(ctx, &audit.Entry{
+		Action:     models.AuditUserRegistered,
+		ActorID:    id,
+		ActorName:  login,
+		TargetType: models.AuditTargetUser,
+		TargetID:   audit.TargetID(id),
+	})
+
 	log.Debug("user registered")
 
 	return id, nil
 }
 
-// Login logs in an existing user and returns auth token
+// Login logs in an existing user, starts a new session and returns its access and refresh tokens.
+// Failed attempts are recorded in the audit and lock the login after too many of them
 //
 // # Returns ErrUserNotFound, if user with provided login not found
 //
-// Returns ErrInvalidCredentials, if stored password hash not equal to hash of provided password
-func (a *Auth) Login(ctx context.Context, login string, password string) (token string, err error) {
+// # Returns ErrInvalidCredentials, if stored password hash not equal to hash of provided password
+//
+// Returns *ratelimit.LockedError matching ratelimit.ErrLocked, if the login is locked. The password is not checked then
+func (a *Auth) Login(ctx context.Context, login string, password string) (tokens *models.Tokens, err error) {
 	const src = "Auth.Login"
 
 	log := a.log.With(
@@ -114,33 +206,279 @@ func (a *Auth) Login(ctx context.Context, login string, password string) (token
 
 	log.Debug("logging in user...")
 
+	if a.loginLimiter != nil {
+		err = a.loginLimiter.Check(ctx, loginKey(login))
+		if errors.Is(err, ratelimit.ErrLocked) {
+			log.Warn("login is locked")
+			a.saveFailedLogin(ctx, log, login, models.FailedLoginLocked)
+			return nil, e.WrapErr(err, src)
+		}
+
+		// An unavailable limiter does not prevent users from logging in
+		if err != nil {
+			log.Error("failed to check login attempts", sl.Err(err))
+		}
+	}
+
 	user, err := a.userProvider.User(ctx, login)
 	if err != nil {
 		if errors.Is(err, usersrepo.ErrUserNotFound) {
-			return "", e.WrapErr(ErrUserNotFound, src)
+			a.failLogin(ctx, log, login, models.FailedLoginUserNotFound)
+			return nil, e.WrapErr(ErrUserNotFound, src)
 		}
 
-		return "", e.WrapErr(err, src)
+		return nil, e.WrapErr(err, src)
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
 		log.Error("failed to validate user password", sl.Err(err))
-		return "", e.WrapErr(ErrInvalidCredentials, src)
+		a.failLogin(ctx, log, login, models.FailedLoginInvalidPassword)
+		return nil, e.WrapErr(ErrInvalidCredentials, src)
 	}
 
-	token, err = a.tokenGenerator.NewToken(
-		user.ID,
-		user.Login,
-	)
+	if a.loginLimiter != nil {
+		err = a.loginLimiter.Reset(ctx, loginKey(login))
+		if err != nil {
+			log.Error("failed to reset login attempts", sl.Err(err))
+		}
+	}
 
+	sessionID, err := randomString(sessionIDBytes)
 	if err != nil {
-		log.Error("failed to generate token", sl.Err(err))
+		log.Error("failed to generate session id", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
 
-		return "", e.WrapErr(err, src)
+	tokens, err = a.newTokens(&jwt.Claims{
+		UserID:    user.ID,
+		Login:     user.Login,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		log.Error("failed to generate tokens", sl.Err(err))
+		return nil, e.WrapErr(err, src)
 	}
 
+	err = a.sessionStorage.Save(ctx, &models.Session{
+		ID:        sessionID,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(a.refreshTokenTTL),
+	}, hashToken(tokens.RefreshToken))
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	a.record(ctx, &audit.Entry{
+		Action:     models.AuditUserLoggedIn,
+		ActorID:    user.ID,
+		ActorName:  user.Login,
+		TargetType: models.AuditTargetSession,
+		TargetID:   sessionID,
+	})
+
 	log.Debug("user logged in")
 
+	return tokens, nil
+}
+
+// Refresh exchanges the refresh token for a new pair of tokens of the same session.
+// Every refresh token is accepted once: reusing it means that it has leaked, so the whole session is revoked
+//
+// Returns ErrInvalidRefreshToken, if the token is unknown or used, or its session is expired or revoked
+func (a *Auth) Refresh(ctx context.Context, refreshToken string) (tokens *models.Tokens, err error) {
+	const src = "Auth.Refresh"
+
+	log := a.log.With(
+		slog.String("src", src),
+	)
+
+	token, err := a.refreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	log = log.With(slog.String("login", token.Login))
+
+	if token.Session.RevokedAt != nil || !time.Now().Before(token.Session.ExpiresAt) {
+		return nil, e.WrapErr(ErrInvalidRefreshToken, src)
+	}
+
+	if token.UsedAt != nil {
+		log.Warn("refresh token reused, revoking session")
+		return nil, e.WrapErr(a.revokeReused(ctx, token), src)
+	}
+
+	tokens, err = a.newTokens(&jwt.Claims{
+		UserID:           token.Session.UserID,
+		Login:            token.Login,
+		SessionID:        token.SessionID,
+		OrganizationID:   token.Session.OrganizationID,
+		OrganizationRole: token.Session.OrganizationRole,
+	})
+	if err != nil {
+		log.Error("failed to generate tokens", sl.Err(err))
+		return nil, e.WrapErr(err, src)
+	}
+
+	err = a.sessionStorage.Rotate(ctx, token, hashToken(tokens.RefreshToken), time.Now().Add(a.refreshTokenTTL))
+	if err != nil {
+		if errors.Is(err, sessionsrepo.ErrRefreshTokenUsed) {
+			log.Warn("refresh token reused concurrently, revoking session")
+			return nil, e.WrapErr(a.revokeReused(ctx, token), src)
+		}
+
+		return nil, e.WrapErr(err, src)
+	}
+
+	log.Debug("tokens refreshed")
+
+	return tokens, nil
+}
+
+// Logout revokes the session of the refresh token, so neither its refresh tokens nor its access tokens are accepted
+//
+// Returns ErrInvalidRefreshToken, if the token is unknown
+func (a *Auth) Logout(ctx context.Context, refreshToken string) error {
+	const src = "Auth.Logout"
+
+	token, err := a.refreshToken(ctx, refreshToken)
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	err = a.sessionStorage.Revoke(ctx, token.SessionID)
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	a.record(ctx, &audit.Entry{
+		Action:     models.AuditUserLoggedOut,
+		ActorID:    token.Session.UserID,
+		ActorName:  token.Login,
+		TargetType: models.AuditTargetSession,
+		TargetID:   token.SessionID,
+	})
+
+	a.log.Debug("user logged out", slog.String("src", src), slog.String("login", token.Login))
+
+	return nil
+}
+
+// IsRevoked reports whether access tokens of the session must be rejected.
+// Unknown sessions are reported as revoked
+func (a *Auth) IsRevoked(ctx context.Context, sessionID string) (bool, error) {
+	const src = "Auth.IsRevoked"
+
+	session, err := a.sessionStorage.Session(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, sessionsrepo.ErrSessionNotFound) {
+			return true, nil
+		}
+
+		return false, e.WrapErr(err, src)
+	}
+
+	return session.RevokedAt != nil || !time.Now().Before(session.ExpiresAt), nil
+}
+
+func (a *Auth) refreshToken(ctx context.Context, refreshToken string) (*models.RefreshToken, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	token, err := a.sessionStorage.RefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sessionsrepo.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+
+		return nil, err
+	}
+
 	return token, nil
 }
+
+// revokeReused revokes the session of a reused refresh token and returns ErrInvalidRefreshToken
+func (a *Auth) revokeReused(ctx context.Context, token *models.RefreshToken) error {
+	err := a.sessionStorage.Revoke(ctx, token.SessionID)
+	if err != nil {
+		return err
+	}
+
+	return ErrInvalidRefreshToken
+}
+
+func (a *Auth) newTokens(claims *jwt.Claims) (*models.Tokens, error) {
+	accessToken, err := a.tokenGenerator.NewToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomString(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// failLogin counts the failed attempt of the login and records it in the audit.
+// Errors are only logged, so they do not change the response to the client
+func (a *Auth) failLogin(ctx context.Context, log *slog.Logger, login string, reason string) {
+	if a.loginLimiter != nil {
+		err := a.loginLimiter.Fail(ctx, loginKey(login))
+		if err != nil {
+			log.Error("failed to count login attempt", sl.Err(err))
+		}
+	}
+
+	a.saveFailedLogin(ctx, log, login, reason)
+}
+
+func (a *Auth) saveFailedLogin(ctx context.Context, log *slog.Logger, login string, reason string) {
+	if a.failedLogins == nil {
+		return
+	}
+
+	err := a.failedLogins.Save(ctx, &models.FailedLogin{
+		Login:  login,
+		IP:     clientip.From(ctx),
+		Reason: reason,
+	})
+	if err != nil {
+		log.Error("failed to save failed login", sl.Err(err))
+	}
+}
+
+func (a *Auth) record(ctx context.Context, entry *audit.Entry) {
+	if a.auditLog == nil {
+		return
+	}
+
+	a.auditLog.Record(ctx, entry)
+}
+
+// loginKey is the key of the login in the limiter, which may be shared with other kinds of keys
+func loginKey(login string) string {
+	return "login:" + login
+}
+
+func randomString(size int) (string, error) {
+	var value = make([]byte, size)
+
+	_, err := rand.Read(value)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
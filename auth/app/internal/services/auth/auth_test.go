@@ -5,11 +5,16 @@ import (
 	"errors"
 	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/sessionsrepo"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/usersrepo"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/auth/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/ratelimit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/clientip"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/fake"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
 	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
 
 	"github.com/brianvoe/gofakeit/v6"
@@ -23,10 +28,11 @@ const defaultUserID uint64 = 1
 
 func TestAuth_Login(t *testing.T) {
 	type fields struct {
-		log          *slog.Logger
-		userSaver    UserSaver
-		userProvider UserProvider
-		tokenParser  TokenGenerator
+		log            *slog.Logger
+		userSaver      UserSaver
+		userProvider   UserProvider
+		tokenParser    TokenGenerator
+		sessionStorage SessionStorage
 	}
 	type args struct {
 		ctx      context.Context
@@ -72,12 +78,21 @@ func TestAuth_Login(t *testing.T) {
 					}, nil)
 
 				tokenParser.
-					On("NewToken", defaultUserID, a.login).
+					On("NewToken", mock.MatchedBy(func(claims *jwt.Claims) bool {
+						return claims.UserID == defaultUserID && claims.Login == a.login && claims.SessionID != ""
+					})).
 					Once().
 					Return(wantToken, nil)
 
+				sessionStorage := mocks.NewSessionStorage(t)
+				sessionStorage.
+					On("Save", mock.Anything, mock.AnythingOfType("*models.Session"), mock.AnythingOfType("string")).
+					Once().
+					Return(nil)
+
 				f.userProvider = userProvider
 				f.tokenParser = tokenParser
+				f.sessionStorage = sessionStorage
 			},
 			wantToken: gofakeit.UUID(),
 		},
@@ -174,7 +189,9 @@ func TestAuth_Login(t *testing.T) {
 					}, nil)
 
 				tokenParser.
-					On("NewToken", defaultUserID, a.login).
+					On("NewToken", mock.MatchedBy(func(claims *jwt.Claims) bool {
+						return claims.UserID == defaultUserID && claims.Login == a.login && claims.SessionID != ""
+					})).
 					Once().
 					Return("", errors.New("unexpected error"))
 
@@ -200,8 +217,9 @@ func TestAuth_Login(t *testing.T) {
 				userSaver:      tt.fields.userSaver,
 				userProvider:   tt.fields.userProvider,
 				tokenGenerator: tt.fields.tokenParser,
+				sessionStorage: tt.fields.sessionStorage,
 			}
-			token, err := a.Login(tt.args.ctx, tt.args.login, tt.args.password)
+			tokens, err := a.Login(tt.args.ctx, tt.args.login, tt.args.password)
 			if err != nil {
 				if !tt.wantErr {
 					t.Fatalf("unexpected error: %s", err.Error())
@@ -212,6 +230,12 @@ func TestAuth_Login(t *testing.T) {
 				}
 			}
 
+			var token string
+			if tokens != nil {
+				token = tokens.AccessToken
+				assert.NotEmpty(t, tokens.RefreshToken)
+			}
+
 			assert.Equal(t, tt.wantToken, token)
 		})
 	}
@@ -353,7 +377,222 @@ func TestAuth_RegisterNewUser(t *testing.T) {
 	}
 }
 
+func TestAuth_Refresh(t *testing.T) {
+	const (
+		refreshToken = "refresh-token"
+		sessionID    = "session"
+	)
+
+	type args struct {
+		ctx          context.Context
+		refreshToken string
+	}
+
+	tests := []struct {
+		name    string
+		args    *args
+		prepare func(t *testing.T, sessionStorage *mocks.SessionStorage, tokenGenerator *mocks.TokenGenerator)
+
+		targetErr error
+	}{
+		{
+			name: "test_refresh",
+			args: &args{refreshToken: refreshToken},
+			prepare: func(t *testing.T, sessionStorage *mocks.SessionStorage, tokenGenerator *mocks.TokenGenerator) {
+				token := newRefreshToken(sessionID, nil, nil)
+
+				sessionStorage.
+					On("RefreshToken", mock.Anything, hashToken(refreshToken)).
+					Once().
+					Return(token, nil)
+
+				tokenGenerator.
+					On("NewToken", &jwt.Claims{UserID: defaultUserID, Login: token.Login, SessionID: sessionID}).
+					Once().
+					Return(gofakeit.UUID(), nil)
+
+				sessionStorage.
+					On("Rotate", mock.Anything, token, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+					Once().
+					Return(nil)
+			},
+		},
+		{
+			name:      "err_empty_token",
+			args:      &args{refreshToken: ""},
+			targetErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "err_unknown_token",
+			args: &args{refreshToken: refreshToken},
+			prepare: func(t *testing.T, sessionStorage *mocks.SessionStorage, tokenGenerator *mocks.TokenGenerator) {
+				sessionStorage.
+					On("RefreshToken", mock.Anything, hashToken(refreshToken)).
+					Once().
+					Return(nil, sessionsrepo.ErrRefreshTokenNotFound)
+			},
+			targetErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "err_revoked_session",
+			args: &args{refreshToken: refreshToken},
+			prepare: func(t *testing.T, sessionStorage *mocks.SessionStorage, tokenGenerator *mocks.TokenGenerator) {
+				revokedAt := time.Now()
+
+				sessionStorage.
+					On("RefreshToken", mock.Anything, hashToken(refreshToken)).
+					Once().
+					Return(newRefreshToken(sessionID, nil, &revokedAt), nil)
+			},
+			targetErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "err_reused_token_revokes_session",
+			args: &args{refreshToken: refreshToken},
+			prepare: func(t *testing.T, sessionStorage *mocks.SessionStorage, tokenGenerator *mocks.TokenGenerator) {
+				usedAt := time.Now()
+
+				sessionStorage.
+					On("RefreshToken", mock.Anything, hashToken(refreshToken)).
+					Once().
+					Return(newRefreshToken(sessionID, &usedAt, nil), nil)
+
+				sessionStorage.
+					On("Revoke", mock.Anything, sessionID).
+					Once().
+					Return(nil)
+			},
+			targetErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "err_concurrently_reused_token_revokes_session",
+			args: &args{refreshToken: refreshToken},
+			prepare: func(t *testing.T, sessionStorage *mocks.SessionStorage, tokenGenerator *mocks.TokenGenerator) {
+				token := newRefreshToken(sessionID, nil, nil)
+
+				sessionStorage.
+					On("RefreshToken", mock.Anything, hashToken(refreshToken)).
+					Once().
+					Return(token, nil)
+
+				tokenGenerator.
+					On("NewToken", &jwt.Claims{UserID: defaultUserID, Login: token.Login, SessionID: sessionID}).
+					Once().
+					Return(gofakeit.UUID(), nil)
+
+				sessionStorage.
+					On("Rotate", mock.Anything, token, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+					Once().
+					Return(sessionsrepo.ErrRefreshTokenUsed)
+
+				sessionStorage.
+					On("Revoke", mock.Anything, sessionID).
+					Once().
+					Return(nil)
+			},
+			targetErr: ErrInvalidRefreshToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.args.ctx == nil {
+				tt.args.ctx = context.Background()
+			}
+
+			sessionStorage := mocks.NewSessionStorage(t)
+			tokenGenerator := mocks.NewTokenGenerator(t)
+
+			if tt.prepare != nil {
+				tt.prepare(t, sessionStorage, tokenGenerator)
+			}
+
+			a := New(sl.NewDiscardLogger(), nil, nil, tokenGenerator, sessionStorage, time.Hour, nil, nil, nil)
+
+			tokens, err := a.Refresh(tt.args.ctx, tt.args.refreshToken)
+			if tt.targetErr != nil {
+				require.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, tokens.AccessToken)
+			assert.NotEqual(t, tt.args.refreshToken, tokens.RefreshToken)
+		})
+	}
+}
+
+type memoryFailedLogins struct {
+	failedLogins []*models.FailedLogin
+}
+
+func (m *memoryFailedLogins) Save(_ context.Context, failedLogin *models.FailedLogin) error {
+	m.failedLogins = append(m.failedLogins, failedLogin)
+	return nil
+}
+
+func TestAuth_LoginLockout(t *testing.T) {
+	var (
+		ctx          = clientip.With(context.Background(), "10.0.0.1")
+		login        = fake.RandLogin()
+		password     = fake.RandPassword()
+		failedLogins = &memoryFailedLogins{}
+		limiter      = ratelimit.New(ratelimit.NewMemoryCounters(time.Hour), &ratelimit.Policy{
+			MaxFailures: 2,
+			Lockout:     time.Minute,
+			MaxLockout:  time.Hour,
+		})
+	)
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	require.NoError(t, err)
+
+	// The password of the locked login is not checked, so the user is requested only for the first attempts
+	userProvider := mocks.NewUserProvider(t)
+	userProvider.
+		On("User", mock.Anything, login).
+		Twice().
+		Return(&models.User{ID: defaultUserID, Login: login, PasswordHash: string(passHash)}, nil)
+
+	a := New(sl.NewDiscardLogger(), nil, userProvider, nil, nil, time.Hour, limiter, failedLogins, nil)
+
+	for i := 0; i < 2; i++ {
+		_, err = a.Login(ctx, login, "wrong"+password)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	_, err = a.Login(ctx, login, password)
+	require.ErrorIs(t, err, ratelimit.ErrLocked)
+
+	var lockedErr *ratelimit.LockedError
+	require.ErrorAs(t, err, &lockedErr)
+	require.Greater(t, lockedErr.RetryAfter, time.Duration(0))
+
+	require.NoError(t, limiter.Check(ctx, loginKey(fake.RandLogin())))
+
+	require.Len(t, failedLogins.failedLogins, 3)
+	require.Equal(t, models.FailedLoginInvalidPassword, failedLogins.failedLogins[0].Reason)
+	require.Equal(t, models.FailedLoginLocked, failedLogins.failedLogins[2].Reason)
+	require.Equal(t, "10.0.0.1", failedLogins.failedLogins[2].IP)
+	require.Equal(t, login, failedLogins.failedLogins[2].Login)
+}
+
+func newRefreshToken(sessionID string, usedAt *time.Time, revokedAt *time.Time) *models.RefreshToken {
+	return &models.RefreshToken{
+		ID:        1,
+		SessionID: sessionID,
+		UsedAt:    usedAt,
+		Session: &models.Session{
+			ID:        sessionID,
+			UserID:    defaultUserID,
+			ExpiresAt: time.Now().Add(time.Hour),
+			RevokedAt: revokedAt,
+		},
+		Login: gofakeit.Name(),
+	}
+}
+
 func TestNew(t *testing.T) {
-	authService := New(sl.NewDiscardLogger(), nil, nil, nil)
+	authService := New(sl.NewDiscardLogger(), nil, nil, nil, nil, 0, nil, nil, nil)
 	require.NotNil(t, authService, "cannot get nil from Auth.New constructor")
 }
@@ -2,30 +2,33 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	jwt "github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // TokenGenerator is an autogenerated mock type for the TokenGenerator type
 type TokenGenerator struct {
 	mock.Mock
 }
 
-// NewToken provides a mock function with given fields: userId, login
-func (_m *TokenGenerator) NewToken(userId uint64, login string) (string, error) {
-	ret := _m.Called(userId, login)
+// NewToken provides a mock function with given fields: claims
+func (_m *TokenGenerator) NewToken(claims *jwt.Claims) (string, error) {
+	ret := _m.Called(claims)
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(uint64, string) (string, error)); ok {
-		return rf(userId, login)
+	if rf, ok := ret.Get(0).(func(*jwt.Claims) (string, error)); ok {
+		return rf(claims)
 	}
-	if rf, ok := ret.Get(0).(func(uint64, string) string); ok {
-		r0 = rf(userId, login)
+	if rf, ok := ret.Get(0).(func(*jwt.Claims) string); ok {
+		r0 = rf(claims)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(uint64, string) error); ok {
-		r1 = rf(userId, login)
+	if rf, ok := ret.Get(1).(func(*jwt.Claims) error); ok {
+		r1 = rf(claims)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,366 @@
+package organizations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/organizationsrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/sessionsrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+var (
+	ErrAccessDenied        = errors.New("organizations: access denied")
+	ErrUserNotFound        = errors.New("organizations: user not found")
+	ErrMemberNotFound      = errors.New("organizations: member not found")
+	ErrMemberAlreadyExists = errors.New("organizations: member already exists")
+	ErrInvalidRole         = errors.New("organizations: invalid role")
+	ErrOwnerRemoval        = errors.New("organizations: owner cannot be removed")
+	ErrSessionRevoked      = errors.New("organizations: session revoked")
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=OrganizationStorage
+type OrganizationStorage interface {
+	Create(ctx context.Context, organization *models.Organization, ownerID uint64) error
+
+	Organizations(ctx context.Context, userID uint64) (organizations []*models.Organization, err error)
+
+	Member(ctx context.Context, organizationID uint64, userID uint64) (member *models.OrganizationMember, err error)
+
+	Members(ctx context.Context, organizationID uint64) (members []*models.OrganizationMember, err error)
+
+	AddMember(
+		ctx context.Context,
+		organizationID uint64,
+		login string,
+		role string,
+	) (member *models.OrganizationMember, err error)
+
+	RemoveMember(ctx context.Context, organizationID uint64, userID uint64) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=SessionStorage
+type SessionStorage interface {
+	SetOrganization(ctx context.Context, id string, organizationID uint64) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=TokenGenerator
+type TokenGenerator interface {
+	NewToken(claims *jwt.Claims) (token string, err error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=AuditLog
+
+// AuditLog records created organizations and changes of their members
+type AuditLog interface {
+	Record(ctx context.Context, entry *audit.Entry)
+}
+
+type Organizations struct {
+	log            *slog.Logger
+	storage        OrganizationStorage
+	sessions       SessionStorage
+	tokenGenerator TokenGenerator
+	auditLog       AuditLog
+}
+
+func New(
+	log *slog.Logger,
+	storage OrganizationStorage,
+	sessions SessionStorage,
+	tokenGenerator TokenGenerator,
+	auditLog AuditLog,
+) *Organizations {
+	return &Organizations{
+		log:            log,
+		storage:        storage,
+		sessions:       sessions,
+		tokenGenerator: tokenGenerator,
+		auditLog:       auditLog,
+	}
+}
+
+// ValidRole reports whether a member may be added with the role. The owner role belongs only to the creator
+func ValidRole(role string) bool {
+	switch role {
+	case models.OrganizationRoleAdmin, models.OrganizationRoleMember:
+		return true
+	default:
+		return false
+	}
+}
+
+// Create creates the organization owned by the user
+func (o *Organizations) Create(ctx context.Context, userID uint64, name string) (*models.Organization, error) {
+	const src = "Organizations.Create"
+
+	organization := &models.Organization{Name: name}
+
+	err := o.storage.Create(ctx, organization, userID)
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	o.auditLog.Record(ctx, &audit.Entry{
+		Action:     models.AuditOrganizationCreated,
+		ActorID:    userID,
+		TargetType: models.AuditTargetOrganization,
+		TargetID:   audit.TargetID(organization.ID),
+		After:      map[string]string{"name": name},
+	})
+
+	o.log.Info(
+		"organization created",
+		slog.String("src", src),
+		slog.Uint64("id", organization.ID),
+		slog.Uint64("ownerID", userID),
+	)
+
+	return organization, nil
+}
+
+// Organizations returns organizations the user is a member of
+func (o *Organizations) Organizations(ctx context.Context, userID uint64) ([]*models.Organization, error) {
+	const src = "Organizations.Organizations"
+
+	organizations, err := o.storage.Organizations(ctx, userID)
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	return organizations, nil
+}
+
+// Members returns members of the organization. Only members may list them
+//
+// Returns ErrAccessDenied, if the user is not a member of the organization
+func (o *Organizations) Members(ctx context.Context, userID uint64, organizationID uint64) ([]*models.OrganizationMember, error) {
+	const src = "Organizations.Members"
+
+	_, err := o.member(ctx, organizationID, userID)
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	members, err := o.storage.Members(ctx, organizationID)
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	return members, nil
+}
+
+// AddMember adds the user with the login to the organization. Only owners and admins may add members.
+// Empty role means models.OrganizationRoleMember
+//
+// Returns ErrInvalidRole, if the role cannot be given to a member, ErrAccessDenied, if the user may not
+// manage members, and ErrUserNotFound, if there is no user with the login
+func (o *Organizations) AddMember(
+	ctx context.Context,
+	userID uint64,
+	organizationID uint64,
+	login string,
+	role string,
+) (*models.OrganizationMember, error) {
+	const src = "Organizations.AddMember"
+
+	if role == "" {
+		role = models.OrganizationRoleMember
+	}
+
+	if !ValidRole(role) {
+		return nil, e.WrapErr(ErrInvalidRole, src)
+	}
+
+	manager, err := o.member(ctx, organizationID, userID)
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	if !managesMembers(manager.Role) {
+		return nil, e.WrapErr(ErrAccessDenied, src)
+	}
+
+	member, err := o.storage.AddMember(ctx, organizationID, login, role)
+	if err != nil {
+		switch {
+		case errors.Is(err, organizationsrepo.ErrUserNotFound):
+			return nil, e.WrapErr(ErrUserNotFound, src)
+		case errors.Is(err, organizationsrepo.ErrMemberAlreadyExists):
+			return nil, e.WrapErr(ErrMemberAlreadyExists, src)
+		default:
+			return nil, e.WrapErr(err, src)
+		}
+	}
+
+	o.auditLog.Record(ctx, &audit.Entry{
+		Action:     models.AuditOrganizationMemberAdded,
+		ActorID:    userID,
+		TargetType: models.AuditTargetOrganization,
+		TargetID:   audit.TargetID(organizationID),
+		After:      memberState(member),
+	})
+
+	o.log.Info(
+		"member added",
+		slog.String("src", src),
+		slog.Uint64("organizationID", organizationID),
+		slog.Uint64("userID", member.UserID),
+		slog.String("role", role),
+	)
+
+	return member, nil
+}
+
+// RemoveMember removes the member memberID from the organization. Members may leave the organization,
+// owners remove anyone and admins remove members. The owner cannot be removed
+//
+// Returns ErrOwnerRemoval, if memberID is the owner, ErrAccessDenied, if the user may not remove the member,
+// and ErrMemberNotFound, if memberID is not a member of the organization
+func (o *Organizations) RemoveMember(ctx context.Context, userID uint64, organizationID uint64, memberID uint64) error {
+	const src = "Organizations.RemoveMember"
+
+	manager, err := o.member(ctx, organizationID, userID)
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	member := manager
+	if memberID != userID {
+		member, err = o.storage.Member(ctx, organizationID, memberID)
+		if err != nil {
+			if errors.Is(err, organizationsrepo.ErrMemberNotFound) {
+				return e.WrapErr(ErrMemberNotFound, src)
+			}
+
+			return e.WrapErr(err, src)
+		}
+	}
+
+	if member.Role == models.OrganizationRoleOwner {
+		return e.WrapErr(ErrOwnerRemoval, src)
+	}
+
+	if !mayRemove(manager, member) {
+		return e.WrapErr(ErrAccessDenied, src)
+	}
+
+	err = o.storage.RemoveMember(ctx, organizationID, memberID)
+	if err != nil {
+		if errors.Is(err, organizationsrepo.ErrMemberNotFound) {
+			return e.WrapErr(ErrMemberNotFound, src)
+		}
+
+		return e.WrapErr(err, src)
+	}
+
+	o.auditLog.Record(ctx, &audit.Entry{
+		Action:     models.AuditOrganizationMemberRemoved,
+		ActorID:    userID,
+		TargetType: models.AuditTargetOrganization,
+		TargetID:   audit.TargetID(organizationID),
+		Before:     memberState(member),
+	})
+
+	o.log.Info(
+		"member removed",
+		slog.String("src", src),
+		slog.Uint64("organizationID", organizationID),
+		slog.Uint64("userID", memberID),
+	)
+
+	return nil
+}
+
+// Switch makes the organization active in the session of the claims and returns an access token with it.
+// Zero organizationID switches to the personal workspace. Refreshed tokens of the session keep the organization
+//
+// Returns ErrAccessDenied, if the user is not a member of the organization,
+// and ErrSessionRevoked, if the session of the claims has been revoked
+func (o *Organizations) Switch(ctx context.Context, claims *jwt.Claims, organizationID uint64) (string, error) {
+	const src = "Organizations.Switch"
+
+	log := o.log.With(
+		slog.String("src", src),
+		slog.Uint64("userID", claims.UserID),
+		slog.Uint64("organizationID", organizationID),
+	)
+
+	next := &jwt.Claims{
+		UserID:    claims.UserID,
+		Login:     claims.Login,
+		SessionID: claims.SessionID,
+	}
+
+	if organizationID != 0 {
+		member, err := o.member(ctx, organizationID, claims.UserID)
+		if err != nil {
+			return "", e.WrapErr(err, src)
+		}
+
+		next.OrganizationID = organizationID
+		next.OrganizationRole = member.Role
+	}
+
+	// tokens issued before sessions were introduced have no session to keep the organization in
+	if claims.SessionID != "" {
+		err := o.sessions.SetOrganization(ctx, claims.SessionID, organizationID)
+		if err != nil {
+			if errors.Is(err, sessionsrepo.ErrSessionNotFound) {
+				return "", e.WrapErr(ErrSessionRevoked, src)
+			}
+
+			return "", e.WrapErr(err, src)
+		}
+	}
+
+	token, err := o.tokenGenerator.NewToken(next)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+		return "", e.WrapErr(err, src)
+	}
+
+	log.Debug("organization switched")
+
+	return token, nil
+}
+
+func (o *Organizations) member(ctx context.Context, organizationID uint64, userID uint64) (*models.OrganizationMember, error) {
+	member, err := o.storage.Member(ctx, organizationID, userID)
+	if err != nil {
+		if errors.Is(err, organizationsrepo.ErrMemberNotFound) {
+			return nil, ErrAccessDenied
+		}
+
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// memberState is the audited state of a member
+func memberState(member *models.OrganizationMember) map[string]any {
+	return map[string]any{"userId": member.UserID, "login": member.Login, "role": member.Role}
+}
+
+func managesMembers(role string) bool {
+	return role == models.OrganizationRoleOwner || role == models.OrganizationRoleAdmin
+}
+
+func mayRemove(manager *models.OrganizationMember, member *models.OrganizationMember) bool {
+	switch {
+	case manager.UserID == member.UserID:
+		return true
+	case manager.Role == models.OrganizationRoleOwner:
+		return true
+	case manager.Role == models.OrganizationRoleAdmin:
+		return member.Role == models.OrganizationRoleMember
+	default:
+		return false
+	}
+}
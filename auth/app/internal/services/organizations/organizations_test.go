@@ -0,0 +1,319 @@
+package organizations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/organizationsrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/sessionsrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/organizations/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	organizationID   uint64 = 1
+	defaultSessionID        = "session"
+)
+
+// Users of the organization
+const (
+	ownerID uint64 = iota + 1
+	adminID
+	memberID
+	strangerID
+)
+
+var roles = map[uint64]string{
+	ownerID:  models.OrganizationRoleOwner,
+	adminID:  models.OrganizationRoleAdmin,
+	memberID: models.OrganizationRoleMember,
+}
+
+type fields struct {
+	storage        *mocks.OrganizationStorage
+	sessions       *mocks.SessionStorage
+	tokenGenerator *mocks.TokenGenerator
+	auditLog       *mocks.AuditLog
+}
+
+func newFields(t *testing.T) *fields {
+	return &fields{
+		storage:        mocks.NewOrganizationStorage(t),
+		sessions:       mocks.NewSessionStorage(t),
+		tokenGenerator: mocks.NewTokenGenerator(t),
+		auditLog:       mocks.NewAuditLog(t),
+	}
+}
+
+func (f *fields) organizations() *Organizations {
+	return New(sl.NewDiscardLogger(), f.storage, f.sessions, f.tokenGenerator, f.auditLog)
+}
+
+// findMember expects the user to be looked up among the members. Strangers are not found
+func (f *fields) findMember(userID uint64) {
+	role, ok := roles[userID]
+	if !ok {
+		f.storage.
+			On("Member", mock.Anything, organizationID, userID).
+			Once().
+			Return(nil, organizationsrepo.ErrMemberNotFound)
+		return
+	}
+
+	f.storage.
+		On("Member", mock.Anything, organizationID, userID).
+		Once().
+		Return(&models.OrganizationMember{OrganizationID: organizationID, UserID: userID, Role: role}, nil)
+}
+
+// expectRecord expects the audit entry of the action on the organization by the user
+func (f *fields) expectRecord(action string, userID uint64) {
+	f.auditLog.
+		On("Record", mock.Anything, mock.MatchedBy(func(entry *audit.Entry) bool {
+			return entry.Action == action &&
+				entry.ActorID == userID &&
+				entry.TargetType == models.AuditTargetOrganization &&
+				entry.TargetID == audit.TargetID(organizationID)
+		})).
+		Once().
+		Return()
+}
+
+func TestOrganizations_AddMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  uint64
+		login   string
+		role    string
+		prepare func(f *fields)
+
+		targetErr error
+	}{
+		{
+			name:   "by_owner",
+			userID: ownerID,
+			login:  "stranger",
+			role:   models.OrganizationRoleAdmin,
+			prepare: func(f *fields) {
+				f.findMember(ownerID)
+				f.storage.
+					On("AddMember", mock.Anything, organizationID, "stranger", models.OrganizationRoleAdmin).
+					Once().
+					Return(&models.OrganizationMember{OrganizationID: organizationID, UserID: strangerID, Login: "stranger", Role: models.OrganizationRoleAdmin}, nil)
+				f.expectRecord(models.AuditOrganizationMemberAdded, ownerID)
+			},
+		},
+		{
+			name:   "by_admin",
+			userID: adminID,
+			login:  "stranger",
+			prepare: func(f *fields) {
+				f.findMember(adminID)
+				f.storage.
+					On("AddMember", mock.Anything, organizationID, "stranger", models.OrganizationRoleMember).
+					Once().
+					Return(&models.OrganizationMember{OrganizationID: organizationID, UserID: strangerID, Login: "stranger", Role: models.OrganizationRoleMember}, nil)
+				f.expectRecord(models.AuditOrganizationMemberAdded, adminID)
+			},
+		},
+		{
+			name:   "err_by_member",
+			userID: memberID,
+			login:  "stranger",
+			prepare: func(f *fields) {
+				f.findMember(memberID)
+			},
+			targetErr: ErrAccessDenied,
+		},
+		{
+			name:   "err_by_stranger",
+			userID: strangerID,
+			login:  "stranger",
+			prepare: func(f *fields) {
+				f.findMember(strangerID)
+			},
+			targetErr: ErrAccessDenied,
+		},
+		{
+			name:      "err_owner_role",
+			userID:    ownerID,
+			login:     "stranger",
+			role:      models.OrganizationRoleOwner,
+			prepare:   func(f *fields) {},
+			targetErr: ErrInvalidRole,
+		},
+		{
+			name:   "err_unknown_user",
+			userID: ownerID,
+			login:  "unknown",
+			prepare: func(f *fields) {
+				f.findMember(ownerID)
+				f.storage.
+					On("AddMember", mock.Anything, organizationID, "unknown", models.OrganizationRoleMember).
+					Once().
+					Return(nil, organizationsrepo.ErrUserNotFound)
+			},
+			targetErr: ErrUserNotFound,
+		},
+		{
+			name:   "err_already_member",
+			userID: ownerID,
+			login:  "member",
+			prepare: func(f *fields) {
+				f.findMember(ownerID)
+				f.storage.
+					On("AddMember", mock.Anything, organizationID, "member", models.OrganizationRoleMember).
+					Once().
+					Return(nil, organizationsrepo.ErrMemberAlreadyExists)
+			},
+			targetErr: ErrMemberAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			member, err := f.organizations().AddMember(context.Background(), tt.userID, organizationID, tt.login, tt.role)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, strangerID, member.UserID)
+		})
+	}
+}
+
+func TestOrganizations_RemoveMember(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   uint64
+		memberID uint64
+
+		targetErr error
+	}{
+		{name: "member_by_owner", userID: ownerID, memberID: memberID},
+		{name: "admin_by_owner", userID: ownerID, memberID: adminID},
+		{name: "member_by_admin", userID: adminID, memberID: memberID},
+		{name: "member_leaves", userID: memberID, memberID: memberID},
+		{name: "err_admin_by_member", userID: memberID, memberID: adminID, targetErr: ErrAccessDenied},
+		{name: "err_owner_by_admin", userID: adminID, memberID: ownerID, targetErr: ErrOwnerRemoval},
+		{name: "err_owner_leaves", userID: ownerID, memberID: ownerID, targetErr: ErrOwnerRemoval},
+		{name: "err_by_stranger", userID: strangerID, memberID: memberID, targetErr: ErrAccessDenied},
+		{name: "err_not_member", userID: ownerID, memberID: strangerID, targetErr: ErrMemberNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+
+			f.findMember(tt.userID)
+			if _, ok := roles[tt.userID]; ok && tt.memberID != tt.userID {
+				f.findMember(tt.memberID)
+			}
+
+			if tt.targetErr == nil {
+				f.storage.On("RemoveMember", mock.Anything, organizationID, tt.memberID).Once().Return(nil)
+				f.expectRecord(models.AuditOrganizationMemberRemoved, tt.userID)
+			}
+
+			err := f.organizations().RemoveMember(context.Background(), tt.userID, organizationID, tt.memberID)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestOrganizations_Switch(t *testing.T) {
+	tests := []struct {
+		name           string
+		claims         *jwt.Claims
+		organizationID uint64
+		prepare        func(f *fields)
+
+		targetErr error
+	}{
+		{
+			name:           "to_organization",
+			claims:         &jwt.Claims{UserID: adminID, Login: "admin", SessionID: defaultSessionID},
+			organizationID: organizationID,
+			prepare: func(f *fields) {
+				f.findMember(adminID)
+				f.sessions.On("SetOrganization", mock.Anything, defaultSessionID, organizationID).Once().Return(nil)
+				f.tokenGenerator.
+					On("NewToken", &jwt.Claims{
+						UserID:           adminID,
+						Login:            "admin",
+						SessionID:        defaultSessionID,
+						OrganizationID:   organizationID,
+						OrganizationRole: models.OrganizationRoleAdmin,
+					}).
+					Once().
+					Return("token", nil)
+			},
+		},
+		{
+			name:   "to_personal_workspace",
+			claims: &jwt.Claims{UserID: adminID, Login: "admin", SessionID: defaultSessionID},
+			prepare: func(f *fields) {
+				f.sessions.On("SetOrganization", mock.Anything, defaultSessionID, uint64(0)).Once().Return(nil)
+				f.tokenGenerator.
+					On("NewToken", &jwt.Claims{UserID: adminID, Login: "admin", SessionID: defaultSessionID}).
+					Once().
+					Return("token", nil)
+			},
+		},
+		{
+			name:           "err_stranger",
+			claims:         &jwt.Claims{UserID: strangerID, SessionID: defaultSessionID},
+			organizationID: organizationID,
+			prepare: func(f *fields) {
+				f.findMember(strangerID)
+			},
+			targetErr: ErrAccessDenied,
+		},
+		{
+			name:           "err_revoked_session",
+			claims:         &jwt.Claims{UserID: adminID, SessionID: "revoked"},
+			organizationID: organizationID,
+			prepare: func(f *fields) {
+				f.findMember(adminID)
+				f.sessions.
+					On("SetOrganization", mock.Anything, "revoked", organizationID).
+					Once().
+					Return(sessionsrepo.ErrSessionNotFound)
+			},
+			targetErr: ErrSessionRevoked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			token, err := f.organizations().Switch(context.Background(), tt.claims, tt.organizationID)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "token", token)
+		})
+	}
+}
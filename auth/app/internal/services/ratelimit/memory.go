@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCounters keep attempts in memory of a single replica. Attempts are forgotten after ttl without failures,
+// so ttl should not be less than the longest lockout
+type MemoryCounters struct {
+	ttl time.Duration
+
+	mu       *sync.Mutex
+	attempts map[string]*Attempts
+	sweptAt  time.Time
+}
+
+func NewMemoryCounters(ttl time.Duration) *MemoryCounters {
+	return &MemoryCounters{
+		ttl:      ttl,
+		mu:       &sync.Mutex{},
+		attempts: map[string]*Attempts{},
+	}
+}
+
+func (m *MemoryCounters) Attempts(_ context.Context, key string) (*Attempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *attempts
+
+	return &copied, nil
+}
+
+func (m *MemoryCounters) Fail(_ context.Context, key string, at time.Time) (*Attempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if at.Sub(m.sweptAt) >= m.ttl {
+		m.sweep(at)
+	}
+
+	attempts, ok := m.attempts[key]
+	if !ok || m.expired(attempts, at) {
+		attempts = &Attempts{}
+		m.attempts[key] = attempts
+	}
+
+	attempts.Failures++
+	attempts.LastFailedAt = at
+
+	copied := *attempts
+
+	return &copied, nil
+}
+
+func (m *MemoryCounters) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+
+	return nil
+}
+
+// sweep deletes expired attempts, so the keys of a single failure do not stay in memory forever
+func (m *MemoryCounters) sweep(now time.Time) {
+	for key, attempts := range m.attempts {
+		if m.expired(attempts, now) {
+			delete(m.attempts, key)
+		}
+	}
+
+	m.sweptAt = now
+}
+
+func (m *MemoryCounters) expired(attempts *Attempts, now time.Time) bool {
+	return !now.Before(attempts.LastFailedAt.Add(m.ttl))
+}
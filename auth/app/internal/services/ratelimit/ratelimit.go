@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrLocked = errors.New("ratelimit: too many failed attempts")
+
+// LockedError is returned by Limiter.Check for locked keys. It matches ErrLocked
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (l *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrLocked.Error(), l.RetryAfter)
+}
+
+func (l *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// Attempts are the failed attempts of a key
+type Attempts struct {
+	Failures     int
+	LastFailedAt time.Time
+}
+
+// Counters keep failed attempts. Keys without failures for a while may be forgotten
+type Counters interface {
+	// Attempts returns nil for keys without failures
+	Attempts(ctx context.Context, key string) (*Attempts, error)
+
+	// Fail counts a failed attempt made at the given time and returns the updated attempts
+	Fail(ctx context.Context, key string, at time.Time) (*Attempts, error)
+
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy describes the lockout of keys with failed attempts
+type Policy struct {
+	// MaxFailures is the number of failures after which the key is locked
+	MaxFailures int
+	// Lockout is the duration of the first lockout. Every next failure doubles it
+	Lockout time.Duration
+	// MaxLockout limits the duration of a lockout
+	MaxLockout time.Duration
+}
+
+// Limiter locks keys after too many failed attempts. Attempts of a locked key are rejected without being counted,
+// so a failure after the lockout doubles it
+type Limiter struct {
+	counters Counters
+	policy   *Policy
+	now      func() time.Time
+}
+
+func New(counters Counters, policy *Policy) *Limiter {
+	return &Limiter{
+		counters: counters,
+		policy:   policy,
+		now:      time.Now,
+	}
+}
+
+// Check returns *LockedError with the longest lockout, if any of the keys is locked
+func (l *Limiter) Check(ctx context.Context, keys ...string) error {
+	var (
+		now        = l.now()
+		retryAfter time.Duration
+	)
+
+	for _, key := range keys {
+		attempts, err := l.counters.Attempts(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if attempts == nil {
+			continue
+		}
+
+		lockedUntil := attempts.LastFailedAt.Add(l.policy.lockout(attempts.Failures))
+		if now.Before(lockedUntil) && lockedUntil.Sub(now) > retryAfter {
+			retryAfter = lockedUntil.Sub(now)
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// Fail counts a failed attempt of every key
+func (l *Limiter) Fail(ctx context.Context, keys ...string) error {
+	now := l.now()
+
+	for _, key := range keys {
+		_, err := l.counters.Fail(ctx, key, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reset forgets failed attempts of the keys
+func (l *Limiter) Reset(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		err := l.counters.Reset(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockout returns the time for which the key is locked after the last of failures
+func (p *Policy) lockout(failures int) time.Duration {
+	if p.MaxFailures <= 0 || failures < p.MaxFailures {
+		return 0
+	}
+
+	lockout := p.Lockout
+	for i := p.MaxFailures; i < failures && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+
+	if p.MaxLockout > 0 && lockout > p.MaxLockout {
+		return p.MaxLockout
+	}
+
+	return lockout
+}
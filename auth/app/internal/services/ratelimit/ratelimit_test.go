@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		after    time.Duration
+		// wantRetryAfter is zero, if the key is not locked
+		wantRetryAfter time.Duration
+	}{
+		{name: "below_limit", failures: 2},
+		{name: "first_lockout", failures: 3, after: 10 * time.Second, wantRetryAfter: 50 * time.Second},
+		{name: "first_lockout_passed", failures: 3, after: time.Minute},
+		{name: "doubled_lockout", failures: 4, after: time.Minute, wantRetryAfter: time.Minute},
+		{name: "max_lockout", failures: 20, after: time.Minute, wantRetryAfter: 9 * time.Minute},
+		{name: "max_lockout_passed", failures: 20, after: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				ctx     = context.Background()
+				now     = time.Now()
+				limiter = New(NewMemoryCounters(time.Hour), &Policy{
+					MaxFailures: 3,
+					Lockout:     time.Minute,
+					MaxLockout:  10 * time.Minute,
+				})
+			)
+
+			limiter.now = func() time.Time { return now }
+
+			for i := 0; i < tt.failures; i++ {
+				require.NoError(t, limiter.Fail(ctx, "login:user", "ip:127.0.0.1"))
+			}
+
+			now = now.Add(tt.after)
+
+			err := limiter.Check(ctx, "login:user")
+			if tt.wantRetryAfter == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrLocked)
+
+			var lockedErr *LockedError
+			require.ErrorAs(t, err, &lockedErr)
+			require.Equal(t, tt.wantRetryAfter, lockedErr.RetryAfter)
+
+			// Other keys are not affected by the lockout of the login
+			require.NoError(t, limiter.Check(ctx, "login:other"))
+
+			require.NoError(t, limiter.Reset(ctx, "login:user", "ip:127.0.0.1"))
+			require.NoError(t, limiter.Check(ctx, "login:user", "ip:127.0.0.1"))
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package signingkeys
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwks"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+// expirationLeeway keeps retired keys a bit longer than their tokens to tolerate clock skew between services
+const expirationLeeway = time.Minute
+
+const pemBlockType = "PRIVATE KEY"
+
+var ErrInvalidPrivateKey = errors.New("signingkeys: invalid private key")
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=KeyStorage
+type KeyStorage interface {
+	Keys(ctx context.Context) (keys []*models.SigningKey, err error)
+
+	Rotate(
+		ctx context.Context,
+		key *models.SigningKey,
+		retiredExpiresAt time.Time,
+	) error
+
+	DeleteExpired(ctx context.Context) error
+}
+
+// Policy describes how access tokens are signed
+type Policy struct {
+	// Algorithm is jwt.AlgorithmEdDSA or jwt.AlgorithmRS256. Changing it rotates the key
+	Algorithm string
+	// RotationPeriod is the age of the signing key after which a new one is generated
+	RotationPeriod time.Duration
+	// TokenTTL is the lifetime of access tokens. Retired keys are published for this time
+	TokenTTL time.Duration
+}
+
+// SigningKeys keeps the keys of the storage in memory. The keys are shared by all replicas of the service,
+// so every replica signs with the newest key and verifies tokens of the others
+type SigningKeys struct {
+	log     *slog.Logger
+	storage KeyStorage
+	policy  *Policy
+	now     func() time.Time
+
+	mu      *sync.RWMutex
+	signing *jwt.Key
+	keys    map[string]*jwt.Key
+}
+
+func New(
+	log *slog.Logger,
+	storage KeyStorage,
+	policy *Policy,
+) *SigningKeys {
+	return &SigningKeys{
+		log:     log,
+		storage: storage,
+		policy:  policy,
+		now:     time.Now,
+		mu:      &sync.RWMutex{},
+		keys:    map[string]*jwt.Key{},
+	}
+}
+
+// Sync deletes expired keys, generates a new signing key if the current one is older than the rotation period
+// and loads the keys into memory
+func (s *SigningKeys) Sync(ctx context.Context) error {
+	const src = "SigningKeys.Sync"
+
+	log := s.log.With(
+		slog.String("src", src),
+	)
+
+	err := s.storage.DeleteExpired(ctx)
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	keys, err := s.storage.Keys(ctx)
+	if err != nil {
+		return e.WrapErr(err, src)
+	}
+
+	now := s.now()
+
+	if current := currentKey(keys); current == nil ||
+		current.Algorithm != s.policy.Algorithm ||
+		!now.Before(current.CreatedAt.Add(s.policy.RotationPeriod)) {
+		err = s.rotate(ctx, now)
+		if err != nil {
+			log.Error("failed to rotate signing key", sl.Err(err))
+			return e.WrapErr(err, src)
+		}
+
+		keys, err = s.storage.Keys(ctx)
+		if err != nil {
+			return e.WrapErr(err, src)
+		}
+	}
+
+	var (
+		signing *jwt.Key
+		loaded  = make(map[string]*jwt.Key, len(keys))
+	)
+
+	for _, stored := range keys {
+		if stored.ExpiresAt != nil && !now.Before(*stored.ExpiresAt) {
+			continue
+		}
+
+		key, err := decodeKey(stored)
+		if err != nil {
+			log.Error("failed to decode signing key", slog.String("id", stored.ID), sl.Err(err))
+			return e.WrapErr(err, src)
+		}
+
+		loaded[key.ID] = key
+
+		if stored.RetiredAt == nil {
+			signing = key
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.signing = signing
+	s.keys = loaded
+
+	return nil
+}
+
+// Watch calls Sync every period until ctx is done
+func (s *SigningKeys) Watch(ctx context.Context, period time.Duration, onError func(err error)) {
+	if period <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := s.Sync(ctx)
+				if err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// SigningKey returns the key new tokens are signed with
+func (s *SigningKeys) SigningKey() (*jwt.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.signing == nil {
+		return nil, jwt.ErrKeyNotFound
+	}
+
+	return s.signing, nil
+}
+
+// Key returns the signing key or a retired key which tokens have not expired yet
+func (s *SigningKeys) Key(id string) (*jwt.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, jwt.ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// JWKS returns the public parts of all keys accepted by Key
+func (s *SigningKeys) JWKS() (*jwks.Set, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var set = &jwks.Set{Keys: []*jwks.Key{}}
+
+	for _, key := range s.keys {
+		publicKey, err := jwks.FromPublicKey(key.ID, key.Algorithm, key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		set.Keys = append(set.Keys, publicKey)
+	}
+
+	return set, nil
+}
+
+func (s *SigningKeys) rotate(ctx context.Context, now time.Time) error {
+	key, err := jwt.GenerateKey(s.policy.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.Rotate(ctx, &models.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: pemBlockType, Bytes: privateKey})),
+	}, now.Add(s.policy.TokenTTL+expirationLeeway))
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("signing key rotated", slog.String("id", key.ID), slog.String("algorithm", key.Algorithm))
+
+	return nil
+}
+
+// currentKey returns the newest key which has not been retired
+func currentKey(keys []*models.SigningKey) *models.SigningKey {
+	var current *models.SigningKey
+
+	for _, key := range keys {
+		if key.RetiredAt == nil {
+			current = key
+		}
+	}
+
+	return current
+}
+
+func decodeKey(stored *models.SigningKey) (*jwt.Key, error) {
+	block, _ := pem.Decode([]byte(stored.PrivateKey))
+	if block == nil || block.Type != pemBlockType {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	return &jwt.Key{
+		ID:         stored.ID,
+		Algorithm:  stored.Algorithm,
+		PrivateKey: privateKey,
+		PublicKey:  privateKey.Public(),
+	}, nil
+}
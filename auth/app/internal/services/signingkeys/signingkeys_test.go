@@ -0,0 +1,251 @@
+package signingkeys
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/signingkeys/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/jwt"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rotationPeriod = 24 * time.Hour
+	tokenTTL       = 15 * time.Minute
+)
+
+type fields struct {
+	storage *mocks.KeyStorage
+	// keys are the stored keys returned by the storage
+	keys []*models.SigningKey
+}
+
+// storedKey generates a key of the algorithm created at createdAt
+func storedKey(t *testing.T, algorithm string, createdAt time.Time) *models.SigningKey {
+	key, err := jwt.GenerateKey(algorithm)
+	require.NoError(t, err)
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	require.NoError(t, err)
+
+	return &models.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: pemBlockType, Bytes: privateKey})),
+		CreatedAt:  createdAt,
+	}
+}
+
+// load expects the stored keys to be loaded
+func (f *fields) load() {
+	f.storage.On("DeleteExpired", mock.Anything).Once().Return(nil)
+	f.storage.
+		On("Keys", mock.Anything).
+		Once().
+		Return(func(context.Context) ([]*models.SigningKey, error) {
+			return f.keys, nil
+		})
+}
+
+// expectRotation expects a new key of the algorithm to retire the current one and the keys to be loaded again
+func (f *fields) expectRotation(algorithm string, now time.Time) {
+	f.storage.
+		On("Rotate", mock.Anything, mock.MatchedBy(func(key *models.SigningKey) bool {
+			return key.Algorithm == algorithm
+		}), now.Add(tokenTTL+expirationLeeway)).
+		Once().
+		Run(func(args mock.Arguments) {
+			for _, stored := range f.keys {
+				if stored.RetiredAt == nil {
+					retiredExpiresAt := args.Get(2).(time.Time)
+					stored.RetiredAt = &now
+					stored.ExpiresAt = &retiredExpiresAt
+				}
+			}
+
+			key := args.Get(1).(*models.SigningKey)
+			key.CreatedAt = now
+			f.keys = append(f.keys, key)
+		}).
+		Return(nil)
+
+	f.storage.
+		On("Keys", mock.Anything).
+		Once().
+		Return(func(context.Context) ([]*models.SigningKey, error) {
+			return f.keys, nil
+		})
+}
+
+func TestSigningKeys_Sync(t *testing.T) {
+	var now = time.Now()
+
+	tests := []struct {
+		name      string
+		algorithm string
+		prepare   func(t *testing.T, f *fields)
+
+		wantErr bool
+		// wantRotated reports whether a new key signs tokens after the sync
+		wantRotated bool
+		// wantKeys is the number of keys accepted after the sync
+		wantKeys int
+	}{
+		{
+			name:      "first_key",
+			algorithm: jwt.AlgorithmEdDSA,
+			prepare: func(t *testing.T, f *fields) {
+				f.load()
+				f.expectRotation(jwt.AlgorithmEdDSA, now)
+			},
+			wantRotated: true,
+			wantKeys:    1,
+		},
+		{
+			name:      "not_rotated",
+			algorithm: jwt.AlgorithmEdDSA,
+			prepare: func(t *testing.T, f *fields) {
+				f.keys = []*models.SigningKey{storedKey(t, jwt.AlgorithmEdDSA, now.Add(-time.Hour))}
+				f.load()
+			},
+			wantKeys: 1,
+		},
+		{
+			name:      "rotated",
+			algorithm: jwt.AlgorithmEdDSA,
+			prepare: func(t *testing.T, f *fields) {
+				f.keys = []*models.SigningKey{storedKey(t, jwt.AlgorithmEdDSA, now.Add(-25*time.Hour))}
+				f.load()
+				f.expectRotation(jwt.AlgorithmEdDSA, now)
+			},
+			wantRotated: true,
+			wantKeys:    2,
+		},
+		{
+			name:      "rotated_rsa",
+			algorithm: jwt.AlgorithmRS256,
+			prepare: func(t *testing.T, f *fields) {
+				f.keys = []*models.SigningKey{storedKey(t, jwt.AlgorithmRS256, now.Add(-25*time.Hour))}
+				f.load()
+				f.expectRotation(jwt.AlgorithmRS256, now)
+			},
+			wantRotated: true,
+			wantKeys:    2,
+		},
+		{
+			name:      "algorithm_changed",
+			algorithm: jwt.AlgorithmEdDSA,
+			prepare: func(t *testing.T, f *fields) {
+				f.keys = []*models.SigningKey{storedKey(t, jwt.AlgorithmRS256, now.Add(-time.Hour))}
+				f.load()
+				f.expectRotation(jwt.AlgorithmEdDSA, now)
+			},
+			wantRotated: true,
+			wantKeys:    2,
+		},
+		{
+			name:      "retired_key_expired",
+			algorithm: jwt.AlgorithmEdDSA,
+			prepare: func(t *testing.T, f *fields) {
+				var (
+					retired   = storedKey(t, jwt.AlgorithmEdDSA, now.Add(-25*time.Hour))
+					retiredAt = now.Add(-time.Hour)
+					expiresAt = now.Add(-time.Second)
+				)
+
+				retired.RetiredAt = &retiredAt
+				retired.ExpiresAt = &expiresAt
+
+				f.keys = []*models.SigningKey{retired, storedKey(t, jwt.AlgorithmEdDSA, retiredAt)}
+				f.load()
+			},
+			wantKeys: 1,
+		},
+		{
+			name:      "err_delete_expired",
+			algorithm: jwt.AlgorithmEdDSA,
+			prepare: func(t *testing.T, f *fields) {
+				f.storage.On("DeleteExpired", mock.Anything).Once().Return(errors.New("unexpected error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fields{storage: mocks.NewKeyStorage(t)}
+			tt.prepare(t, f)
+
+			var stored = make(map[string]bool, len(f.keys))
+			for _, key := range f.keys {
+				stored[key.ID] = true
+			}
+
+			keys := New(sl.NewDiscardLogger(), f.storage, &Policy{
+				Algorithm:      tt.algorithm,
+				RotationPeriod: rotationPeriod,
+				TokenTTL:       tokenTTL,
+			})
+			keys.now = func() time.Time { return now }
+
+			err := keys.Sync(context.Background())
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			signing, err := keys.SigningKey()
+			require.NoError(t, err)
+			assert.Equal(t, tt.algorithm, signing.Algorithm)
+			assert.Equal(t, tt.wantRotated, !stored[signing.ID])
+
+			set, err := keys.JWKS()
+			require.NoError(t, err)
+			assert.Len(t, set.Keys, tt.wantKeys)
+
+			for _, key := range set.Keys {
+				_, err = keys.Key(key.KeyID)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSigningKeys_Token(t *testing.T) {
+	var (
+		now = time.Now()
+		f   = &fields{storage: mocks.NewKeyStorage(t)}
+	)
+
+	f.load()
+	f.expectRotation(jwt.AlgorithmEdDSA, now)
+
+	keys := New(sl.NewDiscardLogger(), f.storage, &Policy{
+		Algorithm:      jwt.AlgorithmEdDSA,
+		RotationPeriod: rotationPeriod,
+		TokenTTL:       tokenTTL,
+	})
+	keys.now = func() time.Time { return now }
+
+	require.NoError(t, keys.Sync(context.Background()))
+
+	generator := &jwt.TokenGenerator{Keys: keys, TokenTTL: tokenTTL}
+
+	token, err := generator.NewToken(&jwt.Claims{UserID: 1, Login: "user", SessionID: "session"})
+	require.NoError(t, err)
+
+	claims, err := generator.ParseClaims(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user", claims.Login)
+}
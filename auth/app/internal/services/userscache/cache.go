@@ -142,6 +142,11 @@ func (c *Cache) Set(ctx context.Context, user *models.User) (err error) {
 	return nil
 }
 
+// Invalidate removes the user from the cache, so its next lookup reads the storage
+func (c *Cache) Invalidate(login string) {
+	c.invalidate(login)
+}
+
 func (c *Cache) save(key string, item *cacheItem) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
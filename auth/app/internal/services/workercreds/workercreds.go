@@ -0,0 +1,183 @@
+package workercreds
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/credentialsrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/e"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+)
+
+// TokenPrefix distinguishes worker tokens from access tokens of users
+const TokenPrefix = "wrk_"
+
+const tokenBytes = 32
+
+var (
+	ErrInvalidToken       = errors.New("workercreds: invalid worker token")
+	ErrCredentialNotFound = errors.New("workercreds: worker credential not found")
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=CredentialStorage
+type CredentialStorage interface {
+	Save(ctx context.Context, credential *models.WorkerCredential) error
+
+	Credentials(ctx context.Context) (credentials []*models.WorkerCredential, err error)
+
+	CredentialByTokenHash(
+		ctx context.Context,
+		tokenHash string,
+	) (credential *models.WorkerCredential, err error)
+
+	Revoke(ctx context.Context, id uint64) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name=AuditLog
+
+// AuditLog records issued and revoked credentials. The actor is the admin stored in the context
+type AuditLog interface {
+	Record(ctx context.Context, entry *audit.Entry)
+}
+
+type WorkerCredentials struct {
+	log      *slog.Logger
+	storage  CredentialStorage
+	auditLog AuditLog
+}
+
+func New(
+	log *slog.Logger,
+	storage CredentialStorage,
+	auditLog AuditLog,
+) *WorkerCredentials {
+	return &WorkerCredentials{
+		log:      log,
+		storage:  storage,
+		auditLog: auditLog,
+	}
+}
+
+// Create issues a new worker token. The token is returned only once, the storage keeps its hash
+func (w *WorkerCredentials) Create(ctx context.Context, name string) (credential *models.WorkerCredential, token string, err error) {
+	const src = "WorkerCredentials.Create"
+
+	log := w.log.With(
+		slog.String("src", src),
+		slog.String("name", name),
+	)
+
+	token, err = newToken()
+	if err != nil {
+		log.Error("failed to generate worker token", sl.Err(err))
+		return nil, "", e.WrapErr(err, src)
+	}
+
+	credential = &models.WorkerCredential{
+		Name:      name,
+		TokenHash: hashToken(token),
+	}
+
+	err = w.storage.Save(ctx, credential)
+	if err != nil {
+		return nil, "", e.WrapErr(err, src)
+	}
+
+	w.auditLog.Record(ctx, &audit.Entry{
+		Action:     models.AuditWorkerCredentialCreated,
+		TargetType: models.AuditTargetWorkerCredential,
+		TargetID:   audit.TargetID(credential.ID),
+		After:      map[string]string{"name": name},
+	})
+
+	log.Info("worker credential created", slog.Uint64("id", credential.ID))
+
+	return credential, token, nil
+}
+
+func (w *WorkerCredentials) Credentials(ctx context.Context) ([]*models.WorkerCredential, error) {
+	const src = "WorkerCredentials.Credentials"
+
+	credentials, err := w.storage.Credentials(ctx)
+	if err != nil {
+		return nil, e.WrapErr(err, src)
+	}
+
+	return credentials, nil
+}
+
+// Revoke revokes the credential, so its token is not accepted anymore
+//
+// Returns ErrCredentialNotFound, if there is no credential with provided id
+func (w *WorkerCredentials) Revoke(ctx context.Context, id uint64) error {
+	const src = "WorkerCredentials.Revoke"
+
+	err := w.storage.Revoke(ctx, id)
+	if err != nil {
+		if errors.Is(err, credentialsrepo.ErrCredentialNotFound) {
+			return e.WrapErr(ErrCredentialNotFound, src)
+		}
+
+		return e.WrapErr(err, src)
+	}
+
+	w.auditLog.Record(ctx, &audit.Entry{
+		Action:     models.AuditWorkerCredentialRevoked,
+		TargetType: models.AuditTargetWorkerCredential,
+		TargetID:   audit.TargetID(id),
+	})
+
+	w.log.Info("worker credential revoked", slog.String("src", src), slog.Uint64("id", id))
+
+	return nil
+}
+
+// Authenticate returns the credential of the token
+//
+// Returns ErrInvalidToken, if the token was not issued or has been revoked
+func (w *WorkerCredentials) Authenticate(ctx context.Context, token string) (*models.WorkerCredential, error) {
+	const src = "WorkerCredentials.Authenticate"
+
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, e.WrapErr(ErrInvalidToken, src)
+	}
+
+	credential, err := w.storage.CredentialByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, credentialsrepo.ErrCredentialNotFound) {
+			return nil, e.WrapErr(ErrInvalidToken, src)
+		}
+
+		return nil, e.WrapErr(err, src)
+	}
+
+	if credential.RevokedAt != nil {
+		return nil, e.WrapErr(ErrInvalidToken, src)
+	}
+
+	return credential, nil
+}
+
+func newToken() (string, error) {
+	var token = make([]byte, tokenBytes)
+
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
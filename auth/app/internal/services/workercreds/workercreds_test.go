@@ -0,0 +1,211 @@
+package workercreds
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/domain/models"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/repositories/credentialsrepo"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/audit"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/internal/services/workercreds/mocks"
+	"github.com/AleksandrVishniakov/distributed-calculator/auth/app/pkg/sl"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const defaultCredentialID uint64 = 1
+
+type fields struct {
+	storage  *mocks.CredentialStorage
+	auditLog *mocks.AuditLog
+}
+
+func newFields(t *testing.T) *fields {
+	return &fields{
+		storage:  mocks.NewCredentialStorage(t),
+		auditLog: mocks.NewAuditLog(t),
+	}
+}
+
+func (f *fields) service() *WorkerCredentials {
+	return New(sl.NewDiscardLogger(), f.storage, f.auditLog)
+}
+
+// expectRecord expects the audit entry of the action on the default credential
+func (f *fields) expectRecord(action string) {
+	f.auditLog.
+		On("Record", mock.Anything, mock.MatchedBy(func(entry *audit.Entry) bool {
+			return entry.Action == action &&
+				entry.TargetType == models.AuditTargetWorkerCredential &&
+				entry.TargetID == audit.TargetID(defaultCredentialID)
+		})).
+		Once().
+		Return()
+}
+
+func TestWorkerCredentials_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(f *fields)
+
+		wantErr bool
+	}{
+		{
+			name: "created",
+			prepare: func(f *fields) {
+				f.storage.
+					On("Save", mock.Anything, mock.MatchedBy(func(credential *models.WorkerCredential) bool {
+						return credential.Name == "daemon1" && credential.TokenHash != ""
+					})).
+					Once().
+					Run(func(args mock.Arguments) {
+						args.Get(1).(*models.WorkerCredential).ID = defaultCredentialID
+					}).
+					Return(nil)
+				f.expectRecord(models.AuditWorkerCredentialCreated)
+			},
+		},
+		{
+			name: "err_storage",
+			prepare: func(f *fields) {
+				f.storage.
+					On("Save", mock.Anything, mock.AnythingOfType("*models.WorkerCredential")).
+					Once().
+					Return(errors.New("unexpected error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			credential, token, err := f.service().Create(context.Background(), "daemon1")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Contains(t, token, TokenPrefix)
+			assert.Equal(t, hashToken(token), credential.TokenHash)
+		})
+	}
+}
+
+func TestWorkerCredentials_Authenticate(t *testing.T) {
+	const token = TokenPrefix + "token"
+
+	var revokedAt = time.Now()
+
+	tests := []struct {
+		name    string
+		token   string
+		prepare func(f *fields)
+
+		targetErr error
+	}{
+		{
+			name:  "issued_token",
+			token: token,
+			prepare: func(f *fields) {
+				f.storage.
+					On("CredentialByTokenHash", mock.Anything, hashToken(token)).
+					Once().
+					Return(&models.WorkerCredential{ID: defaultCredentialID, Name: "daemon1"}, nil)
+			},
+		},
+		{
+			name:  "err_revoked_token",
+			token: token,
+			prepare: func(f *fields) {
+				f.storage.
+					On("CredentialByTokenHash", mock.Anything, hashToken(token)).
+					Once().
+					Return(&models.WorkerCredential{ID: defaultCredentialID, RevokedAt: &revokedAt}, nil)
+			},
+			targetErr: ErrInvalidToken,
+		},
+		{
+			name:  "err_unknown_token",
+			token: token,
+			prepare: func(f *fields) {
+				f.storage.
+					On("CredentialByTokenHash", mock.Anything, hashToken(token)).
+					Once().
+					Return(nil, credentialsrepo.ErrCredentialNotFound)
+			},
+			targetErr: ErrInvalidToken,
+		},
+		{
+			name:      "err_user_token",
+			token:     "header.payload.signature",
+			prepare:   func(f *fields) {},
+			targetErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			credential, err := f.service().Authenticate(context.Background(), tt.token)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, defaultCredentialID, credential.ID)
+		})
+	}
+}
+
+func TestWorkerCredentials_Revoke(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(f *fields)
+
+		targetErr error
+	}{
+		{
+			name: "revoked",
+			prepare: func(f *fields) {
+				f.storage.On("Revoke", mock.Anything, defaultCredentialID).Once().Return(nil)
+				f.expectRecord(models.AuditWorkerCredentialRevoked)
+			},
+		},
+		{
+			name: "err_unknown_credential",
+			prepare: func(f *fields) {
+				f.storage.
+					On("Revoke", mock.Anything, defaultCredentialID).
+					Once().
+					Return(credentialsrepo.ErrCredentialNotFound)
+			},
+			targetErr: ErrCredentialNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFields(t)
+			tt.prepare(f)
+
+			err := f.service().Revoke(context.Background(), defaultCredentialID)
+			if tt.targetErr != nil {
+				assert.ErrorIs(t, err, tt.targetErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
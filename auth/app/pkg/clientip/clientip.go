@@ -0,0 +1,46 @@
+package clientip
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc/peer"
+)
+
+type ipKey struct{}
+
+// With puts the address of the client into the context
+func With(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ipKey{}, ip)
+}
+
+// From returns the address of the client or an empty string, if it is unknown
+func From(ctx context.Context) string {
+	ip, _ := ctx.Value(ipKey{}).(string)
+	return ip
+}
+
+// FromRequest returns the host of the remote address of the request. Proxy headers are not trusted
+func FromRequest(r *http.Request) string {
+	return host(r.RemoteAddr)
+}
+
+// FromPeer returns the host of the address of the gRPC client
+func FromPeer(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	return host(p.Addr.String())
+}
+
+func host(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
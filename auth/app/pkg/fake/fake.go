@@ -7,7 +7,13 @@ import (
 	"github.com/brianvoe/gofakeit/v6"
 )
 
+// RandPassword returns a password which satisfies the password policy of the service
 func RandPassword() string {
 	rand.NewSource(time.Now().UnixNano())
-	return gofakeit.Password(true, true, true, true, true, rand.Intn(56)+8)
+	return "a1" + gofakeit.Password(true, true, true, true, true, rand.Intn(54)+6)
+}
+
+// RandLogin returns a login which satisfies the login policy of the service
+func RandLogin() string {
+	return gofakeit.LetterN(uint(rand.Intn(29) + 3))
 }
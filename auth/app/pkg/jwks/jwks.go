@@ -0,0 +1,73 @@
+// Package jwks encodes public keys as JSON Web Keys (RFC 7517). Only Ed25519 and RSA keys are supported.
+// The keys are decoded by the package of api-gateway with the same name
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrUnsupportedKey = errors.New("jwks: unsupported key")
+
+// Key is a public key. Parameters of other key types are empty
+type Key struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg"`
+
+	// Curve and X are parameters of OKP keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+
+	// N and E are parameters of RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+// Set is the document published at the JWKS endpoint
+type Set struct {
+	Keys []*Key `json:"keys"`
+}
+
+// FromPublicKey encodes the verification key of a signature algorithm
+func FromPublicKey(keyID string, algorithm string, publicKey crypto.PublicKey) (*Key, error) {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return &Key{
+			KeyType:   "OKP",
+			KeyID:     keyID,
+			Use:       "sig",
+			Algorithm: algorithm,
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	case *rsa.PublicKey:
+		return &Key{
+			KeyType:   "RSA",
+			KeyID:     keyID,
+			Use:       "sig",
+			Algorithm: algorithm,
+			N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, publicKey)
+	}
+}
+
+// Find returns the key with the id
+func (s *Set) Find(keyID string) (*Key, bool) {
+	for _, key := range s.Keys {
+		if key.KeyID == keyID {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
@@ -18,10 +18,24 @@ const (
 type Config struct {
 	Env      Environment   `yaml:"env"`
 	TokenTTL time.Duration `yaml:"tokenTTL"`
-	HTTP     HTTP          `yaml:"http"`
-	GRPC     GRPC          `yaml:"grpc"`
-	Cache    Cache         `yaml:"cache"`
-	DB       Database      `yaml:"db"`
+	// RefreshTokenTTL is the time for which a session lasts after login or the last refresh
+	RefreshTokenTTL time.Duration `yaml:"refreshTokenTTL" env-default:"720h"`
+	JWT             JWT           `yaml:"jwt"`
+	HTTP            HTTP          `yaml:"http"`
+	GRPC            GRPC          `yaml:"grpc"`
+	Cache           Cache         `yaml:"cache"`
+	DB              Database      `yaml:"db"`
+	TLS             TLS           `yaml:"tls"`
+	RateLimit       RateLimit     `yaml:"rate-limit"`
+}
+
+// JWT describes keys which sign access tokens. The keys are stored in the database and published at the JWKS endpoint
+type JWT struct {
+	// Algorithm is EdDSA or RS256
+	Algorithm      string        `yaml:"algorithm" env:"JWT_ALGORITHM" env-default:"EdDSA"`
+	RotationPeriod time.Duration `yaml:"rotation-period" env-default:"720h"`
+	// SyncPeriod is how often the keys are reloaded from the database and rotated when needed
+	SyncPeriod time.Duration `yaml:"sync-period" env-default:"1m"`
 }
 
 type HTTP struct {
@@ -33,6 +47,28 @@ type GRPC struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+// TLS is disabled, if neither certificate nor CA file is set. Files may also be set with environment variables
+type TLS struct {
+	CertFile     string        `yaml:"cert-file" env:"TLS_CERT_FILE"`
+	KeyFile      string        `yaml:"key-file" env:"TLS_KEY_FILE"`
+	CAFile       string        `yaml:"ca-file" env:"TLS_CA_FILE"`
+	ClientAuth   bool          `yaml:"client-auth" env:"TLS_CLIENT_AUTH"`
+	HTTP         bool          `yaml:"http" env:"TLS_HTTP"`
+	ReloadPeriod time.Duration `yaml:"reload-period" env:"TLS_RELOAD_PERIOD" env-default:"1m"`
+}
+
+// RateLimit locks logins and client addresses after failed logins. Zero max failures disable the lockout.
+// Counters are kept in memory, so every replica of the service counts its own failures
+type RateLimit struct {
+	LoginMaxFailures int `yaml:"login-max-failures" env-default:"5"`
+	IPMaxFailures    int `yaml:"ip-max-failures" env-default:"20"`
+	// Lockout is the duration of the first lockout. Every next failure doubles it up to MaxLockout
+	Lockout    time.Duration `yaml:"lockout" env-default:"1m"`
+	MaxLockout time.Duration `yaml:"max-lockout" env-default:"1h"`
+	// Window is the time without failures after which failures are forgotten
+	Window time.Duration `yaml:"window" env-default:"1h"`
+}
+
 type Cache struct {
 	TTL     time.Duration `yaml:"ttl"`
 	MaxSize int           `yaml:"max-size"`
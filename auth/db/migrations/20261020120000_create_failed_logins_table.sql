@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS failed_logins (
+    id BIGSERIAL PRIMARY KEY,
+    login VARCHAR(64) NOT NULL,
+    ip VARCHAR(64) NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS failed_logins_login_idx ON failed_logins (login, created_at);
+CREATE INDEX IF NOT EXISTS failed_logins_ip_idx ON failed_logins (ip, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS failed_logins;
+-- +goose StatementEnd
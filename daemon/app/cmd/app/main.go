@@ -3,50 +3,105 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/servers/grpcsrv"
 	"google.golang.org/grpc"
 	"log"
 	"net"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/executors_pool"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/identity"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/operations"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/orchestrator_conn"
 	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/orhestrator_pinger"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/task_puller"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/pkg/tlsconfig"
 )
 
 func main() {
 	ctx := context.Background()
 	wg := &sync.WaitGroup{}
 
-	id, err := strconv.Atoi(os.Getenv("DAEMON_ID"))
+	executors, err := strconv.Atoi(os.Getenv("MAX_GOROUTINES"))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	executors, err := strconv.Atoi(os.Getenv("MAX_GOROUTINES"))
+	period, err := strconv.Atoi(os.Getenv("PING_PERIOD_MS"))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	period, err := strconv.Atoi(os.Getenv("PING_PERIOD_MS"))
+	mode, err := executors_pool.ParseExecutionMode(os.Getenv("EXECUTION_MODE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	registry, err := operationsRegistry(os.Getenv("PRECISION_BITS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pull, err := pullWorkMode(os.Getenv("WORK_MODE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tlsReloader, err := tlsconfig.Load(tlsconfig.FromEnv(os.Getenv))
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	tlsReloadPeriod, err := strconv.Atoi(envOrDefault("TLS_RELOAD_PERIOD_MS", "60000"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tlsReloader.Watch(ctx, time.Duration(tlsReloadPeriod)*time.Millisecond, func(err error) {
+		log.Printf("tls certificates reload error: %s", err.Error())
+	})
+
 	poolManager := executors_pool.NewManager(executors)
 	defer poolManager.Shutdown()
 
-	//handler := handlers.NewHTTPHandler(os.Getenv("ORCHESTRATOR_HOST"), poolManager)
+	//handler := handlers.NewHTTPHandler(dialer, poolManager, registry, mode)
 	//server := httpsrv.NewHTTPServer(os.Getenv("HTTP_PORT"), handler.InitRoutes())
 
+	capabilities, err := daemonCapabilities(registry, os.Getenv("PRECISION_BITS"), os.Getenv("MAX_OPERAND"), os.Getenv("DAEMON_LABELS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	identityStore, err := identity.Load(stateFilePath(os.Getenv("DAEMON_STATE_PATH")))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dialer := &orchestrator_conn.Dialer{
+		Host:                 os.Getenv("ORCHESTRATOR_HOST"),
+		WorkerToken:          os.Getenv("WORKER_TOKEN"),
+		Identity:             identityStore,
+		TransportCredentials: tlsReloader.ClientCredentials(),
+	}
+
+	daemonHost, err := advertisedHost(os.Getenv("DAEMON_HOST"), os.Getenv("GRPC_PORT"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	pinger, err := orhestrator_pinger.NewOrchestratorPinger(
 		ctx,
-		uint64(id),
-		os.Getenv("DAEMON_HOST"),
-		os.Getenv("ORCHESTRATOR_HOST"),
+		identityStore,
+		daemonHost,
+		dialer,
 		executors,
+		capabilities,
+		pull,
 	)
 
 	if err != nil {
@@ -58,8 +113,34 @@ func main() {
 		time.Duration(period)*time.Millisecond,
 	)
 
-	gRPCServer := grpc.NewServer()
-	grpcsrv.Register(gRPCServer, os.Getenv("ORCHESTRATOR_HOST"), poolManager)
+	if pull {
+		wait, err := strconv.Atoi(envOrDefault("PULL_WAIT_MS", "20000"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		puller, err := task_puller.NewTaskPuller(
+			ctx,
+			identityStore,
+			dialer,
+			poolManager,
+			registry,
+			mode,
+			executors,
+			time.Duration(wait)*time.Millisecond,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Println("acquiring tasks from", os.Getenv("ORCHESTRATOR_HOST"))
+		puller.Run(ctx)
+
+		return
+	}
+
+	gRPCServer := grpc.NewServer(grpc.Creds(tlsReloader.ServerCredentials()))
+	grpcsrv.Register(gRPCServer, dialer, poolManager, registry, mode)
 
 	//go func() {
 	//	log.Println("server started on port", os.Getenv("HTTP_PORT"))
@@ -83,3 +164,107 @@ func main() {
 
 	wg.Wait()
 }
+
+func operationsRegistry(precisionBits string) (*operations.Registry, error) {
+	if precisionBits == "" {
+		return operations.DefaultRegistry(), nil
+	}
+
+	precision, err := strconv.ParseUint(precisionBits, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return operations.PreciseRegistry(uint(precision)), nil
+}
+
+// daemonCapabilities builds capabilities advertised to the orchestrator.
+// labels are passed in form "key1=value1,key2=value2"
+func daemonCapabilities(
+	registry *operations.Registry,
+	precisionBits string,
+	maxOperand string,
+	labels string,
+) (*dto.CapabilitiesDTO, error) {
+	var capabilities = &dto.CapabilitiesDTO{
+		Operations:     registry.Types(),
+		Domains:        []string{"real"},
+		PrecisionModes: []string{"float64"},
+		Labels:         map[string]string{},
+	}
+
+	if precisionBits != "" {
+		capabilities.PrecisionModes = []string{"bigfloat-" + precisionBits}
+	}
+
+	if maxOperand != "" {
+		value, err := strconv.ParseFloat(maxOperand, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		capabilities.MaxOperand = value
+	}
+
+	for _, label := range strings.Split(labels, ",") {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(label, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid daemon label: %s", label)
+		}
+
+		capabilities.Labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return capabilities, nil
+}
+
+// pullWorkMode reports whether the daemon acquires tasks by itself (WORK_MODE=pull)
+// or waits for the orchestrator to call it (WORK_MODE=push, default)
+func pullWorkMode(mode string) (bool, error) {
+	switch mode {
+	case "", "push":
+		return false, nil
+	case orhestrator_pinger.PullWorkerMode:
+		return true, nil
+	}
+
+	return false, fmt.Errorf("unknown work mode: %s", mode)
+}
+
+func envOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+const defaultStateFilePath = "daemon-identity.json"
+
+func stateFilePath(path string) string {
+	if path == "" {
+		return defaultStateFilePath
+	}
+
+	return path
+}
+
+// advertisedHost returns the address the orchestrator should use to reach the daemon.
+// Without DAEMON_HOST the container hostname is used, so scaled daemons get distinct addresses
+func advertisedHost(host string, port string) (string, error) {
+	if host != "" {
+		return host, nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(hostname, port), nil
+}
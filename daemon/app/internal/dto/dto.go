@@ -15,11 +15,73 @@ type CalculationRequestDTO struct {
 }
 
 type OrchestratorPingDTO struct {
-	ID        uint64 `json:"id"`
-	Url       string `json:"url"`
-	Executors int    `json:"executors"`
+	ID           uint64           `json:"id"`
+	Url          string           `json:"url"`
+	Executors    int              `json:"executors"`
+	Capabilities *CapabilitiesDTO `json:"capabilities"`
+}
+
+type CapabilitiesDTO struct {
+	Operations     []operations.OperationType `json:"operations,omitempty"`
+	Domains        []string                   `json:"domains,omitempty"`
+	PrecisionModes []string                   `json:"precisionModes,omitempty"`
+	MaxOperand     float64                    `json:"maxOperand,omitempty"`
+	Labels         map[string]string          `json:"labels,omitempty"`
 }
 
 type CalculationResultDTO struct {
 	Result float64 `json:"result"`
 }
+
+type AcquireTasksRequestDTO struct {
+	WorkerId uint64 `json:"workerId"`
+	Max      int    `json:"max"`
+	WaitMS   int64  `json:"waitMS"`
+}
+
+type AcquireTasksResponseDTO struct {
+	Tasks []*LeasedTaskDTO `json:"tasks"`
+}
+
+type LeasedTaskDTO struct {
+	Id             uint64                   `json:"id"`
+	UserID         uint64                   `json:"userId"`
+	First          float64                  `json:"first"`
+	Second         float64                  `json:"second"`
+	Operation      operations.OperationType `json:"operation"`
+	DurationMS     int64                    `json:"durationMS"`
+	LeaseExpiresAt time.Time                `json:"leaseExpiresAt"`
+}
+
+// SubtreeNodeDTO is a node of an expression sub-tree sent by the orchestrator.
+// Nodes without children are already calculated and carry their result in Value
+type SubtreeNodeDTO struct {
+	Id         uint64                   `json:"id"`
+	Operation  operations.OperationType `json:"operation"`
+	DurationMS int64                    `json:"durationMS"`
+	Value      float64                  `json:"value"`
+	Left       *SubtreeNodeDTO          `json:"left,omitempty"`
+	Right      *SubtreeNodeDTO          `json:"right,omitempty"`
+}
+
+type SubtreeRequestDTO struct {
+	UserID uint64          `json:"userId"`
+	Root   *SubtreeNodeDTO `json:"root"`
+}
+
+type SubtreeResponseDTO struct {
+	Ok bool `json:"ok"`
+}
+
+type NodeResultDTO struct {
+	Id            uint64  `json:"id"`
+	Result        float64 `json:"result"`
+	ComputeTimeUS int64   `json:"computeTimeUS"`
+}
+
+type SubtreeResultDTO struct {
+	RootId   uint64           `json:"rootId"`
+	Results  []*NodeResultDTO `json:"results"`
+	FailedId uint64           `json:"failedId,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
@@ -6,20 +6,28 @@ import (
 
 	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/executors_pool"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/operations"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/orchestrator_conn"
 )
 
 type HTTPHandler struct {
-	orchestratorHost string
-	poolManager      *executors_pool.PoolManager
+	dialer      *orchestrator_conn.Dialer
+	poolManager *executors_pool.PoolManager
+	registry    *operations.Registry
+	mode        executors_pool.ExecutionMode
 }
 
 func NewHTTPHandler(
-	orchestratorHost string,
+	dialer *orchestrator_conn.Dialer,
 	poolManager *executors_pool.PoolManager,
+	registry *operations.Registry,
+	mode executors_pool.ExecutionMode,
 ) *HTTPHandler {
 	return &HTTPHandler{
-		orchestratorHost: orchestratorHost,
-		poolManager:      poolManager,
+		dialer:      dialer,
+		poolManager: poolManager,
+		registry:    registry,
+		mode:        mode,
 	}
 }
 
@@ -42,7 +50,7 @@ func (h *HTTPHandler) calculate(w http.ResponseWriter, r *http.Request) {
 
 	pool := h.poolManager.Pool(requestDTO.UserID)
 
-	executor, err := executors_pool.NewCalculationExecutor(r.Context(), requestDTO, h.orchestratorHost)
+	executor, err := executors_pool.NewCalculationExecutor(r.Context(), requestDTO, h.dialer, h.registry, h.mode)
 	if err != nil {
 		dto.NewResponseError(http.StatusInternalServerError, err.Error()).Abort(w)
 		return
@@ -3,32 +3,51 @@ package grpcsrv
 import (
 	"context"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"strconv"
 	"time"
 
 	daemonsrv "github.com/AleksandrVishniakov/dc-protos/gen/go/daemon/v1"
 	dtos "github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/executors_pool"
 	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/operations"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/orchestrator_conn"
+	"github.com/AleksandrVishniakov/distributed-calculator/protocol/taskmd"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type Server struct {
 	daemonsrv.UnimplementedDaemonServer
 
-	orchestratorGRPCHost string
-	poolManager          *executors_pool.PoolManager
+	dialer      *orchestrator_conn.Dialer
+	poolManager *executors_pool.PoolManager
+	registry    *operations.Registry
+	mode        executors_pool.ExecutionMode
 }
 
 func Register(
 	gRPCServer *grpc.Server,
-	orchestratorHost string,
+	dialer *orchestrator_conn.Dialer,
 	poolManager *executors_pool.PoolManager,
+	registry *operations.Registry,
+	mode executors_pool.ExecutionMode,
 ) {
-	daemonsrv.RegisterDaemonServer(gRPCServer, &Server{
-		orchestratorGRPCHost: orchestratorHost,
-		poolManager:          poolManager,
-	})
+	server := &Server{
+		dialer:      dialer,
+		poolManager: poolManager,
+		registry:    registry,
+		mode:        mode,
+	}
+
+	daemonsrv.RegisterDaemonServer(gRPCServer, server)
+	gRPCServer.RegisterService(&subtreesServiceDesc, server)
+
+	// the orchestrator probes daemons with the standard health checking protocol
+	// to detect daemons which can reach it, but can not be dialed back
+	grpc_health_v1.RegisterHealthServer(gRPCServer, health.NewServer())
 }
 
 func (s *Server) CalculateTask(ctx context.Context, dto *daemonsrv.CalculationRequestDTO) (*daemonsrv.CalculationResponseDTO, error) {
@@ -37,11 +56,11 @@ func (s *Server) CalculateTask(ctx context.Context, dto *daemonsrv.CalculationRe
 	executor, err := executors_pool.NewCalculationExecutor(ctx, &dtos.CalculationRequestDTO{
 		ID:        dto.Id,
 		UserID:    dto.UserID,
-		First:     float64(dto.First),
-		Second:    float64(dto.Second),
+		First:     operand(ctx, taskmd.First, dto.First),
+		Second:    operand(ctx, taskmd.Second, dto.Second),
 		Operation: operations.OperationType(dto.Operation),
 		Duration:  time.Duration(dto.Duration) * time.Millisecond,
-	}, s.orchestratorGRPCHost)
+	}, s.dialer, s.registry, s.mode)
 
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
@@ -51,3 +70,23 @@ func (s *Server) CalculateTask(ctx context.Context, dto *daemonsrv.CalculationRe
 
 	return &daemonsrv.CalculationResponseDTO{Ok: true}, nil
 }
+
+// operand returns the float64 operand sent in metadata or the float32 operand of the request without it
+func operand(ctx context.Context, key string, fallback float32) float64 {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return float64(fallback)
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return float64(fallback)
+	}
+
+	value, err := strconv.ParseFloat(values[0], 64)
+	if err != nil {
+		return float64(fallback)
+	}
+
+	return value
+}
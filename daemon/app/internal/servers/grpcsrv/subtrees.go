@@ -0,0 +1,74 @@
+package grpcsrv
+
+import (
+	"context"
+
+	dtos "github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/executors_pool"
+	_ "github.com/AleksandrVishniakov/distributed-calculator/daemon/app/pkg/jsoncodec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	subtreesServiceName    = "daemon.v1.Subtrees"
+	calculateSubtreeMethod = "/" + subtreesServiceName + "/CalculateSubtree"
+)
+
+type SubtreesServer interface {
+	CalculateSubtree(ctx context.Context, request *dtos.SubtreeRequestDTO) (*dtos.SubtreeResponseDTO, error)
+}
+
+var subtreesServiceDesc = grpc.ServiceDesc{
+	ServiceName: subtreesServiceName,
+	HandlerType: (*SubtreesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CalculateSubtree",
+			Handler:    calculateSubtreeHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "subtrees.go",
+}
+
+func calculateSubtreeHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var request = &dtos.SubtreeRequestDTO{}
+
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(SubtreesServer).CalculateSubtree(ctx, request)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: calculateSubtreeMethod,
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(SubtreesServer).CalculateSubtree(ctx, req.(*dtos.SubtreeRequestDTO))
+	}
+
+	return interceptor(ctx, request, info, handler)
+}
+
+func (s *Server) CalculateSubtree(ctx context.Context, request *dtos.SubtreeRequestDTO) (*dtos.SubtreeResponseDTO, error) {
+	if request.Root == nil {
+		return nil, status.Error(codes.InvalidArgument, "empty subtree")
+	}
+
+	pool := s.poolManager.Pool(request.UserID)
+
+	executor, err := executors_pool.NewSubtreeExecutor(ctx, request.Root, s.dialer, s.registry, s.mode)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	pool.Run(executor)
+
+	return &dtos.SubtreeResponseDTO{Ok: true}, nil
+}
@@ -2,17 +2,44 @@ package executors_pool
 
 import (
 	"context"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"fmt"
 	"log"
-	"sync"
+	"strconv"
 	"time"
 
 	orchestrator "github.com/AleksandrVishniakov/dc-protos/gen/go/orchestrator/v1"
 	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
 	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/operations"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/orchestrator_conn"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/pkg/jsoncodec"
+	"github.com/AleksandrVishniakov/distributed-calculator/protocol/taskmd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type ExecutionMode string
+
+const (
+	// ModeReal reports the result as soon as the operation is computed
+	ModeReal ExecutionMode = "real"
+
+	// ModeThrottle holds the result until the orchestrator-provided duration has passed
+	ModeThrottle ExecutionMode = "throttle"
 )
 
+func ParseExecutionMode(mode string) (ExecutionMode, error) {
+	switch ExecutionMode(mode) {
+	case "", ModeReal:
+		return ModeReal, nil
+	case ModeThrottle:
+		return ModeThrottle, nil
+	}
+
+	return "", fmt.Errorf("unknown execution mode: %s", mode)
+}
+
 type CalculationExecutor struct {
 	id        uint64
 	first     float64
@@ -20,19 +47,21 @@ type CalculationExecutor struct {
 	operation operations.OperationType
 	duration  time.Duration
 
+	mode     ExecutionMode
+	registry *operations.Registry
+
+	cc     *grpc.ClientConn
 	client orchestrator.OrchestratorClient
 }
 
 func NewCalculationExecutor(
 	ctx context.Context,
 	request *dto.CalculationRequestDTO,
-	orchestratorGRPCHost string,
+	dialer *orchestrator_conn.Dialer,
+	registry *operations.Registry,
+	mode ExecutionMode,
 ) (*CalculationExecutor, error) {
-	cc, err := grpc.DialContext(
-		ctx,
-		orchestratorGRPCHost,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	cc, err := dialer.Dial(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -44,63 +73,130 @@ func NewCalculationExecutor(
 		operation: request.Operation,
 		duration:  request.Duration,
 
+		mode:     mode,
+		registry: registry,
+
+		cc:     cc,
 		client: orchestrator.NewOrchestratorClient(cc),
 	}, nil
 }
 
 func (e *CalculationExecutor) Task(ctx context.Context) {
-	e.sendStartingRequest(ctx)
-
-	var result float64
-
-	switch e.operation {
-	case operations.Plus:
-		result = e.first + e.second
-	case operations.Minus:
-		result = e.first - e.second
-	case operations.Multiply:
-		result = e.first * e.second
-	case operations.Divide:
-		result = e.first / e.second
+	if !e.sendStartingRequest(ctx) {
+		return
 	}
 
-	wg := sync.WaitGroup{}
+	result, computeTime, err := e.calculate(ctx)
+	if ctx.Err() != nil {
+		return
+	}
 
-	wg.Add(1)
-	time.AfterFunc(e.duration, func() {
-		defer wg.Done()
-		e.sendResultRequest(ctx, result)
-	})
+	if err != nil {
+		log.Printf("task %d calculation error: %s", e.id, err.Error())
+		e.sendFailureRequest(ctx, err)
+		return
+	}
+
+	if e.mode == ModeThrottle && computeTime < e.duration {
+		timer := time.NewTimer(e.duration - computeTime)
 
-	wg.Wait()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+
+	e.sendResultRequest(ctx, result, computeTime)
 }
 
-func (e *CalculationExecutor) sendStartingRequest(ctx context.Context) {
-	log.Println("starting request")
+func (e *CalculationExecutor) calculate(ctx context.Context) (result float64, computeTime time.Duration, err error) {
+	operation, err := e.registry.Operation(e.operation)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	startedAt := time.Now()
+
+	result, err = operation.Calculate(ctx, e.first, e.second)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return result, time.Since(startedAt), nil
+}
+
+// sendStartingRequest reports that the task has started. Returns false if the task must be dropped
+func (e *CalculationExecutor) sendStartingRequest(ctx context.Context) bool {
 	resp, err := e.client.StartTask(ctx, &orchestrator.TaskStartingRequest{
 		Id: e.id,
 	})
 
 	if err != nil {
-		log.Fatal("starting request err: ", err)
+		logRequestError(e.id, "starting", err)
+		return false
 	}
 
 	if !resp.Ok {
-		log.Fatal("starting request is not ok")
+		log.Printf("task %d starting request is not ok, dropping task", e.id)
+		return false
 	}
+
+	return true
 }
 
-func (e *CalculationExecutor) sendResultRequest(ctx context.Context, result float64) {
+func (e *CalculationExecutor) sendResultRequest(ctx context.Context, result float64, computeTime time.Duration) {
+	ctx = metadata.AppendToOutgoingContext(
+		ctx,
+		taskmd.ComputeTime, strconv.FormatInt(computeTime.Microseconds(), 10),
+		taskmd.Result, strconv.FormatFloat(result, 'g', -1, 64),
+	)
+
 	resp, err := e.client.SendTaskResult(ctx, &orchestrator.TaskResultRequest{
 		Id:     e.id,
 		Result: float32(result),
 	})
 
 	if err != nil {
-		log.Fatal("starting request err: ", err)
+		logRequestError(e.id, "result", err)
+		return
 	}
 
 	if !resp.Ok {
-		log.Fatal("starting request is not ok")
+		log.Printf("task %d result request is not ok, dropping task", e.id)
+	}
+}
+
+// sendFailureRequest reports the calculation error as a failed sub-tree of a single operation,
+// so the orchestrator marks the task and its expression as failed
+func (e *CalculationExecutor) sendFailureRequest(ctx context.Context, calculationErr error) {
+	var response = &dto.SubtreeResponseDTO{}
+
+	err := e.cc.Invoke(ctx, sendSubtreeResultMethod, &dto.SubtreeResultDTO{
+		RootId:   e.id,
+		Results:  []*dto.NodeResultDTO{},
+		FailedId: e.id,
+		Error:    calculationErr.Error(),
+	}, response, grpc.CallContentSubtype(jsoncodec.Name))
+	if err != nil {
+		log.Printf("task %d failure request error: %s", e.id, err.Error())
+		return
+	}
+
+	if !response.Ok {
+		log.Printf("task %d failure request is not ok", e.id)
+	}
+}
+
+// logRequestError logs a failed request about the task. The orchestrator rejects requests about tasks
+// which are stale, reassigned to another worker or already finished, such tasks are dropped,
+// the daemon itself keeps serving other tasks
+func logRequestError(id uint64, request string, err error) {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied, codes.NotFound, codes.FailedPrecondition:
+		log.Printf("task %d %s request rejected, dropping task: %s", id, request, err.Error())
+	default:
+		log.Printf("task %d %s request error: %s", id, request, err.Error())
 	}
 }
@@ -0,0 +1,144 @@
+package executors_pool
+
+import (
+	"context"
+	"log"
+	"time"
+
+	orchestrator "github.com/AleksandrVishniakov/dc-protos/gen/go/orchestrator/v1"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/operations"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/orchestrator_conn"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/pkg/jsoncodec"
+	"google.golang.org/grpc"
+)
+
+// sendSubtreeResultMethod is served by the orchestrator
+const sendSubtreeResultMethod = "/orchestrator.v1.Subtrees/SendSubtreeResult"
+
+// SubtreeExecutor calculates a whole sub-tree locally and sends all intermediate results in one message
+type SubtreeExecutor struct {
+	root *dto.SubtreeNodeDTO
+
+	mode     ExecutionMode
+	registry *operations.Registry
+
+	cc     *grpc.ClientConn
+	client orchestrator.OrchestratorClient
+}
+
+func NewSubtreeExecutor(
+	ctx context.Context,
+	root *dto.SubtreeNodeDTO,
+	dialer *orchestrator_conn.Dialer,
+	registry *operations.Registry,
+	mode ExecutionMode,
+) (*SubtreeExecutor, error) {
+	cc, err := dialer.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SubtreeExecutor{
+		root:     root,
+		mode:     mode,
+		registry: registry,
+		cc:       cc,
+		client:   orchestrator.NewOrchestratorClient(cc),
+	}, nil
+}
+
+func (e *SubtreeExecutor) Task(ctx context.Context) {
+	resp, err := e.client.StartTask(ctx, &orchestrator.TaskStartingRequest{
+		Id: e.root.Id,
+	})
+	if err != nil {
+		log.Printf("subtree %d starting request error: %s", e.root.Id, err.Error())
+		return
+	}
+
+	if !resp.Ok {
+		log.Printf("subtree %d starting request is not ok", e.root.Id)
+		return
+	}
+
+	var result = &dto.SubtreeResultDTO{
+		RootId:  e.root.Id,
+		Results: []*dto.NodeResultDTO{},
+	}
+
+	_, err = e.calculate(ctx, e.root, result)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	var response = &dto.SubtreeResponseDTO{}
+
+	err = e.cc.Invoke(ctx, sendSubtreeResultMethod, result, response, grpc.CallContentSubtype(jsoncodec.Name))
+	if err != nil {
+		log.Printf("subtree %d result request error: %s", e.root.Id, err.Error())
+		return
+	}
+
+	if !response.Ok {
+		log.Printf("subtree %d result request is not ok", e.root.Id)
+	}
+}
+
+// calculate evaluates node after its children and appends every operation result to result.
+// In ModeThrottle every operation takes at least its duration, as if it was calculated separately
+func (e *SubtreeExecutor) calculate(ctx context.Context, node *dto.SubtreeNodeDTO, result *dto.SubtreeResultDTO) (float64, error) {
+	if node.Left == nil || node.Right == nil {
+		return node.Value, nil
+	}
+
+	first, err := e.calculate(ctx, node.Left, result)
+	if err != nil {
+		return 0, err
+	}
+
+	second, err := e.calculate(ctx, node.Right, result)
+	if err != nil {
+		return 0, err
+	}
+
+	operation, err := e.registry.Operation(node.Operation)
+	if err != nil {
+		result.FailedId = node.Id
+		return 0, err
+	}
+
+	startedAt := time.Now()
+
+	value, err := operation.Calculate(ctx, first, second)
+	if err != nil {
+		result.FailedId = node.Id
+		return 0, err
+	}
+
+	computeTime := time.Since(startedAt)
+	duration := time.Duration(node.DurationMS) * time.Millisecond
+
+	if e.mode == ModeThrottle && computeTime < duration {
+		timer := time.NewTimer(duration - computeTime)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		}
+	}
+
+	result.Results = append(result.Results, &dto.NodeResultDTO{
+		Id:            node.Id,
+		Result:        value,
+		ComputeTimeUS: computeTime.Microseconds(),
+	})
+
+	return value, nil
+}
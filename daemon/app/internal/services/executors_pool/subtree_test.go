@@ -0,0 +1,165 @@
+package executors_pool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/operations"
+)
+
+func leaf(value float64) *dto.SubtreeNodeDTO {
+	return &dto.SubtreeNodeDTO{Value: value}
+}
+
+func node(id uint64, operation operations.OperationType, left *dto.SubtreeNodeDTO, right *dto.SubtreeNodeDTO) *dto.SubtreeNodeDTO {
+	return &dto.SubtreeNodeDTO{Id: id, Operation: operation, Left: left, Right: right}
+}
+
+func TestSubtreeExecutor_Calculate(t *testing.T) {
+	type Test struct {
+		name     string
+		root     *dto.SubtreeNodeDTO
+		expected float64
+		results  map[uint64]float64
+		order    []uint64
+		failedId uint64
+		err      error
+	}
+
+	var tt = []Test{
+		{
+			name:     "leaf",
+			root:     leaf(5),
+			expected: 5,
+			results:  map[uint64]float64{},
+		},
+		{
+			name:     "single_operation",
+			root:     node(1, operations.Multiply, leaf(2), leaf(3)),
+			expected: 6,
+			results:  map[uint64]float64{1: 6},
+			order:    []uint64{1},
+		},
+		{
+			// (2 + 3) * (10 - 4) / 4
+			name: "nested_operations",
+			root: node(5, operations.Divide,
+				node(3, operations.Multiply,
+					node(1, operations.Plus, leaf(2), leaf(3)),
+					node(2, operations.Minus, leaf(10), leaf(4)),
+				),
+				leaf(4),
+			),
+			expected: 7.5,
+			results:  map[uint64]float64{1: 5, 2: 6, 3: 30, 5: 7.5},
+			order:    []uint64{1, 2, 3, 5},
+		},
+		{
+			// (1 + 1) + 1 / (2 - 2)
+			name: "division_by_zero",
+			root: node(4, operations.Plus,
+				node(1, operations.Plus, leaf(1), leaf(1)),
+				node(3, operations.Divide, leaf(1), node(2, operations.Minus, leaf(2), leaf(2))),
+			),
+			expected: math.Inf(1),
+			results:  map[uint64]float64{1: 2, 2: 0, 3: math.Inf(1), 4: math.Inf(1)},
+			order:    []uint64{1, 2, 3, 4},
+		},
+		{
+			name:     "unknown_operation",
+			root:     node(2, operations.Plus, node(1, operations.OperationType(100), leaf(1), leaf(1)), leaf(1)),
+			results:  map[uint64]float64{},
+			failedId: 1,
+			err:      operations.ErrUnknownOperation,
+		},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				executor = &SubtreeExecutor{root: test.root, mode: ModeReal, registry: operations.DefaultRegistry()}
+				result   = &dto.SubtreeResultDTO{RootId: test.root.Id, Results: []*dto.NodeResultDTO{}}
+			)
+
+			value, err := executor.calculate(context.Background(), test.root, result)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, but got %v", test.err, err)
+			}
+
+			if err == nil && value != test.expected {
+				t.Fatalf("expected %v, but got %v", test.expected, value)
+			}
+
+			if result.FailedId != test.failedId {
+				t.Fatalf("expected %v, but got %v", test.failedId, result.FailedId)
+			}
+
+			if len(result.Results) != len(test.results) {
+				t.Fatalf("expected %v, but got %v", len(test.results), len(result.Results))
+			}
+
+			for i, nodeResult := range result.Results {
+				if nodeResult.Id != test.order[i] {
+					t.Fatalf("expected %v, but got %v", test.order[i], nodeResult.Id)
+				}
+
+				if nodeResult.Result != test.results[nodeResult.Id] {
+					t.Fatalf("expected %v, but got %v", test.results[nodeResult.Id], nodeResult.Result)
+				}
+			}
+		})
+	}
+}
+
+func TestSubtreeExecutor_CalculateThrottle(t *testing.T) {
+	var (
+		duration = 30 * time.Millisecond
+		root     = node(2, operations.Plus, node(1, operations.Plus, leaf(1), leaf(1)), leaf(1))
+	)
+
+	root.DurationMS = duration.Milliseconds()
+	root.Left.DurationMS = duration.Milliseconds()
+
+	executor := &SubtreeExecutor{root: root, mode: ModeThrottle, registry: operations.DefaultRegistry()}
+
+	startedAt := time.Now()
+
+	value, err := executor.calculate(context.Background(), root, &dto.SubtreeResultDTO{})
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	if value != 3 {
+		t.Fatalf("expected %v, but got %v", 3, value)
+	}
+
+	// every operation takes its own duration
+	if elapsed := time.Since(startedAt); elapsed < 2*duration {
+		t.Fatalf("expected %v, but got %v", 2*duration, elapsed)
+	}
+}
+
+func TestSubtreeExecutor_CalculateCancelled(t *testing.T) {
+	var root = node(1, operations.Plus, leaf(1), leaf(1))
+	root.DurationMS = time.Minute.Milliseconds()
+
+	executor := &SubtreeExecutor{root: root, mode: ModeThrottle, registry: operations.DefaultRegistry()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var result = &dto.SubtreeResultDTO{Results: []*dto.NodeResultDTO{}}
+
+	_, err := executor.calculate(ctx, root, result)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, but got %v", context.DeadlineExceeded, err)
+	}
+
+	if len(result.Results) != 0 {
+		t.Fatalf("expected %v, but got %v", 0, len(result.Results))
+	}
+}
@@ -0,0 +1,88 @@
+package identity
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Identity is the orchestrator-issued id and registration secret of a daemon.
+// It is persisted locally, so a restarted daemon keeps its id
+type Identity struct {
+	ID     uint64 `json:"id"`
+	Secret string `json:"secret"`
+}
+
+type Store struct {
+	mu   *sync.RWMutex
+	path string
+
+	identity Identity
+}
+
+// Load reads the identity from path. A missing file means the daemon is not registered yet
+func Load(path string) (*Store, error) {
+	var store = &Store{
+		mu:   &sync.RWMutex{},
+		path: path,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &store.identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (s *Store) Identity() Identity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.identity
+}
+
+// Save replaces the identity and writes it to the file
+func (s *Store) Save(identity Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(identity)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		err = os.MkdirAll(dir, 0o700)
+		if err != nil {
+			return err
+		}
+	}
+
+	tmpPath := s.path + ".tmp"
+
+	err = os.WriteFile(tmpPath, data, 0o600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		return err
+	}
+
+	s.identity = identity
+
+	return nil
+}
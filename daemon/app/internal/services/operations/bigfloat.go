@@ -0,0 +1,51 @@
+package operations
+
+import (
+	"context"
+	"math"
+	"math/big"
+)
+
+// PreciseRegistry returns a registry computing every operation with math/big floats
+// of the given precision (in bits). It is slower than DefaultRegistry, but does not
+// accumulate float64 rounding errors inside a single operation
+func PreciseRegistry(precision uint) *Registry {
+	r := NewRegistry()
+
+	r.Register(Plus, bigFloatOperation(precision, (*big.Float).Add))
+	r.Register(Minus, bigFloatOperation(precision, (*big.Float).Sub))
+	r.Register(Multiply, bigFloatOperation(precision, (*big.Float).Mul))
+	r.Register(Divide, bigFloatOperation(precision, (*big.Float).Quo))
+
+	return r
+}
+
+func bigFloatOperation(precision uint, fn func(z, x, y *big.Float) *big.Float) Operation {
+	return OperationFunc(func(ctx context.Context, first float64, second float64) (result float64, err error) {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		if math.IsNaN(first) || math.IsNaN(second) {
+			return math.NaN(), nil
+		}
+
+		// big.Float panics with big.ErrNaN on operations like Inf - Inf
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(big.ErrNaN); !ok {
+					panic(r)
+				}
+
+				result, err = math.NaN(), nil
+			}
+		}()
+
+		x := new(big.Float).SetPrec(precision).SetFloat64(first)
+		y := new(big.Float).SetPrec(precision).SetFloat64(second)
+
+		result, _ = fn(new(big.Float).SetPrec(precision), x, y).Float64()
+
+		return result, nil
+	})
+}
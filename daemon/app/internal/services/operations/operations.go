@@ -1,5 +1,14 @@
 package operations
 
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+var ErrUnknownOperation = errors.New("operations: unknown operation")
+
 type OperationType int
 
 const (
@@ -8,3 +17,89 @@ const (
 	Multiply
 	Divide
 )
+
+// Operation is a single computation the daemon is able to run.
+// Implementations may be arbitrarily expensive and should respect ctx cancellation
+type Operation interface {
+	Calculate(ctx context.Context, first float64, second float64) (float64, error)
+}
+
+// OperationFunc adapts an ordinary function to the Operation interface
+type OperationFunc func(ctx context.Context, first float64, second float64) (float64, error)
+
+func (f OperationFunc) Calculate(ctx context.Context, first float64, second float64) (float64, error) {
+	return f(ctx, first, second)
+}
+
+type Registry struct {
+	mu         *sync.RWMutex
+	operations map[OperationType]Operation
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		mu:         &sync.RWMutex{},
+		operations: make(map[OperationType]Operation),
+	}
+}
+
+// DefaultRegistry returns a registry with plain float64 arithmetic for all basic operations
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	r.Register(Plus, OperationFunc(func(_ context.Context, first float64, second float64) (float64, error) {
+		return first + second, nil
+	}))
+
+	r.Register(Minus, OperationFunc(func(_ context.Context, first float64, second float64) (float64, error) {
+		return first - second, nil
+	}))
+
+	r.Register(Multiply, OperationFunc(func(_ context.Context, first float64, second float64) (float64, error) {
+		return first * second, nil
+	}))
+
+	r.Register(Divide, OperationFunc(func(_ context.Context, first float64, second float64) (float64, error) {
+		return first / second, nil
+	}))
+
+	return r
+}
+
+// Register sets the implementation of operationType, replacing the previous one
+func (r *Registry) Register(operationType OperationType, operation Operation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.operations[operationType] = operation
+}
+
+// Operation returns the implementation of operationType
+//
+// Returns ErrUnknownOperation, if nothing is registered for operationType
+func (r *Registry) Operation(operationType OperationType) (Operation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	operation, ok := r.operations[operationType]
+	if !ok {
+		return nil, ErrUnknownOperation
+	}
+
+	return operation, nil
+}
+
+// Types returns all registered operation types in ascending order
+func (r *Registry) Types() []OperationType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var types = make([]OperationType, 0, len(r.operations))
+	for operationType := range r.operations {
+		types = append(types, operationType)
+	}
+
+	slices.Sort(types)
+
+	return types
+}
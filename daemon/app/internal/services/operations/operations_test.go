@@ -0,0 +1,140 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestRegistry_Operation(t *testing.T) {
+	type Test struct {
+		name      string
+		operation OperationType
+		first     float64
+		second    float64
+		expected  float64
+		err       error
+	}
+
+	var tt = []Test{
+		{name: "plus", operation: Plus, first: 2, second: 3, expected: 5},
+		{name: "minus", operation: Minus, first: 2, second: 3, expected: -1},
+		{name: "multiply", operation: Multiply, first: 2, second: 3, expected: 6},
+		{name: "divide", operation: Divide, first: 3, second: 2, expected: 1.5},
+		{name: "divide_negative", operation: Divide, first: -1, second: 4, expected: -0.25},
+		{name: "division_by_zero", operation: Divide, first: 1, second: 0, expected: math.Inf(1)},
+		{name: "negative_division_by_zero", operation: Divide, first: -1, second: 0, expected: math.Inf(-1)},
+		{name: "zero_by_zero", operation: Divide, first: 0, second: 0, expected: math.NaN()},
+		{name: "unknown_operation", operation: OperationType(100), first: 1, second: 1, err: ErrUnknownOperation},
+	}
+
+	var registries = map[string]*Registry{
+		"default": DefaultRegistry(),
+		"precise": PreciseRegistry(256),
+	}
+
+	for registryName, registry := range registries {
+		for _, test := range tt {
+			t.Run(registryName+"_"+test.name, func(t *testing.T) {
+				result, err := calculate(registry, test.operation, test.first, test.second)
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected %v, but got %v", test.err, err)
+				}
+
+				if math.IsNaN(test.expected) {
+					if !math.IsNaN(result) {
+						t.Fatalf("expected %v, but got %v", test.expected, result)
+					}
+
+					return
+				}
+
+				if result != test.expected {
+					t.Fatalf("expected %v, but got %v", test.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestPreciseRegistry(t *testing.T) {
+	type Test struct {
+		name      string
+		operation OperationType
+		first     float64
+		second    float64
+		expected  float64
+	}
+
+	var tt = []Test{
+		{name: "one_third", operation: Divide, first: 1, second: 3, expected: 1.0 / 3},
+		{name: "small_sum", operation: Plus, first: 0.1, second: 0.2, expected: 0.30000000000000004},
+		{name: "large_product", operation: Multiply, first: 1e200, second: 1e200, expected: math.Inf(1)},
+		{name: "infinity_difference", operation: Minus, first: math.Inf(1), second: math.Inf(1), expected: math.NaN()},
+	}
+
+	var registry = PreciseRegistry(256)
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := calculate(registry, test.operation, test.first, test.second)
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			if math.IsNaN(test.expected) {
+				if !math.IsNaN(result) {
+					t.Fatalf("expected %v, but got %v", test.expected, result)
+				}
+
+				return
+			}
+
+			if result != test.expected {
+				t.Fatalf("expected %v, but got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestPreciseRegistry_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	operation, err := PreciseRegistry(256).Operation(Plus)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	_, err = operation.Calculate(ctx, 1, 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, but got %v", context.Canceled, err)
+	}
+}
+
+func TestRegistry_Types(t *testing.T) {
+	var registry = NewRegistry()
+
+	registry.Register(Divide, OperationFunc(func(_ context.Context, first float64, second float64) (float64, error) {
+		return 0, nil
+	}))
+	registry.Register(Plus, OperationFunc(func(_ context.Context, first float64, second float64) (float64, error) {
+		return 0, nil
+	}))
+
+	var expected = []OperationType{Plus, Divide}
+	if types := registry.Types(); !slices.Equal(types, expected) {
+		t.Fatalf("expected %v, but got %v", expected, types)
+	}
+}
+
+func calculate(registry *Registry, operationType OperationType, first float64, second float64) (float64, error) {
+	operation, err := registry.Operation(operationType)
+	if err != nil {
+		return 0, err
+	}
+
+	return operation.Calculate(context.Background(), first, second)
+}
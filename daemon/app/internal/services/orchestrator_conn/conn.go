@@ -0,0 +1,86 @@
+package orchestrator_conn
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/identity"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	// AuthorizationMetadataKey carries the worker token in every call to the orchestrator
+	AuthorizationMetadataKey = "authorization"
+
+	// WorkerIdMetadataKey and WorkerSecretMetadataKey carry the identity issued by the orchestrator.
+	// They are sent with every call, so the orchestrator accepts results only for tasks assigned to this daemon
+	WorkerIdMetadataKey     = "x-worker-id"
+	WorkerSecretMetadataKey = "x-worker-secret"
+)
+
+// Dialer connects to the orchestrator on behalf of the daemon
+type Dialer struct {
+	// Host is the gRPC address of the orchestrator
+	Host string
+
+	// WorkerToken is either the shared WORKER_TOKEN or a worker credential issued by the auth service
+	WorkerToken string
+
+	// Identity stores the identity issued by the orchestrator on registration
+	Identity *identity.Store
+
+	// TransportCredentials secure connections to the orchestrator. Connections are insecure without them
+	TransportCredentials credentials.TransportCredentials
+}
+
+// Dial connects to the orchestrator. Every call made through the connection is authenticated
+// with the worker token and the current daemon identity
+func (d *Dialer) Dial(ctx context.Context) (*grpc.ClientConn, error) {
+	transportCredentials := d.TransportCredentials
+	if transportCredentials == nil {
+		transportCredentials = insecure.NewCredentials()
+	}
+
+	return grpc.DialContext(
+		ctx,
+		d.Host,
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithPerRPCCredentials(&workerCredentials{token: d.WorkerToken, identity: d.Identity}),
+	)
+}
+
+type workerCredentials struct {
+	token    string
+	identity *identity.Store
+}
+
+func (c *workerCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	var md = map[string]string{}
+
+	if c.token != "" {
+		md[AuthorizationMetadataKey] = "Bearer " + c.token
+	}
+
+	if c.identity == nil {
+		return md, nil
+	}
+
+	current := c.identity.Identity()
+
+	if current.ID != 0 {
+		md[WorkerIdMetadataKey] = strconv.FormatUint(current.ID, 10)
+	}
+
+	if current.Secret != "" {
+		md[WorkerSecretMetadataKey] = current.Secret
+	}
+
+	return md, nil
+}
+
+func (c *workerCredentials) RequireTransportSecurity() bool {
+	return false
+}
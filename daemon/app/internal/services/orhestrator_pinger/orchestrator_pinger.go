@@ -2,47 +2,74 @@ package orhestrator_pinger
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	orchestrator "github.com/AleksandrVishniakov/dc-protos/gen/go/orchestrator/v1"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/identity"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/orchestrator_conn"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// CapabilitiesMetadataKey is the outgoing gRPC metadata key which carries
+	// JSON encoded daemon capabilities together with a register request
+	CapabilitiesMetadataKey = "x-worker-capabilities"
+
+	// WorkerIdMetadataKey and WorkerSecretMetadataKey carry the identity issued by the orchestrator.
+	// The secret is sent back by orchestrator_conn on every following call
+	WorkerIdMetadataKey     = orchestrator_conn.WorkerIdMetadataKey
+	WorkerSecretMetadataKey = orchestrator_conn.WorkerSecretMetadataKey
+
+	// WorkerModeMetadataKey tells the orchestrator that the daemon acquires tasks by itself
+	// and must not be called with them
+	WorkerModeMetadataKey = "x-worker-mode"
+	PullWorkerMode        = "pull"
 )
 
 type OrchestratorPinger struct {
-	id               uint64
+	identity         *identity.Store
 	client           orchestrator.OrchestratorClient
 	host             string
 	executors        int
 	orchestratorHost string
-	requestJSON      []byte
+	capabilities     []byte
+	pull             bool
 }
 
 func NewOrchestratorPinger(
 	ctx context.Context,
-	id uint64,
+	identityStore *identity.Store,
 	host string,
-	gRPCHost string,
+	dialer *orchestrator_conn.Dialer,
 	executors int,
+	capabilities *dto.CapabilitiesDTO,
+	pull bool,
 ) (*OrchestratorPinger, error) {
-	cc, err := grpc.DialContext(
-		ctx,
-		gRPCHost,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	capabilitiesJSON, err := json.Marshal(capabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := dialer.Dial(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
 	pinger := &OrchestratorPinger{
-		client:    orchestrator.NewOrchestratorClient(cc),
-		id:        id,
-		executors: executors,
-		host:      host,
+		client:       orchestrator.NewOrchestratorClient(cc),
+		identity:     identityStore,
+		executors:    executors,
+		host:         host,
+		capabilities: capabilitiesJSON,
+		pull:         pull,
 	}
 
 	time.AfterFunc(2*time.Second, func() {
@@ -77,11 +104,24 @@ func (d *OrchestratorPinger) MustPingOrchestrator(
 }
 
 func (d *OrchestratorPinger) SendPing(ctx context.Context) error {
+	current := d.identity.Identity()
+
+	ctx = metadata.AppendToOutgoingContext(
+		ctx,
+		CapabilitiesMetadataKey, string(d.capabilities),
+	)
+
+	if d.pull {
+		ctx = metadata.AppendToOutgoingContext(ctx, WorkerModeMetadataKey, PullWorkerMode)
+	}
+
+	var header metadata.MD
+
 	resp, err := d.client.RegisterWorker(ctx, &orchestrator.WorkerRegisterRequest{
-		Id:        d.id,
+		Id:        current.ID,
 		Url:       d.host,
 		Executors: uint32(d.executors),
-	})
+	}, grpc.Header(&header))
 
 	if err != nil {
 		return fmt.Errorf("grpc client error: %w", err)
@@ -91,5 +131,31 @@ func (d *OrchestratorPinger) SendPing(ctx context.Context) error {
 		return fmt.Errorf("grpc client error: response is not ok")
 	}
 
-	return nil
+	return d.saveIssuedIdentity(current, header)
+}
+
+// saveIssuedIdentity persists the id and secret sent by the orchestrator, if they differ from the current ones
+func (d *OrchestratorPinger) saveIssuedIdentity(current identity.Identity, header metadata.MD) error {
+	var issued = current
+
+	if values := header.Get(WorkerIdMetadataKey); len(values) > 0 {
+		id, err := strconv.ParseUint(values[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid worker id issued: %w", err)
+		}
+
+		issued.ID = id
+	}
+
+	if values := header.Get(WorkerSecretMetadataKey); len(values) > 0 {
+		issued.Secret = values[0]
+	}
+
+	if issued == current {
+		return nil
+	}
+
+	log.Printf("registered as worker %d", issued.ID)
+
+	return d.identity.Save(issued)
 }
@@ -0,0 +1,166 @@
+package task_puller
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/executors_pool"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/identity"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/operations"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/orchestrator_conn"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/pkg/jsoncodec"
+
+	"google.golang.org/grpc"
+)
+
+// acquireTasksMethod is served by the orchestrator task queue
+const acquireTasksMethod = "/orchestrator.v1.TaskQueue/AcquireTasks"
+
+const (
+	retryDelay = 2 * time.Second
+	busyDelay  = 200 * time.Millisecond
+)
+
+// TaskPuller acquires tasks from the orchestrator instead of waiting for them to be pushed,
+// so the daemon needs only outbound connections
+type TaskPuller struct {
+	cc       *grpc.ClientConn
+	identity *identity.Store
+
+	dialer      *orchestrator_conn.Dialer
+	poolManager *executors_pool.PoolManager
+	registry    *operations.Registry
+	mode        executors_pool.ExecutionMode
+
+	executors int
+	wait      time.Duration
+	inFlight  *atomic.Int64
+}
+
+func NewTaskPuller(
+	ctx context.Context,
+	identityStore *identity.Store,
+	dialer *orchestrator_conn.Dialer,
+	poolManager *executors_pool.PoolManager,
+	registry *operations.Registry,
+	mode executors_pool.ExecutionMode,
+	executors int,
+	wait time.Duration,
+) (*TaskPuller, error) {
+	cc, err := dialer.Dial(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &TaskPuller{
+		cc:          cc,
+		identity:    identityStore,
+		dialer:      dialer,
+		poolManager: poolManager,
+		registry:    registry,
+		mode:        mode,
+		executors:   executors,
+		wait:        wait,
+		inFlight:    &atomic.Int64{},
+	}, nil
+}
+
+// Run acquires tasks until ctx is done. It waits for the first registration,
+// because tasks are leased to the orchestrator-issued id
+func (p *TaskPuller) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		current := p.identity.Identity()
+		if current.ID == 0 {
+			sleep(ctx, retryDelay)
+			continue
+		}
+
+		free := p.executors - int(p.inFlight.Load())
+		if free <= 0 {
+			sleep(ctx, busyDelay)
+			continue
+		}
+
+		tasks, err := p.acquire(ctx, current, free)
+		if err != nil {
+			log.Printf("tasks acquiring error: %s", err.Error())
+			sleep(ctx, retryDelay)
+			continue
+		}
+
+		for _, task := range tasks {
+			err = p.run(ctx, task)
+			if err != nil {
+				log.Printf("task %d starting error: %s", task.Id, err.Error())
+			}
+		}
+	}
+}
+
+func (p *TaskPuller) acquire(ctx context.Context, current identity.Identity, max int) ([]*dto.LeasedTaskDTO, error) {
+	var response = &dto.AcquireTasksResponseDTO{}
+
+	err := p.cc.Invoke(ctx, acquireTasksMethod, &dto.AcquireTasksRequestDTO{
+		WorkerId: current.ID,
+		Max:      max,
+		WaitMS:   p.wait.Milliseconds(),
+	}, response, grpc.CallContentSubtype(jsoncodec.Name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Tasks, nil
+}
+
+func (p *TaskPuller) run(ctx context.Context, task *dto.LeasedTaskDTO) error {
+	executor, err := executors_pool.NewCalculationExecutor(ctx, &dto.CalculationRequestDTO{
+		ID:        task.Id,
+		UserID:    task.UserID,
+		First:     task.First,
+		Second:    task.Second,
+		Operation: task.Operation,
+		Duration:  time.Duration(task.DurationMS) * time.Millisecond,
+	}, p.dialer, p.registry, p.mode)
+
+	if err != nil {
+		return err
+	}
+
+	p.inFlight.Add(1)
+
+	p.poolManager.Pool(task.UserID).Run(&trackedExecutor{
+		executor: executor,
+		done: func() {
+			p.inFlight.Add(-1)
+		},
+	})
+
+	return nil
+}
+
+// trackedExecutor reports to the puller when a task is over, so it knows how many executors are free
+type trackedExecutor struct {
+	executor executors_pool.Executor
+	done     func()
+}
+
+func (e *trackedExecutor) Task(ctx context.Context) {
+	defer e.done()
+
+	e.executor.Task(ctx)
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,281 @@
+package task_puller
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/dto"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/executors_pool"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/identity"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/operations"
+	"github.com/AleksandrVishniakov/distributed-calculator/daemon/app/internal/services/orchestrator_conn"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// orchestrator serves AcquireTasks with handle and rejects every other method as a stale task
+type orchestrator struct {
+	handle   func(request *dto.AcquireTasksRequestDTO) (*dto.AcquireTasksResponseDTO, error)
+	requests chan *dto.AcquireTasksRequestDTO
+}
+
+func newOrchestrator(t *testing.T, handle func(request *dto.AcquireTasksRequestDTO) (*dto.AcquireTasksResponseDTO, error)) (*orchestrator, string) {
+	var o = &orchestrator{
+		handle:   handle,
+		requests: make(chan *dto.AcquireTasksRequestDTO, 100),
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	server := grpc.NewServer(grpc.UnknownServiceHandler(o.serve))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return o, listener.Addr().String()
+}
+
+func (o *orchestrator) serve(_ any, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	if method != acquireTasksMethod {
+		return status.Error(codes.NotFound, "task not found")
+	}
+
+	var request = &dto.AcquireTasksRequestDTO{}
+
+	err := stream.RecvMsg(request)
+	if err != nil {
+		return err
+	}
+
+	o.requests <- request
+
+	response, err := o.handle(request)
+	if err != nil {
+		return err
+	}
+
+	return stream.SendMsg(response)
+}
+
+func newTaskPuller(t *testing.T, host string, executors int) *TaskPuller {
+	store, err := identity.Load(filepath.Join(t.TempDir(), "identity.json"))
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	err = store.Save(identity.Identity{ID: 7, Secret: "secret"})
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	puller, err := NewTaskPuller(
+		context.Background(),
+		store,
+		&orchestrator_conn.Dialer{Host: host, Identity: store},
+		executors_pool.NewManager(executors),
+		operations.DefaultRegistry(),
+		executors_pool.ModeReal,
+		executors,
+		time.Second,
+	)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	return puller
+}
+
+func noTasks(_ *dto.AcquireTasksRequestDTO) (*dto.AcquireTasksResponseDTO, error) {
+	return &dto.AcquireTasksResponseDTO{Tasks: []*dto.LeasedTaskDTO{}}, nil
+}
+
+func TestTaskPuller_Acquire(t *testing.T) {
+	var expected = []*dto.LeasedTaskDTO{
+		{Id: 1, UserID: 2, First: 0.1, Second: 0.2, Operation: operations.Plus, DurationMS: 100},
+		{Id: 3, UserID: 2, First: 1, Second: 3, Operation: operations.Divide},
+	}
+
+	o, host := newOrchestrator(t, func(_ *dto.AcquireTasksRequestDTO) (*dto.AcquireTasksResponseDTO, error) {
+		return &dto.AcquireTasksResponseDTO{Tasks: expected}, nil
+	})
+
+	puller := newTaskPuller(t, host, 4)
+
+	tasks, err := puller.acquire(context.Background(), puller.identity.Identity(), 3)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	request := <-o.requests
+	if *request != (dto.AcquireTasksRequestDTO{WorkerId: 7, Max: 3, WaitMS: 1000}) {
+		t.Fatalf("expected %v, but got %v", dto.AcquireTasksRequestDTO{WorkerId: 7, Max: 3, WaitMS: 1000}, *request)
+	}
+
+	if len(tasks) != len(expected) {
+		t.Fatalf("expected %v, but got %v", len(expected), len(tasks))
+	}
+
+	for i, task := range tasks {
+		if *task != *expected[i] {
+			t.Fatalf("expected %v, but got %v", *expected[i], *task)
+		}
+	}
+}
+
+func TestTaskPuller_RunRequestsFreeExecutors(t *testing.T) {
+	type Test struct {
+		name      string
+		executors int
+		inFlight  int64
+		expected  int
+	}
+
+	var tt = []Test{
+		{name: "idle", executors: 3, expected: 3},
+		{name: "partly_busy", executors: 3, inFlight: 2, expected: 1},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			o, host := newOrchestrator(t, noTasks)
+
+			puller := newTaskPuller(t, host, test.executors)
+			puller.inFlight.Store(test.inFlight)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			go puller.Run(ctx)
+
+			select {
+			case request := <-o.requests:
+				if request.Max != test.expected {
+					t.Fatalf("expected %v, but got %v", test.expected, request.Max)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected %v, but got %v", "acquire request", "timeout")
+			}
+		})
+	}
+}
+
+func TestTaskPuller_RunBusy(t *testing.T) {
+	o, host := newOrchestrator(t, noTasks)
+
+	puller := newTaskPuller(t, host, 2)
+	puller.inFlight.Store(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*busyDelay)
+	defer cancel()
+
+	puller.Run(ctx)
+
+	if len(o.requests) != 0 {
+		t.Fatalf("expected %v, but got %v", 0, len(o.requests))
+	}
+}
+
+func TestTaskPuller_RunRetries(t *testing.T) {
+	var calls atomic.Int64
+
+	o, host := newOrchestrator(t, func(_ *dto.AcquireTasksRequestDTO) (*dto.AcquireTasksResponseDTO, error) {
+		if calls.Add(1) == 1 {
+			return nil, status.Error(codes.Unavailable, "orchestrator is unavailable")
+		}
+
+		return noTasks(nil)
+	})
+
+	puller := newTaskPuller(t, host, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startedAt := time.Now()
+
+	go puller.Run(ctx)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-o.requests:
+		case <-time.After(retryDelay + 5*time.Second):
+			t.Fatalf("expected %v, but got %v", "acquire request", "timeout")
+		}
+	}
+
+	if elapsed := time.Since(startedAt); elapsed < retryDelay {
+		t.Fatalf("expected %v, but got %v", retryDelay, elapsed)
+	}
+}
+
+func TestTaskPuller_RunReleasesExecutors(t *testing.T) {
+	o, host := newOrchestrator(t, noTasks)
+
+	puller := newTaskPuller(t, host, 2)
+
+	err := puller.run(context.Background(), &dto.LeasedTaskDTO{Id: 1, UserID: 1, First: 1, Second: 2, Operation: operations.Plus})
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	// the orchestrator rejects the task, so the daemon drops it and frees the executor
+	deadline := time.Now().Add(5 * time.Second)
+	for puller.inFlight.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %v, but got %v", 0, puller.inFlight.Load())
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(o.requests) != 0 {
+		t.Fatalf("expected %v, but got %v", 0, len(o.requests))
+	}
+}
+
+type blockingExecutor struct {
+	release chan struct{}
+}
+
+func (e *blockingExecutor) Task(_ context.Context) {
+	<-e.release
+}
+
+func TestTrackedExecutor(t *testing.T) {
+	var (
+		executor = &blockingExecutor{release: make(chan struct{})}
+		done     = make(chan struct{})
+	)
+
+	tracked := &trackedExecutor{
+		executor: executor,
+		done: func() {
+			close(done)
+		},
+	}
+
+	go tracked.Task(context.Background())
+
+	select {
+	case <-done:
+		t.Fatalf("expected %v, but got %v", "running task", "done")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(executor.release)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected %v, but got %v", "done", "timeout")
+	}
+}
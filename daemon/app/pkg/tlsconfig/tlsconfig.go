@@ -0,0 +1,291 @@
+// Package tlsconfig builds TLS configurations of internal links from certificate files.
+// The files are re-read when they change, so certificates and the CA can be rotated without a restart.
+//
+// It is the gRPC part of the package of api-gateway: daemon has no HTTP server and client
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var ErrNoCertificates = errors.New("tlsconfig: no certificates found in CA file")
+
+type Config struct {
+	// CertFile and KeyFile are the PEM encoded certificate and key presented to peers.
+	// Servers require them, clients present them only for mutual TLS
+	CertFile string
+	KeyFile  string
+	// CAFile verifies peers. Empty file means the system roots
+	CAFile string
+	// ClientAuth makes gRPC servers require client certificates signed by the CA (mutual TLS)
+	ClientAuth bool
+}
+
+// FromEnv reads TLS_CERT_FILE, TLS_KEY_FILE, TLS_CA_FILE and TLS_CLIENT_AUTH
+func FromEnv(getenv func(string) string) *Config {
+	return &Config{
+		CertFile:   getenv("TLS_CERT_FILE"),
+		KeyFile:    getenv("TLS_KEY_FILE"),
+		CAFile:     getenv("TLS_CA_FILE"),
+		ClientAuth: strings.EqualFold(getenv("TLS_CLIENT_AUTH"), "true"),
+	}
+}
+
+// Enabled reports whether TLS is configured. Links stay plaintext otherwise
+func (c *Config) Enabled() bool {
+	return c.CertFile != "" || c.CAFile != ""
+}
+
+// Reloader keeps the certificate and the CA loaded from Config files.
+// A nil Reloader means that TLS is disabled, its credentials are insecure then
+type Reloader struct {
+	config *Config
+
+	mu       *sync.RWMutex
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+// Load reads the files of config. Returns nil Reloader, if TLS is not enabled
+func Load(config *Config) (*Reloader, error) {
+	if !config.Enabled() {
+		return nil, nil
+	}
+
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, errors.New("tlsconfig: both certificate and key files are required")
+	}
+
+	var r = &Reloader{
+		config:   config,
+		mu:       &sync.RWMutex{},
+		modTimes: map[string]time.Time{},
+	}
+
+	_, err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload re-reads the files, if some of them have changed since the last load.
+// The previous certificates are kept, if the new ones can not be loaded
+func (r *Reloader) Reload() (bool, error) {
+	modTimes, changed, err := r.changedFiles()
+	if err != nil || !changed {
+		return false, err
+	}
+
+	var cert *tls.Certificate
+
+	if r.config.CertFile != "" {
+		loaded, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+		if err != nil {
+			return false, fmt.Errorf("tlsconfig: %w", err)
+		}
+
+		cert = &loaded
+	}
+
+	var pool *x509.CertPool
+
+	if r.config.CAFile != "" {
+		data, err := os.ReadFile(r.config.CAFile)
+		if err != nil {
+			return false, fmt.Errorf("tlsconfig: %w", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return false, ErrNoCertificates
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = cert
+	r.pool = pool
+	r.modTimes = modTimes
+
+	return true, nil
+}
+
+func (r *Reloader) changedFiles() (map[string]time.Time, bool, error) {
+	var (
+		modTimes = map[string]time.Time{}
+		changed  bool
+	)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, path := range []string{r.config.CertFile, r.config.KeyFile, r.config.CAFile} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, false, fmt.Errorf("tlsconfig: %w", err)
+		}
+
+		modTimes[path] = info.ModTime()
+
+		if !info.ModTime().Equal(r.modTimes[path]) {
+			changed = true
+		}
+	}
+
+	return modTimes, changed, nil
+}
+
+// Watch calls Reload every period until ctx is done. Errors are passed to onError
+func (r *Reloader) Watch(ctx context.Context, period time.Duration, onError func(err error)) {
+	if r == nil || period <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := r.Reload()
+				if err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, r.pool
+}
+
+// serverConfig returns the configuration of servers. Client certificates are required only if mutualTLS is set.
+// They are verified with the current CA pool, so a rotated CA is used by connections opened after the rotation
+func (r *Reloader) serverConfig(mutualTLS bool) *tls.Config {
+	var config = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			if cert == nil {
+				return nil, errors.New("tlsconfig: server certificate is not configured")
+			}
+
+			return cert, nil
+		},
+	}
+
+	if mutualTLS {
+		// The chain is verified by VerifyPeerCertificate with the current CA pool
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			_, pool := r.current()
+			return verify(rawCerts, pool, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+
+	return config
+}
+
+// clientConfig returns the configuration of clients. The server is verified with the current CA,
+// so a rotated CA is used by connections opened after the rotation
+func (r *Reloader) clientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The chain is verified by VerifyConnection with the current CA pool
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			_, pool := r.current()
+
+			var rawCerts [][]byte
+			for _, cert := range state.PeerCertificates {
+				rawCerts = append(rawCerts, cert.Raw)
+			}
+
+			return verify(rawCerts, pool, state.ServerName, x509.ExtKeyUsageServerAuth)
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+
+			return cert, nil
+		},
+	}
+}
+
+// ServerCredentials returns gRPC server credentials. Mutual TLS follows Config.ClientAuth
+func (r *Reloader) ServerCredentials() credentials.TransportCredentials {
+	if r == nil {
+		return insecure.NewCredentials()
+	}
+
+	return credentials.NewTLS(r.serverConfig(r.config.ClientAuth))
+}
+
+// ClientCredentials returns gRPC client credentials
+func (r *Reloader) ClientCredentials() credentials.TransportCredentials {
+	if r == nil {
+		return insecure.NewCredentials()
+	}
+
+	return credentials.NewTLS(r.clientConfig())
+}
+
+// verify checks the peer chain with roots. Empty dnsName skips the host name check
+func verify(rawCerts [][]byte, roots *x509.CertPool, dnsName string, usage x509.ExtKeyUsage) error {
+	if len(rawCerts) == 0 {
+		return errors.New("tlsconfig: peer has not presented a certificate")
+	}
+
+	var certs []*x509.Certificate
+
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("tlsconfig: %w", err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	var intermediates = x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       dnsName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+
+	return err
+}
@@ -8,9 +8,12 @@ require (
 )
 
 require (
+	github.com/AleksandrVishniakov/distributed-calculator/protocol v0.0.0
 	golang.org/x/net v0.24.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240401170217-c3f982113cda // indirect
 	google.golang.org/protobuf v1.33.0 // indirect
 )
+
+replace github.com/AleksandrVishniakov/distributed-calculator/protocol => ../protocol
@@ -1,4 +1,4 @@
-import React, {forwardRef, useState} from 'react';
+import React, {forwardRef, useEffect, useState} from 'react';
 import './App.css';
 import useSavedState from "./hooks/useSavedState";
 import RegisterScreen from "./components/RegisterScreen/RegisterScreen";
@@ -12,6 +12,9 @@ import {WorkerAPI} from "./api/WorkersAPI";
 import MuiAlert, { AlertProps } from '@mui/material/Alert';
 import Snackbar from '@mui/material/Snackbar';
 
+// Access tokens live for 15 minutes, so they are refreshed in advance
+const tokenRefreshPeriod = 10 * 60 * 1000
+
 enum Screens {
     Home,
     Register,
@@ -83,7 +86,7 @@ function AppNavigation(
 
                     onLogout={()=>{
                         setScreen(Screens.Home)
-                        localStorage.removeItem("token")
+                        authAPI.logout().catch(console.error)
                     }}
                     onError={onError}
                 />
@@ -137,6 +140,26 @@ const App: React.FC<{
         setErrorSnackbarOpen(true)
     }
 
+    useEffect(() => {
+        if (screen !== Screens.Actions) {
+            return
+        }
+
+        const refresh = () => {
+            props.authAPI.refresh().catch((e: Error) => {
+                setScreen(Screens.Home)
+                handleError(e.message)
+            })
+        }
+
+        refresh()
+
+        const interval = setInterval(refresh, tokenRefreshPeriod)
+
+        return () => clearInterval(interval)
+        // eslint-disable-next-line react-hooks/exhaustive-deps
+    }, [screen, props.authAPI])
+
     return (
         <div className={`App ${theme}`}>
             {
@@ -2,6 +2,7 @@ import {APIError} from "./APIError";
 
 interface LoginResponse {
     token: string
+    refreshToken: string
 }
 
 interface RegisterResponse {
@@ -35,9 +36,53 @@ export class AuthAPI {
             throw new Error(apiErrorToString(apiError))
         }
 
-        const token = await response.json() as LoginResponse
+        saveTokens(await response.json() as LoginResponse)
+    }
+
+    // refresh exchanges the saved refresh token for a new pair of tokens, so the short-lived access token does not expire
+    public async refresh() {
+        const url = `${this.host}/api/v1/refresh`
+
+        const response = await fetch(url, {
+            method: "POST",
+            headers: {
+                "Content-Type": "application/json",
+            },
+            body: JSON.stringify({
+                refreshToken: localStorage.getItem("refreshToken") || "",
+            }),
+        })
+
+        if (!response.ok) {
+            const apiError = await response.json() as APIError
+
+            throw new Error(apiErrorToString(apiError))
+        }
+
+        saveTokens(await response.json() as LoginResponse)
+    }
+
+    // logout revokes the session, so its tokens are rejected by the orchestrator too
+    public async logout() {
+        const url = `${this.host}/api/v1/logout`
+        const refreshToken = localStorage.getItem("refreshToken")
 
-        localStorage.setItem("token", token.token)
+        localStorage.removeItem("token")
+        localStorage.removeItem("refreshToken")
+
+        if (!refreshToken) {
+            return
+        }
+
+        await fetch(url, {
+            method: "POST",
+            headers: {
+                "Content-Type": "application/json",
+            },
+            body: JSON.stringify({
+                refreshToken: refreshToken,
+            }),
+        })
     }
 
     public async register(login: string, password: string) {
@@ -63,6 +108,11 @@ export class AuthAPI {
     }
 }
 
+function saveTokens(tokens: LoginResponse) {
+    localStorage.setItem("token", tokens.token)
+    localStorage.setItem("refreshToken", tokens.refreshToken)
+}
+
 function apiErrorToString(apiError: APIError): string {
     return apiError.code + ": " + apiError.message
 }
\ No newline at end of file
@@ -8,6 +8,8 @@ interface Operator {
 export class OperatorsAPI {
     private readonly host: string
 
+    private readonly getToken = () => localStorage.getItem("token") || ""
+
     constructor(host: string = "http://localhost:8000") {
         this.host = host
     }
@@ -19,6 +21,7 @@ export class OperatorsAPI {
             method: "GET",
             headers: {
                 "Content-Type": "application/json",
+                "Authorization": `Bearer ${this.getToken()}`,
             },
         })
 
@@ -54,6 +57,7 @@ export class OperatorsAPI {
             method: "POST",
             headers: {
                 "Content-Type": "application/json",
+                "Authorization": `Bearer ${this.getToken()}`,
             },
             body: JSON.stringify(operators)
         })
@@ -5,6 +5,9 @@ interface Worker {
     url: string
     executors: number
     lastModified: Date
+    state: string
+    inFlight: number
+    unreachable: boolean
 }
 
 interface Task {
@@ -17,6 +20,8 @@ interface Task {
 export class WorkerAPI {
     private readonly host: string;
 
+    private readonly getToken = () => localStorage.getItem("token") || "";
+
     constructor(host: string = "http://localhost:8000") {
         this.host = host;
     }
@@ -28,6 +33,7 @@ export class WorkerAPI {
             method: "GET",
             headers: {
                 "Content-Type": "application/json",
+                "Authorization": `Bearer ${this.getToken()}`,
             }
         })
 
@@ -47,6 +53,7 @@ export class WorkerAPI {
             method: "GET",
             headers: {
                 "Content-Type": "application/json",
+                "Authorization": `Bearer ${this.getToken()}`,
             }
         })
 
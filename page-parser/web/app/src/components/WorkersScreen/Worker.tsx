@@ -14,11 +14,14 @@ interface Props {
     url: string
     executors: number
     lastModified: Date
+    state: string
+    inFlight: number
+    unreachable: boolean
 
     getTasks: () => Promise<Array<Task>>
 }
 
-const Worker: React.FC<Props> = ({id, url, lastModified, executors, getTasks}) => {
+const Worker: React.FC<Props> = ({id, url, lastModified, executors, state, inFlight, unreachable, getTasks}) => {
     const [calculationsOpen, setCalculationsOpen] = React.useState(false);
     const [tasks, setTasks] = React.useState<Task[] | null>(null);
 
@@ -40,6 +43,8 @@ const Worker: React.FC<Props> = ({id, url, lastModified, executors, getTasks}) =
                 <ul>
                     <li>Ссылка: {url}</li>
                     <li>Количество горутин: {executors}</li>
+                    <li>Состояние: {formatState(state)}{unreachable ? " (оркестратор не может подключиться)" : ""}</li>
+                    <li>Задач в работе: {inFlight}</li>
                     <li>Последнее обновление: {new Date(lastModified).toTimeString().split(" ")[0]}</li>
                 </ul>
                 <button
@@ -64,6 +69,23 @@ const Worker: React.FC<Props> = ({id, url, lastModified, executors, getTasks}) =
     )
 }
 
+const formatState = (state: string): string => {
+    switch (state) {
+        case "active":
+            return "активна"
+        case "suspect":
+            return "не отвечает"
+        case "draining":
+            return "завершает работу"
+        case "dead":
+            return "недоступна"
+        case "quarantined":
+            return "на карантине"
+        default:
+            return state
+    }
+}
+
 const formatTask = (task: Task): string => {
     let operator: string
     switch (task.operationType) {
@@ -9,6 +9,9 @@ interface WorkerDTO {
     url: string
     executors: number
     lastModified: Date
+    state: string
+    inFlight: number
+    unreachable: boolean
 }
 
 interface Task {
@@ -56,7 +59,7 @@ const WorkersScreen: React.FC<Props> = ({workersAPI, onError}) => {
     return (
         <section className="WorkersScreen">
             <h2 className="WorkersScreen__title">
-                Активные машины
+                Машины
             </h2>
 
             <div className="WorkersScreen__workers-container">
@@ -68,6 +71,9 @@ const WorkersScreen: React.FC<Props> = ({workersAPI, onError}) => {
                             url={w.url}
                             executors={w.executors}
                             lastModified={w.lastModified}
+                            state={w.state}
+                            inFlight={w.inFlight}
+                            unreachable={w.unreachable}
 
                             getTasks={getTaskCallback(w.id)}
                         />
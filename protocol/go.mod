@@ -0,0 +1,3 @@
+module github.com/AleksandrVishniakov/distributed-calculator/protocol
+
+go 1.22.0
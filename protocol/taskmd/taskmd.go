@@ -0,0 +1,17 @@
+// Package taskmd defines gRPC metadata keys which the orchestrator and daemons send with tasks
+// in addition to the messages of dc-protos
+package taskmd
+
+const (
+	// First and Second carry operands of a task with float64 precision, because operands of dc-protos are float32.
+	// Daemons fall back to the float32 operands without them
+	First  = "x-first"
+	Second = "x-second"
+
+	// Result carries the result of a task with float64 precision, because the result of dc-protos is float32.
+	// The orchestrator falls back to the float32 result without it
+	Result = "x-result"
+
+	// ComputeTime carries the computation time of a task measured by a daemon (in microseconds)
+	ComputeTime = "x-compute-time-us"
+)